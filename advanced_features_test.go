@@ -2,6 +2,7 @@ package xmlparser
 
 import (
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -337,4 +338,372 @@ func TestExtendedBuiltInTypes(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+// Test occurrence bounds on nested sequence and choice groups
+func TestGroupOccurrenceValidation(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="settings">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="name" type="xs:string"/>
+                <xs:sequence minOccurs="1" maxOccurs="2">
+                    <xs:element name="key" type="xs:string"/>
+                    <xs:element name="value" type="xs:string"/>
+                </xs:sequence>
+                <xs:choice minOccurs="0" maxOccurs="2">
+                    <xs:element name="flag" type="xs:string"/>
+                    <xs:element name="option" type="xs:string"/>
+                </xs:choice>
+                <xs:element name="comment" type="xs:string" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+    <xs:element name="marks">
+        <xs:complexType>
+            <xs:sequence minOccurs="2" maxOccurs="3">
+                <xs:element name="d"/>
+                <xs:element name="e" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Single group iteration",
+			xml:        `<settings><name>n</name><key>k</key><value>v</value></settings>`,
+			shouldPass: true,
+		},
+		{
+			name: "Maximum group iterations and choices",
+			xml: `<settings><name>n</name><key>a</key><value>1</value><key>b</key><value>2</value>` +
+				`<option>o</option><flag>f</flag><comment>c</comment></settings>`,
+			shouldPass: true,
+		},
+		{
+			name: "Too many sequence group iterations",
+			xml: `<settings><name>n</name><key>a</key><value>1</value><key>b</key><value>2</value>` +
+				`<key>c</key><value>3</value></settings>`,
+			shouldPass:  false,
+			errorString: "allows at most 2 occurrences of its sequence group, but found 3",
+		},
+		{
+			name:       "Repeated group with optional member",
+			xml:        `<marks><d/><d/><d/></marks>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Repeated group beyond its maxOccurs",
+			xml:         `<marks><d/><d/><d/><d/></marks>`,
+			shouldPass:  false,
+			errorString: "element <marks> allows at most 3 occurrences of its sequence group, but found 4",
+		},
+		{
+			name:        "Missing required sequence group",
+			xml:         `<settings><name>n</name></settings>`,
+			shouldPass:  false,
+			errorString: "requires at least 1 <key> child, but found 0",
+		},
+		{
+			name:        "Incomplete group iteration",
+			xml:         `<settings><name>n</name><key>a</key><value>1</value><key>b</key></settings>`,
+			shouldPass:  false,
+			errorString: "requires at least 1 <value> child, but found 0",
+		},
+		{
+			name: "Too many choice selections",
+			xml: `<settings><name>n</name><key>a</key><value>1</value>` +
+				`<flag>x</flag><flag>y</flag><option>z</option></settings>`,
+			shouldPass:  false,
+			errorString: "choice allows at most 2 selections, but found 3",
+		},
+		{
+			name:        "Choice after trailing element",
+			xml:         `<settings><name>n</name><key>a</key><value>1</value><comment>c</comment><flag>x</flag></settings>`,
+			shouldPass:  false,
+			errorString: "element <flag> is out of order in <settings>: it must appear before <comment>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test that content models are compiled when the schema is parsed and shared by concurrent validations
+func TestContentModelCompilation(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:complexType name="UnusedType">
+        <xs:sequence>
+            <xs:element name="unused" type="xs:string"/>
+        </xs:sequence>
+    </xs:complexType>
+    <xs:complexType name="ItemType">
+        <xs:sequence>
+            <xs:element name="sku" type="xs:string"/>
+        </xs:sequence>
+    </xs:complexType>
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="item" type="ItemType" maxOccurs="unbounded"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	// UnusedType, ItemType and the anonymous order type
+	if count := len(schema.compiled.contentModels); count != 3 {
+		t.Fatalf("Expected 3 content models to be compiled with the schema, got %d", count)
+	}
+	itemType := schema.ComplexTypeMap["ItemType"]
+	if model := schema.contentModel(itemType); model == nil || model != schema.compiled.contentModels[itemType] {
+		t.Errorf("Expected the compiled content model of ItemType to be used")
+	}
+
+	doc, err := Parse([]byte(`<order><item><sku>A1</sku></item><item><sku>B2</sku></item></order>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := schema.Validate(doc); err != nil {
+				t.Errorf("Expected validation to pass, but got error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Test mixed content models
+func TestMixedContentValidation(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="doc">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="para" maxOccurs="unbounded">
+                    <xs:complexType mixed="true">
+                        <xs:sequence>
+                            <xs:element name="b" type="xs:string" minOccurs="0" maxOccurs="unbounded"/>
+                        </xs:sequence>
+                    </xs:complexType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Text interleaved with elements in mixed content",
+			xml:        `<doc><para>Hello <b>bold</b> world <b>again</b>!</para></doc>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Text only in mixed content",
+			xml:        `<doc><para>Just text</para></doc>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Text in element-only content",
+			xml:         `<doc>stray<para>ok</para></doc>`,
+			shouldPass:  false,
+			errorString: "element <doc> contains unexpected text 'stray'",
+		},
+		{
+			name:        "Children of mixed content are still validated",
+			xml:         `<doc><para>Hello <i>italic</i></para></doc>`,
+			shouldPass:  false,
+			errorString: "element <i> is not a valid child of <para>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test that attribute issues are reported in document order with the attribute's own position
+func TestAttributeDiagnosticsOrder(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="item">
+        <xs:complexType>
+            <xs:attribute name="id" type="xs:integer" use="required"/>
+            <xs:attribute name="code" type="xs:string" fixed="A1"/>
+            <xs:attribute name="count" type="xs:positiveInteger"/>
+            <xs:attribute name="name" type="xs:string" use="required"/>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	doc, err := Parse([]byte("<item count=\"0\"\n      extra=\"x\" code=\"B2\"\n      id=\"abc\"/>"))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	validationErr := schema.Validate(doc)
+	if validationErr == nil {
+		t.Fatal("Expected validation to fail")
+	}
+
+	expected := []string{
+		"attribute 'count' in element <item> (line 1, column 7): value '0' must be positive (at /item/@count)",
+		"unexpected attribute 'extra' in element <item> (line 2, column 7) (at /item/@extra)",
+		"attribute 'code' in element <item> (line 2, column 17) has fixed value 'A1', but got 'B2' (at /item/@code)",
+		"attribute 'id' in element <item> (line 3, column 7): value 'abc' is not a valid integer (at /item/@id)",
+		"required attribute 'name' is missing from element <item> (at /item)",
+	}
+	errors := validationErr.(*ValidationError).Messages()
+	if len(errors) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(errors), errors)
+	}
+	for i := range expected {
+		if errors[i] != expected[i] {
+			t.Errorf("Error %d:\n  expected: %s\n  got:      %s", i, expected[i], errors[i])
+		}
+	}
+
+	// Columns count characters, and single-quoted values are skipped correctly
+	doc, err = Parse([]byte(`<item name='é > x' id="1"/>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	positions := doc.Root.AttrPositions
+	if len(positions) != 2 || positions[0] != (Position{Line: 1, Column: 7}) || positions[1] != (Position{Line: 1, Column: 20}) {
+		t.Errorf("Unexpected attribute positions: %v", positions)
+	}
+}
+
+// Test that absent occurrence attributes behave exactly like their default values
+func TestDefaultOccurrenceBounds(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="implicit">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:sequence>
+                    <xs:element name="key" type="xs:string"/>
+                    <xs:element name="value" type="xs:string"/>
+                </xs:sequence>
+                <xs:element name="note" type="xs:int" minOccurs="00"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+    <xs:element name="explicit">
+        <xs:complexType>
+            <xs:sequence minOccurs="1" maxOccurs="1">
+                <xs:sequence minOccurs="1" maxOccurs="1">
+                    <xs:element name="key" type="xs:string" minOccurs="1" maxOccurs="1"/>
+                    <xs:element name="value" type="xs:string" minOccurs="1" maxOccurs="1"/>
+                </xs:sequence>
+                <xs:element name="note" type="xs:int" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{"Implicit single group", `<implicit><key>a</key><value>1</value></implicit>`, true, ""},
+		{"Explicit single group", `<explicit><key>a</key><value>1</value></explicit>`, true, ""},
+		{"Implicit group repeated", `<implicit><key>a</key><value>1</value><key>b</key><value>2</value></implicit>`,
+			false, "element <implicit> allows at most 1 <key> child, but found 2"},
+		{"Explicit group repeated", `<explicit><key>a</key><value>1</value><key>b</key><value>2</value></explicit>`,
+			false, "element <explicit> allows at most 1 <key> child, but found 2"},
+		{"Empty element with padded minOccurs", `<implicit><key>a</key><value>1</value><note/></implicit>`, true, ""},
+		{"Empty element with explicit minOccurs", `<explicit><key>a</key><value>1</value><note/></explicit>`, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+			if validationErr, ok := err.(*ValidationError); ok && len(validationErr.Issues) != 1 {
+				t.Errorf("Expected exactly one error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// builtInXMLAttributes declares the attributes of the XML namespace, which schemas reference
+// as ref="xml:lang" and similar without importing a schema document for them.
+var builtInXMLAttributes = map[string]Attribute{
+	"lang":  {Name: "lang", Type: "xs:language"},
+	"space": {Name: "space", SimpleType: &SimpleType{Restriction: &Restriction{Base: "xs:NCName", Enumeration: []*Facet{{Value: "default"}, {Value: "preserve"}}}}},
+	"base":  {Name: "base", Type: "xs:anyURI"},
+	"id":    {Name: "id", Type: "xs:ID"},
+}
+
+// forEachAttribute calls fn for every attribute declaration in the schema: global
+// attributes and those of global and anonymous complex types at any depth.
+func (s *Schema) forEachAttribute(fn func(attribute *Attribute, global bool)) {
+	for i := range s.Attributes {
+		fn(&s.Attributes[i], true)
+	}
+	s.forEachComplexType(func(complexType *ComplexType) {
+		attributes := complexType.declaredAttributes()
+		for i := range attributes {
+			fn(&attributes[i], false)
+		}
+	})
+}
+
+// assignAttributeForms decides which attribute declarations of a schema document are
+// namespace-qualified: global attributes always are; local ones follow their form attribute,
+// or the document's attributeFormDefault when it is absent.
+func (s *Schema) assignAttributeForms() {
+	s.forEachAttribute(func(attribute *Attribute, global bool) {
+		switch {
+		case attribute.Ref != "":
+			// Takes the form of the referenced global attribute when resolved
+		case global || attribute.Form == "qualified":
+			attribute.qualified = true
+		case attribute.Form == "":
+			attribute.qualified = s.AttributeFormDefault == "qualified"
+		}
+	})
+}
+
+// buildAttributeMap indexes global attributes by namespace and local name.
+func (s *Schema) buildAttributeMap() error {
+	for i := range s.Attributes {
+		attribute := &s.Attributes[i]
+		if attribute.Name == "" {
+			return fmt.Errorf("schema attribute at index %d is missing required 'name' attribute", i)
+		}
+		key := xml.Name{Space: attribute.namespace, Local: attribute.Name}
+		if _, exists := s.attributeNSMap[key]; exists {
+			return fmt.Errorf("duplicate attribute definition: '%s'", attribute.Name)
+		}
+		s.attributeNSMap[key] = attribute
+	}
+	return nil
+}
+
+// resolveAttributeRefs completes attribute declarations given as ref="prefix:name" with the
+// referenced global attribute, resolving the prefix against the schema document's namespace
+// declarations. The use of the reference is kept; its fixed and default values take precedence.
+// Unresolved references keep only their name, so any value of the attribute is accepted.
+func (s *Schema) resolveAttributeRefs() {
+	s.forEachAttribute(func(attribute *Attribute, global bool) {
+		if attribute.Ref == "" || attribute.Name != "" {
+			return
+		}
+
+		qname := s.ResolveQName(attribute.Ref)
+		if qname.Prefix == "xml" {
+			qname.Namespace = xmlNamespace
+		} else if qname.Prefix == "" && qname.Namespace == "" {
+			qname.Namespace = s.TargetNamespace
+		}
+
+		var target Attribute
+		if qname.Namespace == xmlNamespace {
+			target = builtInXMLAttributes[qname.LocalName]
+		} else if global := s.attributeNSMap[xml.Name{Space: qname.Namespace, Local: qname.LocalName}]; global != nil {
+			target = *global
+		}
+
+		attribute.Name = qname.LocalName
+		attribute.Type, attribute.typeRef, attribute.SimpleType = target.Type, target.typeRef, target.SimpleType
+		attribute.qualified, attribute.namespace = qname.Namespace != "", qname.Namespace
+		if attribute.Fixed == "" {
+			attribute.Fixed = target.Fixed
+		}
+		if attribute.Default == "" {
+			attribute.Default = target.Default
+		}
+		if attribute.Annotation == nil {
+			attribute.Annotation = target.Annotation
+		}
+	})
+}
+
+// findAttributeDef returns the definition matching an instance attribute by namespace and
+// local name, or nil. Unqualified declarations match attributes without a namespace only;
+// prohibited attributes match nothing.
+func findAttributeDef(attributeDefs []Attribute, name xml.Name) *Attribute {
+	for i := range attributeDefs {
+		if attributeDefs[i].Name == name.Local && attributeDefs[i].namespace == name.Space && attributeDefs[i].Use != "prohibited" {
+			return &attributeDefs[i]
+		}
+	}
+	return nil
+}
+
+// attributeFormMismatch explains why an attribute matching a declaration by local name only
+// does not match it, or returns "" if no declaration has the attribute's local name.
+func attributeFormMismatch(attributeDefs []Attribute, name xml.Name) string {
+	for i := range attributeDefs {
+		attrDef := &attributeDefs[i]
+		if attrDef.Name != name.Local || attrDef.Use == "prohibited" {
+			continue
+		}
+		if attrDef.namespace == "" {
+			return "it must not be namespace-qualified"
+		}
+		return fmt.Sprintf("it must be in namespace '%s'", attrDef.namespace)
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test namespace-aware attribute matching with attributeFormDefault, form and ref
+func TestAttributeNamespaces(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "attribute_namespaces")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	linkXSD := `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:example:link">
+    <xs:attribute name="href" type="xs:anyURI"/>
+    <xs:attribute name="rel" type="xs:NCName"/>
+</xs:schema>`
+	if err := os.WriteFile(filepath.Join(tempDir, "link.xsd"), []byte(linkXSD), 0644); err != nil {
+		t.Fatalf("Failed to write link.xsd: %v", err)
+	}
+
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+           xmlns:l="urn:example:link"
+           xmlns:d="urn:example:doc"
+           targetNamespace="urn:example:doc"
+           elementFormDefault="qualified">
+    <xs:import namespace="urn:example:link" schemaLocation="link.xsd"/>
+    <xs:attribute name="version" type="xs:int"/>
+    <xs:element name="doc">
+        <xs:complexType>
+            <xs:attribute name="id" type="xs:string" use="required"/>
+            <xs:attribute name="status" type="xs:string" form="qualified"/>
+            <xs:attribute ref="d:version"/>
+            <xs:attribute ref="l:href" use="required"/>
+            <xs:attribute ref="l:rel"/>
+            <xs:attribute ref="xml:lang"/>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`), tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Qualified and unqualified attributes",
+			xml:        `<d:doc xmlns:d="urn:example:doc" xmlns:l="urn:example:link" id="1" d:status="ok" d:version="2" l:href="a.xml" l:rel="next" xml:lang="en"/>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Foreign namespace bound to another prefix",
+			xml:        `<doc xmlns="urn:example:doc" xmlns:x="urn:example:link" id="1" x:href="a.xml"/>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Unqualified attribute given a namespace",
+			xml:         `<d:doc xmlns:d="urn:example:doc" xmlns:l="urn:example:link" d:id="1" l:href="a.xml"/>`,
+			errorString: "unexpected attribute 'id' in element <doc> (line 1, column 61): it must not be namespace-qualified",
+		},
+		{
+			name:        "Qualified attribute without namespace",
+			xml:         `<d:doc xmlns:d="urn:example:doc" xmlns:l="urn:example:link" id="1" l:href="a.xml" status="ok"/>`,
+			errorString: "unexpected attribute 'status' in element <doc> (line 1, column 83): it must be in namespace 'urn:example:doc'",
+		},
+		{
+			name:        "Referenced attribute in the wrong namespace",
+			xml:         `<d:doc xmlns:d="urn:example:doc" id="1" d:href="a.xml"/>`,
+			errorString: "it must be in namespace 'urn:example:link'",
+		},
+		{
+			name:        "Missing required referenced attribute",
+			xml:         `<d:doc xmlns:d="urn:example:doc" id="1"/>`,
+			errorString: "required attribute 'href' is missing from element <doc>",
+		},
+		{
+			name:        "Type of referenced attribute",
+			xml:         `<d:doc xmlns:d="urn:example:doc" xmlns:l="urn:example:link" id="1" l:href="a.xml" d:version="two"/>`,
+			errorString: "attribute 'version' in element <doc>",
+		},
+		{
+			name:        "Type of built-in xml:lang",
+			xml:         `<d:doc xmlns:d="urn:example:doc" xmlns:l="urn:example:link" id="1" l:href="a.xml" xml:lang="not a tag"/>`,
+			errorString: "is not a valid language",
+		},
+		{
+			name:        "Undeclared foreign attribute",
+			xml:         `<d:doc xmlns:d="urn:example:doc" xmlns:l="urn:example:link" xmlns:o="urn:other" id="1" l:href="a.xml" o:extra="x"/>`,
+			errorString: "unexpected attribute 'extra' in element <doc>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+}
+
+// Test that attributeFormDefault="qualified" applies to local attributes without a form
+func TestAttributeFormDefault(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:test" attributeFormDefault="qualified">
+    <xs:element name="item">
+        <xs:complexType>
+            <xs:attribute name="code" type="xs:string"/>
+            <xs:attribute name="note" type="xs:string" form="unqualified"/>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	doc, err := Parse([]byte(`<t:item xmlns:t="urn:test" t:code="A" note="n"/>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		t.Errorf("Expected validation to pass, got: %v", err)
+	}
+
+	doc, err = Parse([]byte(`<t:item xmlns:t="urn:test" code="A"/>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	expectValidationError(t, schema.Validate(doc), "it must be in namespace 'urn:test'")
+
+	// Documents built in code are serialized with a prefix for qualified attributes
+	root := &Node{
+		Name:  xml.Name{Space: "urn:test", Local: "item"},
+		Attrs: []xml.Attr{{Name: xml.Name{Space: "urn:test", Local: "code"}, Value: "A"}},
+	}
+	data, err := (&Document{Root: root}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	reparsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Failed to parse serialized document: %v\n%s", err, data)
+	}
+	if err := schema.Validate(reparsed); err != nil {
+		t.Errorf("Expected serialized document to be valid, got: %v\n%s", err, data)
+	}
+}
@@ -0,0 +1,203 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// RecordResult is the validation result of one record of a batch document.
+type RecordResult struct {
+	Index  int      // Position among the records, starting at 1
+	Node   *Node    // The record element
+	Errors []string // Issues found within the record; empty when it is valid
+}
+
+// Valid reports whether the record has no issues.
+func (r *RecordResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// BatchResult is the per-record validation result of a batch document: a root element
+// (the envelope) whose content is essentially one repeated record element.
+type BatchResult struct {
+	RecordElement string         // Local name of the record elements
+	Records       []RecordResult // One result per record, in document order
+
+	// Issues outside the records: the envelope itself, record counts and order, and
+	// IDREFs that do not resolve anywhere in the document
+	Errors []string
+
+	root   *Node         // Root element of the validated document
+	source *recordSource // Where Split reads records whose content was released, if any
+}
+
+// Valid reports whether the envelope and every record are valid.
+func (b *BatchResult) Valid() bool {
+	return len(b.Errors) == 0 && len(b.InvalidRecords()) == 0
+}
+
+// ValidRecords returns the results of the records without issues.
+func (b *BatchResult) ValidRecords() []RecordResult {
+	return b.filter(true)
+}
+
+// InvalidRecords returns the results of the records with issues.
+func (b *BatchResult) InvalidRecords() []RecordResult {
+	return b.filter(false)
+}
+
+// filter returns the records whose validity is valid.
+func (b *BatchResult) filter(valid bool) []RecordResult {
+	var records []RecordResult
+	for i := range b.Records {
+		if b.Records[i].Valid() == valid {
+			records = append(records, b.Records[i])
+		}
+	}
+	return records
+}
+
+// Summary describes the result in one line, e.g. "4 of 5 <order> records valid, 1 invalid".
+func (b *BatchResult) Summary() string {
+	invalid := len(b.InvalidRecords())
+	summary := fmt.Sprintf("%d of %d <%s> records valid, %d invalid",
+		len(b.Records)-invalid, len(b.Records), b.RecordElement, invalid)
+	if len(b.Errors) > 0 {
+		summary += fmt.Sprintf("; %d envelope issues", len(b.Errors))
+	}
+	return summary
+}
+
+// ValidateRecords validates a batch document and reports the issues of each record
+// separately, so batch processors can accept good records and reject bad ones individually.
+// Records are the children of the root element named recordElement (by local name); when
+// recordElement is empty, the most frequent child name is used. An error is returned only
+// when the document cannot be validated at all, e.g. because its root is not declared.
+func (s *Schema) ValidateRecords(doc *Document, recordElement string, opts ValidateOptions) (*BatchResult, error) {
+	rootDef, rootErr := s.rootDeclaration(doc, opts)
+	if rootErr != nil {
+		return nil, rootErr
+	}
+	if recordElement == "" {
+		recordElement = mostFrequentChild(doc.Root)
+	}
+
+	result := &BatchResult{RecordElement: recordElement, root: doc.Root}
+	for _, child := range doc.Root.Children {
+		if child.Name.Local == recordElement {
+			result.Records = append(result.Records, RecordResult{Index: len(result.Records) + 1, Node: child})
+		}
+	}
+
+	opts.MaxErrors, opts.FailFast = 0, false // Every record is reported on
+	opts.OnIssue = nil                       // Record issues are reported in the result only
+	v := newValidator(s, opts)
+	v.records = make(map[*Node]*RecordResult, len(result.Records))
+	for i := range result.Records {
+		v.records[result.Records[i].Node] = &result.Records[i]
+	}
+
+	errors := v.validateNode(doc.Root, rootDef)
+	errors = append(errors, messagesOf(v.checkIDReferences())...)
+	if len(errors) > 0 {
+		result.Errors = storedMessages(errors)
+	}
+	for i := range result.Records {
+		if len(result.Records[i].Errors) > 0 {
+			result.Records[i].Errors = storedMessages(result.Records[i].Errors)
+		}
+	}
+	return result, nil
+}
+
+// Split divides the batch into two documents wrapped in the original envelope: accepted
+// holds the valid records and rejected the invalid ones, in their original order. The root
+// element's attributes and its children that are not records are copied into both; use
+// Document.Marshal to serialize them. Envelope issues are not considered, so callers that
+// require a valid envelope should check Errors first. The records of a ValidateRecordsAt
+// result are read from its document again; one that can no longer be read is left empty.
+func (b *BatchResult) Split() (accepted, rejected *Document) {
+	records := make(map[*Node]int, len(b.Records)) // Index of each record element's result
+	for i := range b.Records {
+		records[b.Records[i].Node] = i
+	}
+
+	envelope := func(keepValid bool) *Document {
+		root := &Node{
+			Name:          b.root.Name,
+			Attrs:         append([]xml.Attr(nil), b.root.Attrs...),
+			Content:       b.root.Content,
+			Position:      b.root.Position,
+			AttrPositions: append([]Position(nil), b.root.AttrPositions...),
+			scope:         b.root.scope,
+		}
+		for _, child := range b.root.Children {
+			if i, isRecord := records[child]; isRecord {
+				if b.Records[i].Valid() != keepValid {
+					continue
+				}
+				if b.source != nil {
+					if record, err := b.source.read(i); err == nil {
+						child = record
+					}
+				}
+			}
+			root.Children = append(root.Children, cloneNode(child, root))
+		}
+		return &Document{Root: root}
+	}
+	return envelope(true), envelope(false)
+}
+
+// cloneNode returns a deep copy of node attached to parent.
+func cloneNode(node, parent *Node) *Node {
+	clone := &Node{
+		Parent:        parent,
+		Name:          node.Name,
+		Attrs:         append([]xml.Attr(nil), node.Attrs...),
+		Content:       node.Content,
+		Position:      node.Position,
+		AttrPositions: append([]Position(nil), node.AttrPositions...),
+		scope:         node.scope,
+	}
+	for _, child := range node.Children {
+		clone.Children = append(clone.Children, cloneNode(child, clone))
+	}
+	if node.Nodes != nil {
+		// The element nodes are its children, in the same order
+		clone.Nodes = make([]*Node, 0, len(node.Nodes))
+		elements := clone.Children
+		for _, item := range node.Nodes {
+			if item.Kind == ElementNode {
+				clone.Nodes, elements = append(clone.Nodes, elements[0]), elements[1:]
+			} else {
+				clone.Nodes = append(clone.Nodes, &Node{Parent: clone, Kind: item.Kind, Name: item.Name,
+					Content: item.Content, Position: item.Position})
+			}
+		}
+	}
+	return clone
+}
+
+// mostFrequentChild returns the most frequent local name among a node's children,
+// preferring the name that appears first on ties.
+func mostFrequentChild(node *Node) string {
+	names := make([]string, len(node.Children))
+	for i, child := range node.Children {
+		names[i] = child.Name.Local
+	}
+	return mostFrequent(names)
+}
+
+// mostFrequent returns the most frequent of names, preferring the one that appears first on ties.
+func mostFrequent(names []string) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, name := range names {
+		counts[name]++
+		if counts[name] > counts[best] {
+			best = name
+		}
+	}
+	return best
+}
@@ -0,0 +1,153 @@
+package xmlparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test per-record validation of batch documents
+func TestValidateRecords(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="batch">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="header" type="xs:string"/>
+                <xs:element name="order" maxOccurs="unbounded">
+                    <xs:complexType>
+                        <xs:sequence>
+                            <xs:element name="id" type="xs:ID"/>
+                            <xs:element name="quantity" type="xs:positiveInteger"/>
+                        </xs:sequence>
+                    </xs:complexType>
+                </xs:element>
+            </xs:sequence>
+            <xs:attribute name="count" type="xs:integer" use="required"/>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	doc, err := Parse([]byte(`<batch count="x">
+    <header>daily</header>
+    <order><id>o1</id><quantity>5</quantity></order>
+    <order><id>o2</id><quantity>0</quantity></order>
+    <order><id>o3</id><quantity>2</quantity></order>
+    <order><id>o1</id><quantity>1</quantity><note/></order>
+</batch>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	for _, recordElement := range []string{"order", ""} {
+		result, err := schema.ValidateRecords(doc, recordElement, ValidateOptions{})
+		if err != nil {
+			t.Fatalf("ValidateRecords failed: %v", err)
+		}
+
+		if result.RecordElement != "order" || len(result.Records) != 4 {
+			t.Fatalf("Expected 4 <order> records, got %d <%s> records", len(result.Records), result.RecordElement)
+		}
+		var valid []int
+		for _, record := range result.ValidRecords() {
+			valid = append(valid, record.Index)
+		}
+		if !reflect.DeepEqual(valid, []int{1, 3}) {
+			t.Errorf("Expected records 1 and 3 to be valid, got %v", valid)
+		}
+		if errors := result.Records[1].Errors; len(errors) != 1 || errors[0] != "in element <quantity>: value '0' must be positive (at /batch/order[2]/quantity[1])" {
+			t.Errorf("Unexpected errors for record 2: %v", errors)
+		}
+		if errors := result.Records[3].Errors; len(errors) != 2 {
+			t.Errorf("Expected a duplicate ID and an unexpected child in record 4, got: %v", errors)
+		}
+		if len(result.Errors) != 1 {
+			t.Errorf("Expected the invalid count attribute as the only envelope issue, got: %v", result.Errors)
+		}
+		if result.Valid() {
+			t.Error("Expected the batch to be invalid")
+		}
+		if summary := result.Summary(); summary != "2 of 4 <order> records valid, 2 invalid; 1 envelope issues" {
+			t.Errorf("Unexpected summary: %s", summary)
+		}
+	}
+
+	if _, err := schema.ValidateRecords(&Document{Root: &Node{Name: doc.Root.Children[0].Name}}, "", ValidateOptions{}); err == nil {
+		t.Error("Expected error for an undeclared root element")
+	}
+}
+
+// Test splitting a batch into accepted and rejected documents with the original envelope
+func TestBatchSplit(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+           targetNamespace="urn:example:orders" elementFormDefault="qualified">
+    <xs:element name="batch">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="header" type="xs:string"/>
+                <xs:element name="order" type="xs:positiveInteger" minOccurs="0" maxOccurs="unbounded"/>
+            </xs:sequence>
+            <xs:attribute name="sender" type="xs:string"/>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	doc, err := Parse([]byte(`<o:batch xmlns:o="urn:example:orders" sender="A &amp; B">
+    <o:header>daily</o:header>
+    <o:order>1</o:order>
+    <o:order>-2</o:order>
+    <o:order>3</o:order>
+</o:batch>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	result, err := schema.ValidateRecords(doc, "order", ValidateOptions{})
+	if err != nil {
+		t.Fatalf("ValidateRecords failed: %v", err)
+	}
+	accepted, rejected := result.Split()
+
+	expected := map[*Document]string{
+		accepted: `<?xml version="1.0" encoding="UTF-8"?>
+<o:batch xmlns:o="urn:example:orders" sender="A &amp; B">
+  <o:header>daily</o:header>
+  <o:order>1</o:order>
+  <o:order>3</o:order>
+</o:batch>
+`,
+		rejected: `<?xml version="1.0" encoding="UTF-8"?>
+<o:batch xmlns:o="urn:example:orders" sender="A &amp; B">
+  <o:header>daily</o:header>
+  <o:order>-2</o:order>
+</o:batch>
+`,
+	}
+	for part, xml := range expected {
+		data, err := part.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(data) != xml {
+			t.Errorf("Unexpected document:\n%s\nwant:\n%s", data, xml)
+		}
+	}
+
+	// The accepted records form a valid document on their own
+	reparsed, err := Parse([]byte(expected[accepted]))
+	if err != nil {
+		t.Fatalf("Failed to parse accepted document: %v", err)
+	}
+	if err := schema.Validate(reparsed); err != nil {
+		t.Errorf("Expected accepted document to be valid, got: %v", err)
+	}
+	if len(doc.Root.Children) != 4 {
+		t.Error("Split must not modify the original document")
+	}
+}
@@ -0,0 +1,151 @@
+package xmlparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The benchmarks validate the schemas and documents of testdata/bench, and documents
+// generated from them at scale. Profile one with, for example:
+//
+//	go test -run '^$' -bench BenchmarkValidateLarge -benchmem -memprofile mem.out -cpuprofile cpu.out
+//	go tool pprof -sample_index=alloc_space mem.out
+
+const benchCorpus = "testdata/bench"
+
+// benchSchema parses a schema of the benchmark corpus.
+func benchSchema(b *testing.B, name string) *Schema {
+	b.Helper()
+	schema, err := ParseXSDFile(filepath.Join(benchCorpus, name))
+	if err != nil {
+		b.Fatalf("Failed to parse schema %s: %v", name, err)
+	}
+	return schema
+}
+
+// benchInvoice returns an invoice of the benchmark schema with the given number of lines,
+// each indented on a line of its own, one in every invalidEvery of which is invalid (none
+// if invalidEvery is 0).
+func benchInvoice(lines, invalidEvery int) []byte {
+	var b strings.Builder
+	b.WriteString(`<invoice xmlns="urn:example:invoice" currency="EUR"><issued>2024-05-01T09:30:00Z</issued>`)
+	b.WriteString(`<seller id="s1"><name>Widgets Ltd</name><address><street>1 Industrial Way</street><city>Leeds</city>` +
+		`<country>en-GB</country></address><vatNumber>GB123456789</vatNumber></seller>`)
+	b.WriteString(`<buyer id="b1"><name>Example Store</name><address><street>Hauptstraße 5</street><city>Berlin</city>` +
+		`<country>de</country></address><phone>+49 30 123456</phone></buyer><lines>`)
+	for i := 1; i <= lines; i++ {
+		sku := fmt.Sprintf("WID-%04d", i%10000)
+		if invalidEvery > 0 && i%invalidEvery == 0 {
+			sku = "widget"
+		}
+		fmt.Fprintf(&b, "\n    "+`<line number="%d" supplier="s1"><sku>%s</sku><description>Widget %d</description>`+
+			`<quantity>%d</quantity><unitPrice>%d.25</unitPrice></line>`, i, sku, i, i%9+1, i%50)
+	}
+	b.WriteString("\n  " + `</lines><total>1000.00</total></invoice>`)
+	return []byte(b.String())
+}
+
+// benchDocument returns a document of the recursive benchmark schema whose sections are
+// nested depth levels deep.
+func benchDocument(depth int) []byte {
+	var b strings.Builder
+	b.WriteString("<document>")
+	for i := 0; i < depth; i++ {
+		fmt.Fprintf(&b, `<section level="%d"><title>Section %d</title><para>Text of section %d.</para>`, i, i, i)
+	}
+	b.WriteString(strings.Repeat("</section>", depth))
+	b.WriteString("</document>")
+	return []byte(b.String())
+}
+
+// benchValidate runs the sub-benchmarks of validating a document: "Document" validates the
+// parsed document, "Bytes" parses it too.
+func benchValidate(b *testing.B, schema *Schema, xmlBytes []byte, valid bool) {
+	doc, err := Parse(xmlBytes)
+	if err != nil {
+		b.Fatalf("Failed to parse XML: %v", err)
+	}
+	if err := schema.Validate(doc); (err == nil) != valid {
+		b.Fatalf("Expected valid to be %v, got: %v", valid, err)
+	}
+
+	b.Run("Document", func(b *testing.B) {
+		b.SetBytes(int64(len(xmlBytes)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			schema.Validate(doc)
+		}
+	})
+	b.Run("Bytes", func(b *testing.B) {
+		b.SetBytes(int64(len(xmlBytes)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			schema.ValidateBytes(xmlBytes)
+		}
+	})
+}
+
+func BenchmarkParseXSD(b *testing.B) {
+	for _, name := range []string{"invoice.xsd", "document.xsd"} {
+		xsdBytes, err := os.ReadFile(filepath.Join(benchCorpus, name))
+		if err != nil {
+			b.Fatalf("Failed to read schema: %v", err)
+		}
+		b.Run(strings.TrimSuffix(name, ".xsd"), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseXSD(xsdBytes, benchCorpus); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParse measures building the document tree, without validation.
+func BenchmarkParse(b *testing.B) {
+	documents := []struct {
+		name     string
+		xmlBytes []byte
+	}{
+		{"Large", benchInvoice(10000, 0)},
+		{"Deep", benchDocument(500)},
+	}
+	for _, document := range documents {
+		b.Run(document.name, func(b *testing.B) {
+			b.SetBytes(int64(len(document.xmlBytes)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := Parse(document.xmlBytes); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkValidateSmall(b *testing.B) {
+	xmlBytes, err := os.ReadFile(filepath.Join(benchCorpus, "invoice.xml"))
+	if err != nil {
+		b.Fatalf("Failed to read document: %v", err)
+	}
+	benchValidate(b, benchSchema(b, "invoice.xsd"), xmlBytes, true)
+}
+
+func BenchmarkValidateLarge(b *testing.B) {
+	schema := benchSchema(b, "invoice.xsd")
+	b.Run("Valid", func(b *testing.B) {
+		benchValidate(b, schema, benchInvoice(10000, 0), true)
+	})
+	// Invalid lines measure the cost of building issues
+	b.Run("Invalid", func(b *testing.B) {
+		benchValidate(b, schema, benchInvoice(10000, 10), false)
+	})
+}
+
+func BenchmarkValidateDeep(b *testing.B) {
+	benchValidate(b, benchSchema(b, "document.xsd"), benchDocument(500), true)
+}
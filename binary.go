@@ -0,0 +1,54 @@
+package xmlparser
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// xmlWhitespace removes the whitespace characters of XML from a string.
+var xmlWhitespace = strings.NewReplacer(" ", "", "\t", "", "\n", "", "\r", "")
+
+// decodeBase64Binary decodes an xs:base64Binary lexical value. Whitespace may appear anywhere,
+// as in line-wrapped MIME content; the remaining characters must form complete, correctly
+// padded groups whose unused trailing bits are zero.
+func decodeBase64Binary(content string) ([]byte, error) {
+	data, err := base64.StdEncoding.Strict().DecodeString(xmlWhitespace.Replace(content))
+	if err != nil {
+		return nil, fmt.Errorf("value '%s' is not valid base64Binary: %v", excerpt(content), err)
+	}
+	return data, nil
+}
+
+// decodeHexBinary decodes an xs:hexBinary lexical value: an even number of hexadecimal
+// digits, two per octet.
+func decodeHexBinary(content string) ([]byte, error) {
+	if len(content)%2 != 0 {
+		return nil, fmt.Errorf("value '%s' is not valid hexBinary: odd number of hex digits", excerpt(content))
+	}
+	data, err := hex.DecodeString(content)
+	if err != nil {
+		return nil, fmt.Errorf("value '%s' is not valid hexBinary: %v", excerpt(content), err)
+	}
+	return data, nil
+}
+
+// valueLength returns the length of a value as measured by the length facets: octets for
+// binary types, characters (bytes of the content) otherwise.
+func valueLength(content, baseType string) int {
+	var data []byte
+	var err error
+	switch baseType {
+	case "xs:base64Binary":
+		data, err = decodeBase64Binary(content)
+	case "xs:hexBinary":
+		data, err = decodeHexBinary(content)
+	default:
+		return len(content)
+	}
+	if err != nil {
+		return len(content) // Reported by the built-in type check
+	}
+	return len(data)
+}
@@ -0,0 +1,102 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test decoding validation of the binary built-in types
+func TestBinaryBuiltInTypes(t *testing.T) {
+	tests := []struct {
+		typeName    string
+		value       string
+		errorString string // empty when the value is valid
+	}{
+		{"xs:base64Binary", "", ""},
+		{"xs:base64Binary", "aGVsbG8=", ""},
+		{"xs:base64Binary", "aGVs bG8=", ""},
+		{"xs:base64Binary", "aGVs\n  bG8h\r\n", ""},
+		{"xs:base64Binary", "aGVsbA==", ""},
+		{"xs:base64Binary", "aGVsbG8", "not valid base64Binary"},
+		{"xs:base64Binary", "aGVsbG8==", "not valid base64Binary"},
+		{"xs:base64Binary", "aGVsbA=", "not valid base64Binary"},
+		{"xs:base64Binary", "a===", "not valid base64Binary"},
+		{"xs:base64Binary", "aGVsbG9=", "not valid base64Binary"},
+		{"xs:base64Binary", "aGVs_bG8", "not valid base64Binary"},
+		{"xs:hexBinary", "", ""},
+		{"xs:hexBinary", "0FB7", ""},
+		{"xs:hexBinary", "0fb7", ""},
+		{"xs:hexBinary", "0FB", "odd number of hex digits"},
+		{"xs:hexBinary", "0G", "not valid hexBinary"},
+		{"xs:hexBinary", "0F B7", "not valid hexBinary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeName+" "+tt.value, func(t *testing.T) {
+			err := validateBuiltInType(tt.value, tt.typeName, XSD10)
+			if tt.errorString == "" {
+				if err != nil {
+					t.Errorf("Expected '%s' to be a valid %s, got: %v", tt.value, tt.typeName, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorString) {
+				t.Errorf("Expected error containing '%s' for %s '%s', got: %v", tt.errorString, tt.typeName, tt.value, err)
+			}
+		})
+	}
+}
+
+// Test that length facets of binary types count octets rather than characters
+func TestBinaryLengthFacets(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="digestType">
+        <xs:restriction base="xs:base64Binary">
+            <xs:minLength value="4"/>
+            <xs:maxLength value="5"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:simpleType name="keyType">
+        <xs:restriction base="xs:hexBinary">
+            <xs:maxLength value="2"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:element name="digest" type="digestType"/>
+    <xs:element name="key" type="keyType"/>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{"Four octets", `<digest>AAECAw==</digest>`, true, ""},
+		{"Five octets wrapped", "<digest>\n  AAECAwQ=\n</digest>", true, ""},
+		{"Three octets", `<digest>AAEC</digest>`, false, "too short (minimum length: 4, actual: 3)"},
+		{"Six octets", `<digest>AAECAwQF</digest>`, false, "too long (maximum length: 5, actual: 6)"},
+		{"Two hex octets", `<key>0FB7</key>`, true, ""},
+		{"Three hex octets", `<key>0FB7C2</key>`, false, "too long (maximum length: 2, actual: 3)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// subtreeBudget returns the budget configured for the subtree of node, if any.
+func (v *validator) subtreeBudget(node *Node) (SubtreeBudget, bool) {
+	if len(v.opts.SubtreeBudgets) == 0 {
+		return SubtreeBudget{}, false
+	}
+	if budget, ok := v.opts.SubtreeBudgets[node.Name]; ok {
+		return budget, true
+	}
+	budget, ok := v.opts.SubtreeBudgets[xml.Name{Local: node.Name.Local}]
+	return budget, ok
+}
+
+// checkSubtreeBudget reports the limits of a budget that the subtree of node exceeds.
+func (v *validator) checkSubtreeBudget(node *Node, budget SubtreeBudget) []string {
+	elements, textLength := v.subtreeSize(node)
+	var errors []string
+	if budget.MaxElements > 0 && elements > budget.MaxElements {
+		errors = append(errors, fmt.Sprintf("element <%s> exceeds its subtree budget: %d descendant elements (maximum: %d)",
+			node.Name.Local, elements, budget.MaxElements))
+	}
+	if budget.MaxTextLength > 0 && textLength > budget.MaxTextLength {
+		errors = append(errors, fmt.Sprintf("element <%s> exceeds its subtree budget: %d bytes of text (maximum: %d)",
+			node.Name.Local, textLength, budget.MaxTextLength))
+	}
+	return errors
+}
+
+// subtreeSize returns the number of elements below node and the length of the text of its
+// subtree, without the leading and trailing whitespace of each element's text. The sizes
+// of subtrees already dropped by streaming validation are those recorded before.
+func (v *validator) subtreeSize(node *Node) (elements, textLength int) {
+	if size, ok := v.pruned[node]; ok {
+		return size.elements, size.textLength
+	}
+	textLength = len(strings.TrimSpace(node.Content))
+	for _, child := range node.Children {
+		childElements, childText := v.subtreeSize(child)
+		elements += 1 + childElements
+		textLength += childText
+	}
+	return elements, textLength
+}
+
+// subtreeStats is the size of the subtree of an element whose content was dropped.
+type subtreeStats struct {
+	elements, textLength int
+}
@@ -0,0 +1,113 @@
+package xmlparser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SchemaCache keeps the schema documents loaded through it, keyed by resolved location, so
+// that parsing several schemas of a large tree (UBL, NIEM) fetches each shared import once.
+// Set it in ParseOptions.Cache and reuse it across parses; it is safe for concurrent use.
+// Within a single parse, a location referenced several times is always loaded once.
+//
+// Documents are kept in memory for the lifetime of the cache. With a directory, remote
+// documents are also stored on disk and survive the process; local files are not copied,
+// as they are already on disk. Cached documents are never refreshed: use a new cache, or
+// remove the directory, to pick up changes to the schemas.
+type SchemaCache struct {
+	dir       string
+	mu        sync.Mutex
+	documents map[string][]byte
+}
+
+// NewSchemaCache returns an empty cache, storing remote documents in dir unless it is empty.
+// The directory is created when the first document is stored.
+func NewSchemaCache(dir string) *SchemaCache {
+	return &SchemaCache{dir: dir, documents: make(map[string][]byte)}
+}
+
+// get returns the cached content of a location, from memory or from the cache directory.
+func (c *SchemaCache) get(location string) ([]byte, bool) {
+	c.mu.Lock()
+	data, ok := c.documents[location]
+	c.mu.Unlock()
+	if ok || !c.onDisk(location) {
+		return data, ok
+	}
+
+	data, err := os.ReadFile(c.path(location))
+	if err != nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	c.documents[location] = data
+	c.mu.Unlock()
+	return data, true
+}
+
+// put caches the content of a location. Failing to write the cache directory is not an
+// error: the document is then fetched again by the next process.
+func (c *SchemaCache) put(location string, data []byte) {
+	c.mu.Lock()
+	c.documents[location] = data
+	c.mu.Unlock()
+	if !c.onDisk(location) {
+		return
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+	// Written under a temporary name and renamed, so readers never see a partial document
+	file, err := os.CreateTemp(c.dir, "schema-*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := file.Write(data)
+	closeErr := file.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(file.Name(), c.path(location)) != nil {
+		os.Remove(file.Name())
+	}
+}
+
+// onDisk reports whether a location is stored in the cache directory.
+func (c *SchemaCache) onDisk(location string) bool {
+	return c.dir != "" && isRemoteLocation(location)
+}
+
+// path returns the file caching a location in the cache directory.
+func (c *SchemaCache) path(location string) string {
+	sum := sha256.Sum256([]byte(location))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".xsd")
+}
+
+// cachingResolver serves documents from a cache, loading and caching the others with a
+// fallback resolver.
+type cachingResolver struct {
+	cache    *SchemaCache
+	fallback Resolver
+}
+
+// Resolve returns the cached document at location, loading it on a miss.
+func (r cachingResolver) Resolve(namespace, location string) (io.ReadCloser, error) {
+	return r.ResolveContext(context.Background(), namespace, location)
+}
+
+// ResolveContext resolves like Resolve, loading misses with ctx.
+func (r cachingResolver) ResolveContext(ctx context.Context, namespace, location string) (io.ReadCloser, error) {
+	if data, ok := r.cache.get(location); ok {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	data, err := readSchema(ctx, r.fallback, namespace, location)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.put(location, data)
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
@@ -0,0 +1,161 @@
+package xmlparser
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// Test that schema documents shared within and across parses are fetched once
+func TestSchemaCache(t *testing.T) {
+	documents := map[string]string{
+		"/main.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:a="urn:a" xmlns:b="urn:b" xmlns:c="urn:c">
+	<xs:import namespace="urn:a" schemaLocation="a.xsd"/>
+	<xs:import namespace="urn:b" schemaLocation="b.xsd"/>
+	<xs:import namespace="urn:c" schemaLocation="common/c.xsd"/>
+	<xs:element name="pair">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element ref="a:first"/>
+				<xs:element ref="b:second"/>
+				<xs:element name="code" type="c:Code"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+		"/a.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:a">
+	<xs:import namespace="urn:c" schemaLocation="common/c.xsd"/>
+	<xs:element name="first" type="xs:string"/>
+</xs:schema>`,
+		"/b.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:b">
+	<xs:import namespace="urn:c" schemaLocation="common/c.xsd"/>
+	<xs:element name="second" type="xs:string"/>
+</xs:schema>`,
+		"/common/c.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:c">
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string">
+			<xs:maxLength value="3"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`,
+	}
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		w.Write([]byte(documents[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	fetched := func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		counts := requests
+		requests = make(map[string]int)
+		return counts
+	}
+	once := map[string]int{"/main.xsd": 1, "/a.xsd": 1, "/b.xsd": 1, "/common/c.xsd": 1}
+
+	// A document imported three times within a parse is fetched once, even without a cache
+	if _, err := ParseXSDFromLocation(server.URL+"/main.xsd", ParseOptions{}); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if counts := fetched(); !reflect.DeepEqual(counts, once) {
+		t.Errorf("Expected each document to be fetched once, got %v", counts)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "xmlparser_cache_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cache := NewSchemaCache(tmpDir)
+	for i := 0; i < 2; i++ {
+		schema, err := ParseXSDFromLocation(server.URL+"/main.xsd", ParseOptions{Cache: cache})
+		if err != nil {
+			t.Fatalf("Failed to parse schema with cache: %v", err)
+		}
+		doc, err := Parse([]byte(`<pair><first xmlns="urn:a">A</first><second xmlns="urn:b">B</second><code>ABCD</code></pair>`))
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+		expectValidationError(t, schema.Validate(doc), "value 'ABCD' is too long")
+	}
+	if counts := fetched(); !reflect.DeepEqual(counts, once) {
+		t.Errorf("Expected the cache to serve the second parse, got %v", counts)
+	}
+
+	// A new cache on the same directory reads the documents stored by the first one
+	if _, err := ParseXSDFromLocation(server.URL+"/main.xsd", ParseOptions{Cache: NewSchemaCache(tmpDir)}); err != nil {
+		t.Fatalf("Failed to parse schema with cache: %v", err)
+	}
+	if counts := fetched(); len(counts) != 0 {
+		t.Errorf("Expected the cache directory to serve every document, got %v", counts)
+	}
+}
+
+// Test that a document imported by two imported schemas is parsed once per parse, and its
+// components merged once
+func TestSharedImportParsedOnce(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:a">
+	<xs:import namespace="urn:c" schemaLocation="common/c.xsd"/>
+	<xs:element name="first" type="xs:string"/>
+</xs:schema>`)},
+		"b.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:b">
+	<xs:import namespace="urn:c" schemaLocation="common/c.xsd"/>
+	<xs:element name="second" type="xs:string"/>
+</xs:schema>`)},
+		"common/c.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:c">
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string">
+			<xs:maxLength value="3"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`)},
+	}
+	mainSchema := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:c="urn:c">
+	<xs:import namespace="urn:a" schemaLocation="a.xsd"/>
+	<xs:import namespace="urn:b" schemaLocation="b.xsd"/>
+	<xs:element name="code" type="c:Code"/>
+</xs:schema>`
+
+	loader := newSchemaLoader(context.Background(), FSResolver(fsys))
+	schema, err := parseXSDWithImportsAndTracker([]byte(mainSchema), "", loader)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	shared := loader.schemas[locationKey("common/c.xsd")]
+	if shared == nil {
+		t.Fatal("Expected the shared document to be kept parsed")
+	}
+	again, err := loadReferencedSchema("common/c.xsd", "urn:c", "", "imported", loader)
+	if err != nil {
+		t.Fatalf("Failed to load the shared document again: %v", err)
+	}
+	if again != shared {
+		t.Error("Expected the shared document to be parsed once")
+	}
+	if len(loader.sources) != 3 {
+		t.Errorf("Expected 3 documents loaded, got %d", len(loader.sources))
+	}
+
+	if len(schema.SimpleTypes) != 1 {
+		t.Errorf("Expected the shared type to be merged once, got %d types", len(schema.SimpleTypes))
+	}
+	doc, err := Parse([]byte(`<code>ABCD</code>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	expectValidationError(t, schema.Validate(doc), "value 'ABCD' is too long")
+}
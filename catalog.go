@@ -0,0 +1,228 @@
+package xmlparser
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Catalog is an OASIS XML Catalog, which maps the URIs of schema documents to local copies so
+// schemas can be parsed offline. The uri, system, rewriteURI, rewriteSystem, uriSuffix,
+// systemSuffix and nextCatalog entries are supported, within group elements and with
+// xml:base; public entries and delegation are not, as schema documents have no public
+// identifiers.
+type Catalog struct {
+	entries []catalogEntry
+	next    []*Catalog // Catalogs of nextCatalog entries, consulted in order when nothing matches
+}
+
+// catalogEntry is one mapping of a catalog, with its target resolved against the entry's base.
+type catalogEntry struct {
+	kind   string // Local name of the entry element
+	match  string // Name, identifier, prefix or suffix matched
+	target string // File path or http(s) URL of the local copy, or of the prefix to substitute
+}
+
+// LoadCatalog reads the OASIS XML catalog at path, together with the catalogs its nextCatalog
+// entries name. Relative targets are resolved against the location of the catalog file.
+func LoadCatalog(path string) (*Catalog, error) {
+	return loadCatalog(path, make(map[string]bool))
+}
+
+// loadCatalog reads a catalog, skipping nextCatalog entries already visited.
+func loadCatalog(path string, visited map[string]bool) (*Catalog, error) {
+	visited[locationKey(path)] = true
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	catalog := &Catalog{}
+	var nextCatalogs []string
+	bases := []string{locationBase(path)}
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid catalog '%s': %w", path, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			base := bases[len(bases)-1]
+			if xmlBase := catalogAttr(t, xml.Name{Space: xmlNamespace, Local: "base"}); xmlBase != "" {
+				if base, err = resolveSchemaLocation(xmlBase, base); err != nil {
+					return nil, fmt.Errorf("invalid catalog '%s': %w", path, err)
+				}
+			}
+			bases = append(bases, base)
+
+			entry, err := newCatalogEntry(t, base)
+			if err != nil {
+				return nil, fmt.Errorf("invalid catalog '%s': %w", path, err)
+			}
+			switch {
+			case entry == nil:
+			case entry.kind == "nextCatalog":
+				nextCatalogs = append(nextCatalogs, entry.target)
+			default:
+				catalog.entries = append(catalog.entries, *entry)
+			}
+		case xml.EndElement:
+			bases = bases[:len(bases)-1]
+		}
+	}
+
+	for _, next := range nextCatalogs {
+		if visited[locationKey(next)] {
+			continue
+		}
+		nextCatalog, err := loadCatalog(next, visited)
+		if err != nil {
+			return nil, err
+		}
+		catalog.next = append(catalog.next, nextCatalog)
+	}
+	return catalog, nil
+}
+
+// catalogEntryAttrs gives, for each supported entry, the attributes holding what it matches
+// and its target.
+var catalogEntryAttrs = map[string][2]string{
+	"uri":           {"name", "uri"},
+	"system":        {"systemId", "uri"},
+	"rewriteURI":    {"uriStartString", "rewritePrefix"},
+	"rewriteSystem": {"systemIdStartString", "rewritePrefix"},
+	"uriSuffix":     {"uriSuffix", "uri"},
+	"systemSuffix":  {"systemIdSuffix", "uri"},
+	"nextCatalog":   {"", "catalog"},
+}
+
+// newCatalogEntry returns the entry an element of a catalog declares, or nil for elements
+// that are not supported entries, such as catalog and group.
+func newCatalogEntry(start xml.StartElement, base string) (*catalogEntry, error) {
+	attrs, supported := catalogEntryAttrs[start.Name.Local]
+	if !supported {
+		return nil, nil
+	}
+
+	entry := &catalogEntry{kind: start.Name.Local}
+	if attrs[0] != "" {
+		if entry.match = catalogAttr(start, xml.Name{Local: attrs[0]}); entry.match == "" {
+			return nil, fmt.Errorf("%s entry without %s attribute", entry.kind, attrs[0])
+		}
+	}
+	target := catalogAttr(start, xml.Name{Local: attrs[1]})
+	if target == "" {
+		return nil, fmt.Errorf("%s entry without %s attribute", entry.kind, attrs[1])
+	}
+
+	var err error
+	if entry.target, err = resolveSchemaLocation(target, base); err != nil {
+		return nil, err
+	}
+	// A rewritten prefix keeps the trailing slash that resolution cleans away
+	if strings.HasSuffix(target, "/") && !strings.HasSuffix(entry.target, "/") {
+		entry.target += "/"
+	}
+	return entry, nil
+}
+
+// catalogAttr returns the value of an attribute of a catalog element, or "".
+func catalogAttr(start xml.StartElement, name xml.Name) string {
+	for _, attr := range start.Attr {
+		if attr.Name == name {
+			return strings.TrimSpace(attr.Value)
+		}
+	}
+	return ""
+}
+
+// Lookup returns the local copy the catalog maps a schema document to. location is the
+// schemaLocation resolved against the referencing document, looked up as a URI and as a
+// system identifier; namespace, the namespace of an xs:import, is looked up as a URI when
+// the location itself is not mapped. Exact entries win over rewrite entries, which win over
+// suffix entries; among rewrite and suffix entries the longest match wins.
+func (c *Catalog) Lookup(namespace, location string) (string, bool) {
+	if target, ok := c.lookup(location); ok {
+		return target, true
+	}
+	if namespace != "" {
+		return c.lookup(namespace)
+	}
+	return "", false
+}
+
+// lookup maps one URI through the catalog and then its next catalogs.
+func (c *Catalog) lookup(uri string) (string, bool) {
+	var rewrite, suffix *catalogEntry
+	for i := range c.entries {
+		entry := &c.entries[i]
+		switch entry.kind {
+		case "uri", "system":
+			if entry.match == uri {
+				return entry.target, true
+			}
+		case "rewriteURI", "rewriteSystem":
+			if strings.HasPrefix(uri, entry.match) && (rewrite == nil || len(entry.match) > len(rewrite.match)) {
+				rewrite = entry
+			}
+		case "uriSuffix", "systemSuffix":
+			if strings.HasSuffix(uri, entry.match) && (suffix == nil || len(entry.match) > len(suffix.match)) {
+				suffix = entry
+			}
+		}
+	}
+
+	switch {
+	case rewrite != nil:
+		return rewrite.target + strings.TrimPrefix(uri, rewrite.match), true
+	case suffix != nil:
+		return suffix.target, true
+	}
+	for _, next := range c.next {
+		if target, ok := next.lookup(uri); ok {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// CatalogResolver returns a Resolver that loads the schema documents mapped by catalog from
+// their local copies, through fallback (the default loading if nil), and any other document
+// from its own location through fallback.
+func CatalogResolver(catalog *Catalog, fallback Resolver) Resolver {
+	if fallback == nil {
+		fallback = defaultResolver{}
+	}
+	return catalogResolver{catalog: catalog, fallback: fallback}
+}
+
+// catalogResolver maps locations through a catalog before loading them with a fallback.
+type catalogResolver struct {
+	catalog  *Catalog
+	fallback Resolver
+}
+
+// Resolve loads the local copy of a mapped location, or the location itself.
+func (r catalogResolver) Resolve(namespace, location string) (io.ReadCloser, error) {
+	return r.ResolveContext(context.Background(), namespace, location)
+}
+
+// ResolveContext resolves like Resolve, passing ctx on to the fallback resolver.
+func (r catalogResolver) ResolveContext(ctx context.Context, namespace, location string) (io.ReadCloser, error) {
+	if target, ok := r.catalog.Lookup(namespace, location); ok {
+		location = target
+	}
+	if contextResolver, ok := r.fallback.(ContextResolver); ok {
+		return contextResolver.ResolveContext(ctx, namespace, location)
+	}
+	return r.fallback.Resolve(namespace, location)
+}
@@ -0,0 +1,162 @@
+package xmlparser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test that schema locations are mapped to local copies through an OASIS XML catalog
+func TestCatalog(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_catalog_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"catalog.xml": `<?xml version="1.0"?>
+<catalog xmlns="urn:oasis:names:tc:entity:xmlns:xml:catalog">
+	<rewriteURI uriStartString="http://example.com/schemas/" rewritePrefix="mirror/"/>
+	<group xml:base="vendor/">
+		<uri name="urn:example:ext" uri="ext.xsd"/>
+	</group>
+	<nextCatalog catalog="more/catalog.xml"/>
+</catalog>`,
+		"more/catalog.xml": `<catalog xmlns="urn:oasis:names:tc:entity:xmlns:xml:catalog">
+	<system systemId="http://example.org/units.xsd" uri="units.xsd"/>
+	<nextCatalog catalog="../catalog.xml"/>
+</catalog>`,
+		"mirror/types.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="common.xsd"/>
+	<xs:simpleType name="Code">
+		<xs:restriction base="Token3"/>
+	</xs:simpleType>
+</xs:schema>`,
+		"mirror/common.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="Token3">
+		<xs:restriction base="xs:string">
+			<xs:maxLength value="3"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`,
+		"vendor/ext.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:example:ext">
+	<xs:simpleType name="Priority">
+		<xs:restriction base="xs:integer">
+			<xs:maxInclusive value="5"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`,
+		"more/units.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="Unit">
+		<xs:restriction base="xs:string">
+			<xs:enumeration value="kg"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	schemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:ext="urn:example:ext">
+	<xs:include schemaLocation="http://example.com/schemas/types.xsd"/>
+	<xs:include schemaLocation="http://example.org/units.xsd"/>
+	<xs:import namespace="urn:example:ext" schemaLocation="https://ext.example.net/v2/ext.xsd"/>
+	<xs:element name="item">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="code" type="Code"/>
+				<xs:element name="unit" type="Unit"/>
+				<xs:element name="priority" type="ext:Priority"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`
+
+	// Nothing may be fetched from the network
+	offline := ResolverFunc(func(namespace, location string) (io.ReadCloser, error) {
+		if isRemoteLocation(location) {
+			return nil, fmt.Errorf("unexpected fetch of %s", location)
+		}
+		return os.Open(location)
+	})
+
+	catalogPath := filepath.Join(tmpDir, "catalog.xml")
+	schema, err := ParseXSDWithOptions([]byte(schemaContent), ParseOptions{Catalog: catalogPath, Resolver: offline})
+	if err != nil {
+		t.Fatalf("Failed to parse schema with catalog: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Valid document",
+			xml:        `<item><code>ABC</code><unit>kg</unit><priority>2</priority></item>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Type from a rewritten include",
+			xml:         `<item><code>ABCD</code><unit>kg</unit><priority>2</priority></item>`,
+			shouldPass:  false,
+			errorString: "length",
+		},
+		{
+			name:        "Type from the next catalog",
+			xml:         `<item><code>ABC</code><unit>lb</unit><priority>2</priority></item>`,
+			shouldPass:  false,
+			errorString: "lb",
+		},
+		{
+			name:        "Type from an import mapped by namespace",
+			xml:         `<item><code>ABC</code><unit>kg</unit><priority>9</priority></item>`,
+			shouldPass:  false,
+			errorString: "value '9' exceeds maximum allowed value 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+
+	// Locations the catalog does not map go to the resolver unchanged
+	unmapped := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="http://unknown.example.com/a.xsd"/>
+</xs:schema>`
+	if _, err := ParseXSDWithOptions([]byte(unmapped), ParseOptions{Catalog: catalogPath, Resolver: offline}); err == nil ||
+		!strings.Contains(err.Error(), "unexpected fetch of http://unknown.example.com/a.xsd") {
+		t.Errorf("Expected the unmapped location to reach the resolver, got: %v", err)
+	}
+
+	if _, err := ParseXSDWithOptions([]byte(unmapped), ParseOptions{Catalog: filepath.Join(tmpDir, "missing.xml")}); err == nil ||
+		!strings.Contains(err.Error(), "failed to read catalog") {
+		t.Errorf("Expected error for a missing catalog, got: %v", err)
+	}
+}
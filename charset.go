@@ -0,0 +1,289 @@
+package xmlparser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// CharsetReader converts a document in one of the common encodings other than UTF-8 to
+// UTF-8, with the signature of xml.Decoder.CharsetReader: ISO-8859-1 (Latin-1),
+// ISO-8859-15 (Latin-9), Windows-1252 and US-ASCII, by their usual names and aliases. It is
+// the default of DocumentOptions.CharsetReader. Other encodings, such as Shift_JIS, need a
+// reader of their own, such as charset.NewReaderLabel of golang.org/x/net/html/charset.
+func CharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	name := strings.ToLower(strings.TrimSpace(charset))
+	if name == "utf-8" || name == "utf8" {
+		return input, nil
+	}
+	table, ok := singleByteCharsets[name]
+	if !ok {
+		return nil, fmt.Errorf("encoding '%s' is not supported", charset)
+	}
+	return &charsetDecoder{input: input, table: table, charset: charset}, nil
+}
+
+// charsetReaderFunc is the type of xml.Decoder.CharsetReader and DocumentOptions.CharsetReader.
+type charsetReaderFunc = func(charset string, input io.Reader) (io.Reader, error)
+
+// singleByteCharset maps the bytes of a single-byte encoding from 0x80 on to runes; the
+// bytes below are ASCII. A zero rune marks a byte the encoding does not define.
+type singleByteCharset [128]rune
+
+// newLatinCharset returns ISO-8859-1 with the given bytes mapped to other runes.
+func newLatinCharset(overrides map[byte]rune) *singleByteCharset {
+	table := &singleByteCharset{}
+	for i := range table {
+		table[i] = rune(0x80 + i)
+	}
+	for b, r := range overrides {
+		table[b-0x80] = r
+	}
+	return table
+}
+
+var (
+	latin1Charset = newLatinCharset(nil)
+	latin9Charset = newLatinCharset(map[byte]rune{
+		0xA4: '€', 0xA6: 'Š', 0xA8: 'š', 0xB4: 'Ž', 0xB8: 'ž', 0xBC: 'Œ', 0xBD: 'œ', 0xBE: 'Ÿ',
+	})
+	// The five bytes Windows-1252 leaves undefined keep their ISO-8859-1 control characters,
+	// as browsers decode them
+	windows1252Charset = newLatinCharset(map[byte]rune{
+		0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡', 0x88: 'ˆ',
+		0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“',
+		0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›',
+		0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+	})
+	asciiCharset = &singleByteCharset{}
+)
+
+// singleByteCharsets holds the encodings of CharsetReader by lower-case name.
+var singleByteCharsets = map[string]*singleByteCharset{
+	"iso-8859-1": latin1Charset, "iso8859-1": latin1Charset, "iso_8859-1": latin1Charset, "latin1": latin1Charset,
+	"latin-1": latin1Charset, "l1": latin1Charset, "cp819": latin1Charset, "ibm819": latin1Charset,
+	"iso-8859-15": latin9Charset, "iso8859-15": latin9Charset, "iso_8859-15": latin9Charset, "latin9": latin9Charset,
+	"latin-9": latin9Charset, "l9": latin9Charset,
+	"windows-1252": windows1252Charset, "cp1252": windows1252Charset, "x-cp1252": windows1252Charset,
+	"us-ascii": asciiCharset, "ascii": asciiCharset, "iso646-us": asciiCharset,
+}
+
+// charsetDecoder converts the bytes of a single-byte encoding read from input to UTF-8.
+type charsetDecoder struct {
+	input   io.Reader
+	table   *singleByteCharset
+	charset string // Name of the encoding, for errors
+
+	raw     []byte // Bytes read from input
+	decoded []byte // Bytes converted and not returned yet, from offset on
+	offset  int
+	err     error // Error to return once the converted bytes are returned
+}
+
+// Read returns converted bytes, reading and converting more when none are left.
+func (d *charsetDecoder) Read(b []byte) (int, error) {
+	for d.offset == len(d.decoded) {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.raw == nil {
+			d.raw = make([]byte, 4096)
+		}
+		n, err := d.input.Read(d.raw)
+		if n == 0 && err == nil {
+			return 0, nil
+		}
+		d.decoded, d.offset, d.err = d.decoded[:0], 0, err
+		for _, c := range d.raw[:n] {
+			if c < utf8.RuneSelf {
+				d.decoded = append(d.decoded, c)
+				continue
+			}
+			r := d.table[c-utf8.RuneSelf]
+			if r == 0 {
+				d.err = fmt.Errorf("byte 0x%02X is not a character of encoding '%s'", c, d.charset)
+				break
+			}
+			d.decoded = utf8.AppendRune(d.decoded, r)
+		}
+	}
+	n := copy(b, d.decoded[d.offset:])
+	d.offset += n
+	return n, nil
+}
+
+// encodingDeclaration matches the encoding declared by an XML declaration.
+var encodingDeclaration = regexp.MustCompile(`^<\?xml\s[^>]*?\bencoding\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// maxDeclaration is the length of the longest XML declaration whose encoding is detected.
+const maxDeclaration = 256
+
+// declaredCharset returns the encoding declared by the XML declaration at the start of
+// data, or "" if it declares none or UTF-8.
+func declaredCharset(data []byte) string {
+	match := encodingDeclaration.FindSubmatch(data)
+	if match == nil {
+		return ""
+	}
+	charset := string(match[1]) + string(match[2])
+	if strings.EqualFold(charset, "utf-8") {
+		return ""
+	}
+	return charset
+}
+
+// peekDeclaration returns the XML declaration at the start of r without consuming it,
+// reading no further than its end; when r does not start with one, it returns what was
+// read until that showed.
+func peekDeclaration(r *bufio.Reader) []byte {
+	const prefix = "<?xml"
+	for n := 1; ; n++ {
+		data, err := r.Peek(n)
+		if err != nil || data[n-1] == '>' || n == maxDeclaration ||
+			!strings.HasPrefix(string(data), prefix) && !strings.HasPrefix(prefix, string(data)) {
+			return data
+		}
+	}
+}
+
+// utf8Input returns the input of a document, converted to UTF-8 with charsetReader when
+// it declares another encoding, and the CharsetReader of the decoder reading it, which
+// passes the converted input through. The input is converted before the decoder reads it,
+// rather than by the decoder once it reads the declaration, so that the offsets the
+// decoder reports, from which source positions are computed, count the bytes it reads.
+func utf8Input(input io.Reader, charsetReader charsetReaderFunc) (io.Reader, charsetReaderFunc, error) {
+	buffered := bufio.NewReader(input)
+	charset := declaredCharset(peekDeclaration(buffered))
+	if charset == "" {
+		return buffered, charsetReader, nil
+	}
+	converted, err := charsetReader(charset, buffered)
+	if err != nil {
+		return nil, nil, err
+	}
+	return converted, passThroughCharset(charset, charsetReader), nil
+}
+
+// passThroughCharset returns the CharsetReader of a decoder reading a document converted
+// from charset already: it passes the input through when the declaration names charset,
+// and converts it with charsetReader otherwise.
+func passThroughCharset(charset string, charsetReader charsetReaderFunc) charsetReaderFunc {
+	return func(label string, input io.Reader) (io.Reader, error) {
+		if label == charset {
+			return input, nil
+		}
+		return charsetReader(label, input)
+	}
+}
+
+// charsetWriter returns a writer converting the UTF-8 written to it to charset, for
+// documents read in one of the single-byte encodings of CharsetReader to be written back in
+// it, or w itself for other encodings.
+func charsetWriter(w io.Writer, charset string) io.Writer {
+	table, ok := singleByteCharsets[strings.ToLower(strings.TrimSpace(charset))]
+	if !ok {
+		return w
+	}
+	encoder := &charsetEncoder{output: w, bytes: make(map[rune]byte, len(table))}
+	for i, r := range table {
+		if r != 0 {
+			encoder.bytes[r] = byte(utf8.RuneSelf + i)
+		}
+	}
+	return encoder
+}
+
+// charsetEncoder converts UTF-8 written to it to a single-byte encoding. Characters the
+// encoding does not have are written as character references.
+type charsetEncoder struct {
+	output io.Writer
+	bytes  map[rune]byte // Bytes of the encoding from 0x80 on, by rune
+
+	partial []byte // Incomplete UTF-8 sequence ending the last write
+	encoded []byte
+}
+
+// Write converts b and writes it to the output, holding an incomplete UTF-8 sequence at
+// its end until the next write.
+func (e *charsetEncoder) Write(b []byte) (int, error) {
+	data := b
+	if len(e.partial) > 0 {
+		data = append(e.partial, b...)
+		e.partial = nil
+	}
+
+	e.encoded = e.encoded[:0]
+	for i := 0; i < len(data); {
+		c := data[i]
+		if c < utf8.RuneSelf {
+			e.encoded = append(e.encoded, c)
+			i++
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			e.partial = append([]byte(nil), data[i:]...)
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if encoded, ok := e.bytes[r]; ok {
+			e.encoded = append(e.encoded, encoded)
+		} else {
+			e.encoded = fmt.Appendf(e.encoded, "&#x%X;", r)
+		}
+		i += size
+	}
+
+	if _, err := e.output.Write(e.encoded); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// offsetReader reads a document converted to UTF-8 from a single-byte encoding, and maps
+// the offsets of the converted bytes back to those of the document, where every
+// character is one byte.
+type offsetReader struct {
+	input  io.Reader // Converted document
+	offset int64     // Converted bytes read
+
+	wide  []wideCharacter // Characters above ASCII past the last mapped offset
+	extra int64           // Bytes the characters before the last mapped offset take beyond one
+}
+
+// wideCharacter is a character of more than one byte in the converted document.
+type wideCharacter struct {
+	end   int64 // Offset just past the character
+	extra int64 // Bytes it takes beyond one
+}
+
+// Read reads converted bytes, noting the characters above ASCII among them.
+func (o *offsetReader) Read(b []byte) (int, error) {
+	n, err := o.input.Read(b)
+	for i, c := range b[:n] {
+		if c >= 0xC0 { // Leading byte of a multi-byte sequence
+			size := int64(2)
+			if c >= 0xE0 {
+				size++
+			}
+			if c >= 0xF0 {
+				size++
+			}
+			o.wide = append(o.wide, wideCharacter{end: o.offset + int64(i) + size, extra: size - 1})
+		}
+	}
+	o.offset += int64(n)
+	return n, err
+}
+
+// sourceOffset returns the offset in the document of the byte at offset in the converted
+// document. Offsets are mapped in increasing order.
+func (o *offsetReader) sourceOffset(offset int64) int64 {
+	for len(o.wide) > 0 && o.wide[0].end <= offset {
+		o.extra += o.wide[0].extra
+		o.wide = o.wide[1:]
+	}
+	return offset - o.extra
+}
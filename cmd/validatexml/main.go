@@ -0,0 +1,206 @@
+// Command validatexml validates XML documents and checks XML schemas from the command line.
+//
+// Usage:
+//
+//	validatexml [--noout] [--nonet] --schema schema.xsd document.xml...
+//	validatexml compile [--strict] [--catalog catalog.xml] [--no-network] [--xsd11] schema.xsd...
+//
+// Validation follows the conventions of xmllint --schema, so scripts and Makefiles can
+// switch binaries unchanged: each document is echoed to stdout unless --noout is given
+// ("-" reads standard input), and stderr gets "doc.xml validates", or one
+// "doc.xml:12: element quantity: Schemas validity error : ..." line per issue, giving the
+// line of the element or attribute at fault, followed by "doc.xml fails to validate".
+// Options may be given before or after the documents.
+//
+// The compile subcommand parses the schemas with their imports and includes, prints the
+// component counts, the unsupported constructs the validator ignores, the Unique Particle
+// Attribution and Element Declarations Consistent findings, and lint warnings. With
+// --strict, it fails when there are unsupported constructs or findings, so schema changes
+// can be gated in CI.
+//
+// The exit status is that of xmllint: 0 on success, 1 for usage errors and unreadable or
+// malformed documents (and for compile --strict failures), 3 when a document is invalid,
+// and 5 when a schema cannot be compiled. With several documents, the last failure
+// determines the status.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	xmlparser "github.com/moolekkari/validatexml-go"
+)
+
+// Exit statuses, as defined by xmllint.
+const (
+	exitOK           = 0
+	exitUnclassified = 1 // Usage errors, unreadable or malformed documents
+	exitValidation   = 3 // A document is invalid
+	exitSchema       = 5 // A schema cannot be compiled
+)
+
+const usage = `usage: validatexml [--noout] [--nonet] --schema schema.xsd document.xml...
+       validatexml compile [--strict] [--catalog catalog.xml] [--no-network] [--xsd11] schema.xsd...`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes a subcommand and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, usage)
+		return exitUnclassified
+	}
+
+	// Anything else is validation, whose options and documents may come in any order
+	if args[0] == "compile" {
+		return compile(args[1:], stdout, stderr)
+	}
+	return validate(args, stdin, stdout, stderr)
+}
+
+// validate validates documents against a schema, as xmllint --schema does.
+func validate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validatexml", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	schemaPath := flags.String("schema", "", "W3C XML schema to validate the documents against")
+	noOut := flags.Bool("noout", false, "do not echo the documents")
+	noNet := flags.Bool("nonet", false, "do not fetch remote schemas")
+	documents, err := parseInterleaved(flags, args)
+	if err != nil {
+		return exitUnclassified
+	}
+	if *schemaPath == "" || len(documents) == 0 {
+		fmt.Fprintln(stderr, usage)
+		return exitUnclassified
+	}
+
+	schema, err := xmlparser.ParseXSDFromLocation(*schemaPath, xmlparser.ParseOptions{NoNetwork: *noNet})
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		fmt.Fprintf(stderr, "WXS schema %s failed to compile\n", *schemaPath)
+		return exitSchema
+	}
+
+	status := exitOK
+	for _, name := range documents {
+		if documentStatus := validateDocument(schema, name, stdin, *noOut, stdout, stderr); documentStatus != exitOK {
+			status = documentStatus
+		}
+	}
+	return status
+}
+
+// validateDocument validates one document, reporting the outcome on stderr as xmllint does,
+// and returns its exit status.
+func validateDocument(schema *xmlparser.Schema, name string, stdin io.Reader, noOut bool, stdout, stderr io.Writer) int {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: failed to load external entity \"%s\"\n", name)
+		return exitUnclassified
+	}
+
+	// xmllint rejects duplicate attributes and undeclared prefixes as well
+	doc, err := xmlparser.ParseWithOptions(data, xmlparser.DocumentOptions{Strict: true})
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: parser error : %v\n", name, err)
+		return exitUnclassified
+	}
+	if !noOut {
+		stdout.Write(data)
+	}
+
+	if err := schema.Validate(doc); err != nil {
+		var validationErr *xmlparser.ValidationError
+		if errors.As(err, &validationErr) {
+			for _, issue := range validationErr.Issues {
+				fmt.Fprintf(stderr, "%s: Schemas validity error : %s\n", issueLocation(name, issue), issue.Message)
+			}
+			if validationErr.Omitted > 0 {
+				fmt.Fprintf(stderr, "%s: Schemas validity error : ... and %d more\n", name, validationErr.Omitted)
+			}
+		} else {
+			fmt.Fprintf(stderr, "%s: Schemas validity error : %v\n", name, err)
+		}
+		fmt.Fprintf(stderr, "%s fails to validate\n", name)
+		return exitValidation
+	}
+	fmt.Fprintf(stderr, "%s validates\n", name)
+	return exitOK
+}
+
+// issueLocation returns where an issue is found as xmllint gives it: the document, then
+// the line and the local name of the element, when they are known.
+func issueLocation(name string, issue xmlparser.ValidationIssue) string {
+	if issue.Line == 0 {
+		return name
+	}
+	path, _, _ := strings.Cut(issue.XPath, "/@")
+	element := path[strings.LastIndex(path, "/")+1:]
+	element, _, _ = strings.Cut(element, "[")
+	element = element[strings.Index(element, ":")+1:]
+	return fmt.Sprintf("%s:%d: element %s", name, issue.Line, element)
+}
+
+// parseInterleaved parses flags that may appear before, between or after the positional
+// arguments, as xmllint accepts them, and returns the positional arguments.
+func parseInterleaved(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// compile runs the compile subcommand.
+func compile(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("compile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	strict := flags.Bool("strict", false, "fail on unsupported constructs and findings")
+	catalog := flags.String("catalog", "", "OASIS XML catalog mapping schema locations to local copies")
+	noNetwork := flags.Bool("no-network", false, "do not fetch remote schemas")
+	xsd11 := flags.Bool("xsd11", false, "parse the schemas as XSD 1.1")
+	if err := flags.Parse(args); err != nil {
+		return exitUnclassified
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, usage)
+		return exitUnclassified
+	}
+
+	opts := xmlparser.ParseOptions{Catalog: *catalog, NoNetwork: *noNetwork}
+	if *xsd11 {
+		opts.Version = xmlparser.XSD11
+	}
+	report, err := xmlparser.Compile(flags.Args(), opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "validatexml: %v\n", err)
+		return exitSchema
+	}
+
+	fmt.Fprint(stdout, report)
+	if *strict && !report.Clean() {
+		fmt.Fprintf(stdout, "FAIL: %d unsupported constructs, %d findings\n", len(report.Unsupported), len(report.Findings))
+		return exitUnclassified
+	}
+	fmt.Fprintln(stdout, "OK")
+	return exitOK
+}
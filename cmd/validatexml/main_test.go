@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test the exit statuses and xmllint-style output of validation and of the compile subcommand
+func TestRun(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "validatexml_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"order.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="order">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="quantity" type="xs:int"/>
+			</xs:sequence>
+			<xs:attribute name="id" type="xs:ID"/>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+		"ambiguous.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="order">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="note" type="xs:string" minOccurs="0"/>
+				<xs:element name="note" type="xs:string"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+		"broken.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="order">`,
+		"valid.xml":     "<order id=\"o1\">\n  <quantity>5</quantity>\n</order>",
+		"invalid.xml":   "<order id=\"o1\">\n  <quantity>five</quantity>\n</order>",
+		"malformed.xml": "<order>\n  <quantity>5</quantity>\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(tmpDir, name) }
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		status int
+		stdout []string // Substrings expected on stdout
+		stderr []string // Substrings expected on stderr
+	}{
+		{
+			name:   "valid document",
+			args:   []string{"--schema", path("order.xsd"), path("valid.xml")},
+			status: exitOK,
+			stdout: []string{"<quantity>5</quantity>"},
+			stderr: []string{path("valid.xml") + " validates\n"},
+		},
+		{
+			name:   "invalid document",
+			args:   []string{"--noout", "--schema", path("order.xsd"), path("invalid.xml")},
+			status: exitValidation,
+			stderr: []string{
+				path("invalid.xml") + ":2: element quantity: Schemas validity error : ",
+				path("invalid.xml") + " fails to validate\n",
+			},
+		},
+		{
+			name:   "malformed document",
+			args:   []string{"--noout", "--schema", path("order.xsd"), path("malformed.xml")},
+			status: exitUnclassified,
+			stderr: []string{path("malformed.xml") + ": parser error : XML parsing error"},
+		},
+		{
+			name:   "missing document",
+			args:   []string{"--noout", "--schema", path("order.xsd"), path("missing.xml")},
+			status: exitUnclassified,
+			stderr: []string{`warning: failed to load external entity "` + path("missing.xml") + `"`},
+		},
+		{
+			name:   "schema that does not compile",
+			args:   []string{"--noout", "--schema", path("broken.xsd"), path("valid.xml")},
+			status: exitSchema,
+			stderr: []string{"WXS schema " + path("broken.xsd") + " failed to compile\n"},
+		},
+		{
+			name:   "last failure determines the status",
+			args:   []string{"--schema", path("order.xsd"), path("invalid.xml"), path("valid.xml"), "--noout"},
+			status: exitValidation,
+			stderr: []string{path("invalid.xml") + " fails to validate\n", path("valid.xml") + " validates\n"},
+		},
+		{
+			name:   "document from standard input",
+			args:   []string{"--noout", "--schema", path("order.xsd"), "-"},
+			stdin:  "<order>\n\n  <quantity>-</quantity>\n</order>",
+			status: exitValidation,
+			stderr: []string{"-:3: element quantity: Schemas validity error : ", "- fails to validate\n"},
+		},
+		{
+			name:   "no arguments",
+			status: exitUnclassified,
+			stderr: []string{"usage: validatexml"},
+		},
+		{
+			name:   "document before the options",
+			args:   []string{path("valid.xml"), "--schema", path("order.xsd"), "--noout"},
+			status: exitOK,
+			stderr: []string{path("valid.xml") + " validates\n"},
+		},
+		{
+			name:   "document without a schema",
+			args:   []string{path("valid.xml")},
+			status: exitUnclassified,
+			stderr: []string{"usage: validatexml"},
+		},
+		{
+			name:   "compile",
+			args:   []string{"compile", path("order.xsd")},
+			status: exitOK,
+			stdout: []string{"OK\n"},
+		},
+		{
+			name:   "compile with findings",
+			args:   []string{"compile", path("ambiguous.xsd")},
+			status: exitOK,
+			stdout: []string{"finding: ", "OK\n"},
+		},
+		{
+			name:   "strict compile with findings",
+			args:   []string{"compile", "--strict", path("ambiguous.xsd")},
+			status: exitUnclassified,
+			stdout: []string{"FAIL: 0 unsupported constructs, 1 findings\n"},
+		},
+		{
+			name:   "compile of a schema that does not compile",
+			args:   []string{"compile", path("broken.xsd")},
+			status: exitSchema,
+			stderr: []string{"validatexml: failed to compile '" + path("broken.xsd") + "'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if status != tt.status {
+				t.Errorf("Expected exit status %d, got %d; stderr:\n%s", tt.status, status, stderr.String())
+			}
+			for _, expected := range tt.stdout {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("Expected stdout to contain %q, got:\n%s", expected, stdout.String())
+				}
+			}
+			for _, expected := range tt.stderr {
+				if !strings.Contains(stderr.String(), expected) {
+					t.Errorf("Expected stderr to contain %q, got:\n%s", expected, stderr.String())
+				}
+			}
+		})
+	}
+}
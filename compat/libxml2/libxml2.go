@@ -0,0 +1,74 @@
+// Package libxml2 mirrors the document parsing functions of github.com/lestrrat-go/libxml2
+// on top of the pure Go validatexml-go parser. Together with its xsd subpackage, it lets a
+// project that only parses documents and validates them against XML schemas switch from
+// the cgo binding by changing its import paths:
+//
+//	import (
+//		"github.com/moolekkari/validatexml-go/compat/libxml2"
+//		"github.com/moolekkari/validatexml-go/compat/libxml2/xsd"
+//	)
+//
+//	doc, err := libxml2.ParseString(input)
+//	if err != nil {
+//		return err
+//	}
+//	defer doc.Free()
+//
+//	schema, err := xsd.ParseFromFile("schema.xsd")
+//	if err != nil {
+//		return err
+//	}
+//	defer schema.Free()
+//
+//	if err := schema.Validate(doc); err != nil {
+//		for _, e := range err.(xsd.SchemaValidationError).Errors() {
+//			log.Println(e)
+//		}
+//	}
+//
+// The DOM of the binding (node navigation, XPath, serialization) is not provided, and the
+// parser options of the binding are not accepted.
+package libxml2
+
+import (
+	"io"
+
+	xmlparser "github.com/moolekkari/validatexml-go"
+)
+
+// Document is a parsed XML document, in the place of the binding's types.Document.
+type Document struct {
+	doc *xmlparser.Document
+}
+
+// Parse parses an XML document.
+func Parse(buf []byte) (*Document, error) {
+	doc, err := xmlparser.Parse(buf)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{doc: doc}, nil
+}
+
+// ParseString parses an XML document from a string.
+func ParseString(s string) (*Document, error) {
+	return Parse([]byte(s))
+}
+
+// ParseReader parses an XML document read from r.
+func ParseReader(r io.Reader) (*Document, error) {
+	doc, err := xmlparser.ParseReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{doc: doc}, nil
+}
+
+// Free does nothing: documents are garbage collected. It exists so that deferred calls
+// written for the binding keep compiling.
+func (d *Document) Free() {}
+
+// Unwrap returns the validatexml-go document, for code moving on to its native API.
+func (d *Document) Unwrap() *xmlparser.Document {
+	return d.doc
+}
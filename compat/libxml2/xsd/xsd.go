@@ -0,0 +1,89 @@
+// Package xsd mirrors the schema validation API of github.com/lestrrat-go/libxml2/xsd on
+// top of validatexml-go. See the parent libxml2 package for how to switch to it.
+package xsd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	xmlparser "github.com/moolekkari/validatexml-go"
+	"github.com/moolekkari/validatexml-go/compat/libxml2"
+)
+
+// Schema is a parsed XML schema.
+type Schema struct {
+	schema *xmlparser.Schema
+}
+
+// Option configures Parse.
+type Option func(*xmlparser.ParseOptions)
+
+// WithPath sets the path of the schema document, against whose directory relative
+// schemaLocation values of xs:import and xs:include are resolved (by default, the current
+// directory).
+func WithPath(path string) Option {
+	return func(opts *xmlparser.ParseOptions) {
+		opts.BasePath = filepath.Dir(path)
+	}
+}
+
+// Parse parses an XML schema with its imports and includes.
+func Parse(buf []byte, options ...Option) (*Schema, error) {
+	var opts xmlparser.ParseOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	schema, err := xmlparser.ParseXSDWithOptions(buf, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: schema}, nil
+}
+
+// ParseFromFile parses the XML schema in a file, resolving relative schema locations
+// against its directory.
+func ParseFromFile(path string) (*Schema, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(buf, WithPath(path))
+}
+
+// Free does nothing: schemas are garbage collected. It exists so that deferred calls
+// written for the binding keep compiling.
+func (s *Schema) Free() {}
+
+// Validate validates a document against the schema. When the document is invalid, the
+// error is a SchemaValidationError listing every issue. The options of the binding's
+// Validate have no effect.
+func (s *Schema) Validate(d *libxml2.Document, options ...int) error {
+	err := s.schema.Validate(d.Unwrap())
+	var validationErr *xmlparser.ValidationError
+	if errors.As(err, &validationErr) {
+		issues := validationErr.Unwrap()
+		if validationErr.Omitted > 0 {
+			issues = append(issues, fmt.Errorf("... and %d more", validationErr.Omitted))
+		}
+		return SchemaValidationError{errors: issues}
+	}
+	return err
+}
+
+// SchemaValidationError is the error of an invalid document, as in the binding.
+type SchemaValidationError struct {
+	errors []error
+}
+
+// Error returns the same summary as the binding.
+func (e SchemaValidationError) Error() string {
+	return "schema validation failed"
+}
+
+// Errors returns one error per validation issue, in document order, followed by a count of
+// the issues omitted past the limit of a ValidationError, if any.
+func (e SchemaValidationError) Errors() []error {
+	return e.errors
+}
@@ -0,0 +1,80 @@
+package xsd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moolekkari/validatexml-go/compat/libxml2"
+)
+
+// Test that code written for the libxml2 binding validates documents unchanged
+func TestValidate(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_libxml2_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"types.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string">
+			<xs:maxLength value="3"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`,
+		"main.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="types.xsd"/>
+	<xs:element name="codes">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="code" type="Code" maxOccurs="unbounded"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	mainPath := filepath.Join(tmpDir, "main.xsd")
+
+	fromFile, err := ParseFromFile(mainPath)
+	if err != nil {
+		t.Fatalf("ParseFromFile failed: %v", err)
+	}
+	defer fromFile.Free()
+	fromBytes, err := Parse([]byte(files["main.xsd"]), WithPath(mainPath))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	for _, schema := range []*Schema{fromFile, fromBytes} {
+		valid, err := libxml2.ParseString(`<codes><code>ABC</code></codes>`)
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+		defer valid.Free()
+		if err := schema.Validate(valid); err != nil {
+			t.Errorf("Expected validation to pass, but got error: %v", err)
+		}
+
+		invalid, err := libxml2.ParseReader(strings.NewReader(`<codes><code>ABCD</code><code>EFGHI</code></codes>`))
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+		err = schema.Validate(invalid)
+		validationErr, ok := err.(SchemaValidationError)
+		if !ok {
+			t.Fatalf("Expected a SchemaValidationError, got: %v", err)
+		}
+		if validationErr.Error() != "schema validation failed" || len(validationErr.Errors()) != 2 ||
+			!strings.Contains(validationErr.Errors()[1].Error(), "value 'EFGHI' is too long") {
+			t.Errorf("Unexpected validation errors: %v", validationErr.Errors())
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package xmlparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CompileReport is the outcome of Compile: what a schema set contains and what in it the
+// validator ignores or cannot apply reliably, for gating schema changes before deployment.
+type CompileReport struct {
+	Schemas    []*Schema       // The compiled schemas, in the order of their locations
+	Components ComponentCounts // Components of all the schemas
+
+	// Constructs of the schema documents that are not supported and are ignored by validation
+	Unsupported []string
+
+	// Violations of the schema component constraints the validator relies on: Unique
+	// Particle Attribution (content models must be deterministic) and Element Declarations
+	// Consistent (elements with the same name in a content model must have the same type)
+	Findings []string
+
+	// Lint: named types that no global element or attribute uses, directly or indirectly
+	Warnings []string
+}
+
+// ComponentCounts counts the components of a schema set.
+type ComponentCounts struct {
+	Documents     int // Schema documents loaded, including imported and included ones
+	Elements      int // Global element declarations
+	LocalElements int // Local element declarations
+	ComplexTypes  int // Named complex types
+	SimpleTypes   int // Named simple types
+	Attributes    int // Global attribute declarations
+}
+
+// Clean reports whether the schema set uses no unsupported construct and has no findings.
+// Warnings are not considered.
+func (r *CompileReport) Clean() bool {
+	return len(r.Unsupported) == 0 && len(r.Findings) == 0
+}
+
+// Summary describes the components in one line, e.g. "2 documents: 3 global elements,
+// 5 local elements, 2 complex types, 1 simple types, 0 global attributes".
+func (r *CompileReport) Summary() string {
+	c := r.Components
+	return fmt.Sprintf("%d documents: %d global elements, %d local elements, %d complex types, %d simple types, %d global attributes",
+		c.Documents, c.Elements, c.LocalElements, c.ComplexTypes, c.SimpleTypes, c.Attributes)
+}
+
+// String lists the report, one entry per line: the summary, then the unsupported
+// constructs, findings and warnings.
+func (r *CompileReport) String() string {
+	var b strings.Builder
+	b.WriteString(r.Summary())
+	b.WriteString("\n")
+	for _, entry := range r.Unsupported {
+		b.WriteString("unsupported: " + entry + "\n")
+	}
+	for _, entry := range r.Findings {
+		b.WriteString("finding: " + entry + "\n")
+	}
+	for _, entry := range r.Warnings {
+		b.WriteString("warning: " + entry + "\n")
+	}
+	return b.String()
+}
+
+// Compile parses the schema documents at locations (file paths or http(s) URLs) with their
+// imports and includes, as a schema set with one schema per target namespace, and lints
+// them without validating any document. An error is returned when a schema cannot be
+// parsed or two schemas have the same target namespace; everything else is reported.
+func Compile(locations []string, opts ParseOptions) (*CompileReport, error) {
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("compile requires at least one schema location")
+	}
+
+	report := &CompileReport{}
+	set := NewSchemaSet()
+	for _, location := range locations {
+		schema, err := ParseXSDFromLocation(location, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile '%s': %w", location, err)
+		}
+		if err := set.add(schema); err != nil {
+			return nil, fmt.Errorf("failed to compile '%s': %w", location, err)
+		}
+		report.Schemas = append(report.Schemas, schema)
+
+		linter := &schemaLinter{schema: schema, location: location, report: report, reported: make(map[string]bool)}
+		linter.countComponents()
+		for _, source := range schema.sources {
+			sourceLocation := source.Location
+			if sourceLocation == "" {
+				sourceLocation = location
+			}
+			if err := linter.findUnsupported(sourceLocation, source.Data); err != nil {
+				return nil, fmt.Errorf("failed to compile '%s': %w", location, err)
+			}
+		}
+		linter.checkContentModels()
+		linter.findUnusedTypes()
+	}
+	return report, nil
+}
+
+// supportedConstructs lists the elements of the XML Schema namespace the parser understands.
+// Annotations are listed as they are meant to be ignored.
+var supportedConstructs = map[string]bool{
+	"schema": true, "import": true, "include": true, "annotation": true, "documentation": true, "appinfo": true,
+	"element": true, "attribute": true, "complexType": true, "simpleType": true,
+	"sequence": true, "choice": true, "all": true, "anyAttribute": true,
+	"complexContent": true, "extension": true, "restriction": true,
+	"minLength": true, "maxLength": true, "pattern": true, "enumeration": true,
+	"minInclusive": true, "maxInclusive": true, "totalDigits": true, "fractionDigits": true,
+	"assert": true, "assertion": true,
+}
+
+// unsupportedAttributes lists the attributes of supported constructs that are ignored.
+var unsupportedAttributes = map[string][]string{
+	"element":     {"default", "fixed", "substitutionGroup", "abstract", "block", "final"},
+	"complexType": {"abstract", "block", "final"},
+}
+
+// schemaLinter collects the report entries of one compiled schema.
+type schemaLinter struct {
+	schema   *Schema
+	location string // Location of the main schema document
+	report   *CompileReport
+	reported map[string]bool // Findings already reported, as a content model may repeat one
+}
+
+// countComponents adds the schema's components to the report.
+func (l *schemaLinter) countComponents() {
+	counts := &l.report.Components
+	counts.Documents += len(l.schema.sources)
+	counts.Elements += len(l.schema.Elements)
+	counts.ComplexTypes += len(l.schema.ComplexTypes)
+	counts.SimpleTypes += len(l.schema.SimpleTypes)
+	counts.Attributes += len(l.schema.Attributes)
+	l.schema.forEachElement(func(element *Element, global bool) {
+		if !global {
+			counts.LocalElements++
+		}
+	})
+}
+
+// findUnsupported reports the unsupported elements and attributes of a schema document.
+// The content of an unsupported element is skipped, so each is reported once.
+func (l *schemaLinter) findUnsupported(location string, data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		line, _ := decoder.InputPos()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("XML parsing error in '%s': %w", location, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != xsdNamespace {
+			continue
+		}
+		if !supportedConstructs[start.Name.Local] {
+			l.report.Unsupported = append(l.report.Unsupported,
+				fmt.Sprintf("%s:%d: <xs:%s> is not supported and is ignored", location, line, start.Name.Local))
+			if err := decoder.Skip(); err != nil {
+				return fmt.Errorf("XML parsing error in '%s': %w", location, err)
+			}
+			continue
+		}
+		for _, name := range unsupportedAttributes[start.Name.Local] {
+			for _, attr := range start.Attr {
+				if attr.Name.Space == "" && attr.Name.Local == name {
+					l.report.Unsupported = append(l.report.Unsupported,
+						fmt.Sprintf("%s:%d: attribute '%s' of <xs:%s> is not supported and is ignored", location, line, name, start.Name.Local))
+				}
+			}
+		}
+	}
+}
+
+// checkContentModels checks the effective content model of every complex type.
+func (l *schemaLinter) checkContentModels() {
+	for i := range l.schema.ComplexTypes {
+		complexType := &l.schema.ComplexTypes[i]
+		l.checkContentModel(complexType, complexTypeLabel(complexType))
+	}
+	l.schema.forEachElement(func(element *Element, global bool) {
+		if element.ComplexType != nil {
+			l.checkContentModel(element.ComplexType, fmt.Sprintf("complex type of element <%s>", element.Name))
+		}
+	})
+}
+
+// checkContentModel reports the constraint violations of one complex type's content model.
+func (l *schemaLinter) checkContentModel(complexType *ComplexType, label string) {
+	var elements []*Element
+	if model := l.schema.contentModel(complexType); model != nil {
+		l.checkParticle(model, map[xml.Name]bool{}, label)
+		collectParticleElements(model, &elements)
+	} else if complexType.All != nil {
+		seen := make(map[xml.Name]bool)
+		for i := range complexType.All.Elements {
+			element := &complexType.All.Elements[i]
+			name := particleName(element)
+			if seen[name] {
+				l.finding("%s: element <%s> can match more than one particle of the content model (Unique Particle Attribution)", label, name.Local)
+			}
+			seen[name] = true
+			elements = append(elements, element)
+		}
+	}
+
+	types := make(map[xml.Name]any)
+	for _, element := range elements {
+		name, identity := particleName(element), l.typeIdentity(element)
+		if first, exists := types[name]; exists && first != identity {
+			l.finding("%s: elements <%s> have different types (Element Declarations Consistent)", label, name.Local)
+		}
+		types[name] = identity
+	}
+}
+
+// checkParticle reports the elements of a particle that can match more than one particle:
+// alternatives of a choice starting with the same element, and elements that may either
+// continue a particle of variable occurrence or follow it. follow is the set of elements
+// that may come right after the particle.
+func (l *schemaLinter) checkParticle(p *compiledParticle, follow map[xml.Name]bool, label string) {
+	first := firstNames(p)
+	if p.min != p.max {
+		for _, name := range sortedNames(first) {
+			if follow[name] {
+				l.finding("%s: element <%s> can match more than one particle of the content model (Unique Particle Attribution)", label, name.Local)
+			}
+		}
+	}
+
+	// Elements that may come after the last member: those after the particle, and the first
+	// ones of the particle itself when it repeats
+	inner := follow
+	if p.max == -1 || p.max > 1 {
+		inner = unionNames(follow, first)
+	}
+
+	switch p.kind {
+	case sequenceParticle:
+		// The elements that may follow each member, computed from the last one backwards
+		follows := make([]map[xml.Name]bool, len(p.members))
+		after := inner
+		for i := len(p.members) - 1; i >= 0; i-- {
+			follows[i] = after
+			if p.members[i].emptiable {
+				after = unionNames(after, firstNames(p.members[i]))
+			} else {
+				after = firstNames(p.members[i])
+			}
+		}
+		for i, member := range p.members {
+			l.checkParticle(member, follows[i], label)
+		}
+	case choiceParticle:
+		seen := make(map[xml.Name]bool)
+		for _, member := range p.members {
+			for _, name := range sortedNames(firstNames(member)) {
+				if seen[name] {
+					l.finding("%s: element <%s> can match more than one particle of the content model (Unique Particle Attribution)", label, name.Local)
+				}
+				seen[name] = true
+			}
+			l.checkParticle(member, inner, label)
+		}
+	}
+}
+
+// finding reports a constraint violation once.
+func (l *schemaLinter) finding(format string, args ...any) {
+	message := l.location + ": " + fmt.Sprintf(format, args...)
+	if !l.reported[message] {
+		l.reported[message] = true
+		l.report.Findings = append(l.report.Findings, message)
+	}
+}
+
+// typeIdentity returns a comparable identity of an element declaration's type: the
+// definition of anonymous types, the resolved name of named ones.
+func (l *schemaLinter) typeIdentity(element *Element) any {
+	switch {
+	case element.ComplexType != nil:
+		return element.ComplexType
+	case element.SimpleType != nil:
+		return element.SimpleType
+	case element.typeRef.Local != "":
+		return element.typeRef
+	default:
+		return element.Type
+	}
+}
+
+// findUnusedTypes warns about the named types no global element or attribute uses.
+func (l *schemaLinter) findUnusedTypes() {
+	pruner := &schemaPruner{
+		schema:       l.schema,
+		elements:     make(map[*Element]bool),
+		complexTypes: make(map[*ComplexType]bool),
+		simpleTypes:  make(map[*SimpleType]bool),
+	}
+	for i := range l.schema.Elements {
+		pruner.markElement(&l.schema.Elements[i])
+	}
+	for i := range l.schema.Attributes {
+		attribute := &l.schema.Attributes[i]
+		if attribute.SimpleType != nil {
+			pruner.markSimpleTypeContent(attribute.SimpleType)
+		}
+		pruner.markTypeReference(attribute.Type, attribute.typeRef)
+	}
+
+	for i := range l.schema.ComplexTypes {
+		if complexType := &l.schema.ComplexTypes[i]; !pruner.complexTypes[complexType] {
+			l.report.Warnings = append(l.report.Warnings,
+				fmt.Sprintf("%s: complex type '%s' is not used by any global element or attribute", l.location, complexType.Name))
+		}
+	}
+	for i := range l.schema.SimpleTypes {
+		if simpleType := &l.schema.SimpleTypes[i]; !pruner.simpleTypes[simpleType] {
+			l.report.Warnings = append(l.report.Warnings,
+				fmt.Sprintf("%s: simple type '%s' is not used by any global element or attribute", l.location, simpleType.Name))
+		}
+	}
+}
+
+// particleName returns the name instances of an element declaration have.
+func particleName(element *Element) xml.Name {
+	return xml.Name{Space: element.namespace, Local: ParseQName(element.Name).LocalName}
+}
+
+// firstNames returns the names of the elements that can start a particle.
+func firstNames(p *compiledParticle) map[xml.Name]bool {
+	names := make(map[xml.Name]bool)
+	switch p.kind {
+	case elementParticle:
+		names[particleName(p.element)] = true
+	case sequenceParticle:
+		for _, member := range p.members {
+			for name := range firstNames(member) {
+				names[name] = true
+			}
+			if !member.emptiable {
+				break
+			}
+		}
+	case choiceParticle:
+		for _, member := range p.members {
+			for name := range firstNames(member) {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
+
+// unionNames returns a new set with the names of both sets.
+func unionNames(a, b map[xml.Name]bool) map[xml.Name]bool {
+	union := make(map[xml.Name]bool, len(a)+len(b))
+	for name := range a {
+		union[name] = true
+	}
+	for name := range b {
+		union[name] = true
+	}
+	return union
+}
+
+// sortedNames returns the names of a set in a stable order, for reproducible reports.
+func sortedNames(names map[xml.Name]bool) []xml.Name {
+	sorted := make([]xml.Name, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Space != sorted[j].Space {
+			return sorted[i].Space < sorted[j].Space
+		}
+		return sorted[i].Local < sorted[j].Local
+	})
+	return sorted
+}
+
+// collectParticleElements appends the element declarations of a particle's element
+// particles, at any depth of group nesting.
+func collectParticleElements(p *compiledParticle, elements *[]*Element) {
+	if p.kind == elementParticle {
+		*elements = append(*elements, p.element)
+	}
+	for _, member := range p.members {
+		collectParticleElements(member, elements)
+	}
+}
@@ -0,0 +1,126 @@
+package xmlparser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test that Compile reports the components, unsupported constructs, findings and warnings of a schema set
+func TestCompile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_compile_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"main.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="types.xsd"/>
+	<xs:element name="order">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="note" type="xs:string" minOccurs="0"/>
+				<xs:element name="note" type="xs:string"/>
+				<xs:choice>
+					<xs:element name="item" type="Code"/>
+					<xs:sequence>
+						<xs:element name="item" type="xs:int"/>
+					</xs:sequence>
+				</xs:choice>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+	<xs:element name="status" type="xs:string" default="new"/>
+</xs:schema>`,
+		"types.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string">
+			<xs:whiteSpace value="collapse"/>
+		</xs:restriction>
+	</xs:simpleType>
+	<xs:simpleType name="Unused">
+		<xs:list itemType="xs:int"/>
+	</xs:simpleType>
+</xs:schema>`,
+		"other.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:example:other">
+	<xs:element name="ping">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="a" type="xs:string" maxOccurs="unbounded"/>
+				<xs:element name="b" type="xs:string" minOccurs="0"/>
+				<xs:element name="a" type="xs:string"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+		"clean.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:example:clean">
+	<xs:element name="pong">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="a" type="xs:string" maxOccurs="unbounded"/>
+				<xs:element name="b" type="xs:string"/>
+				<xs:element name="a" type="xs:string" minOccurs="0"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	mainPath, typesPath := filepath.Join(tmpDir, "main.xsd"), filepath.Join(tmpDir, "types.xsd")
+	otherPath := filepath.Join(tmpDir, "other.xsd")
+
+	report, err := Compile([]string{mainPath, otherPath}, ParseOptions{})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	if summary := report.Summary(); summary != "3 documents: 3 global elements, 7 local elements, 0 complex types, 2 simple types, 0 global attributes" {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+	expectedUnsupported := []string{
+		mainPath + ":17: attribute 'default' of <xs:element> is not supported and is ignored",
+		typesPath + ":4: <xs:whiteSpace> is not supported and is ignored",
+		typesPath + ":8: <xs:list> is not supported and is ignored",
+	}
+	if !reflect.DeepEqual(report.Unsupported, expectedUnsupported) {
+		t.Errorf("Expected unsupported constructs %q, got %q", expectedUnsupported, report.Unsupported)
+	}
+	expectedFindings := []string{
+		mainPath + ": complex type of element <order>: element <note> can match more than one particle of the content model (Unique Particle Attribution)",
+		mainPath + ": complex type of element <order>: element <item> can match more than one particle of the content model (Unique Particle Attribution)",
+		mainPath + ": complex type of element <order>: elements <item> have different types (Element Declarations Consistent)",
+		otherPath + ": complex type of element <ping>: element <a> can match more than one particle of the content model (Unique Particle Attribution)",
+	}
+	if !reflect.DeepEqual(report.Findings, expectedFindings) {
+		t.Errorf("Expected findings %q, got %q", expectedFindings, report.Findings)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "simple type 'Unused' is not used") {
+		t.Errorf("Expected a warning for the unused simple type, got %q", report.Warnings)
+	}
+	if report.Clean() {
+		t.Error("Expected the report not to be clean")
+	}
+
+	clean, err := Compile([]string{filepath.Join(tmpDir, "clean.xsd")}, ParseOptions{})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if !clean.Clean() || len(clean.Warnings) != 0 {
+		t.Errorf("Expected a clean report, got:\n%s", clean)
+	}
+
+	if _, err := Compile([]string{mainPath, mainPath}, ParseOptions{}); err == nil ||
+		!strings.Contains(err.Error(), "the set already has a schema for no namespace") {
+		t.Errorf("Expected error for two schemas without namespace, got: %v", err)
+	}
+	if _, err := Compile(nil, ParseOptions{}); err == nil {
+		t.Error("Expected error without schema locations")
+	}
+}
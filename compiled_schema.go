@@ -0,0 +1,173 @@
+package xmlparser
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// compiledSchema holds what validation derives from the schema components, besides the
+// links between them: the content model of every complex type, the hashed values of large
+// enumerations and the regular expressions of patterns. It is built when the schema is
+// parsed and only read afterwards, so concurrent validations share it without
+// synchronization.
+type compiledSchema struct {
+	contentModels   map[*ComplexType]*compiledParticle
+	enumerationSets map[*Restriction]map[string]struct{}
+	patterns        map[string]*regexp.Regexp
+}
+
+// Compile prepares the schema for validation: it builds the lookup maps of its global
+// components, links every element and attribute declaration and every restriction to the
+// type definitions it names, and compiles the content models of complex types, the
+// regular expressions of patterns and the values of large enumerations. Validation then
+// follows these links instead of looking types up by name for each element and value.
+// Compile fails if a pattern is not a valid regular expression.
+//
+// The parse functions return compiled schemas. Call Compile on schemas assembled by hand,
+// or after changing the exported fields of a parsed schema, before validating with them;
+// components Compile has not reached are still looked up and compiled as they are used.
+// Compile relinks the components in place, so like Prune it must not be called while the
+// schema is validating documents.
+func (s *Schema) Compile() error {
+	if err := s.buildLookupMaps(); err != nil {
+		return fmt.Errorf("failed to build schema lookup maps: %w", err)
+	}
+	return s.compile()
+}
+
+// compile links and compiles the components of the schema, once its documents are composed
+// or its components pruned. It reports the first pattern that does not compile.
+func (s *Schema) compile() error {
+	compiled := compiledSchema{
+		contentModels:   make(map[*ComplexType]*compiledParticle),
+		enumerationSets: make(map[*Restriction]map[string]struct{}),
+		patterns:        make(map[string]*regexp.Regexp),
+	}
+
+	// The declarations validation reaches: those written in the schema, and those of the
+	// effective content models and attribute uses of derived types
+	var elements []*Element
+	var attributes []*Attribute
+	s.forEachComplexType(func(complexType *ComplexType) {
+		if model := compileContentModel(complexType); model != nil {
+			compiled.contentModels[complexType] = model
+			collectParticleElements(model, &elements)
+		}
+		if complexType.All != nil {
+			for i := range complexType.All.Elements {
+				elements = append(elements, &complexType.All.Elements[i])
+			}
+		}
+		for i := range complexType.Attributes {
+			attributes = append(attributes, &complexType.Attributes[i])
+		}
+	})
+	s.forEachElement(func(element *Element, global bool) {
+		elements = append(elements, element)
+	})
+	s.forEachAttribute(func(attribute *Attribute, global bool) {
+		attributes = append(attributes, attribute)
+	})
+	var restrictions []*Restriction
+	var owners []string // The component defining each restriction, for errors
+	addRestriction := func(simpleType *SimpleType, owner string) {
+		if simpleType != nil && simpleType.Restriction != nil {
+			restrictions = append(restrictions, simpleType.Restriction)
+			owners = append(owners, owner)
+		}
+	}
+	for i := range s.SimpleTypes {
+		addRestriction(&s.SimpleTypes[i], fmt.Sprintf("simple type '%s'", s.SimpleTypes[i].Name))
+	}
+	for _, element := range elements {
+		addRestriction(element.SimpleType, fmt.Sprintf("element '%s'", element.Name))
+	}
+	for _, attribute := range attributes {
+		addRestriction(attribute.SimpleType, fmt.Sprintf("attribute '%s'", attribute.Name))
+	}
+
+	// Patterns are compiled first, so a schema with an invalid one is not linked
+	for i, restriction := range restrictions {
+		if restriction.Pattern == nil || restriction.Pattern.Value == "" {
+			continue
+		}
+		if _, ok := compiled.patterns[restriction.Pattern.Value]; ok {
+			continue
+		}
+		pattern, err := compilePattern(restriction.Pattern.Value)
+		if err != nil {
+			return fmt.Errorf("in %s: invalid pattern '%s': %w", owners[i], restriction.Pattern.Value, err)
+		}
+		compiled.patterns[restriction.Pattern.Value] = pattern
+	}
+
+	// Links left by an earlier compilation are cleared first, so lookups see the current
+	// definitions
+	for _, restriction := range restrictions {
+		restriction.linkedBase, restriction.builtIn = nil, ""
+	}
+	for _, element := range elements {
+		element.linked = false
+	}
+	for _, attribute := range attributes {
+		attribute.linked = false
+	}
+
+	for _, restriction := range restrictions {
+		base := s.restrictionBase(restriction)
+		restriction.linkedBase, restriction.builtIn = base, s.builtInBase(restriction.Base, base)
+	}
+	for _, element := range elements {
+		if !element.linked {
+			element.linkedComplexType = s.getComplexType(element)
+			element.linkedSimpleType, _ = s.findSimpleType(element)
+			element.linked = true
+		}
+	}
+	for _, attribute := range attributes {
+		if !attribute.linked {
+			attribute.linkedSimpleType = s.attributeSimpleType(attribute)
+			attribute.linked = true
+		}
+	}
+
+	for _, restriction := range restrictions {
+		if len(restriction.Enumeration) > enumerationSetThreshold {
+			values := make(map[string]struct{}, len(restriction.Enumeration))
+			for _, enum := range restriction.Enumeration {
+				values[enumerationKey(enum.Value, restriction.builtIn)] = struct{}{}
+			}
+			compiled.enumerationSets[restriction] = values
+		}
+	}
+	s.compiled = compiled
+	return nil
+}
+
+// contentModel returns the compiled content model of a complex type, or nil if the type
+// has no sequence or choice particle.
+func (s *Schema) contentModel(complexType *ComplexType) *compiledParticle {
+	if model, ok := s.compiled.contentModels[complexType]; ok {
+		return model
+	}
+	return compileContentModel(complexType)
+}
+
+// enumerationSet returns the hashed values of a large enumeration, or nil if they were not
+// compiled.
+func (s *Schema) enumerationSet(restriction *Restriction) map[string]struct{} {
+	return s.compiled.enumerationSets[restriction]
+}
+
+// pattern returns the regular expression of a pattern facet value.
+func (s *Schema) pattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := s.compiled.patterns[pattern]; ok {
+		return compiled, nil
+	}
+	// Schemas assembled by hand and not compiled: the pattern is compiled for each value
+	compiled, err := compilePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern in schema: %s", pattern)
+	}
+	return compiled, nil
+}
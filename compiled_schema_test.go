@@ -0,0 +1,59 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSchemaCompile(t *testing.T) {
+	// A schema assembled from its exported fields is validated once compiled
+	schema := &Schema{
+		SimpleTypes: []SimpleType{
+			{Name: "Quantity", Restriction: &Restriction{Base: "xs:integer", MaxInclusive: &Facet{Value: "10"}}},
+			{Name: "SmallQuantity", Restriction: &Restriction{Base: "Quantity", MaxInclusive: &Facet{Value: "3"}}},
+		},
+		Elements: []Element{{Name: "quantity", Type: "Quantity"}},
+	}
+	if err := schema.Compile(); err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+	quantity := &schema.Elements[0]
+	if !quantity.linked || quantity.linkedSimpleType != schema.SimpleTypeMap["Quantity"] {
+		t.Fatalf("Expected the element to be linked to its type, got %+v", quantity)
+	}
+	if small := schema.SimpleTypeMap["SmallQuantity"].Restriction; small.linkedBase != schema.SimpleTypeMap["Quantity"] || small.builtIn != "xs:integer" {
+		t.Fatalf("Expected the restriction to be linked to its base, got %+v", small)
+	}
+
+	validate := func(xml string) error {
+		doc, err := Parse([]byte(xml))
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+		return schema.Validate(doc)
+	}
+	if err := validate(`<quantity>5</quantity>`); err != nil {
+		t.Errorf("Expected a valid quantity, got: %v", err)
+	}
+	if err := validate(`<quantity>11</quantity>`); err == nil {
+		t.Error("Expected an error for a quantity above the maximum")
+	}
+
+	// Changes to the exported fields are linked when the schema is compiled again
+	schema.Elements[0].Type = "SmallQuantity"
+	if err := schema.Compile(); err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+	if err := validate(`<quantity>5</quantity>`); err == nil || !strings.Contains(err.Error(), "5") {
+		t.Errorf("Expected an error for a quantity above the new maximum, got: %v", err)
+	}
+
+	// Types that are not defined are still reported
+	schema.Elements[0].Type = "Missing"
+	if err := schema.Compile(); err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+	if err := validate(`<quantity>5</quantity>`); err == nil || !strings.Contains(err.Error(), "type definition 'Missing' not found") {
+		t.Errorf("Expected an error for the missing type, got: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package xmlparser
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// concurrencyTestSchema uses the features whose schema state validation reads: content
+// models of every kind, derived and xsi:type types, large enumerations, patterns,
+// identity, defaults and wildcards.
+const concurrencyTestSchema = `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:simpleType name="Country">
+    <xs:restriction base="xs:string">
+      <xs:enumeration value="AT"/><xs:enumeration value="BE"/><xs:enumeration value="CH"/>
+      <xs:enumeration value="DE"/><xs:enumeration value="DK"/><xs:enumeration value="ES"/>
+      <xs:enumeration value="FI"/><xs:enumeration value="FR"/><xs:enumeration value="GB"/>
+      <xs:enumeration value="IE"/><xs:enumeration value="IT"/><xs:enumeration value="LU"/>
+      <xs:enumeration value="NL"/><xs:enumeration value="NO"/><xs:enumeration value="PL"/>
+      <xs:enumeration value="PT"/><xs:enumeration value="SE"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="Sku">
+    <xs:restriction base="xs:token">
+      <xs:pattern value="[A-Z]{2}-[0-9]{3}"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:complexType name="Party">
+    <xs:sequence>
+      <xs:element name="name" type="xs:string"/>
+      <xs:element name="country" type="Country"/>
+    </xs:sequence>
+    <xs:attribute name="id" type="xs:ID" use="required"/>
+  </xs:complexType>
+  <xs:complexType name="Company">
+    <xs:complexContent>
+      <xs:extension base="Party">
+        <xs:sequence>
+          <xs:element name="vat" type="xs:string" minOccurs="0"/>
+        </xs:sequence>
+      </xs:extension>
+    </xs:complexContent>
+  </xs:complexType>
+  <xs:complexType name="Line">
+    <xs:all>
+      <xs:element name="sku" type="Sku"/>
+      <xs:element name="quantity" type="xs:positiveInteger"/>
+      <xs:element name="note" type="xs:string" minOccurs="0"/>
+    </xs:all>
+    <xs:attribute name="unit" type="xs:string" default="each"/>
+    <xs:anyAttribute namespace="##other" processContents="lax"/>
+  </xs:complexType>
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="buyer" type="Party"/>
+        <xs:choice>
+          <xs:element name="delivery" type="xs:date"/>
+          <xs:element name="pickup" type="xs:dateTime"/>
+        </xs:choice>
+        <xs:element name="line" type="Line" maxOccurs="unbounded"/>
+        <xs:element name="seller" type="xs:IDREF" minOccurs="0"/>
+        <xs:element name="comment" minOccurs="0" nillable="true">
+          <xs:complexType mixed="true">
+            <xs:sequence>
+              <xs:element name="b" type="xs:string" minOccurs="0" maxOccurs="unbounded"/>
+            </xs:sequence>
+          </xs:complexType>
+        </xs:element>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+// concurrencyTestDocument returns an order with the given number of lines; odd variants
+// have issues in some of them.
+func concurrencyTestDocument(variant, lines int) string {
+	var b strings.Builder
+	b.WriteString(`<order xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:e="urn:ext">`)
+	fmt.Fprintf(&b, `<buyer xsi:type="Company" id="p%d"><name>Buyer</name><country>SE</country><vat>SE1</vat></buyer>`, variant)
+	b.WriteString(`<delivery>2024-05-01</delivery>`)
+	for i := 0; i < lines; i++ {
+		sku := fmt.Sprintf("AB-%03d", i%1000)
+		if variant%2 == 1 && i%7 == 3 {
+			sku = "bad"
+		}
+		fmt.Fprintf(&b, `<line e:batch="%d"><quantity>%d</quantity><sku>%s</sku></line>`, i, i%5+1, sku)
+	}
+	fmt.Fprintf(&b, `<seller>p%d</seller><comment>Leave at <b>door</b></comment></order>`, variant%3)
+	return b.String()
+}
+
+// Test that one parsed Schema gives the same outcome to every kind of validation run
+// concurrently as it does sequentially. Run with -race to check that validation only reads
+// the schema: go test -race -run TestConcurrentValidation
+func TestConcurrentValidation(t *testing.T) {
+	schema, err := ParseXSD([]byte(concurrencyTestSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	// Everything validation derives from the schema is compiled with it
+	country := schema.SimpleTypeMap["Country"].Restriction
+	if len(schema.compiled.enumerationSets[country]) != 17 || schema.compiled.patterns["[A-Z]{2}-[0-9]{3}"] == nil ||
+		schema.compiled.contentModels[schema.ComplexTypeMap["Party"]] == nil {
+		t.Fatalf("Expected the enumerations, patterns and content models to be compiled: %+v", schema.compiled)
+	}
+
+	documents := make([]string, 6)
+	for i := range documents {
+		documents[i] = concurrencyTestDocument(i, 50)
+	}
+
+	// Each check returns a description of its outcome for a document
+	checks := map[string]func(xml string) string{
+		"Validate": func(xml string) string {
+			doc, _ := Parse([]byte(xml))
+			return fmt.Sprint(schema.Validate(doc))
+		},
+		"Validator": func(xml string) string {
+			doc, _ := Parse([]byte(xml))
+			return fmt.Sprint(schema.NewValidator(ValidateOptions{MaxErrors: 3}).Validate(doc))
+		},
+		"ValidateResult": func(xml string) string {
+			doc, _ := Parse([]byte(xml))
+			result := schema.ValidateResult(doc)
+			info, _ := result.Element(doc.Root.Children[0])
+			return fmt.Sprint(result.Issues, len(result.Warnings), result.Stats.Elements, result.Stats.InvalidElements, info.TypeName)
+		},
+		"ValidateParallel": func(xml string) string {
+			doc, _ := Parse([]byte(xml))
+			return fmt.Sprint(schema.ValidateParallel(doc, ParallelOptions{Workers: 3}))
+		},
+		"ValidateReader": func(xml string) string {
+			return fmt.Sprint(schema.ValidateReader(strings.NewReader(xml)))
+		},
+		"ValidateRecords": func(xml string) string {
+			doc, _ := Parse([]byte(xml))
+			result, err := schema.ValidateRecords(doc, "line", ValidateOptions{})
+			return fmt.Sprint(result.Summary(), err)
+		},
+		"Filter": func(xml string) string {
+			var out bytes.Buffer
+			result, err := schema.Filter(&out, strings.NewReader(xml), FilterOptions{DropInvalid: true})
+			return fmt.Sprint(out.Len(), result.Dropped, err)
+		},
+		"ContentModelString": func(string) string {
+			model, err := schema.ContentModelString("Party")
+			return fmt.Sprint(model, err)
+		},
+		"Generator": func(string) string {
+			generator, err := schema.NewGenerator("order")
+			if err != nil {
+				return err.Error()
+			}
+			return fmt.Sprint(schema.Validate(generator.Document(rand.New(rand.NewSource(1)), 5)))
+		},
+	}
+
+	expected := make(map[string][]string)
+	for name, check := range checks {
+		for _, xml := range documents {
+			expected[name] = append(expected[name], check(xml))
+		}
+	}
+	if !strings.Contains(expected["Validate"][1], "does not match pattern") || expected["Validate"][0] != "<nil>" {
+		t.Fatalf("Expected the odd documents only to be invalid, got: %v", expected["Validate"])
+	}
+
+	var wg sync.WaitGroup
+	for round := 0; round < 4; round++ {
+		for name, check := range checks {
+			for i, xml := range documents {
+				wg.Add(1)
+				go func(name string, check func(string) string, i int, xml string) {
+					defer wg.Done()
+					if actual := check(xml); actual != expected[name][i] {
+						t.Errorf("%s of document %d: expected %q, got %q", name, i, expected[name][i], actual)
+					}
+				}(name, check, i, xml)
+			}
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,68 @@
+package xmlparser
+
+import (
+	"context"
+	"errors"
+)
+
+// Message is a message consumed from a queue, such as a Kafka record or an AMQP delivery,
+// as handed to the handlers wrapped by Service.Consumer.
+type Message struct {
+	MessageType string // Schema bundle of the payload; ConsumerOptions.MessageType when empty
+	Payload     []byte // XML document
+	Raw         any    // The message as the client library delivered it, for acknowledgements and headers
+}
+
+// MessageHandler processes a consumed message. An error tells the consumer loop that the
+// message was not processed, so that it is retried or rejected as the loop decides.
+type MessageHandler func(ctx context.Context, message Message) error
+
+// DeadLetterHandler receives the messages that cannot be processed, with the report of
+// why, to park them in a dead-letter queue. A nil error means the message was handled.
+type DeadLetterHandler func(ctx context.Context, message Message, report ValidationReport) error
+
+// ConsumerOptions configures Service.Consumer.
+type ConsumerOptions struct {
+	// MessageType of the messages that do not give one, such as those of a topic that only
+	// carries one message type
+	MessageType string
+
+	// DeadLetter receives invalid messages. When nil, their ValidationError is returned
+	// instead.
+	DeadLetter DeadLetterHandler
+}
+
+// Consumer wraps the handler of a queue consumer so that it only receives valid messages:
+// each payload is validated against the schema of its message type, and invalid ones,
+// malformed ones included, go to the dead-letter handler with their ValidationReport.
+// Messages of a message type the service does not configure are dead-lettered too, with
+// a single CodeUnknownMessageType issue, as no redelivery can make them valid.
+//
+// The returned handler returns the error of the handler a message went to. A message type
+// whose schema is not loaded is an error as well, so the message can be retried once Start
+// succeeds.
+func (s *Service) Consumer(handle MessageHandler, opts ConsumerOptions) MessageHandler {
+	return func(ctx context.Context, message Message) error {
+		if message.MessageType == "" {
+			message.MessageType = opts.MessageType
+		}
+
+		err := s.ValidateContext(ctx, message.MessageType, message.Payload)
+		if err == nil {
+			return handle(ctx, message)
+		}
+
+		var validationErr *ValidationError
+		if _, exists := s.config.Bundles[message.MessageType]; !exists {
+			validationErr = &ValidationError{Issues: []ValidationIssue{
+				{Code: CodeUnknownMessageType, Message: err.Error(), Severity: SeverityError},
+			}}
+		} else if !errors.As(err, &validationErr) {
+			return err
+		}
+		if opts.DeadLetter == nil {
+			return validationErr
+		}
+		return opts.DeadLetter(ctx, message, validationErr.Report(message.MessageType))
+	}
+}
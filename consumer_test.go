@@ -0,0 +1,90 @@
+package xmlparser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConsumer(t *testing.T) {
+	dir, err := os.MkdirTemp("", "consumer")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = os.WriteFile(filepath.Join(dir, "orders.xsd"), []byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	service := NewService(ServiceConfig{Bundles: map[string]SchemaBundle{
+		"order":   {Location: filepath.Join(dir, "orders.xsd")},
+		"invoice": {Location: filepath.Join(dir, "invoices.xsd")},
+	}})
+	service.Start(context.Background())
+
+	var handled []Message
+	var deadLetters []ValidationReport
+	handlerErr := errors.New("handler failed")
+	consume := service.Consumer(func(ctx context.Context, message Message) error {
+		handled = append(handled, message)
+		if message.Raw == "fail" {
+			return handlerErr
+		}
+		return nil
+	}, ConsumerOptions{
+		MessageType: "order",
+		DeadLetter: func(ctx context.Context, message Message, report ValidationReport) error {
+			deadLetters = append(deadLetters, report)
+			return nil
+		},
+	})
+
+	tests := []struct {
+		name       string
+		message    Message
+		err        error
+		handled    bool
+		deadLetter IssueCode
+	}{
+		{"valid", Message{Payload: []byte(`<order><quantity>1</quantity></order>`)}, nil, true, ""},
+		{"handler error", Message{Payload: []byte(`<order><quantity>1</quantity></order>`), Raw: "fail"}, handlerErr, true, ""},
+		{"invalid", Message{Payload: []byte(`<order><quantity>0</quantity></order>`)}, nil, false, CodeInvalidValue},
+		{"malformed", Message{Payload: []byte(`<order>`)}, nil, false, CodeParseError},
+		{"unknown message type", Message{MessageType: "refund", Payload: []byte(`<refund/>`)}, nil, false, CodeUnknownMessageType},
+		{"schema not loaded", Message{MessageType: "invoice", Payload: []byte(`<invoice/>`)}, errors.New(""), false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled, deadLetters = nil, nil
+			err := consume(context.Background(), tt.message)
+			if (err == nil) != (tt.err == nil) || tt.err == handlerErr && !errors.Is(err, handlerErr) {
+				t.Errorf("Expected error %v, got: %v", tt.err, err)
+			}
+			if (len(handled) == 1) != tt.handled {
+				t.Errorf("Expected handled to be %v, got %d messages", tt.handled, len(handled))
+			}
+			if tt.deadLetter == "" && len(deadLetters) > 0 ||
+				tt.deadLetter != "" && (len(deadLetters) != 1 || deadLetters[0].Issues[0].Code != tt.deadLetter) {
+				t.Errorf("Expected dead letter %q, got %+v", tt.deadLetter, deadLetters)
+			}
+		})
+	}
+
+	consume = service.Consumer(func(ctx context.Context, message Message) error { return nil }, ConsumerOptions{})
+	var validationErr *ValidationError
+	if err := consume(context.Background(), Message{MessageType: "order", Payload: []byte(`<order/>`)}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected the ValidationError without a dead-letter handler, got: %v", err)
+	}
+}
@@ -0,0 +1,732 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// particle is a single term of a content model: an element declaration or a nested
+// xs:sequence or xs:choice group. Exactly one of the fields is set.
+type particle struct {
+	element  *Element
+	sequence *Sequence
+	choice   *Choice
+}
+
+// particleKind identifies which slice of a Sequence a particle was decoded into.
+type particleKind int
+
+const (
+	elementParticle particleKind = iota
+	sequenceParticle
+	choiceParticle
+)
+
+// particleRef records the document position of a particle within a Sequence.
+type particleRef struct {
+	kind  particleKind
+	index int
+}
+
+// UnmarshalXML decodes an xs:sequence while remembering the relative order of its
+// element, sequence and choice particles, which separate slices alone cannot preserve.
+func (s *Sequence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "minOccurs":
+			s.MinOccurs = attr.Value
+		case "maxOccurs":
+			s.MaxOccurs = attr.Value
+		}
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "element":
+				var element Element
+				if err := d.DecodeElement(&element, &t); err != nil {
+					return err
+				}
+				s.Elements = append(s.Elements, element)
+				s.order = append(s.order, particleRef{elementParticle, len(s.Elements) - 1})
+			case "sequence":
+				var sequence Sequence
+				if err := d.DecodeElement(&sequence, &t); err != nil {
+					return err
+				}
+				s.Sequences = append(s.Sequences, sequence)
+				s.order = append(s.order, particleRef{sequenceParticle, len(s.Sequences) - 1})
+			case "choice":
+				var choice Choice
+				if err := d.DecodeElement(&choice, &t); err != nil {
+					return err
+				}
+				s.Choices = append(s.Choices, choice)
+				s.order = append(s.order, particleRef{choiceParticle, len(s.Choices) - 1})
+			default:
+				// Annotations and unsupported particles are ignored
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// particles returns the sequence's particles in document order. Sequences built in Go
+// code rather than decoded list their elements first, then nested sequences and choices.
+func (s *Sequence) particles() []particle {
+	particles := make([]particle, 0, len(s.Elements)+len(s.Sequences)+len(s.Choices))
+	if len(s.order) == len(s.Elements)+len(s.Sequences)+len(s.Choices) {
+		for _, ref := range s.order {
+			switch ref.kind {
+			case elementParticle:
+				particles = append(particles, particle{element: &s.Elements[ref.index]})
+			case sequenceParticle:
+				particles = append(particles, particle{sequence: &s.Sequences[ref.index]})
+			case choiceParticle:
+				particles = append(particles, particle{choice: &s.Choices[ref.index]})
+			}
+		}
+		return particles
+	}
+
+	for i := range s.Elements {
+		particles = append(particles, particle{element: &s.Elements[i]})
+	}
+	for i := range s.Sequences {
+		particles = append(particles, particle{sequence: &s.Sequences[i]})
+	}
+	for i := range s.Choices {
+		particles = append(particles, particle{choice: &s.Choices[i]})
+	}
+	return particles
+}
+
+// particles returns the alternatives of a choice.
+func (c *Choice) particles() []particle {
+	particles := make([]particle, 0, len(c.Elements)+len(c.Sequences)+len(c.Choices))
+	for i := range c.Elements {
+		particles = append(particles, particle{element: &c.Elements[i]})
+	}
+	for i := range c.Sequences {
+		particles = append(particles, particle{sequence: &c.Sequences[i]})
+	}
+	for i := range c.Choices {
+		particles = append(particles, particle{choice: &c.Choices[i]})
+	}
+	return particles
+}
+
+// occurs returns the particle's minOccurs and maxOccurs, using -1 for unbounded.
+func (p particle) occurs() (int, int) {
+	var minOccurs, maxOccurs string
+	switch {
+	case p.element != nil:
+		minOccurs, maxOccurs = p.element.MinOccurs, p.element.MaxOccurs
+	case p.sequence != nil:
+		minOccurs, maxOccurs = p.sequence.MinOccurs, p.sequence.MaxOccurs
+	case p.choice != nil:
+		minOccurs, maxOccurs = p.choice.MinOccurs, p.choice.MaxOccurs
+	}
+	min, _ := parseOccurs(minOccurs)
+	return min, parseMaxOccurs(maxOccurs)
+}
+
+// compiledParticle is the validation-time form of a particle. Occurrence bounds are parsed
+// and group members resolved once, so matching does not re-read schema attributes for every
+// child of every validated node.
+type compiledParticle struct {
+	kind      particleKind
+	element   *Element            // Element declaration (element particles only)
+	members   []*compiledParticle // Sequence members in order, or choice alternatives
+	min, max  int                 // Occurrence bounds with defaults applied, max is -1 when unbounded
+	emptiable bool                // Whether the particle can match no children at all
+}
+
+// compileParticle compiles a particle and all particles nested in it.
+func compileParticle(p particle) *compiledParticle {
+	compiled := &compiledParticle{}
+	compiled.min, compiled.max = p.occurs()
+
+	var members []particle
+	switch {
+	case p.element != nil:
+		compiled.kind = elementParticle
+		compiled.element = p.element
+	case p.sequence != nil:
+		compiled.kind = sequenceParticle
+		members = p.sequence.particles()
+	case p.choice != nil:
+		compiled.kind = choiceParticle
+		members = p.choice.particles()
+	}
+
+	compiled.members = make([]*compiledParticle, len(members))
+	for i, member := range members {
+		compiled.members[i] = compileParticle(member)
+	}
+
+	compiled.emptiable = compiled.min == 0 || compiled.computeEmptiable()
+	return compiled
+}
+
+// computeEmptiable reports whether a particle with minOccurs > 0 can still match nothing:
+// a sequence whose members are all emptiable, or a choice with an emptiable alternative.
+func (p *compiledParticle) computeEmptiable() bool {
+	switch p.kind {
+	case sequenceParticle:
+		for _, member := range p.members {
+			if !member.emptiable {
+				return false
+			}
+		}
+		return true
+	case choiceParticle:
+		for _, member := range p.members {
+			if member.emptiable {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compileContentModel compiles the content model of a complex type, or returns nil if the
+// type has no sequence or choice particle.
+func compileContentModel(complexType *ComplexType) *compiledParticle {
+	switch {
+	case complexType.Sequence != nil:
+		return compileParticle(particle{sequence: complexType.Sequence})
+	case complexType.Choice != nil:
+		return compileParticle(particle{choice: complexType.Choice})
+	}
+	return nil
+}
+
+// hasEmptyContent reports whether a complex type declares no particles at all. Elements of
+// such a type (EMPTY content in the XSD spec) may carry attributes, but no child elements,
+// and no text unless the type is mixed.
+func (ct *ComplexType) hasEmptyContent() bool {
+	return ct.Sequence == nil && ct.Choice == nil && ct.All == nil
+}
+
+// childMatcher matches the children of a node against the content model of its type, fed
+// one at a time, as streaming validation reads them.
+type childMatcher interface {
+	// declaration returns the declaration a child is validated against, or nil if it has
+	// none, without matching it
+	declaration(child *Node) *Element
+
+	// feed matches the next child and returns the declaration it is validated against,
+	// or nil if it has none
+	feed(child *Node) *Element
+
+	// finish ends matching once every child has been fed and returns the errors found
+	finish() []string
+
+	// release makes the matcher's storage available for reuse
+	release()
+}
+
+// contentMatcher matches the children of a node against a sequence or choice content model.
+//
+// Children are fed one at a time and consumed left to right. Each particle greedily takes
+// as many consecutive children as it can, and groups repeat for as long as the next child
+// can start them. XSD requires content models to be deterministic (Unique Particle
+// Attribution), so this greedy walk never has to backtrack for a conforming schema, and it
+// only ever looks at the child being matched: the particles being matched are kept as a
+// stack of frames, which waits for the next child whenever one is needed. Each child is
+// thus matched as it is fed, and streaming validation drops it then.
+type contentMatcher struct {
+	v      *validator
+	parent *Node
+	root   *compiledParticle
+	frames []matchFrame // Particles being matched, innermost last; empty once the root is done
+
+	current   *Node  // Child being matched; nil while waiting for the next one
+	ended     bool   // Whether every child has been fed
+	pos       int    // Number of children consumed
+	previous  string // Local name of the last child consumed
+	unmatched *Node  // First child the content model did not consume, which ends matching
+
+	declared map[xml.Name]int // Children declared in the content model, by name
+	required []requirement    // Missing children, reported unless a later child matches them
+
+	recordMatches bool               // Whether matched records the declaration of each child
+	matched       map[*Node]*Element // Declaration each consumed child matched
+
+	undeclared []string // Errors for the children the content model does not declare
+	errors     []string // Errors of the matching; "" for requirements a later child met
+}
+
+// matchFrame is a particle being matched, with the state its matching step resumes from.
+type matchFrame struct {
+	p         *compiledParticle
+	kind      frameKind
+	stage     int  // Point the step resumes at once the frame it pushed is done
+	count     int  // Children, iterations or selections matched so far
+	start     int  // Children consumed when the current iteration or selection began
+	member    int  // Sequence member being matched (sequenceOnceFrame)
+	canRepeat bool // Whether the group is matched over several iterations (sequenceOnceFrame)
+}
+
+// frameKind is the matching step of a frame.
+type frameKind int
+
+const (
+	elementFrame      frameKind = iota // Children matching an element particle
+	surplusFrame                       // Children beyond an element particle's maxOccurs
+	sequenceFrame                      // Iterations of a sequence group
+	sequenceOnceFrame                  // A single iteration of a sequence group
+	choiceFrame                        // Selections of a choice group
+)
+
+// Stages of the frames of groups.
+const (
+	stageStart    = iota // Frame not started, or about to match the next child
+	stageMatched         // The particle pushed, an iteration, selection or member, is done
+	stageReported        // The members pushed to report missing children or surplus are done
+)
+
+// requirement is a required child reported missing by errors[index], unless a later child
+// matches its declaration, which makes it out of order instead.
+type requirement struct {
+	element *Element
+	index   int
+}
+
+// validateContentModel validates the children of a node against a compiled content model
+// and recursively validates every child against its matched declaration.
+func (v *validator) validateContentModel(node *Node, root *compiledParticle) []string {
+	m := v.acquireMatcher(node, root)
+	defer m.release()
+	m.recordMatches = true
+
+	for _, child := range node.Children {
+		m.feed(child)
+	}
+	errors := m.finish()
+
+	// Validate each declared child, including those left unmatched by ordering errors
+	for _, child := range node.Children {
+		def, ok := m.matched[child]
+		if !ok {
+			if def = m.findDeclaration(root, child); def == nil {
+				continue
+			}
+		}
+		errors = append(errors, v.validateNode(child, def)...)
+	}
+	return errors
+}
+
+// declaration returns the declaration of a child in the content model.
+func (m *contentMatcher) declaration(child *Node) *Element {
+	return m.findDeclaration(m.root, child)
+}
+
+// feed matches the next child and returns its declaration in the content model. Children
+// it does not declare are reported, without disturbing the ordering checks.
+func (m *contentMatcher) feed(child *Node) *Element {
+	def := m.findDeclaration(m.root, child)
+	if def == nil {
+		if mismatched := m.findDeclarationByLocalName(m.root, child); mismatched != nil {
+			m.undeclared = append(m.undeclared, namespaceMismatch(child, mismatched))
+		} else if m.root.kind == choiceParticle {
+			m.undeclared = append(m.undeclared, fmt.Sprintf("element <%s> is not a valid choice for <%s>",
+				child.Name.Local, m.parent.Name.Local))
+		} else {
+			m.undeclared = append(m.undeclared, fmt.Sprintf("element <%s> is not a valid child of <%s>",
+				child.Name.Local, m.parent.Name.Local))
+		}
+		return nil
+	}
+
+	m.declared[child.Name]++
+	met := m.required[:0]
+	for _, r := range m.required {
+		if m.v.elementMatches(child.Name, r.element) {
+			m.errors[r.index] = ""
+		} else {
+			met = append(met, r)
+		}
+	}
+	m.required = met
+
+	if m.unmatched == nil {
+		m.current = child
+		m.match()
+		if m.current != nil {
+			m.unmatched, m.current = child, nil
+		}
+	}
+	return def
+}
+
+// finish matches the rest of the content model once every child has been fed, and returns
+// the errors found.
+func (m *contentMatcher) finish() []string {
+	m.ended = true
+	m.match()
+
+	if child := m.unmatched; child != nil {
+		def := m.findDeclaration(m.root, child)
+		if _, max := (particle{element: def}).occurs(); max >= 0 && m.countMatching(def) > max {
+			m.errors = append(m.errors, fmt.Sprintf(
+				"element <%s> allows at most %d <%s> child, but found %d",
+				m.parent.Name.Local, max, def.Name, m.countMatching(def)))
+		} else if m.pos == 0 {
+			m.errors = append(m.errors, fmt.Sprintf("element <%s> is out of order in <%s>",
+				child.Name.Local, m.parent.Name.Local))
+		} else {
+			m.errors = append(m.errors, fmt.Sprintf("element <%s> is out of order in <%s>: it must appear before <%s>",
+				child.Name.Local, m.parent.Name.Local, m.previous))
+		}
+	}
+
+	errors := m.undeclared
+	for _, message := range m.errors {
+		if message != "" {
+			errors = append(errors, message)
+		}
+	}
+	return errors
+}
+
+// release makes the matcher available to acquireMatcher again.
+func (m *contentMatcher) release() {
+	m.v.releaseMatcher(m)
+}
+
+// match runs the frames until the root particle is done, or until one needs the next
+// child before it is fed.
+func (m *contentMatcher) match() {
+	for len(m.frames) > 0 {
+		f := &m.frames[len(m.frames)-1]
+		var done bool
+		switch f.kind {
+		case elementFrame:
+			done = m.matchElement(f)
+		case surplusFrame:
+			done = m.consumeSurplus(f)
+		case sequenceFrame:
+			done = m.matchSequence(f)
+		case sequenceOnceFrame:
+			done = m.matchSequenceOnce(f)
+		case choiceFrame:
+			done = m.matchChoice(f)
+		}
+		if !done {
+			return
+		}
+	}
+}
+
+// push starts matching a particle with the given step. Steps return right after pushing,
+// as their frame may move.
+func (m *contentMatcher) push(p *compiledParticle, kind frameKind, canRepeat bool) {
+	m.frames = append(m.frames, matchFrame{p: p, kind: kind, canRepeat: canRepeat})
+}
+
+// pushParticle starts matching a particle at the current position.
+func (m *contentMatcher) pushParticle(p *compiledParticle) {
+	switch p.kind {
+	case elementParticle:
+		m.push(p, elementFrame, false)
+	case sequenceParticle:
+		m.push(p, sequenceFrame, false)
+	case choiceParticle:
+		m.push(p, choiceFrame, false)
+	}
+}
+
+// pop ends the innermost frame.
+func (m *contentMatcher) pop() {
+	m.frames = m.frames[:len(m.frames)-1]
+}
+
+// waiting reports whether the next child is needed and has not been fed yet.
+func (m *contentMatcher) waiting() bool {
+	return m.current == nil && !m.ended
+}
+
+// consume matches the current child with an element declaration.
+func (m *contentMatcher) consume(element *Element) {
+	if m.recordMatches {
+		m.matched[m.current] = element
+	}
+	m.previous, m.current = m.current.Name.Local, nil
+	m.pos++
+}
+
+// require reports a missing required child, unless the current child or a later one
+// matches its declaration: it is then reported as out of order instead.
+func (m *contentMatcher) require(element *Element, message string) {
+	if m.current != nil && m.v.elementMatches(m.current.Name, element) {
+		return
+	}
+	if !m.ended {
+		m.required = append(m.required, requirement{element: element, index: len(m.errors)})
+	}
+	m.errors = append(m.errors, message)
+}
+
+// matchElement consumes consecutive children matching an element particle, up to
+// maxOccurs. Like the other steps, it returns false when waiting for the next child, and
+// true once its frame is done or it pushed another.
+func (m *contentMatcher) matchElement(f *matchFrame) bool {
+	p := f.p
+	for {
+		if m.waiting() {
+			return false
+		}
+		if m.current == nil || (p.max >= 0 && f.count >= p.max) || !m.v.elementMatches(m.current.Name, p.element) {
+			break
+		}
+		m.consume(p.element)
+		f.count++
+	}
+
+	// A required element that appears later is reported as out of order instead
+	if f.count < p.min {
+		m.require(p.element, fmt.Sprintf(
+			"element <%s> requires at least %d <%s> child, but found %d",
+			m.parent.Name.Local, p.min, p.element.Name, f.count))
+	}
+	m.pop()
+	return true
+}
+
+// consumeSurplus consumes children beyond an element particle's maxOccurs and reports them.
+func (m *contentMatcher) consumeSurplus(f *matchFrame) bool {
+	p := f.p
+	if p.max >= 0 {
+		for {
+			if m.waiting() {
+				return false
+			}
+			if m.current == nil || !m.v.elementMatches(m.current.Name, p.element) {
+				break
+			}
+			m.consume(p.element)
+			f.count++
+		}
+		if f.count > 0 {
+			m.errors = append(m.errors, fmt.Sprintf(
+				"element <%s> allows at most %d <%s> child, but found %d",
+				m.parent.Name.Local, p.max, p.element.Name, p.max+f.count))
+		}
+	}
+	m.pop()
+	return true
+}
+
+// matchSequence matches a sequence group as many times as the following children allow
+// and checks the number of iterations against the group's own occurrence bounds.
+//
+// A group that may occur only once, whether by default or with maxOccurs="1", stops after its
+// single iteration, leaving any repeated children to be reported against their element
+// declarations instead. Groups with larger bounds count every iteration, including those
+// beyond maxOccurs, and report the excess against the group.
+func (m *contentMatcher) matchSequence(f *matchFrame) bool {
+	p := f.p
+	repeat := true
+	switch f.stage {
+	case stageMatched:
+		f.count++
+		f.stage = stageStart
+		repeat = m.pos > f.start
+	case stageReported:
+		m.pop()
+		return true
+	}
+
+	if repeat {
+		if m.waiting() {
+			return false
+		}
+		if m.current != nil && (p.max != 1 || f.count < 1) && m.canStart(p, m.current) {
+			f.start, f.stage = m.pos, stageMatched
+			m.push(p, sequenceOnceFrame, p.max != 1)
+			return true
+		}
+	}
+
+	switch {
+	case f.count == 0 && p.min > 0:
+		// Report the missing required children of the group
+		f.stage = stageReported
+		m.push(p, sequenceOnceFrame, false)
+		return true
+	case f.count < p.min:
+		m.errors = append(m.errors, fmt.Sprintf(
+			"element <%s> requires at least %d occurrences of its sequence group, but found %d",
+			m.parent.Name.Local, p.min, f.count))
+	case p.max >= 0 && f.count > p.max:
+		m.errors = append(m.errors, fmt.Sprintf(
+			"element <%s> allows at most %d occurrences of its sequence group, but found %d",
+			m.parent.Name.Local, p.max, f.count))
+	}
+	m.pop()
+	return true
+}
+
+// matchSequenceOnce matches a single iteration of a sequence group. canRepeat reports whether
+// the group is matched over several iterations, which decides whether a repeated element
+// belongs to the next iteration or exceeds its own maxOccurs.
+func (m *contentMatcher) matchSequenceOnce(f *matchFrame) bool {
+	p := f.p
+	for f.member < len(p.members) {
+		member := p.members[f.member]
+		switch f.stage {
+		case stageStart:
+			f.stage = stageMatched
+			m.pushParticle(member)
+			return true
+		case stageMatched:
+			// Repeated elements that neither a later member nor a new iteration can absorb
+			if member.kind == elementParticle && !f.canRepeat {
+				if m.waiting() {
+					return false
+				}
+				next := m.current
+				if next != nil && m.v.elementMatches(next.Name, member.element) && !m.canStartAny(p.members[f.member+1:], next) {
+					f.stage = stageReported
+					m.push(member, surplusFrame, false)
+					return true
+				}
+			}
+		}
+		f.member, f.stage = f.member+1, stageStart
+	}
+	m.pop()
+	return true
+}
+
+// matchChoice matches a choice group as many times as the following children allow
+// and checks the number of selections against the group's occurrence bounds.
+func (m *contentMatcher) matchChoice(f *matchFrame) bool {
+	p := f.p
+	repeat := true
+	if f.stage == stageMatched {
+		f.count++
+		f.stage = stageStart
+		repeat = m.pos > f.start
+	}
+
+	if repeat {
+		if m.waiting() {
+			return false
+		}
+		if m.current != nil {
+			for _, alternative := range p.members {
+				if m.canStart(alternative, m.current) {
+					f.start, f.stage = m.pos, stageMatched
+					m.pushParticle(alternative)
+					return true
+				}
+			}
+		}
+	}
+
+	switch {
+	case f.count == 0 && !p.emptiable:
+		m.errors = append(m.errors, fmt.Sprintf("element <%s> must contain at least one choice element",
+			m.parent.Name.Local))
+	case f.count > 0 && f.count < p.min:
+		m.errors = append(m.errors, fmt.Sprintf(
+			"element <%s> choice requires at least %d selections, but found %d",
+			m.parent.Name.Local, p.min, f.count))
+	case p.max >= 0 && f.count > p.max:
+		m.errors = append(m.errors, fmt.Sprintf(
+			"element <%s> choice allows at most %d selections, but found %d",
+			m.parent.Name.Local, p.max, f.count))
+	}
+	m.pop()
+	return true
+}
+
+// canStart reports whether a particle can begin with the given child.
+func (m *contentMatcher) canStart(p *compiledParticle, child *Node) bool {
+	switch p.kind {
+	case elementParticle:
+		return m.v.elementMatches(child.Name, p.element)
+	case sequenceParticle:
+		return m.canStartAny(p.members, child)
+	case choiceParticle:
+		for _, alternative := range p.members {
+			if m.canStart(alternative, child) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canStartAny reports whether a run of sequence members can begin with the given child,
+// looking past leading members that may be omitted.
+func (m *contentMatcher) canStartAny(members []*compiledParticle, child *Node) bool {
+	for _, member := range members {
+		if m.canStart(member, child) {
+			return true
+		}
+		if !member.emptiable {
+			return false
+		}
+	}
+	return false
+}
+
+// countMatching returns the number of declared children matching the element declaration.
+func (m *contentMatcher) countMatching(element *Element) int {
+	count := 0
+	for name, n := range m.declared {
+		if m.v.elementMatches(name, element) {
+			count += n
+		}
+	}
+	return count
+}
+
+// findDeclarationByLocalName searches a content model for the declaration a child in the
+// wrong namespace would match if namespaces were ignored, or returns nil.
+func (m *contentMatcher) findDeclarationByLocalName(p *compiledParticle, child *Node) *Element {
+	if p.kind == elementParticle {
+		if ParseQName(p.element.Name).LocalName == child.Name.Local {
+			return p.element
+		}
+		return nil
+	}
+	for _, member := range p.members {
+		if element := m.findDeclarationByLocalName(member, child); element != nil {
+			return element
+		}
+	}
+	return nil
+}
+
+// findDeclaration searches a content model for the element declaration matching a child.
+func (m *contentMatcher) findDeclaration(p *compiledParticle, child *Node) *Element {
+	if p.kind == elementParticle {
+		if m.v.elementMatches(child.Name, p.element) {
+			return p.element
+		}
+		return nil
+	}
+	for _, member := range p.members {
+		if element := m.findDeclaration(member, child); element != nil {
+			return element
+		}
+	}
+	return nil
+}
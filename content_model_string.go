@@ -0,0 +1,89 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// ContentModelString renders the content model of a global complex type as a compact
+// grammar, derived from the compiled particle tree the validator matches against:
+// sequences are written "(id, email, status?)", choices "(card | invoice)" and xs:all
+// groups "(name & email)". Occurrence bounds follow each term as "?", "*", "+", "{2}",
+// "{2,5}" or "{2,}". A type without particles is "EMPTY". Text allowed by mixed content
+// and attributes are not shown. Derived types show their effective content model,
+// including inherited particles.
+func (s *Schema) ContentModelString(typeName string) (string, error) {
+	complexType := s.lookupComplexType(typeName, xml.Name{})
+	if complexType == nil {
+		return "", fmt.Errorf("complex type '%s' not found in schema", typeName)
+	}
+
+	var b strings.Builder
+	if model := s.contentModel(complexType); model != nil {
+		writeParticle(&b, model)
+	} else if complexType.All != nil {
+		writeAll(&b, complexType.All)
+	} else {
+		b.WriteString("EMPTY")
+	}
+	return b.String(), nil
+}
+
+// writeParticle writes a compiled particle and its occurrence bounds.
+func writeParticle(b *strings.Builder, p *compiledParticle) {
+	switch p.kind {
+	case elementParticle:
+		b.WriteString(p.element.Name)
+	case sequenceParticle, choiceParticle:
+		separator := ", "
+		if p.kind == choiceParticle {
+			separator = " | "
+		}
+		b.WriteByte('(')
+		for i, member := range p.members {
+			if i > 0 {
+				b.WriteString(separator)
+			}
+			writeParticle(b, member)
+		}
+		b.WriteByte(')')
+	}
+	writeOccurrence(b, p.min, p.max)
+}
+
+// writeAll writes an xs:all group, whose elements may appear in any order.
+func writeAll(b *strings.Builder, all *All) {
+	b.WriteByte('(')
+	for i := range all.Elements {
+		if i > 0 {
+			b.WriteString(" & ")
+		}
+		b.WriteString(all.Elements[i].Name)
+		min, max := particle{element: &all.Elements[i]}.occurs()
+		writeOccurrence(b, min, max)
+	}
+	b.WriteByte(')')
+	if min, _ := parseOccurs(all.MinOccurs); min == 0 {
+		b.WriteByte('?')
+	}
+}
+
+// writeOccurrence writes the suffix for occurrence bounds, max being -1 when unbounded.
+func writeOccurrence(b *strings.Builder, min, max int) {
+	switch {
+	case min == 1 && max == 1:
+	case min == 0 && max == 1:
+		b.WriteByte('?')
+	case min == 0 && max < 0:
+		b.WriteByte('*')
+	case min == 1 && max < 0:
+		b.WriteByte('+')
+	case max < 0:
+		fmt.Fprintf(b, "{%d,}", min)
+	case min == max:
+		fmt.Fprintf(b, "{%d}", min)
+	default:
+		fmt.Fprintf(b, "{%d,%d}", min, max)
+	}
+}
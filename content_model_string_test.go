@@ -0,0 +1,79 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test that content models are rendered as compact grammars
+func TestContentModelString(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:complexType name="Customer">
+        <xs:sequence>
+            <xs:element name="id" type="xs:string"/>
+            <xs:element name="email" type="xs:string" maxOccurs="unbounded"/>
+            <xs:element name="status" type="xs:string" minOccurs="0"/>
+        </xs:sequence>
+    </xs:complexType>
+    <xs:complexType name="Payment">
+        <xs:choice minOccurs="0" maxOccurs="unbounded">
+            <xs:element name="card" type="xs:string"/>
+            <xs:sequence>
+                <xs:element name="iban" type="xs:string"/>
+                <xs:element name="bic" type="xs:string" minOccurs="2" maxOccurs="5"/>
+            </xs:sequence>
+        </xs:choice>
+    </xs:complexType>
+    <xs:complexType name="Contact">
+        <xs:all minOccurs="0">
+            <xs:element name="name" type="xs:string"/>
+            <xs:element name="phone" type="xs:string" minOccurs="0"/>
+        </xs:all>
+    </xs:complexType>
+    <xs:complexType name="VipCustomer">
+        <xs:complexContent>
+            <xs:extension base="Customer">
+                <xs:sequence>
+                    <xs:element name="tier" type="xs:int" minOccurs="3" maxOccurs="3"/>
+                    <xs:element name="perk" type="xs:string" minOccurs="2" maxOccurs="unbounded"/>
+                </xs:sequence>
+            </xs:extension>
+        </xs:complexContent>
+    </xs:complexType>
+    <xs:complexType name="Marker">
+        <xs:attribute name="id" type="xs:string"/>
+    </xs:complexType>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		typeName string
+		expected string
+	}{
+		{"Customer", "(id, email+, status?)"},
+		{"Payment", "(card | (iban, bic{2,5}))*"},
+		{"Contact", "(name & phone?)?"},
+		{"VipCustomer", "((id, email+, status?), (tier{3}, perk{2,}))"},
+		{"Marker", "EMPTY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			model, err := schema.ContentModelString(tt.typeName)
+			if err != nil {
+				t.Fatalf("ContentModelString failed: %v", err)
+			}
+			if model != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, model)
+			}
+		})
+	}
+
+	if _, err := schema.ContentModelString("Missing"); err == nil ||
+		!strings.Contains(err.Error(), "complex type 'Missing' not found in schema") {
+		t.Errorf("Expected error for an unknown type, got: %v", err)
+	}
+}
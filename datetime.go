@@ -0,0 +1,227 @@
+package xmlparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Lexical patterns of the date and time types. Every pattern ends with an optional
+// timezone group; component ranges are checked separately so errors can say what is wrong.
+// Years have at least four digits, leading zeros only when exactly four, and may be negative.
+const (
+	yearPattern     = `(-?(?:[1-9]\d{3,}|0\d{3}))`
+	timePattern     = `(\d{2}):(\d{2}):(\d{2}(?:\.\d+)?)`
+	timezonePattern = `(Z|[+-]\d{2}:\d{2})?`
+)
+
+var (
+	dateRegexp       = regexp.MustCompile(`^` + yearPattern + `-(\d{2})-(\d{2})` + timezonePattern + `$`)
+	dateTimeRegexp   = regexp.MustCompile(`^` + yearPattern + `-(\d{2})-(\d{2})T` + timePattern + timezonePattern + `$`)
+	timeRegexp       = regexp.MustCompile(`^` + timePattern + timezonePattern + `$`)
+	gYearRegexp      = regexp.MustCompile(`^` + yearPattern + timezonePattern + `$`)
+	gYearMonthRegexp = regexp.MustCompile(`^` + yearPattern + `-(\d{2})` + timezonePattern + `$`)
+	gMonthRegexp     = regexp.MustCompile(`^--(\d{2})` + timezonePattern + `$`)
+	gMonthDayRegexp  = regexp.MustCompile(`^--(\d{2})-(\d{2})` + timezonePattern + `$`)
+	gDayRegexp       = regexp.MustCompile(`^---(\d{2})` + timezonePattern + `$`)
+)
+
+// validateDate validates an xs:date value such as 2024-02-29 or 2024-02-29+02:00.
+func validateDate(content string, version SchemaVersion) error {
+	m := dateRegexp.FindStringSubmatch(content)
+	if m == nil {
+		return fmt.Errorf("value '%s' is not a valid date (expected format: YYYY-MM-DD)", excerpt(content))
+	}
+	return dateTimeError(content, "date", checkYear(m[1], version), checkDate(m[1], m[2], m[3]), checkTimezone(m[4]))
+}
+
+// validateDateTime validates an xs:dateTime value; xs:dateTimeStamp additionally requires a timezone.
+func validateDateTime(content string, requireTimezone bool, version SchemaVersion) error {
+	m := dateTimeRegexp.FindStringSubmatch(content)
+	if m == nil || (requireTimezone && m[7] == "") {
+		if requireTimezone {
+			return fmt.Errorf("value '%s' is not a valid dateTimeStamp (expected format: YYYY-MM-DDTHH:mm:ss with timezone)", excerpt(content))
+		}
+		return fmt.Errorf("value '%s' is not a valid dateTime (expected format: YYYY-MM-DDTHH:mm:ss)", excerpt(content))
+	}
+
+	typeName := "dateTime"
+	if requireTimezone {
+		typeName = "dateTimeStamp"
+	}
+	return dateTimeError(content, typeName, checkYear(m[1], version), checkDate(m[1], m[2], m[3]), checkTime(m[4], m[5], m[6]), checkTimezone(m[7]))
+}
+
+// validateTime validates an xs:time value such as 13:20:00 or 13:20:00.5Z.
+func validateTime(content string) error {
+	m := timeRegexp.FindStringSubmatch(content)
+	if m == nil {
+		return fmt.Errorf("value '%s' is not a valid time (expected format: HH:mm:ss)", excerpt(content))
+	}
+	return dateTimeError(content, "time", checkTime(m[1], m[2], m[3]), checkTimezone(m[4]))
+}
+
+// validateGYear validates an xs:gYear value such as 2024.
+func validateGYear(content string, version SchemaVersion) error {
+	m := gYearRegexp.FindStringSubmatch(content)
+	if m == nil {
+		return fmt.Errorf("value '%s' is not a valid gYear (expected format: YYYY)", excerpt(content))
+	}
+	return dateTimeError(content, "gYear", checkYear(m[1], version), checkTimezone(m[2]))
+}
+
+// validateGYearMonth validates an xs:gYearMonth value such as 2024-02.
+func validateGYearMonth(content string, version SchemaVersion) error {
+	m := gYearMonthRegexp.FindStringSubmatch(content)
+	if m == nil {
+		return fmt.Errorf("value '%s' is not a valid gYearMonth (expected format: YYYY-MM)", excerpt(content))
+	}
+	return dateTimeError(content, "gYearMonth", checkYear(m[1], version), checkMonth(m[2]), checkTimezone(m[3]))
+}
+
+// validateGMonth validates an xs:gMonth value such as --12.
+func validateGMonth(content string) error {
+	m := gMonthRegexp.FindStringSubmatch(content)
+	if m == nil {
+		return fmt.Errorf("value '%s' is not a valid gMonth (expected format: --MM)", excerpt(content))
+	}
+	return dateTimeError(content, "gMonth", checkMonth(m[1]), checkTimezone(m[2]))
+}
+
+// validateGMonthDay validates an xs:gMonthDay value such as --02-29. The day is checked
+// against the longest the month can be, so --02-29 is valid and --04-31 is not.
+func validateGMonthDay(content string) error {
+	m := gMonthDayRegexp.FindStringSubmatch(content)
+	if m == nil {
+		return fmt.Errorf("value '%s' is not a valid gMonthDay (expected format: --MM-DD)", excerpt(content))
+	}
+	if err := checkMonth(m[1]); err != nil {
+		return dateTimeError(content, "gMonthDay", err)
+	}
+	month, _ := strconv.Atoi(m[1])
+	return dateTimeError(content, "gMonthDay", checkDayInRange(m[2], daysInMonth(2000, month)), checkTimezone(m[3]))
+}
+
+// validateGDay validates an xs:gDay value such as ---31.
+func validateGDay(content string) error {
+	m := gDayRegexp.FindStringSubmatch(content)
+	if m == nil {
+		return fmt.Errorf("value '%s' is not a valid gDay (expected format: ---DD)", excerpt(content))
+	}
+	return dateTimeError(content, "gDay", checkDayInRange(m[1], 31), checkTimezone(m[2]))
+}
+
+// dateTimeError wraps the first component error, if any, into a validation error for the value.
+func dateTimeError(content, typeName string, errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return fmt.Errorf("value '%s' is not a valid %s: %v", excerpt(content), typeName, err)
+		}
+	}
+	return nil
+}
+
+// checkYear rejects the negative year zero, and the year zero in XSD 1.0, which has no
+// year 0000. XSD 1.1 numbers years astronomically, as ISO 8601 does: 0000 is 1 BCE and
+// -0001 is 2 BCE.
+func checkYear(year string, version SchemaVersion) error {
+	switch {
+	case year == "-0000":
+		return fmt.Errorf("year -0000 is not allowed")
+	case year == "0000" && version == XSD10:
+		return fmt.Errorf("year 0000 is not allowed in XSD 1.0")
+	}
+	return nil
+}
+
+// leapCycleYear reduces a year of any length to one with the same position in the
+// 400-year Gregorian cycle, so expanded years beyond the int range are handled.
+func leapCycleYear(year string) int {
+	digits := strings.TrimPrefix(year, "-")
+	if len(digits) > 4 {
+		digits = digits[len(digits)-4:]
+	}
+	y, _ := strconv.Atoi(digits)
+	return y
+}
+
+// checkDate checks that a year, month and day form a real calendar date.
+func checkDate(year, month, day string) error {
+	if err := checkMonth(month); err != nil {
+		return err
+	}
+	m, _ := strconv.Atoi(month)
+	if err := checkDayInRange(day, daysInMonth(leapCycleYear(year), m)); err != nil {
+		return fmt.Errorf("%v for %s-%s", err, year, month)
+	}
+	return nil
+}
+
+// checkMonth checks that a two-digit month is between 01 and 12.
+func checkMonth(month string) error {
+	if m, _ := strconv.Atoi(month); m < 1 || m > 12 {
+		return fmt.Errorf("month %s is out of range", month)
+	}
+	return nil
+}
+
+// checkDayInRange checks that a two-digit day is between 01 and maxDay.
+func checkDayInRange(day string, maxDay int) error {
+	if d, _ := strconv.Atoi(day); d < 1 || d > maxDay {
+		return fmt.Errorf("day %s is out of range", day)
+	}
+	return nil
+}
+
+// checkTime checks hour, minute and second ranges. 24:00:00 is allowed as the end of a day.
+func checkTime(hour, minute, second string) error {
+	h, _ := strconv.Atoi(hour)
+	m, _ := strconv.Atoi(minute)
+	s, _ := strconv.ParseFloat(second, 64)
+
+	switch {
+	case h == 24 && (m != 0 || s != 0):
+		return fmt.Errorf("hour 24 is only allowed as 24:00:00")
+	case h > 24:
+		return fmt.Errorf("hour %s is out of range", hour)
+	case m > 59:
+		return fmt.Errorf("minute %s is out of range", minute)
+	case s >= 60:
+		return fmt.Errorf("second %s is out of range", second)
+	}
+	return nil
+}
+
+// checkTimezone checks a timezone offset; offsets range from -14:00 to +14:00.
+func checkTimezone(timezone string) error {
+	if timezone == "" || timezone == "Z" {
+		return nil
+	}
+	h, _ := strconv.Atoi(timezone[1:3])
+	m, _ := strconv.Atoi(timezone[4:6])
+	if m > 59 || h > 14 || (h == 14 && m != 0) {
+		return fmt.Errorf("timezone %s is out of range", timezone)
+	}
+	return nil
+}
+
+// daysInMonth returns the number of days in a month of the proleptic Gregorian calendar.
+func daysInMonth(year, month int) int {
+	switch month {
+	case 2:
+		if isLeapYear(year) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	default:
+		return 31
+	}
+}
+
+// isLeapYear reports whether a year is a Gregorian leap year.
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
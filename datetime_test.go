@@ -0,0 +1,99 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test calendar-aware validation of the date and time built-in types
+func TestDateTimeBuiltInTypes(t *testing.T) {
+	tests := []struct {
+		typeName    string
+		value       string
+		errorString string // empty when the value is valid
+	}{
+		{"xs:date", "2024-02-29", ""},
+		{"xs:date", "2000-02-29", ""},
+		{"xs:date", "2024-12-31+14:00", ""},
+		{"xs:date", "2023-02-29", "day 29 is out of range for 2023-02"},
+		{"xs:date", "1900-02-29", "day 29 is out of range for 1900-02"},
+		{"xs:date", "2024-04-31", "day 31 is out of range for 2024-04"},
+		{"xs:date", "2024-13-01", "month 13 is out of range"},
+		{"xs:date", "2024-00-10", "month 00 is out of range"},
+		{"xs:date", "2024-01-00", "day 00 is out of range"},
+		{"xs:date", "2024-01-01+14:30", "timezone +14:30 is out of range"},
+		{"xs:date", "2024-1-01", "expected format: YYYY-MM-DD"},
+		{"xs:date", "-0428-03-15", ""},
+		{"xs:date", "12023-06-01", ""},
+		{"xs:date", "0000-02-29", "year 0000 is not allowed in XSD 1.0"},
+		{"xs:date", "-0004-02-29", ""},
+		{"xs:date", "-0100-02-29", "day 29 is out of range for -0100-02"},
+		{"xs:date", "123456789012345678902000-02-29", ""},
+		{"xs:date", "123456789012345678901900-02-29", "day 29 is out of range"},
+		{"xs:date", "02024-01-01", "expected format: YYYY-MM-DD"},
+		{"xs:date", "-0000-01-01", "year -0000 is not allowed"},
+		{"xs:date", "+2024-01-01", "expected format: YYYY-MM-DD"},
+		{"xs:dateTime", "2024-06-30T23:59:59.999Z", ""},
+		{"xs:dateTime", "2024-06-30T24:00:00", ""},
+		{"xs:dateTime", "2024-06-30T24:00:01", "hour 24 is only allowed as 24:00:00"},
+		{"xs:dateTime", "2024-06-30T25:00:00", "hour 25 is out of range"},
+		{"xs:dateTime", "2024-06-30T12:60:00", "minute 60 is out of range"},
+		{"xs:dateTime", "2024-06-30T12:00:60", "second 60 is out of range"},
+		{"xs:dateTime", "2024-06-31T12:00:00", "day 31 is out of range for 2024-06"},
+		{"xs:dateTime", "2024-06-30T12:00:00-05:60", "timezone -05:60 is out of range"},
+		{"xs:dateTimeStamp", "2024-06-30T12:00:00-05:00", ""},
+		{"xs:dateTimeStamp", "2024-02-30T12:00:00Z", "is not a valid dateTimeStamp: day 30"},
+		{"xs:time", "00:00:00", ""},
+		{"xs:time", "24:00:00Z", ""},
+		{"xs:time", "23:59:60", "second 60 is out of range"},
+		{"xs:time", "12:30", "expected format: HH:mm:ss"},
+		{"xs:gYear", "2024", ""},
+		{"xs:gYear", "2024Z", ""},
+		{"xs:gYear", "2024+15:00", "timezone +15:00 is out of range"},
+		{"xs:gYear", "-0428", ""},
+		{"xs:gYear", "12023Z", ""},
+		{"xs:gYear", "0000", "year 0000 is not allowed in XSD 1.0"},
+		{"xs:gYear", "-0000", "year -0000 is not allowed"},
+		{"xs:gYear", "-428", "expected format: YYYY"},
+		{"xs:gYear", "012023", "expected format: YYYY"},
+		{"xs:dateTime", "-0044-03-15T12:00:00", ""},
+		{"xs:gYearMonth", "10000-12", ""},
+		{"xs:gYearMonth", "-0000-12", "year -0000 is not allowed"},
+		{"xs:gYearMonth", "2024-02", ""},
+		{"xs:gYearMonth", "2024-02-05:00", ""},
+		{"xs:gYearMonth", "2024-13", "month 13 is out of range"},
+		{"xs:gYearMonth", "2024-2", "expected format: YYYY-MM"},
+		{"xs:gYearMonth", "2024-01+14:01", "timezone +14:01 is out of range"},
+		{"xs:gMonthDay", "--02-29", ""},
+		{"xs:gMonthDay", "--12-31Z", ""},
+		{"xs:gMonthDay", "--02-30", "day 30 is out of range"},
+		{"xs:gMonthDay", "--04-31", "day 31 is out of range"},
+		{"xs:gMonthDay", "--00-01", "month 00 is out of range"},
+		{"xs:gMonthDay", "02-28", "expected format: --MM-DD"},
+		{"xs:gMonth", "--12", ""},
+		{"xs:gMonth", "--13", "month 13 is out of range"},
+		{"xs:gDay", "---31", ""},
+		{"xs:gDay", "---32", "day 32 is out of range"},
+		{"xs:gDay", "---00-01:00", "day 00 is out of range"},
+		{"xs:duration", "P1Y2M3DT4H5M6.5S", ""},
+		{"xs:duration", "-PT0S", ""},
+		{"xs:duration", "P", "not a valid duration"},
+		{"xs:duration", "PT", "not a valid duration"},
+		{"xs:duration", "P1DT", "not a valid duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeName+" "+tt.value, func(t *testing.T) {
+			err := validateBuiltInType(tt.value, tt.typeName, XSD10)
+			if tt.errorString == "" {
+				if err != nil {
+					t.Errorf("Expected '%s' to be a valid %s, got: %v", tt.value, tt.typeName, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorString) {
+				t.Errorf("Expected error containing '%s' for %s '%s', got: %v", tt.errorString, tt.typeName, tt.value, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package xmlparser
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Lexical forms of xs:decimal and xs:integer. Unlike strconv.ParseFloat, neither allows
+// exponents, hexadecimal digits, or special values such as "Inf" and "NaN".
+var (
+	decimalRegexp = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	integerRegexp = regexp.MustCompile(`^[+-]?\d+$`)
+)
+
+// parseDecimal parses an xs:decimal lexical value with arbitrary precision.
+func parseDecimal(value string) (*big.Rat, bool) {
+	if !decimalRegexp.MatchString(value) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strings.TrimPrefix(value, "+"))
+}
+
+// parseInteger parses an xs:integer lexical value with arbitrary precision.
+func parseInteger(value string) (*big.Int, bool) {
+	if !integerRegexp.MatchString(value) {
+		return nil, false
+	}
+	return new(big.Int).SetString(strings.TrimPrefix(value, "+"), 10)
+}
+
+// isDecimalType reports whether a built-in type is xs:decimal or derived from it, such as
+// xs:integer or xs:unsignedInt. Values of these types are compared with arbitrary precision.
+func isDecimalType(typeName string) bool {
+	for depth := 0; typeName != "" && depth < maxDerivationDepth; depth++ {
+		if typeName == "xs:decimal" {
+			return true
+		}
+		typeName = builtInBaseTypes[typeName]
+	}
+	return false
+}
+
+// isIntegerType reports whether a built-in type is xs:integer or derived from it.
+func isIntegerType(typeName string) bool {
+	return isDecimalType(typeName) && typeName != "xs:decimal"
+}
+
+// compareDecimal compares a value of a decimal-derived type with a facet limit, returning
+// -1, 0 or +1 as the value is less than, equal to or greater than the limit.
+func compareDecimal(content, limitValue, baseType string) (int, error) {
+	contentNum, ok := parseDecimal(content)
+	if !ok || (isIntegerType(baseType) && !integerRegexp.MatchString(content)) {
+		if isIntegerType(baseType) {
+			return 0, fmt.Errorf("value '%s' is not a valid integer", excerpt(content))
+		}
+		return 0, fmt.Errorf("value '%s' is not a valid decimal number", excerpt(content))
+	}
+	limitNum, ok := parseDecimal(strings.TrimSpace(limitValue))
+	if !ok {
+		return 0, fmt.Errorf("invalid limit value in schema: %s", limitValue)
+	}
+	return contentNum.Cmp(limitNum), nil
+}
+
+// decimalDigits returns the number of significant digits of a decimal lexical value and
+// how many of them follow the decimal point. Leading zeros and trailing fractional zeros
+// are not significant; zero itself has one digit.
+func decimalDigits(value string) (total, fraction int) {
+	value = strings.TrimLeft(value, "+-")
+	integer, frac, _ := strings.Cut(value, ".")
+	integer = strings.TrimLeft(integer, "0")
+	frac = strings.TrimRight(frac, "0")
+	if integer == "" && frac == "" {
+		return 1, 0
+	}
+	return len(integer) + len(frac), len(frac)
+}
+
+// validateDigitConstraints checks the totalDigits and fractionDigits facets.
+func validateDigitConstraints(content string, restriction *Restriction) []string {
+	if restriction.TotalDigits == nil && restriction.FractionDigits == nil {
+		return nil
+	}
+	content = strings.TrimSpace(content)
+	if !decimalRegexp.MatchString(content) {
+		return nil // Reported by the built-in type check
+	}
+
+	var errors []string
+	total, fraction := decimalDigits(content)
+
+	if restriction.TotalDigits != nil && restriction.TotalDigits.Value != "" {
+		if maxDigits, err := strconv.Atoi(restriction.TotalDigits.Value); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid totalDigits value in schema: %s", restriction.TotalDigits.Value))
+		} else if total > maxDigits {
+			errors = append(errors, fmt.Sprintf("value '%s' has too many digits (totalDigits: %d, actual: %d)",
+				excerpt(content), maxDigits, total))
+		}
+	}
+
+	if restriction.FractionDigits != nil && restriction.FractionDigits.Value != "" {
+		if maxDigits, err := strconv.Atoi(restriction.FractionDigits.Value); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid fractionDigits value in schema: %s", restriction.FractionDigits.Value))
+		} else if fraction > maxDigits {
+			errors = append(errors, fmt.Sprintf("value '%s' has too many fraction digits (fractionDigits: %d, actual: %d)",
+				excerpt(content), maxDigits, fraction))
+		}
+	}
+
+	return errors
+}
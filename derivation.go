@@ -0,0 +1,260 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// anyTypeName is the built-in xs:anyType, the base of every complex type.
+var anyTypeName = xml.Name{Space: xsdNamespace, Local: "anyType"}
+
+// derivation returns the extension or restriction of a type derived by complexContent
+// together with the name of the method, or nil for types that are not derived.
+func (ct *ComplexType) derivation() (*ComplexDerivation, string) {
+	switch {
+	case ct.ComplexContent == nil:
+		return nil, ""
+	case ct.ComplexContent.Extension != nil:
+		return ct.ComplexContent.Extension, "extension"
+	case ct.ComplexContent.Restriction != nil:
+		return ct.ComplexContent.Restriction, "restriction"
+	default:
+		return nil, ""
+	}
+}
+
+// declaredAttributes returns the attribute declarations written in a complex type, which
+// for derived types are those of the extension or restriction.
+func (ct *ComplexType) declaredAttributes() []Attribute {
+	if derivation, _ := ct.derivation(); derivation != nil {
+		return derivation.Attributes
+	}
+	return ct.Attributes
+}
+
+// declaredWildcard returns the xs:anyAttribute written in a complex type, if any.
+func (ct *ComplexType) declaredWildcard() *AnyAttribute {
+	if derivation, _ := ct.derivation(); derivation != nil {
+		return derivation.AnyAttribute
+	}
+	return ct.AnyAttribute
+}
+
+// complexTypeLabel names a complex type in error messages.
+func complexTypeLabel(ct *ComplexType) string {
+	if ct.Name == "" {
+		return "anonymous complex type"
+	}
+	return fmt.Sprintf("complex type '%s'", ct.Name)
+}
+
+// forEachComplexType calls fn for every complex type definition in the schema: global types
+// and the anonymous types of element declarations at any depth. Derived types are walked
+// as declared, through the particles of their extension or restriction.
+func (s *Schema) forEachComplexType(fn func(*ComplexType)) {
+	for i := range s.ComplexTypes {
+		walkComplexType(&s.ComplexTypes[i], fn)
+	}
+	for i := range s.Elements {
+		walkElementType(&s.Elements[i], fn)
+	}
+}
+
+// walkElementType visits an element's anonymous complex type and the types nested in it.
+func walkElementType(element *Element, fn func(*ComplexType)) {
+	if element.ComplexType != nil {
+		walkComplexType(element.ComplexType, fn)
+	}
+}
+
+// walkComplexType visits a complex type and the anonymous types of its element particles.
+func walkComplexType(complexType *ComplexType, fn func(*ComplexType)) {
+	fn(complexType)
+	complexType.forEachDeclaredElement(func(element *Element) {
+		walkElementType(element, fn)
+	})
+}
+
+// declaredContent returns the particles written in a complex type, which for derived types
+// are those of the extension or restriction.
+func (ct *ComplexType) declaredContent() (*Sequence, *Choice, *All) {
+	if derivation, _ := ct.derivation(); derivation != nil {
+		return derivation.Sequence, derivation.Choice, derivation.All
+	}
+	return ct.Sequence, ct.Choice, ct.All
+}
+
+// forEachDeclaredElement calls fn for the local element declarations written in a complex
+// type, at any depth of sequence and choice nesting but not within their anonymous types.
+func (ct *ComplexType) forEachDeclaredElement(fn func(*Element)) {
+	sequence, choice, all := ct.declaredContent()
+	if sequence != nil {
+		forEachSequenceElement(sequence, fn)
+	}
+	if choice != nil {
+		forEachChoiceElement(choice, fn)
+	}
+	if all != nil {
+		for i := range all.Elements {
+			fn(&all.Elements[i])
+		}
+	}
+}
+
+// forEachSequenceElement calls fn for the element declarations within an xs:sequence.
+func forEachSequenceElement(sequence *Sequence, fn func(*Element)) {
+	for i := range sequence.Elements {
+		fn(&sequence.Elements[i])
+	}
+	for i := range sequence.Sequences {
+		forEachSequenceElement(&sequence.Sequences[i], fn)
+	}
+	for i := range sequence.Choices {
+		forEachChoiceElement(&sequence.Choices[i], fn)
+	}
+}
+
+// forEachChoiceElement calls fn for the element declarations within an xs:choice.
+func forEachChoiceElement(choice *Choice, fn func(*Element)) {
+	for i := range choice.Elements {
+		fn(&choice.Elements[i])
+	}
+	for i := range choice.Sequences {
+		forEachSequenceElement(&choice.Sequences[i], fn)
+	}
+	for i := range choice.Choices {
+		forEachChoiceElement(&choice.Choices[i], fn)
+	}
+}
+
+// resolveComplexBases resolves the base type names of complexContent derivations with the
+// schema document's namespace declarations, so that bases defined in imported documents are
+// found once all documents are merged.
+func (s *Schema) resolveComplexBases() {
+	s.forEachComplexType(func(complexType *ComplexType) {
+		if derivation, _ := complexType.derivation(); derivation != nil {
+			complexType.base, _ = s.qualifiedName(derivation.Base)
+		}
+	})
+}
+
+// applyComplexDerivations computes the effective content model and attribute uses of every
+// type derived by complexContent from those of its base type. It runs once all schema
+// documents are merged, since a base type may be defined in any of them.
+func (s *Schema) applyComplexDerivations() error {
+	var err error
+	s.forEachComplexType(func(complexType *ComplexType) {
+		if err == nil {
+			err = s.applyDerivation(complexType, 0)
+		}
+	})
+	return err
+}
+
+// applyDerivation applies the derivation of a complex type, after that of its base type.
+func (s *Schema) applyDerivation(complexType *ComplexType, depth int) error {
+	if complexType.ComplexContent == nil || complexType.inherited {
+		return nil
+	}
+	derivation, method := complexType.derivation()
+	if derivation == nil {
+		return fmt.Errorf("%s: complexContent requires an extension or restriction", complexTypeLabel(complexType))
+	}
+	if depth > maxDerivationDepth {
+		return fmt.Errorf("%s has a circular derivation", complexTypeLabel(complexType))
+	}
+
+	// xs:anyType contributes no declared content or attributes; its attribute wildcard is
+	// taken into account by attributeWildcard
+	base := &ComplexType{}
+	if complexType.base != anyTypeName {
+		if base = s.complexTypeNSMap[complexType.base]; base == nil {
+			return fmt.Errorf("%s derives from '%s', which is not a complex type defined in the schema",
+				complexTypeLabel(complexType), derivation.Base)
+		}
+		if err := s.applyDerivation(base, depth+1); err != nil {
+			return err
+		}
+	}
+
+	complexType.Sequence, complexType.Choice, complexType.All = derivation.Sequence, derivation.Choice, derivation.All
+	if method == "extension" {
+		if err := complexType.extendContent(base); err != nil {
+			return err
+		}
+	}
+	complexType.Attributes = effectiveAttributeUses(base.Attributes, derivation.Attributes)
+	complexType.inherited = true
+	return nil
+}
+
+// extendContent prefixes the content model of an extension with that of its base type:
+// the effective content is a sequence of the base particle followed by the extension's own.
+func (ct *ComplexType) extendContent(base *ComplexType) error {
+	if base.hasEmptyContent() {
+		return nil
+	}
+	if ct.hasEmptyContent() {
+		ct.Sequence, ct.Choice, ct.All = base.Sequence, base.Choice, base.All
+		return nil
+	}
+	if base.All != nil || ct.All != nil {
+		return fmt.Errorf("%s cannot extend content with an xs:all group", complexTypeLabel(ct))
+	}
+
+	combined := &Sequence{}
+	for _, group := range []*ComplexType{base, ct} {
+		if group.Sequence != nil {
+			combined.Sequences = append(combined.Sequences, *group.Sequence)
+			combined.order = append(combined.order, particleRef{sequenceParticle, len(combined.Sequences) - 1})
+		}
+		if group.Choice != nil {
+			combined.Choices = append(combined.Choices, *group.Choice)
+			combined.order = append(combined.order, particleRef{choiceParticle, len(combined.Choices) - 1})
+		}
+	}
+	ct.Sequence, ct.Choice = combined, nil
+	return nil
+}
+
+// effectiveAttributeUses combines the attribute uses of a base type with the declarations of
+// a derived type: a declaration replaces the base's use of the same attribute, and
+// use="prohibited" removes it.
+func effectiveAttributeUses(inherited, declared []Attribute) []Attribute {
+	uses := append([]Attribute(nil), inherited...)
+	for _, attribute := range declared {
+		index := -1
+		for i := range uses {
+			if uses[i].Name == attribute.Name && uses[i].namespace == attribute.namespace {
+				index = i
+				break
+			}
+		}
+
+		switch {
+		case attribute.Use == "prohibited" && index >= 0:
+			uses = append(uses[:index], uses[index+1:]...)
+		case attribute.Use == "prohibited":
+		case index >= 0:
+			uses[index] = attribute
+		default:
+			uses = append(uses, attribute)
+		}
+	}
+	return uses
+}
+
+// derivesFromComplex reports whether a complex type is base or derived from it, by
+// extension or restriction, at any depth.
+func (s *Schema) derivesFromComplex(complexType, base *ComplexType) bool {
+	for depth := 0; complexType != nil && depth <= maxDerivationDepth; depth++ {
+		if complexType == base {
+			return true
+		}
+		if complexType.ComplexContent == nil {
+			return false
+		}
+		complexType = s.complexTypeNSMap[complexType.base]
+	}
+	return false
+}
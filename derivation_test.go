@@ -0,0 +1,191 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+const derivationSchema = `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:test"
+           targetNamespace="urn:test" elementFormDefault="qualified">
+    <xs:complexType name="Party">
+        <xs:sequence>
+            <xs:element name="name" type="xs:string"/>
+        </xs:sequence>
+        <xs:attribute name="id" type="xs:string" use="required"/>
+    </xs:complexType>
+
+    <xs:complexType name="Person">
+        <xs:complexContent>
+            <xs:extension base="t:Party">
+                <xs:sequence>
+                    <xs:element name="birthDate" type="xs:date"/>
+                </xs:sequence>
+                <xs:attribute name="title" type="xs:string"/>
+            </xs:extension>
+        </xs:complexContent>
+    </xs:complexType>
+
+    <xs:complexType name="Employee">
+        <xs:complexContent>
+            <xs:extension base="t:Person">
+                <xs:choice>
+                    <xs:element name="badge" type="xs:positiveInteger"/>
+                    <xs:element name="contractor" type="xs:boolean"/>
+                </xs:choice>
+            </xs:extension>
+        </xs:complexContent>
+    </xs:complexType>
+
+    <xs:complexType name="Anonymous">
+        <xs:complexContent>
+            <xs:restriction base="t:Party">
+                <xs:sequence/>
+                <xs:attribute name="id" type="xs:string" use="optional"/>
+            </xs:restriction>
+        </xs:complexContent>
+    </xs:complexType>
+
+    <xs:element name="party" type="t:Party"/>
+    <xs:element name="person" type="t:Person"/>
+    <xs:element name="employee" type="t:Employee"/>
+    <xs:element name="anonymous" type="t:Anonymous"/>
+</xs:schema>`
+
+// Test complex types derived by extension and restriction of complex content
+func TestComplexContentDerivation(t *testing.T) {
+	schema, err := ParseXSD([]byte(derivationSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	const xsi = ` xmlns:t="urn:test" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"`
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Extension appends content to the base content",
+			xml:        `<person xmlns="urn:test" id="p1" title="Dr"><name>Ada</name><birthDate>1815-12-10</birthDate></person>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Extension requires the base content first",
+			xml:         `<person xmlns="urn:test" id="p1"><birthDate>1815-12-10</birthDate><name>Ada</name></person>`,
+			errorString: "<name>",
+		},
+		{
+			name:        "Extension inherits required attributes",
+			xml:         `<person xmlns="urn:test"><name>Ada</name><birthDate>1815-12-10</birthDate></person>`,
+			errorString: "required attribute 'id' is missing from element <person>",
+		},
+		{
+			name:       "Extension of an extension",
+			xml:        `<employee xmlns="urn:test" id="e1" title="Ms"><name>Grace</name><birthDate>1906-12-09</birthDate><badge>7</badge></employee>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Inherited element types are validated",
+			xml:         `<employee xmlns="urn:test" id="e1"><name>Grace</name><birthDate>someday</birthDate><contractor>true</contractor></employee>`,
+			errorString: "someday",
+		},
+		{
+			name:       "Restriction replaces content and attribute uses",
+			xml:        `<anonymous xmlns="urn:test"/>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Restriction does not inherit content",
+			xml:         `<anonymous xmlns="urn:test"><name>Ada</name></anonymous>`,
+			errorString: "element <name> is not a valid child of <anonymous>",
+		},
+		{
+			name:       "xsi:type selects a derived complex type",
+			xml:        `<party xmlns="urn:test"` + xsi + ` xsi:type="t:Person" id="p1"><name>Ada</name><birthDate>1815-12-10</birthDate></party>`,
+			shouldPass: true,
+		},
+		{
+			name:        "xsi:type must derive from the declared type",
+			xml:         `<person xmlns="urn:test"` + xsi + ` xsi:type="t:Party" id="p1"><name>Ada</name></person>`,
+			errorString: "xsi:type 't:Party' on element <person> is not derived from its declared type 't:Person'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+}
+
+// Test that pruning keeps the base types of reachable derived types
+func TestPruneKeepsBaseTypes(t *testing.T) {
+	schema, err := ParseXSD([]byte(derivationSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+	if err := schema.Prune("employee"); err != nil {
+		t.Fatalf("Failed to prune schema: %v", err)
+	}
+	for _, name := range []string{"Party", "Person", "Employee"} {
+		if _, exists := schema.ComplexTypeMap[name]; !exists {
+			t.Errorf("Expected complex type %s to be kept", name)
+		}
+	}
+	if _, exists := schema.ComplexTypeMap["Anonymous"]; exists {
+		t.Error("Expected unreachable complex type Anonymous to be removed")
+	}
+}
+
+// Test schema errors in complex content derivations
+func TestComplexContentDerivationErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		types     string
+		errorText string
+	}{
+		{
+			name: "Unknown base type",
+			types: `<xs:complexType name="A"><xs:complexContent>
+                        <xs:extension base="Missing"/>
+                    </xs:complexContent></xs:complexType>`,
+			errorText: "complex type 'A' derives from 'Missing', which is not a complex type defined in the schema",
+		},
+		{
+			name: "Circular derivation",
+			types: `<xs:complexType name="A"><xs:complexContent><xs:extension base="B"/></xs:complexContent></xs:complexType>
+                    <xs:complexType name="B"><xs:complexContent><xs:extension base="A"/></xs:complexContent></xs:complexType>`,
+			errorText: "has a circular derivation",
+		},
+		{
+			name: "Extension of xs:all content",
+			types: `<xs:complexType name="A"><xs:all><xs:element name="a" type="xs:string"/></xs:all></xs:complexType>
+                    <xs:complexType name="B"><xs:complexContent><xs:extension base="A">
+                        <xs:sequence><xs:element name="b" type="xs:string"/></xs:sequence>
+                    </xs:extension></xs:complexContent></xs:complexType>`,
+			errorText: "complex type 'B' cannot extend content with an xs:all group",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseXSD([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">` + tt.types + `</xs:schema>`))
+			if err == nil || !strings.Contains(err.Error(), tt.errorText) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errorText, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package xmlparser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CorpusDocument is a document of a corpus validated by DiffValidation, with a name (such as
+// its file name) identifying it in the diff.
+type CorpusDocument struct {
+	Name     string
+	Document *Document
+}
+
+// DocumentChange is how the validation of one document changed between two schemas.
+type DocumentChange struct {
+	Name    string
+	Before  []string // Issues found with the old schema
+	After   []string // Issues found with the new schema
+	Added   []string // Issues found only with the new schema
+	Removed []string // Issues found only with the old schema
+}
+
+// ValidationDiff compares the validation of a document corpus against two versions of a
+// schema, for checking that a schema change accepts and rejects what it is meant to.
+type ValidationDiff struct {
+	NewlyFailing []DocumentChange // Valid with the old schema, invalid with the new one
+	NewlyPassing []DocumentChange // Invalid with the old schema, valid with the new one
+	Changed      []DocumentChange // Invalid with both schemas, with different issues
+	Unchanged    int              // Documents with the same issues, if any, with both schemas
+}
+
+// Empty reports whether the two schemas validate every document the same way.
+func (d *ValidationDiff) Empty() bool {
+	return len(d.NewlyFailing) == 0 && len(d.NewlyPassing) == 0 && len(d.Changed) == 0
+}
+
+// Summary describes the diff in one line, e.g. "2 newly failing, 1 newly passing,
+// 0 with changed issues, 40 unchanged".
+func (d *ValidationDiff) Summary() string {
+	return fmt.Sprintf("%d newly failing, %d newly passing, %d with changed issues, %d unchanged",
+		len(d.NewlyFailing), len(d.NewlyPassing), len(d.Changed), d.Unchanged)
+}
+
+// DiffValidation validates every document of a corpus against the old schema (before) and
+// the new one (after) with the same options, and reports the documents whose outcome or
+// issues differ, in corpus order. Issues are compared as messages, counting repeats, so a
+// document reporting the same problem at another position counts as changed.
+func DiffValidation(before, after *Schema, corpus []CorpusDocument, opts ValidateOptions) *ValidationDiff {
+	diff := &ValidationDiff{}
+	beforeValidator, afterValidator := before.NewValidator(opts), after.NewValidator(opts)
+	for _, entry := range corpus {
+		change := DocumentChange{
+			Name:   entry.Name,
+			Before: issueMessages(beforeValidator.Validate(entry.Document)),
+			After:  issueMessages(afterValidator.Validate(entry.Document)),
+		}
+		change.Added = subtractIssues(change.After, change.Before)
+		change.Removed = subtractIssues(change.Before, change.After)
+
+		switch {
+		case len(change.Added) == 0 && len(change.Removed) == 0:
+			diff.Unchanged++
+		case len(change.Before) == 0:
+			diff.NewlyFailing = append(diff.NewlyFailing, change)
+		case len(change.After) == 0:
+			diff.NewlyPassing = append(diff.NewlyPassing, change)
+		default:
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	return diff
+}
+
+// issueMessages returns the messages of a validation error: one per issue for a
+// ValidationError, the error's text otherwise.
+func issueMessages(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		messages := validationErr.Messages()
+		if validationErr.Omitted > 0 {
+			messages = append(messages, fmt.Sprintf("... and %d more", validationErr.Omitted))
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
+
+// subtractIssues returns the issues of a that are not in b, counting repeats.
+func subtractIssues(a, b []string) []string {
+	remaining := make(map[string]int, len(b))
+	for _, issue := range b {
+		remaining[issue]++
+	}
+	var difference []string
+	for _, issue := range a {
+		if remaining[issue] > 0 {
+			remaining[issue]--
+			continue
+		}
+		difference = append(difference, issue)
+	}
+	return difference
+}
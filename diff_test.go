@@ -0,0 +1,88 @@
+package xmlparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test that validation diffs classify the documents whose outcome changes between schema versions
+func TestDiffValidation(t *testing.T) {
+	before, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="quantity" type="xs:positiveInteger"/>
+                <xs:element name="note" type="xs:string" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse old XSD: %v", err)
+	}
+
+	// The new version caps quantities and drops notes
+	after, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="quantity">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:integer">
+                            <xs:minInclusive value="0"/>
+                            <xs:maxInclusive value="100"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse new XSD: %v", err)
+	}
+
+	sources := []struct{ name, xml string }{
+		{"plain.xml", `<order><quantity>5</quantity></order>`},
+		{"large.xml", `<order><quantity>500</quantity></order>`},
+		{"zero.xml", `<order><quantity>0</quantity></order>`},
+		{"note.xml", `<order><quantity>x</quantity><note>rush</note></order>`},
+		{"broken.xml", `<order/>`},
+	}
+	var corpus []CorpusDocument
+	for _, source := range sources {
+		doc, err := Parse([]byte(source.xml))
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", source.name, err)
+		}
+		corpus = append(corpus, CorpusDocument{Name: source.name, Document: doc})
+	}
+
+	diff := DiffValidation(before, after, corpus, ValidateOptions{})
+	if summary := diff.Summary(); summary != "1 newly failing, 1 newly passing, 1 with changed issues, 2 unchanged" {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+	if diff.Empty() {
+		t.Error("Expected the diff not to be empty")
+	}
+
+	if len(diff.NewlyFailing) != 1 || diff.NewlyFailing[0].Name != "large.xml" ||
+		!reflect.DeepEqual(diff.NewlyFailing[0].Added, []string{"in element <quantity>: value '500' exceeds maximum allowed value 100 (at /order/quantity[1])"}) {
+		t.Errorf("Unexpected newly failing documents: %+v", diff.NewlyFailing)
+	}
+	if len(diff.NewlyPassing) != 1 || diff.NewlyPassing[0].Name != "zero.xml" || len(diff.NewlyPassing[0].Removed) != 1 {
+		t.Errorf("Unexpected newly passing documents: %+v", diff.NewlyPassing)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "note.xml" {
+		t.Fatalf("Unexpected changed documents: %+v", diff.Changed)
+	}
+	if changed := diff.Changed[0]; len(changed.Before) != 1 || len(changed.After) != 2 || len(changed.Added) != 2 || len(changed.Removed) != 1 {
+		t.Errorf("Unexpected issues of the changed document: %+v", changed)
+	}
+
+	if same := DiffValidation(before, before, corpus, ValidateOptions{}); !same.Empty() || same.Unchanged != len(corpus) {
+		t.Errorf("Expected no differences against the same schema, got: %s", same.Summary())
+	}
+}
@@ -0,0 +1,88 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// forEachElement calls fn for every element declaration in the schema: global elements and
+// the local declarations of global and anonymous complex types at any depth.
+func (s *Schema) forEachElement(fn func(element *Element, global bool)) {
+	for i := range s.Elements {
+		fn(&s.Elements[i], true)
+	}
+	s.forEachComplexType(func(complexType *ComplexType) {
+		complexType.forEachDeclaredElement(func(element *Element) {
+			fn(element, false)
+		})
+	})
+}
+
+// assignElementForms decides which element declarations of a schema document are
+// namespace-qualified: global elements always are; local ones follow their form attribute,
+// or the document's elementFormDefault when it is absent.
+func (s *Schema) assignElementForms() {
+	s.forEachElement(func(element *Element, global bool) {
+		switch {
+		case global || element.Form == "qualified":
+			element.qualified = true
+		case element.Form == "":
+			element.qualified = s.ElementFormDefault == "qualified"
+		}
+	})
+}
+
+// globalElement returns the global element declaration with exactly the namespace and local
+// name of an instance element, or nil.
+func (s *Schema) globalElement(name xml.Name) *Element {
+	if s.elementNSMap != nil {
+		return s.elementNSMap[name]
+	}
+	for i := range s.Elements {
+		element := &s.Elements[i]
+		if ParseQName(element.Name).LocalName == name.Local && element.namespace == name.Space {
+			return element
+		}
+	}
+	return nil
+}
+
+// strictRootDeclaration returns the declaration of the document's root element for
+// StrictNamespaces: the global element with the root's namespace and local name.
+func (s *Schema) strictRootDeclaration(root *Node) (*Element, *ValidationError) {
+	if rootDef := s.globalElement(root.Name); rootDef != nil {
+		return rootDef, nil
+	}
+	for i := range s.Elements {
+		if element := &s.Elements[i]; ParseQName(element.Name).LocalName == root.Name.Local {
+			return nil, documentFailure(root, namespaceMismatch(root, element))
+		}
+	}
+	return nil, documentFailure(root, fmt.Sprintf("root element <%s> is not defined in the schema", root.Name.Local))
+}
+
+// elementMatches reports whether an instance element matches an element declaration. With
+// StrictNamespaces, or when the declaration has an explicit form attribute, the element
+// must be in the namespace implied by the declaration's form; otherwise names are compared
+// leniently as elementsMatch does.
+func (v *validator) elementMatches(name xml.Name, element *Element) bool {
+	if v.opts.StrictNamespaces || element.Form != "" {
+		return name.Local == ParseQName(element.Name).LocalName && name.Space == element.namespace
+	}
+	return v.elementsMatch(name, element.Name)
+}
+
+// namespaceMismatch describes an element that matches a declaration by local name but is
+// in a different namespace than the declaration requires.
+func namespaceMismatch(node *Node, element *Element) string {
+	return fmt.Sprintf("element <%s> is in %s, but its declaration requires %s",
+		node.Name.Local, namespaceLabel(node.Name.Space), namespaceLabel(element.namespace))
+}
+
+// namespaceLabel names a namespace in error messages.
+func namespaceLabel(namespace string) string {
+	if namespace == "" {
+		return "no namespace"
+	}
+	return fmt.Sprintf("namespace '%s'", namespace)
+}
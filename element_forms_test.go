@@ -0,0 +1,232 @@
+package xmlparser
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test namespace enforcement of elements with ValidateOptions.StrictNamespaces
+func TestStrictNamespaces(t *testing.T) {
+	qualified, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:test" elementFormDefault="qualified">
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="item" type="xs:string"/>
+                <xs:element name="details" minOccurs="0">
+                    <xs:complexType>
+                        <xs:all>
+                            <xs:element name="note" type="xs:string"/>
+                        </xs:all>
+                    </xs:complexType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse qualified XSD: %v", err)
+	}
+	unqualified, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:test">
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="item" type="xs:string"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse unqualified XSD: %v", err)
+	}
+	noNamespace, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="order" type="xs:string"/>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse no-namespace XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		schema      *Schema
+		strict      bool
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Qualified document",
+			schema:     qualified,
+			strict:     true,
+			xml:        `<order xmlns="urn:test"><item>A</item><details><note>N</note></details></order>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Root without namespace",
+			schema:      qualified,
+			strict:      true,
+			xml:         `<order><item>A</item></order>`,
+			errorString: "element <order> is in no namespace, but its declaration requires namespace 'urn:test'",
+		},
+		{
+			name:       "Root without namespace in lenient mode",
+			schema:     qualified,
+			xml:        `<order><item>A</item></order>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Root in another namespace",
+			schema:      qualified,
+			strict:      true,
+			xml:         `<order xmlns="urn:other"><item>A</item></order>`,
+			errorString: "element <order> is in namespace 'urn:other', but its declaration requires namespace 'urn:test'",
+		},
+		{
+			name:        "Unqualified child of a qualified form",
+			schema:      qualified,
+			strict:      true,
+			xml:         `<t:order xmlns:t="urn:test"><item>A</item></t:order>`,
+			errorString: "element <item> is in no namespace, but its declaration requires namespace 'urn:test'",
+		},
+		{
+			name:        "Unqualified child in xs:all",
+			schema:      qualified,
+			strict:      true,
+			xml:         `<order xmlns="urn:test"><item>A</item><details><note xmlns="">N</note></details></order>`,
+			errorString: "element <note> is in no namespace, but its declaration requires namespace 'urn:test'",
+		},
+		{
+			name:       "Unqualified local elements",
+			schema:     unqualified,
+			strict:     true,
+			xml:        `<t:order xmlns:t="urn:test"><item>A</item></t:order>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Qualified child of an unqualified form",
+			schema:      unqualified,
+			strict:      true,
+			xml:         `<order xmlns="urn:test"><item>A</item></order>`,
+			errorString: "element <item> is in namespace 'urn:test', but its declaration requires no namespace",
+		},
+		{
+			name:       "Schema without target namespace",
+			schema:     noNamespace,
+			strict:     true,
+			xml:        `<order>A</order>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Undeclared root",
+			schema:      noNamespace,
+			strict:      true,
+			xml:         `<invoice>A</invoice>`,
+			errorString: "root element <invoice> is not defined in the schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = tt.schema.ValidateWithOptions(doc, ValidateOptions{StrictNamespaces: tt.strict})
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+}
+
+// Test the form attribute on individual element declarations
+func TestElementForm(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:test">
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="id" type="xs:string"/>
+                <xs:element name="item" type="xs:string" form="qualified"/>
+                <xs:element name="note" type="xs:string" form="unqualified" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		strict      bool
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Forms honored",
+			strict:     true,
+			xml:        `<t:order xmlns:t="urn:test"><id>1</id><t:item>A</t:item><note>N</note></t:order>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Qualified form enforced without strict mode",
+			xml:         `<t:order xmlns:t="urn:test"><id>1</id><item>A</item></t:order>`,
+			errorString: "element <item> is in no namespace, but its declaration requires namespace 'urn:test'",
+		},
+		{
+			name:        "Unqualified form enforced without strict mode",
+			xml:         `<t:order xmlns:t="urn:test"><id>1</id><t:item>A</t:item><t:note>N</t:note></t:order>`,
+			errorString: "element <note> is in namespace 'urn:test', but its declaration requires no namespace",
+		},
+		{
+			name:       "Default form still matched leniently",
+			xml:        `<order xmlns="urn:test"><id>1</id><item>A</item></order>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Default form enforced in strict mode",
+			strict:      true,
+			xml:         `<order xmlns="urn:test"><id>1</id><item>A</item></order>`,
+			errorString: "element <id> is in namespace 'urn:test', but its declaration requires no namespace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.ValidateWithOptions(doc, ValidateOptions{StrictNamespaces: tt.strict})
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+
+	t.Run("Generated documents follow the forms", func(t *testing.T) {
+		gen, err := schema.NewGenerator("order")
+		if err != nil {
+			t.Fatalf("Failed to create generator: %v", err)
+		}
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 20; i++ {
+			doc := gen.Document(r, 5)
+			if err := schema.ValidateWithOptions(doc, ValidateOptions{StrictNamespaces: true}); err != nil {
+				t.Fatalf("Generated document is invalid: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package xmlparser
+
+import "fmt"
+
+// FacetKind is a group of related facets, checked together, for ValidateOptions.FacetOrder.
+type FacetKind int
+
+const (
+	// FacetLength is minLength and maxLength.
+	FacetLength FacetKind = iota
+	// FacetEnumeration is enumeration, checked with a set lookup for large enumerations.
+	FacetEnumeration
+	// FacetDigits is totalDigits and fractionDigits.
+	FacetDigits
+	// FacetRange is minInclusive and maxInclusive, which parse the value for comparison.
+	FacetRange
+	// FacetPattern is pattern, a regular expression match.
+	FacetPattern
+)
+
+// defaultFacetOrder checks the cheap facets first and regular expressions last.
+var defaultFacetOrder = []FacetKind{FacetLength, FacetEnumeration, FacetDigits, FacetRange, FacetPattern}
+
+// String returns the name of the facet kind.
+func (k FacetKind) String() string {
+	switch k {
+	case FacetLength:
+		return "FacetLength"
+	case FacetEnumeration:
+		return "FacetEnumeration"
+	case FacetDigits:
+		return "FacetDigits"
+	case FacetRange:
+		return "FacetRange"
+	case FacetPattern:
+		return "FacetPattern"
+	default:
+		return fmt.Sprintf("FacetKind(%d)", int(k))
+	}
+}
+
+// facetEvaluation is the order in which the facets of a value are checked, and whether
+// checking stops at the first failure.
+type facetEvaluation struct {
+	kinds []FacetKind
+	all   bool
+
+	// failed, if set, is called with the messages of each facet kind that fails
+	failed func(kind FacetKind, messages []string)
+}
+
+// defaultFacetEvaluation stops at the first failure, checking facets in the default order.
+var defaultFacetEvaluation = facetEvaluation{kinds: defaultFacetOrder}
+
+// newFacetEvaluation returns the facet evaluation selected by validation options. Kinds
+// missing from a custom order are checked after the listed ones, in the default order, so
+// that every facet is enforced whatever the order; unknown and repeated kinds are ignored.
+func newFacetEvaluation(opts ValidateOptions) facetEvaluation {
+	if len(opts.FacetOrder) == 0 {
+		return facetEvaluation{kinds: defaultFacetOrder, all: opts.AllFacetDiagnostics}
+	}
+
+	seen := make(map[FacetKind]bool)
+	var kinds []FacetKind
+	for _, kind := range append(append([]FacetKind(nil), opts.FacetOrder...), defaultFacetOrder...) {
+		if kind >= FacetLength && kind <= FacetPattern && !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	return facetEvaluation{kinds: kinds, all: opts.AllFacetDiagnostics}
+}
+
+// checkFacets validates content against the facets of one kind in a restriction.
+func (s *Schema) checkFacets(kind FacetKind, content string, restriction *Restriction) []string {
+	switch kind {
+	case FacetLength:
+		if restriction.MinLength == nil && restriction.MaxLength == nil {
+			return nil
+		}
+		return validateLengthConstraints(content, restriction, s.builtInBase(restriction.Base, s.restrictionBase(restriction)))
+	case FacetEnumeration:
+		if len(restriction.Enumeration) == 0 {
+			return nil
+		}
+		if err := s.validateEnumeration(content, restriction, s.builtInBase(restriction.Base, s.restrictionBase(restriction))); err != nil {
+			return []string{err.Error()}
+		}
+	case FacetDigits:
+		return validateDigitConstraints(content, restriction)
+	case FacetRange:
+		if restriction.MinInclusive == nil && restriction.MaxInclusive == nil {
+			return nil
+		}
+		return validateNumericConstraints(content, restriction, s.builtInBase(restriction.Base, s.restrictionBase(restriction)), s.Version)
+	case FacetPattern:
+		if restriction.Pattern != nil && restriction.Pattern.Value != "" {
+			if err := s.validatePattern(content, restriction.Pattern.Value); err != nil {
+				return []string{err.Error()}
+			}
+		}
+	}
+	return nil
+}
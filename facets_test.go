@@ -0,0 +1,168 @@
+package xmlparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test the facet evaluation order and short-circuiting selected by ValidateOptions
+func TestFacetEvaluation(t *testing.T) {
+	schemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string">
+			<xs:pattern value="[A-Z]{3}"/>
+		</xs:restriction>
+	</xs:simpleType>
+
+	<xs:simpleType name="ShortCode">
+		<xs:restriction base="Code">
+			<xs:maxLength value="3"/>
+			<xs:enumeration value="ABC"/>
+			<xs:enumeration value="XYZ"/>
+		</xs:restriction>
+	</xs:simpleType>
+
+	<xs:element name="code" type="ShortCode"/>
+</xs:schema>`
+
+	schema, err := ParseXSD([]byte(schemaContent))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	doc, err := Parse([]byte(`<code>abcd</code>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	const (
+		lengthError      = "value 'abcd' is too long (maximum length: 3, actual: 4)"
+		enumerationError = "not in the list of allowed values"
+		patternError     = "does not match pattern"
+	)
+
+	tests := []struct {
+		name     string
+		opts     ValidateOptions
+		expected []string
+	}{
+		{
+			name:     "Default order stops at the cheapest failure",
+			opts:     ValidateOptions{},
+			expected: []string{lengthError},
+		},
+		{
+			name:     "Custom order",
+			opts:     ValidateOptions{FacetOrder: []FacetKind{FacetPattern, FacetLength}},
+			expected: []string{patternError},
+		},
+		{
+			name:     "All diagnostics in the default order",
+			opts:     ValidateOptions{AllFacetDiagnostics: true},
+			expected: []string{lengthError, enumerationError, patternError},
+		},
+		{
+			name:     "All diagnostics in a custom order",
+			opts:     ValidateOptions{FacetOrder: []FacetKind{FacetPattern}, AllFacetDiagnostics: true},
+			expected: []string{patternError, lengthError, enumerationError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validationErr, ok := schema.ValidateWithOptions(doc, tt.opts).(*ValidationError)
+			if !ok {
+				t.Fatalf("Expected a *ValidationError")
+			}
+			if len(validationErr.Issues) != len(tt.expected) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.expected), len(validationErr.Issues), validationErr.Messages())
+			}
+			for i, expected := range tt.expected {
+				if !strings.Contains(validationErr.Issues[i].Message, expected) {
+					t.Errorf("Expected error %d to contain %q, got %q", i, expected, validationErr.Issues[i].Message)
+				}
+			}
+		})
+	}
+}
+
+// Test that a custom facet order always covers every facet kind once
+func TestNewFacetEvaluation(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    []FacetKind
+		expected []FacetKind
+	}{
+		{
+			name:     "Default order",
+			expected: []FacetKind{FacetLength, FacetEnumeration, FacetDigits, FacetRange, FacetPattern},
+		},
+		{
+			name:     "Listed kinds first",
+			order:    []FacetKind{FacetRange, FacetPattern},
+			expected: []FacetKind{FacetRange, FacetPattern, FacetLength, FacetEnumeration, FacetDigits},
+		},
+		{
+			name:     "Repeated and unknown kinds ignored",
+			order:    []FacetKind{FacetPattern, FacetKind(42), FacetPattern},
+			expected: []FacetKind{FacetPattern, FacetLength, FacetEnumeration, FacetDigits, FacetRange},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facets := newFacetEvaluation(ValidateOptions{FacetOrder: tt.order})
+			if !reflect.DeepEqual(facets.kinds, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, facets.kinds)
+			}
+		})
+	}
+}
+
+// Test that patterns which are not valid regular expressions fail schema parsing
+func TestInvalidPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "simple type",
+			content:  `<xs:simpleType name="Code"><xs:restriction base="xs:string"><xs:pattern value="[A-Z"/></xs:restriction></xs:simpleType>`,
+			expected: "in simple type 'Code': invalid pattern '[A-Z'",
+		},
+		{
+			name: "element",
+			content: `<xs:element name="code"><xs:simpleType><xs:restriction base="xs:string">` +
+				`<xs:pattern value="a{2,1}"/></xs:restriction></xs:simpleType></xs:element>`,
+			expected: "in element 'code': invalid pattern 'a{2,1}'",
+		},
+		{
+			name: "attribute",
+			content: `<xs:element name="item"><xs:complexType><xs:attribute name="unit"><xs:simpleType>` +
+				`<xs:restriction base="xs:string"><xs:pattern value="(kg"/></xs:restriction></xs:simpleType>` +
+				`</xs:attribute></xs:complexType></xs:element>`,
+			expected: "in attribute 'unit': invalid pattern '(kg'",
+		},
+		{
+			name:     "pattern closing the anchoring group",
+			content:  `<xs:simpleType name="Code"><xs:restriction base="xs:string"><xs:pattern value="a)|(b"/></xs:restriction></xs:simpleType>`,
+			expected: "in simple type 'Code': invalid pattern 'a)|(b'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseXSD([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">` + tt.content + `</xs:schema>`))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expected, err)
+			}
+		})
+	}
+
+	schema := &Schema{SimpleTypes: []SimpleType{{Name: "Code", Restriction: &Restriction{Base: "xs:string", Pattern: &Facet{Value: "[A-Z"}}}}}
+	if err := schema.Compile(); err == nil || !strings.Contains(err.Error(), "invalid pattern '[A-Z'") {
+		t.Errorf("Expected Compile to report the invalid pattern, got: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package xmlparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// FilterOptions configures Filter. The zero value copies documents unchanged.
+type FilterOptions struct {
+	// DropInvalid leaves invalid optional elements, declared with minOccurs="0", out of the
+	// output along with their subtree, instead of reporting their issues: the rest of the
+	// document is validated as if they were absent. Optional elements are held in memory
+	// until their end tag is read, to know whether they are kept.
+	DropInvalid bool
+
+	// Redact replaces the content of the elements declared sensitive (see SensitiveAppInfo)
+	// with Redaction, keeping their tags and attributes. Elements are validated with their
+	// original content.
+	Redact bool
+
+	// Redaction is the text replacing the content of sensitive elements; DefaultRedaction
+	// when empty.
+	Redaction string
+}
+
+// FilterResult describes how Filter changed a document.
+type FilterResult struct {
+	Dropped  []DroppedElement // Invalid optional elements left out, in the order they end
+	Redacted int              // Sensitive elements whose content was replaced
+}
+
+// DroppedElement is an invalid optional element left out by Filter, with the issues found
+// in its subtree.
+type DroppedElement struct {
+	Name   xml.Name
+	Issues []string
+}
+
+// Filter copies the XML document read from r to w while validating it, in a single pass,
+// for gateways that validate and sanitize documents on their way through. Tokens are copied
+// as they appear in the source, so the output only differs from the input where elements
+// are dropped or redacted. Validation streams as with ValidateReader.
+//
+// The output is written as the input is read. When the document is invalid, it is written
+// in full and a ValidationError with the issues left after dropping elements is returned
+// along with the result; the caller decides whether to forward it. On a parsing or write
+// error, the output stops at the failing token.
+//
+// Documents declared in one of the encodings of CharsetReader are validated converted to
+// UTF-8 and written back in their encoding; characters of the redaction text the encoding
+// does not have are written as character references. Documents in other encodings fail
+// to parse.
+func (s *Schema) Filter(w io.Writer, r io.Reader, opts FilterOptions) (*FilterResult, error) {
+	if opts.Redaction == "" {
+		opts.Redaction = DefaultRedaction
+	}
+	buffered := bufio.NewReader(r)
+	charset := declaredCharset(peekDeclaration(buffered))
+	input, charsetReader, err := utf8Input(buffered, CharsetReader)
+	if err != nil {
+		return &FilterResult{}, fmt.Errorf("XML parsing error: %w", err)
+	}
+
+	source := &sourceWindow{reader: input}
+	decoder := xml.NewDecoder(source)
+	decoder.CharsetReader = charsetReader
+	filter := &streamFilter{
+		stream: newStreamValidator(s),
+		opts:   opts,
+		out:    bufio.NewWriter(charsetWriter(w, charset)),
+		result: &FilterResult{},
+	}
+	filter.parser = &xmlParser{
+		decoder:      decoder,
+		source:       source,
+		lastPosition: Position{Line: 1, Column: 1},
+		onStart:      filter.start,
+		onEnd:        filter.end,
+		onData:       filter.data,
+	}
+
+	_, parseErr := filter.parser.parseDocument()
+	if err := filter.out.Flush(); err != nil {
+		return filter.result, err
+	}
+	if parseErr != nil {
+		return filter.result, parseErr
+	}
+	return filter.result, filter.stream.finish()
+}
+
+// streamFilter copies a document to its output as it is validated.
+type streamFilter struct {
+	stream *streamValidator
+	parser *xmlParser
+	opts   FilterOptions
+	out    *bufio.Writer
+	result *FilterResult
+
+	open       []*filteredElement // Elements whose end tag has not been read yet, innermost last
+	redacting  int                // Number of open sensitive elements
+	suppressed bool               // Whether content of the outermost open sensitive element was left out
+}
+
+// filteredElement is the output state of an element being read.
+type filteredElement struct {
+	sensitive bool
+
+	// Output of an optional element held until it is known to be valid, with the issues
+	// found in its subtree so far and the numbers of IDs and IDREFs recorded before it
+	// started; buffer is nil for elements written through
+	buffer      *bytes.Buffer
+	issues      []ValidationIssue
+	ids, idrefs int
+}
+
+// start validates and copies a start tag. The tags of optional elements are held with their
+// content; those of elements inside sensitive elements are left out.
+func (f *streamFilter) start(node *Node) error {
+	if err := f.stream.start(node); err != nil {
+		return err
+	}
+
+	def := f.stream.open[len(f.stream.open)-1].def
+	element := &filteredElement{}
+	if def != nil {
+		element.sensitive = f.opts.Redact && def.Annotation.sensitive()
+		if min, _ := parseOccurs(def.MinOccurs); f.opts.DropInvalid && node.Parent != nil && min == 0 {
+			element.buffer = &bytes.Buffer{}
+			element.ids, element.idrefs = len(f.stream.v.ids), len(f.stream.v.idrefs)
+		}
+	}
+	f.open = append(f.open, element)
+
+	f.copy()
+	if element.sensitive {
+		if f.redacting == 0 {
+			f.suppressed = false
+		}
+		f.redacting++
+	}
+	return nil
+}
+
+// data copies text, comments, processing instructions and directives.
+func (f *streamFilter) data(token xml.Token) error {
+	f.copy()
+	return nil
+}
+
+// end copies an end tag, replacing the content of sensitive elements, and validates the
+// element. Invalid optional elements are dropped from the output and from their parent,
+// with the IDs and IDREFs recorded in their subtree.
+func (f *streamFilter) end(node *Node) error {
+	element := f.open[len(f.open)-1]
+	if element.sensitive {
+		f.redacting--
+		if f.redacting == 0 && f.suppressed {
+			textEscaper.WriteString(f.sink(), f.opts.Redaction)
+			f.result.Redacted++
+		}
+	}
+	f.copy()
+	f.open = f.open[:len(f.open)-1]
+
+	issues := f.stream.validateEnded(node)
+	if element.buffer == nil {
+		f.report(issues)
+		return nil
+	}
+
+	issues = append(element.issues, issues...)
+	if len(issues) == 0 {
+		f.sink().Write(element.buffer.Bytes())
+		return nil
+	}
+
+	v := f.stream.v
+	f.stream.drop(node)
+	v.idrefs = v.idrefs[:element.idrefs]
+	if len(v.ids) > element.ids {
+		for value, id := range v.ids {
+			if isWithin(id.node, node) {
+				delete(v.ids, value)
+			}
+		}
+	}
+	f.result.Dropped = append(f.result.Dropped, DroppedElement{Name: node.Name, Issues: messagesOf(issues)})
+	return nil
+}
+
+// copy writes the source of the current token to the output, unless it is inside a
+// sensitive element.
+func (f *streamFilter) copy() {
+	if f.redacting > 0 {
+		f.suppressed = true
+		return
+	}
+	f.sink().Write(f.parser.tokenSource())
+}
+
+// sink returns where output goes: the buffer of the innermost open optional element, or
+// the output itself.
+func (f *streamFilter) sink() io.Writer {
+	for i := len(f.open) - 1; i >= 0; i-- {
+		if f.open[i].buffer != nil {
+			return f.open[i].buffer
+		}
+	}
+	return f.out
+}
+
+// report records issues with the innermost open optional element, which they make invalid,
+// or as issues of the document.
+func (f *streamFilter) report(issues []ValidationIssue) {
+	for i := len(f.open) - 1; i >= 0; i-- {
+		if f.open[i].buffer != nil {
+			f.open[i].issues = append(f.open[i].issues, issues...)
+			return
+		}
+	}
+	f.stream.report(issues)
+}
+
+// isWithin reports whether node is root or one of its descendants.
+func isWithin(node, root *Node) bool {
+	for ; node != nil; node = node.Parent {
+		if node == root {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package xmlparser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="ssn" type="xs:string">
+    <xs:annotation><xs:appinfo source="urn:validatexml:sensitive"/></xs:annotation>
+  </xs:element>
+  <xs:element name="customers">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="customer" type="CustomerType" maxOccurs="unbounded"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+  <xs:complexType name="CustomerType">
+    <xs:sequence>
+      <xs:element name="name" type="xs:string"/>
+      <xs:element ref="ssn" minOccurs="0"/>
+      <xs:element name="card" minOccurs="0">
+        <xs:annotation><xs:appinfo source="urn:validatexml:sensitive"/></xs:annotation>
+        <xs:complexType>
+          <xs:sequence>
+            <xs:element name="number" type="xs:string"/>
+          </xs:sequence>
+          <xs:attribute name="brand" type="xs:string"/>
+        </xs:complexType>
+      </xs:element>
+      <xs:element name="age" type="xs:positiveInteger" minOccurs="0"/>
+      <xs:element name="referrer" type="xs:IDREF" minOccurs="0"/>
+      <xs:element name="address" minOccurs="0">
+        <xs:complexType>
+          <xs:sequence>
+            <xs:element name="city" type="xs:string"/>
+            <xs:element name="zip" type="xs:integer" minOccurs="0"/>
+          </xs:sequence>
+          <xs:attribute name="id" type="xs:ID"/>
+        </xs:complexType>
+      </xs:element>
+    </xs:sequence>
+    <xs:attribute name="id" type="xs:ID" use="required"/>
+  </xs:complexType>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     FilterOptions
+		input    string
+		output   string
+		dropped  []string
+		redacted int
+		errorMsg string
+	}{
+		{
+			name: "copied unchanged",
+			input: `<?xml version="1.0"?>
+<!-- export -->
+<customers><customer id="c1"><name>Ann &amp; Bob</name><ssn>123</ssn><age>40</age><address><city><![CDATA[Köln]]></city></address></customer><customer id="c2" ><name/></customer></customers>
+`,
+		},
+		{
+			name: "invalid optional elements dropped",
+			opts: FilterOptions{DropInvalid: true},
+			input: `<customers>
+  <customer id="c1"><name>Ann</name><age>-4</age><address><city>Oslo</city><zip>N-0150</zip></address></customer>
+  <customer id="c2"><name>Bob</name><age>x</age><referrer>c1</referrer></customer>
+</customers>`,
+			output: `<customers>
+  <customer id="c1"><name>Ann</name><address><city>Oslo</city></address></customer>
+  <customer id="c2"><name>Bob</name><referrer>c1</referrer></customer>
+</customers>`,
+			dropped: []string{"age", "zip", "age"},
+		},
+		{
+			name: "IDs of dropped elements",
+			opts: FilterOptions{DropInvalid: true},
+			input: `<customers>
+  <customer id="c1"><name>Ann</name><address id="a1"><zip>1</zip></address></customer>
+  <customer id="c2"><name>Bob</name><referrer>a1</referrer></customer>
+</customers>`,
+			output: `<customers>
+  <customer id="c1"><name>Ann</name></customer>
+  <customer id="c2"><name>Bob</name><referrer>a1</referrer></customer>
+</customers>`,
+			dropped:  []string{"address"},
+			errorMsg: "IDREF 'a1'",
+		},
+		{
+			name:     "required elements reported",
+			opts:     FilterOptions{DropInvalid: true},
+			input:    `<customers><customer><name>Ann</name></customer></customers>`,
+			output:   `<customers><customer><name>Ann</name></customer></customers>`,
+			errorMsg: "required attribute 'id'",
+		},
+		{
+			name: "sensitive elements redacted",
+			opts: FilterOptions{Redact: true},
+			input: `<customers>
+  <customer id="c1"><name>Ann</name><ssn>123-45-6789</ssn><card brand="visa"><number>4111</number><!-- test card --></card></customer>
+  <customer id="c2"><name>Bob</name><ssn/></customer>
+</customers>`,
+			output: `<customers>
+  <customer id="c1"><name>Ann</name><ssn>[REDACTED]</ssn><card brand="visa">[REDACTED]</card></customer>
+  <customer id="c2"><name>Bob</name><ssn/></customer>
+</customers>`,
+			redacted: 2,
+		},
+		{
+			name:     "redaction text escaped",
+			opts:     FilterOptions{Redact: true, Redaction: "<hidden>"},
+			input:    `<customers><customer id="c1"><name>Ann</name><ssn>123</ssn></customer></customers>`,
+			output:   `<customers><customer id="c1"><name>Ann</name><ssn>&lt;hidden&gt;</ssn></customer></customers>`,
+			redacted: 1,
+		},
+		{
+			name: "Latin-1 document copied in its encoding",
+			opts: FilterOptions{DropInvalid: true},
+			input: "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+				"<customers><customer id=\"c1\"><name>M\xfcller</name><age>\xe9</age><address><city>K\xf6ln</city></address></customer></customers>",
+			output: "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+				"<customers><customer id=\"c1\"><name>M\xfcller</name><address><city>K\xf6ln</city></address></customer></customers>",
+			dropped: []string{"age"},
+		},
+		{
+			name:     "redaction text outside the document encoding",
+			opts:     FilterOptions{Redact: true, Redaction: "\u20ac hidden"},
+			input:    "<?xml version='1.0' encoding='latin1'?><customers><customer id=\"c1\"><name>\xc5sa</name><ssn>123</ssn></customer></customers>",
+			output:   "<?xml version='1.0' encoding='latin1'?><customers><customer id=\"c1\"><name>\xc5sa</name><ssn>&#x20AC; hidden</ssn></customer></customers>",
+			redacted: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			result, err := schema.Filter(&out, strings.NewReader(tt.input), tt.opts)
+			if tt.errorMsg != "" {
+				expectValidationError(t, err, tt.errorMsg)
+			} else if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			expected := tt.output
+			if expected == "" {
+				expected = tt.input
+			}
+			if out.String() != expected {
+				t.Errorf("Unexpected output:\n%s\nexpected:\n%s", out.String(), expected)
+			}
+			var dropped []string
+			for _, element := range result.Dropped {
+				if len(element.Issues) == 0 {
+					t.Errorf("Expected the issues of dropped element <%s>", element.Name.Local)
+				}
+				dropped = append(dropped, element.Name.Local)
+			}
+			if strings.Join(dropped, " ") != strings.Join(tt.dropped, " ") {
+				t.Errorf("Expected dropped elements %v, got %v", tt.dropped, dropped)
+			}
+			if result.Redacted != tt.redacted {
+				t.Errorf("Expected %d redacted elements, got %d", tt.redacted, result.Redacted)
+			}
+		})
+	}
+}
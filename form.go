@@ -0,0 +1,199 @@
+package xmlparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormField describes an element or attribute for dynamic form renderers. Elements with
+// complex types list their attributes and child elements; elements and attributes with
+// simple content carry the facets of their type, nearest restriction first.
+type FormField struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`                // "element" or "attribute"
+	Label     string `json:"label"`               // xs:documentation text, or the name
+	Type      string `json:"type,omitempty"`      // Built-in base type of simple content, e.g. "xs:integer"
+	Required  bool   `json:"required"`            // Whether the field must always be present
+	MaxOccurs int    `json:"maxOccurs,omitempty"` // Elements only, -1 when unbounded
+	Default   string `json:"default,omitempty"`
+	Fixed     string `json:"fixed,omitempty"`
+
+	// Facets of simple content
+	Options   []string `json:"options,omitempty"` // Enumeration values
+	Pattern   string   `json:"pattern,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Min       string   `json:"min,omitempty"` // minInclusive
+	Max       string   `json:"max,omitempty"` // maxInclusive
+
+	Attributes []*FormField `json:"attributes,omitempty"`
+	Children   []*FormField `json:"children,omitempty"`
+}
+
+// FormMetadata describes the form for documents rooted at the given global element.
+// Elements inside an xs:choice are never required, since only one alternative appears;
+// recursive types are expanded once, and the recursive element is listed without children.
+func (s *Schema) FormMetadata(rootElement string) (*FormField, error) {
+	root, exists := s.ElementMap[rootElement]
+	if !exists {
+		return nil, fmt.Errorf("root element '%s' is not defined in the schema", rootElement)
+	}
+	return s.formElement(root, 1, 1, make(map[*ComplexType]bool)), nil
+}
+
+// FormMetadataJSON returns FormMetadata encoded as indented JSON.
+func (s *Schema) FormMetadataJSON(rootElement string) ([]byte, error) {
+	field, err := s.FormMetadata(rootElement)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(field, "", "  ")
+}
+
+// formElement describes an element whose effective occurrence bounds are min and max.
+func (s *Schema) formElement(def *Element, min, max int, expanding map[*ComplexType]bool) *FormField {
+	field := &FormField{
+		Name:      def.Name,
+		Kind:      "element",
+		Label:     formLabel(def.Annotation, def.Name),
+		Required:  min > 0,
+		MaxOccurs: max,
+	}
+
+	complexType := s.getComplexType(def)
+	if complexType == nil {
+		simpleType, _ := s.findSimpleType(def)
+		s.formSimpleContent(field, def.Type, simpleType)
+		return field
+	}
+	if expanding[complexType] {
+		return field
+	}
+	expanding[complexType] = true
+	defer delete(expanding, complexType)
+
+	for i := range complexType.Attributes {
+		field.Attributes = append(field.Attributes, s.formAttribute(&complexType.Attributes[i]))
+	}
+	if model := s.contentModel(complexType); model != nil {
+		field.Children = s.formParticle(model, 1, 1, expanding)
+	} else if complexType.All != nil {
+		groupMin, _ := parseOccurs(complexType.All.MinOccurs)
+		for i := range complexType.All.Elements {
+			element := &complexType.All.Elements[i]
+			elementMin, _ := parseOccurs(element.MinOccurs)
+			field.Children = append(field.Children,
+				s.formElement(element, groupMin*elementMin, parseMaxOccurs(element.MaxOccurs), expanding))
+		}
+	}
+	return field
+}
+
+// formParticle describes the elements of a compiled particle nested in groups whose
+// combined occurrence bounds are min and max.
+func (s *Schema) formParticle(p *compiledParticle, min, max int, expanding map[*ComplexType]bool) []*FormField {
+	min, max = min*p.min, multiplyMaxOccurs(max, p.max)
+
+	switch p.kind {
+	case elementParticle:
+		return []*FormField{s.formElement(p.element, min, max, expanding)}
+	case choiceParticle:
+		if len(p.members) > 1 {
+			min = 0
+		}
+	}
+
+	var fields []*FormField
+	for _, member := range p.members {
+		fields = append(fields, s.formParticle(member, min, max, expanding)...)
+	}
+	return fields
+}
+
+// multiplyMaxOccurs multiplies two maxOccurs values, where -1 means unbounded.
+func multiplyMaxOccurs(a, b int) int {
+	if a == -1 || b == -1 {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		return -1
+	}
+	return a * b
+}
+
+// formAttribute describes an attribute declaration.
+func (s *Schema) formAttribute(def *Attribute) *FormField {
+	field := &FormField{
+		Name:     def.Name,
+		Kind:     "attribute",
+		Label:    formLabel(def.Annotation, def.Name),
+		Required: def.Use == "required",
+		Default:  def.Default,
+		Fixed:    def.Fixed,
+	}
+
+	simpleType := def.SimpleType
+	if simpleType == nil && def.Type != "" {
+		simpleType = s.lookupSimpleType(def.Type, def.typeRef)
+	}
+	s.formSimpleContent(field, def.Type, simpleType)
+	return field
+}
+
+// formSimpleContent sets the type and facets of a field with simple content. Facets are
+// collected along the restriction chain; a facet restated closer to the field wins.
+func (s *Schema) formSimpleContent(field *FormField, typeName string, simpleType *SimpleType) {
+	field.Type = s.builtInBase(typeName, simpleType)
+
+	for depth := 0; simpleType != nil && simpleType.Restriction != nil && depth < maxDerivationDepth; depth++ {
+		restriction := simpleType.Restriction
+		if field.Options == nil && len(restriction.Enumeration) > 0 {
+			for _, facet := range restriction.Enumeration {
+				field.Options = append(field.Options, facet.Value)
+			}
+		}
+		if field.Pattern == "" && restriction.Pattern != nil {
+			field.Pattern = restriction.Pattern.Value
+		}
+		if field.MinLength == nil {
+			field.MinLength = facetInt(restriction.MinLength)
+		}
+		if field.MaxLength == nil {
+			field.MaxLength = facetInt(restriction.MaxLength)
+		}
+		if field.Min == "" && restriction.MinInclusive != nil {
+			field.Min = restriction.MinInclusive.Value
+		}
+		if field.Max == "" && restriction.MaxInclusive != nil {
+			field.Max = restriction.MaxInclusive.Value
+		}
+		simpleType = s.restrictionBase(restriction)
+	}
+}
+
+// facetInt returns the integer value of a length facet, or nil if absent or malformed.
+func facetInt(facet *Facet) *int {
+	if facet == nil {
+		return nil
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(facet.Value))
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// formLabel returns the first non-empty xs:documentation text with whitespace collapsed,
+// falling back to the component name.
+func formLabel(annotation *Annotation, name string) string {
+	if annotation != nil {
+		for _, documentation := range annotation.Documentation {
+			if text := strings.Join(strings.Fields(documentation.Text), " "); text != "" {
+				return text
+			}
+		}
+	}
+	return name
+}
@@ -0,0 +1,110 @@
+package xmlparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Test that form metadata reflects labels, occurrence, facets and attributes
+func TestFormMetadata(t *testing.T) {
+	xsd := `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="CodeType">
+        <xs:restriction base="xs:string">
+            <xs:pattern value="[A-Z]{3}"/>
+            <xs:maxLength value="3"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:simpleType name="CurrencyType">
+        <xs:restriction base="CodeType">
+            <xs:enumeration value="EUR"/>
+            <xs:enumeration value="USD"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:complexType name="NodeType">
+        <xs:sequence>
+            <xs:element name="node" type="NodeType" minOccurs="0" maxOccurs="unbounded"/>
+        </xs:sequence>
+    </xs:complexType>
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="amount">
+                    <xs:annotation>
+                        <xs:documentation xml:lang="en">
+                            Order   amount
+                        </xs:documentation>
+                    </xs:annotation>
+                    <xs:simpleType>
+                        <xs:restriction base="xs:decimal">
+                            <xs:minInclusive value="0"/>
+                            <xs:maxInclusive value="1000"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="currency" type="CurrencyType"/>
+                <xs:choice>
+                    <xs:element name="email" type="xs:string"/>
+                    <xs:element name="phone" type="xs:string"/>
+                </xs:choice>
+                <xs:element name="tree" type="NodeType" minOccurs="0"/>
+            </xs:sequence>
+            <xs:attribute name="id" type="xs:ID" use="required">
+                <xs:annotation><xs:documentation>Order number</xs:documentation></xs:annotation>
+            </xs:attribute>
+            <xs:attribute name="channel" type="xs:string" default="web"/>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`
+
+	schema, err := ParseXSD([]byte(xsd))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	form, err := schema.FormMetadata("order")
+	if err != nil {
+		t.Fatalf("FormMetadata failed: %v", err)
+	}
+
+	three := 3
+	node := &FormField{Name: "node", Kind: "element", Label: "node", MaxOccurs: -1}
+	expected := &FormField{
+		Name: "order", Kind: "element", Label: "order", Required: true, MaxOccurs: 1,
+		Attributes: []*FormField{
+			{Name: "id", Kind: "attribute", Label: "Order number", Type: "xs:ID", Required: true},
+			{Name: "channel", Kind: "attribute", Label: "channel", Type: "xs:string", Default: "web"},
+		},
+		Children: []*FormField{
+			{Name: "amount", Kind: "element", Label: "Order amount", Type: "xs:decimal", Required: true, MaxOccurs: 1, Min: "0", Max: "1000"},
+			{Name: "currency", Kind: "element", Label: "currency", Type: "xs:string", Required: true, MaxOccurs: 1,
+				Options: []string{"EUR", "USD"}, Pattern: "[A-Z]{3}", MaxLength: &three},
+			{Name: "email", Kind: "element", Label: "email", Type: "xs:string", MaxOccurs: 1},
+			{Name: "phone", Kind: "element", Label: "phone", Type: "xs:string", MaxOccurs: 1},
+			{Name: "tree", Kind: "element", Label: "tree", MaxOccurs: 1, Children: []*FormField{node}},
+		},
+	}
+
+	if !reflect.DeepEqual(form, expected) {
+		got, _ := json.MarshalIndent(form, "", "  ")
+		want, _ := json.MarshalIndent(expected, "", "  ")
+		t.Errorf("Unexpected form metadata.\nGot:  %s\nWant: %s", got, want)
+	}
+
+	data, err := schema.FormMetadataJSON("order")
+	if err != nil {
+		t.Fatalf("FormMetadataJSON failed: %v", err)
+	}
+	var decoded FormField
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("FormMetadataJSON produced invalid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, expected) {
+		t.Errorf("JSON round trip changed the form metadata: %s", data)
+	}
+
+	if _, err := schema.FormMetadata("invoice"); err == nil {
+		t.Error("Expected error for unknown root element")
+	}
+}
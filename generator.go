@@ -0,0 +1,449 @@
+package xmlparser
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// Generator produces random documents that are valid against a schema, for property-based
+// testing of code that consumes them. Occurrence bounds, choices, attributes and simple type
+// facets (enumeration, pattern, length, numeric ranges) are respected; every generated value
+// is checked with the same facet validation used by Validate before it is used.
+// Document-level ID/IDREF integrity is not modelled: generated xs:IDREF values rarely resolve.
+//
+// A Generator plugs into testing/quick through its Values method:
+//
+//	gen, _ := schema.NewGenerator("order")
+//	err := quick.Check(func(doc *xmlparser.Document) bool {
+//		return consumer.Handle(doc) == nil
+//	}, &quick.Config{Values: gen.Values})
+type Generator struct {
+	// MaxDepth bounds element nesting. Below it only the required minimum of each particle
+	// is generated, which keeps recursive content models finite.
+	MaxDepth int
+
+	// Size bounds the number of optional repetitions per particle when documents are
+	// generated through Values.
+	Size int
+
+	schema *Schema
+	root   *Element
+}
+
+// maxRequiredDepth stops generation of schemas whose required content recurses forever.
+const maxRequiredDepth = 64
+
+// maxGenerateAttempts is how many candidate values are tried before giving up on the facets.
+const maxGenerateAttempts = 100
+
+// NewGenerator returns a Generator for documents rooted at the given global element.
+func (s *Schema) NewGenerator(rootElement string) (*Generator, error) {
+	root, exists := s.ElementMap[rootElement]
+	if !exists {
+		return nil, fmt.Errorf("root element '%s' is not defined in the schema", rootElement)
+	}
+	return &Generator{MaxDepth: 8, Size: 5, schema: s, root: root}, nil
+}
+
+// Document generates a random valid document. size bounds the number of optional repetitions
+// per particle, in the spirit of the size argument of testing/quick generators.
+func (g *Generator) Document(r *rand.Rand, size int) *Document {
+	return &Document{Root: g.element(r, size, g.root, nil, 0)}
+}
+
+// Values fills values with generated documents. It matches the Values field of
+// testing/quick.Config, for properties whose arguments are all *Document.
+func (g *Generator) Values(values []reflect.Value, r *rand.Rand) {
+	for i := range values {
+		values[i] = reflect.ValueOf(g.Document(r, g.Size))
+	}
+}
+
+// element generates a node for an element declaration.
+func (g *Generator) element(r *rand.Rand, size int, def *Element, parent *Node, depth int) *Node {
+	node := &Node{Parent: parent, Name: g.elementName(def)}
+
+	complexType := g.schema.getComplexType(def)
+	if complexType == nil {
+		simpleType, _ := g.schema.findSimpleType(def)
+		node.Content = g.simpleValue(r, def.Type, simpleType)
+		return node
+	}
+
+	node.Attrs = g.attributes(r, complexType)
+	if depth >= maxRequiredDepth {
+		return node
+	}
+
+	if model := g.schema.contentModel(complexType); model != nil {
+		g.particle(r, size, model, node, depth)
+	} else if complexType.All != nil {
+		g.all(r, size, complexType.All, node, depth)
+	}
+
+	if complexType.Mixed && r.Intn(2) == 0 {
+		node.Content = randomWord(r, 1, 8)
+	}
+	return node
+}
+
+// elementName returns the XML name of instances of an element declaration: its local name
+// in the namespace implied by the declaration's form.
+func (g *Generator) elementName(def *Element) xml.Name {
+	return xml.Name{Space: def.namespace, Local: ParseQName(def.Name).LocalName}
+}
+
+// particle appends the children generated for a compiled particle to node.
+func (g *Generator) particle(r *rand.Rand, size int, p *compiledParticle, node *Node, depth int) {
+	count := g.occurrences(r, size, p.min, p.max, depth)
+	for i := 0; i < count; i++ {
+		switch p.kind {
+		case elementParticle:
+			node.Children = append(node.Children, g.element(r, size, p.element, node, depth+1))
+		case sequenceParticle:
+			for _, member := range p.members {
+				g.particle(r, size, member, node, depth)
+			}
+		case choiceParticle:
+			if len(p.members) > 0 {
+				g.particle(r, size, p.members[r.Intn(len(p.members))], node, depth)
+			}
+		}
+	}
+}
+
+// all appends the children of an xs:all group in random order.
+func (g *Generator) all(r *rand.Rand, size int, all *All, node *Node, depth int) {
+	var selected []*Element
+	for i := range all.Elements {
+		element := &all.Elements[i]
+		min, _ := parseOccurs(element.MinOccurs)
+		for n := g.occurrences(r, size, min, parseMaxOccurs(element.MaxOccurs), depth); n > 0; n-- {
+			selected = append(selected, element)
+		}
+	}
+	r.Shuffle(len(selected), func(i, j int) { selected[i], selected[j] = selected[j], selected[i] })
+
+	for _, element := range selected {
+		node.Children = append(node.Children, g.element(r, size, element, node, depth+1))
+	}
+}
+
+// occurrences picks a repetition count within a particle's bounds.
+func (g *Generator) occurrences(r *rand.Rand, size, min, max, depth int) int {
+	if depth >= g.MaxDepth || size <= 0 {
+		return min
+	}
+	extra := size
+	if max >= 0 && max-min < extra {
+		extra = max - min
+	}
+	if extra <= 0 {
+		return min
+	}
+	return min + r.Intn(extra+1)
+}
+
+// attributes generates attribute values for a complex type.
+func (g *Generator) attributes(r *rand.Rand, complexType *ComplexType) []xml.Attr {
+	var attrs []xml.Attr
+	for i := range complexType.Attributes {
+		attrDef := &complexType.Attributes[i]
+		if attrDef.Use == "prohibited" || (attrDef.Use != "required" && r.Intn(2) == 0) {
+			continue
+		}
+
+		value := attrDef.Fixed
+		if value == "" {
+			simpleType := attrDef.SimpleType
+			if simpleType == nil && attrDef.Type != "" {
+				simpleType = g.schema.lookupSimpleType(attrDef.Type, attrDef.typeRef)
+			}
+			value = g.simpleValue(r, attrDef.Type, simpleType)
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Space: attrDef.namespace, Local: attrDef.Name}, Value: value})
+	}
+	return attrs
+}
+
+// simpleValue generates a value for a built-in type name and/or a simple type restriction,
+// retrying until the value satisfies the facets of the type and the types it derives from.
+func (g *Generator) simpleValue(r *rand.Rand, typeName string, simpleType *SimpleType) string {
+	baseType := g.schema.builtInBase(typeName, simpleType)
+
+	var candidate string
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		candidate = g.candidateValue(r, baseType, simpleType)
+		if len(g.schema.validateSimpleValue(candidate, baseType, simpleType, defaultFacetEvaluation)) == 0 {
+			return candidate
+		}
+	}
+	return candidate
+}
+
+// candidateValue generates one candidate value, preferring the most specific facet.
+func (g *Generator) candidateValue(r *rand.Rand, baseType string, simpleType *SimpleType) string {
+	var restriction *Restriction
+	if simpleType != nil {
+		restriction = simpleType.Restriction
+	}
+
+	if restriction != nil && len(restriction.Enumeration) > 0 {
+		return restriction.Enumeration[r.Intn(len(restriction.Enumeration))].Value
+	}
+	if restriction != nil && restriction.Pattern != nil && restriction.Pattern.Value != "" {
+		if value, err := generateFromPattern(r, restriction.Pattern.Value); err == nil {
+			return value
+		}
+	}
+	return builtInValue(r, baseType, restriction)
+}
+
+// builtInValue generates a lexically valid value of a built-in type within the
+// length and numeric range facets of restriction, if any.
+func builtInValue(r *rand.Rand, typeName string, restriction *Restriction) string {
+	switch typeName {
+	case "xs:integer", "xs:long", "xs:int":
+		return strconv.FormatInt(randomInRange(r, restriction, -1000000, 1000000), 10)
+	case "xs:short":
+		return strconv.FormatInt(randomInRange(r, restriction, -32768, 32767), 10)
+	case "xs:byte":
+		return strconv.FormatInt(randomInRange(r, restriction, -128, 127), 10)
+	case "xs:nonNegativeInteger", "xs:unsignedLong", "xs:unsignedInt":
+		return strconv.FormatInt(randomInRange(r, restriction, 0, 1000000), 10)
+	case "xs:unsignedShort":
+		return strconv.FormatInt(randomInRange(r, restriction, 0, 65535), 10)
+	case "xs:unsignedByte":
+		return strconv.FormatInt(randomInRange(r, restriction, 0, 255), 10)
+	case "xs:nonPositiveInteger":
+		return strconv.FormatInt(randomInRange(r, restriction, -1000000, 0), 10)
+	case "xs:negativeInteger":
+		return strconv.FormatInt(randomInRange(r, restriction, -1000000, -1), 10)
+	case "xs:positiveInteger":
+		return strconv.FormatInt(randomInRange(r, restriction, 1, 1000000), 10)
+	case "xs:decimal", "xs:double", "xs:float":
+		whole := randomInRange(r, restriction, -100000, 100000)
+		if restriction != nil && facetDigits(restriction.FractionDigits) == 0 {
+			return strconv.FormatInt(whole, 10)
+		}
+		return fmt.Sprintf("%d.%d", whole, r.Intn(10))
+	case "xs:boolean":
+		return []string{"true", "false", "1", "0"}[r.Intn(4)]
+	case "xs:date":
+		return randomDate(r)
+	case "xs:dateTime", "xs:dateTimeStamp":
+		return fmt.Sprintf("%sT%02d:%02d:%02dZ", randomDate(r), r.Intn(24), r.Intn(60), r.Intn(60))
+	case "xs:time":
+		return fmt.Sprintf("%02d:%02d:%02d", r.Intn(24), r.Intn(60), r.Intn(60))
+	case "xs:gYear":
+		return fmt.Sprintf("%04d", 1970+r.Intn(130))
+	case "xs:gYearMonth":
+		return fmt.Sprintf("%04d-%02d", 1970+r.Intn(130), 1+r.Intn(12))
+	case "xs:gMonth":
+		return fmt.Sprintf("--%02d", 1+r.Intn(12))
+	case "xs:gMonthDay":
+		return fmt.Sprintf("--%02d-%02d", 1+r.Intn(12), 1+r.Intn(28))
+	case "xs:gDay":
+		return fmt.Sprintf("---%02d", 1+r.Intn(28))
+	case "xs:duration":
+		return fmt.Sprintf("P%dY%dM%dDT%dH%dM%dS", r.Intn(10), r.Intn(12), r.Intn(28), r.Intn(24), r.Intn(60), r.Intn(60))
+	case "xs:dayTimeDuration":
+		return fmt.Sprintf("P%dDT%dH%dM%dS", r.Intn(28), r.Intn(24), r.Intn(60), r.Intn(60))
+	case "xs:yearMonthDuration":
+		return fmt.Sprintf("P%dY%dM", r.Intn(10), r.Intn(12))
+	case "xs:language":
+		return []string{"en", "en-US", "de", "fr-CA", "zh-Hans"}[r.Intn(5)]
+	case "xs:anyURI":
+		return "https://example.com/" + randomWord(r, 1, 12)
+	case "xs:base64Binary":
+		minLen, maxLen := lengthBounds(restriction, 1, 16)
+		data := make([]byte, minLen+r.Intn(maxLen-minLen+1))
+		r.Read(data)
+		return base64.StdEncoding.EncodeToString(data)
+	case "xs:hexBinary":
+		minLen, maxLen := lengthBounds(restriction, 1, 16)
+		data := make([]byte, minLen+r.Intn(maxLen-minLen+1))
+		r.Read(data)
+		return hex.EncodeToString(data)
+	}
+
+	// Strings and name-like types
+	minLen, maxLen := lengthBounds(restriction, 1, 12)
+	return randomWord(r, minLen, maxLen)
+}
+
+// lengthBounds returns the default length bounds narrowed by minLength/maxLength.
+func lengthBounds(restriction *Restriction, minLen, maxLen int) (int, int) {
+	if restriction != nil {
+		if restriction.MinLength != nil {
+			if value, err := strconv.Atoi(restriction.MinLength.Value); err == nil {
+				minLen = value
+			}
+		}
+		if restriction.MaxLength != nil {
+			if value, err := strconv.Atoi(restriction.MaxLength.Value); err == nil {
+				maxLen = value
+			}
+		}
+		if maxLen < minLen {
+			maxLen = minLen
+		}
+	}
+	return minLen, maxLen
+}
+
+// randomInRange returns an integer within the default bounds narrowed by minInclusive/maxInclusive.
+func randomInRange(r *rand.Rand, restriction *Restriction, lo, hi int64) int64 {
+	if restriction != nil {
+		// Leave room for one fraction digit of decimal values
+		if digits := facetDigits(restriction.TotalDigits); digits >= 1 && digits < 19 {
+			bound := int64(math.Pow10(digits-1)) - 1
+			if lo < -bound {
+				lo = -bound
+			}
+			if hi > bound {
+				hi = bound
+			}
+		}
+		if restriction.MinInclusive != nil {
+			if value, err := strconv.ParseFloat(restriction.MinInclusive.Value, 64); err == nil && int64(value) > lo {
+				lo = int64(value)
+			}
+		}
+		if restriction.MaxInclusive != nil {
+			if value, err := strconv.ParseFloat(restriction.MaxInclusive.Value, 64); err == nil && int64(value) < hi {
+				hi = int64(value)
+			}
+		}
+	}
+	if hi <= lo {
+		return lo
+	}
+	return lo + r.Int63n(hi-lo+1)
+}
+
+// facetDigits returns the value of a totalDigits or fractionDigits facet, or -1 if absent.
+func facetDigits(facet *Facet) int {
+	if facet == nil {
+		return -1
+	}
+	digits, err := strconv.Atoi(facet.Value)
+	if err != nil {
+		return -1
+	}
+	return digits
+}
+
+// randomDate returns a date in YYYY-MM-DD form that is valid in every month.
+func randomDate(r *rand.Rand) string {
+	return fmt.Sprintf("%04d-%02d-%02d", 1970+r.Intn(130), 1+r.Intn(12), 1+r.Intn(28))
+}
+
+// randomWord returns a random name-like string starting with a letter.
+func randomWord(r *rand.Rand, minLen, maxLen int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	const alphanumerics = letters + "0123456789"
+
+	length := minLen
+	if maxLen > minLen {
+		length += r.Intn(maxLen - minLen + 1)
+	}
+
+	var b strings.Builder
+	for i := 0; i < length; i++ {
+		if i == 0 {
+			b.WriteByte(letters[r.Intn(len(letters))])
+		} else {
+			b.WriteByte(alphanumerics[r.Intn(len(alphanumerics))])
+		}
+	}
+	return b.String()
+}
+
+// generateFromPattern generates a string matching a regular expression pattern.
+func generateFromPattern(r *rand.Rand, pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	generateFromRegexp(r, re.Simplify(), &b)
+	return b.String(), nil
+}
+
+// generateFromRegexp writes a random string matched by a parsed regular expression.
+func generateFromRegexp(r *rand.Rand, re *syntax.Regexp, b *strings.Builder) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		b.WriteRune(randomRuneInClass(r, re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteString(randomWord(r, 1, 1))
+	case syntax.OpCapture:
+		generateFromRegexp(r, re.Sub[0], b)
+	case syntax.OpStar:
+		repeatRegexp(r, re.Sub[0], r.Intn(4), b)
+	case syntax.OpPlus:
+		repeatRegexp(r, re.Sub[0], 1+r.Intn(3), b)
+	case syntax.OpQuest:
+		repeatRegexp(r, re.Sub[0], r.Intn(2), b)
+	case syntax.OpRepeat:
+		max := re.Max
+		if max < 0 {
+			max = re.Min + 3
+		}
+		repeatRegexp(r, re.Sub[0], re.Min+r.Intn(max-re.Min+1), b)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			generateFromRegexp(r, sub, b)
+		}
+	case syntax.OpAlternate:
+		generateFromRegexp(r, re.Sub[r.Intn(len(re.Sub))], b)
+	default:
+		// Anchors, word boundaries and empty matches produce no text
+	}
+}
+
+// repeatRegexp writes count random matches of a sub-expression.
+func repeatRegexp(r *rand.Rand, re *syntax.Regexp, count int, b *strings.Builder) {
+	for i := 0; i < count; i++ {
+		generateFromRegexp(r, re, b)
+	}
+}
+
+// randomRuneInClass picks a rune from a character class given as [lo, hi] pairs,
+// preferring printable ASCII so generated text stays readable and XML-safe.
+func randomRuneInClass(r *rand.Rand, ranges []rune) rune {
+	var printable []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < 0x21 {
+			lo = 0x21
+		}
+		if hi > 0x7e {
+			hi = 0x7e
+		}
+		if lo <= hi {
+			printable = append(printable, lo, hi)
+		}
+	}
+	if len(printable) == 0 {
+		printable = ranges
+	}
+	if len(printable) < 2 {
+		return 'a'
+	}
+
+	pair := r.Intn(len(printable)/2) * 2
+	lo, hi := printable[pair], printable[pair+1]
+	return lo + rune(r.Intn(int(hi-lo)+1))
+}
@@ -0,0 +1,120 @@
+package xmlparser
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// Test that generated documents always validate against their schema
+func TestGeneratorProducesValidDocuments(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="SkuType">
+        <xs:restriction base="xs:string">
+            <xs:pattern value="^[A-Z]{3}-\d{4}$"/>
+        </xs:restriction>
+    </xs:simpleType>
+
+    <xs:complexType name="LineType">
+        <xs:sequence>
+            <xs:element name="sku" type="SkuType"/>
+            <xs:element name="quantity">
+                <xs:simpleType>
+                    <xs:restriction base="xs:integer">
+                        <xs:minInclusive value="1"/>
+                        <xs:maxInclusive value="99"/>
+                    </xs:restriction>
+                </xs:simpleType>
+            </xs:element>
+            <xs:choice>
+                <xs:element name="price" type="xs:decimal"/>
+                <xs:element name="free" type="xs:boolean"/>
+            </xs:choice>
+        </xs:sequence>
+        <xs:attribute name="status" use="required">
+            <xs:simpleType>
+                <xs:restriction base="xs:string">
+                    <xs:enumeration value="open"/>
+                    <xs:enumeration value="shipped"/>
+                </xs:restriction>
+            </xs:simpleType>
+        </xs:attribute>
+    </xs:complexType>
+
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="customer">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:string">
+                            <xs:minLength value="3"/>
+                            <xs:maxLength value="10"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="placed" type="xs:dateTime"/>
+                <xs:element name="discount" minOccurs="0">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:decimal">
+                            <xs:totalDigits value="3"/>
+                            <xs:fractionDigits value="1"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="line" type="LineType" maxOccurs="unbounded"/>
+                <xs:sequence minOccurs="0" maxOccurs="3">
+                    <xs:element name="noteKey" type="xs:NCName"/>
+                    <xs:element name="noteValue" type="xs:string"/>
+                </xs:sequence>
+                <xs:element name="meta" minOccurs="0">
+                    <xs:complexType>
+                        <xs:all>
+                            <xs:element name="source" type="xs:string"/>
+                            <xs:element name="channel" type="xs:string" minOccurs="0"/>
+                        </xs:all>
+                    </xs:complexType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	gen, err := schema.NewGenerator("order")
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	property := func(doc *Document) bool {
+		if err := schema.Validate(doc); err != nil {
+			t.Logf("Generated document is invalid: %v", err)
+			return false
+		}
+		return true
+	}
+
+	config := &quick.Config{MaxCount: 300, Values: gen.Values, Rand: rand.New(rand.NewSource(1))}
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGeneratorUnknownRoot(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="test" type="xs:string"/>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	if _, err := schema.NewGenerator("missing"); err == nil || !strings.Contains(err.Error(), "not defined") {
+		t.Errorf("Expected error for unknown root element, got: %v", err)
+	}
+}
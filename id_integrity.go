@@ -0,0 +1,95 @@
+package xmlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// idReference is an xs:ID value, or an xs:IDREF value waiting to be resolved against the
+// document's IDs, with where it appears.
+type idReference struct {
+	value     string
+	node      *Node
+	attribute string // Name of the attribute holding the value, empty for element content
+	sensitive bool   // Whether the value is masked in issues
+
+	// Location path of node for IDREFs of streamed documents, whose elements no longer
+	// have their siblings when references are resolved
+	path string
+}
+
+// recordIdentity records xs:ID, xs:IDREF and xs:IDREFS values (including values of types
+// derived from them) for the document-wide integrity checks. Duplicate IDs are reported
+// immediately; references are resolved by checkIDReferences after the whole document has
+// been validated, since an IDREF may point forward.
+func (v *validator) recordIdentity(value, typeName string, simpleType *SimpleType, node *Node, attribute string, sensitive bool) []string {
+	value = strings.TrimSpace(value)
+
+	switch v.builtInBase(typeName, simpleType) {
+	case "xs:ID":
+		id := idReference{value: value, node: node, attribute: attribute, sensitive: sensitive}
+		if first, exists := v.ids[value]; exists {
+			return []string{duplicateID(id, first)}
+		}
+		v.ids[value] = id
+
+	case "xs:IDREF":
+		v.idrefs = append(v.idrefs, idReference{value: value, node: node, attribute: attribute, sensitive: sensitive,
+			path: v.referencePath(node)})
+
+	case "xs:IDREFS":
+		path := v.referencePath(node)
+		for _, ref := range strings.Fields(value) {
+			v.idrefs = append(v.idrefs, idReference{value: ref, node: node, attribute: attribute, sensitive: sensitive, path: path})
+		}
+	}
+	return nil
+}
+
+// checkIDReferences reports every recorded IDREF that does not match an ID in the document.
+func (v *validator) checkIDReferences() []ValidationIssue {
+	var issues []ValidationIssue
+	for _, ref := range v.idrefs {
+		if _, exists := v.ids[ref.value]; !exists {
+			issues = append(issues, located(ref.node, ref.path, ref.attribute,
+				fmt.Sprintf("IDREF '%s' in %s does not match any ID in the document",
+					ref.excerpt(), identityLocation(ref.node, ref.attribute))))
+		}
+	}
+	return issues
+}
+
+// referencePath returns the location path of an element holding an IDREF in a streamed
+// document, or "" otherwise.
+func (v *validator) referencePath(node *Node) string {
+	if v.pruned == nil {
+		return ""
+	}
+	return nodePath(node)
+}
+
+// duplicateID reports an ID value used again after its first occurrence.
+func duplicateID(id, first idReference) string {
+	value := id.excerpt()
+	if first.sensitive {
+		value = DefaultRedaction
+	}
+	return fmt.Sprintf("duplicate ID '%s' in %s (already used by element <%s>)",
+		value, identityLocation(id.node, id.attribute), first.node.Name.Local)
+}
+
+// excerpt returns the value for error messages, masked when it is sensitive.
+func (r idReference) excerpt() string {
+	if r.sensitive {
+		return DefaultRedaction
+	}
+	return excerpt(r.value)
+}
+
+// identityLocation describes where an ID or IDREF value appears, for error messages.
+func identityLocation(node *Node, attribute string) string {
+	if attribute != "" {
+		return fmt.Sprintf("attribute '%s' in element <%s>", attribute, node.Name.Local)
+	}
+	return fmt.Sprintf("element <%s>", node.Name.Local)
+}
@@ -0,0 +1,109 @@
+package xmlparser
+
+import (
+	"testing"
+)
+
+// Test document-wide ID uniqueness and IDREF/IDREFS resolution
+func TestIDReferenceIntegrity(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="PartID">
+        <xs:restriction base="xs:ID">
+            <xs:pattern value="p\d+"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:element name="assembly">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="part" minOccurs="0" maxOccurs="unbounded">
+                    <xs:complexType>
+                        <xs:sequence>
+                            <xs:element name="uses" type="xs:IDREFS" minOccurs="0"/>
+                        </xs:sequence>
+                        <xs:attribute name="id" type="PartID" use="required"/>
+                        <xs:attribute name="replaces" type="xs:IDREF"/>
+                    </xs:complexType>
+                </xs:element>
+                <xs:element name="label" minOccurs="0" maxOccurs="unbounded">
+                    <xs:complexType>
+                        <xs:sequence>
+                            <xs:element name="key" type="xs:ID"/>
+                        </xs:sequence>
+                    </xs:complexType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name: "Resolved references including forward ones",
+			xml: `<assembly>
+				<part id="p1" replaces="p2"><uses>p2 k1</uses></part>
+				<part id="p2"/>
+				<label><key>k1</key></label>
+			</assembly>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Duplicate ID across attributes",
+			xml:         `<assembly><part id="p1"/><part id="p1"/></assembly>`,
+			shouldPass:  false,
+			errorString: "duplicate ID 'p1' in attribute 'id' in element <part> (already used by element <part>)",
+		},
+		{
+			name:        "Duplicate ID across attribute and element content",
+			xml:         `<assembly><part id="p1"/><label><key>p1</key></label></assembly>`,
+			shouldPass:  false,
+			errorString: "duplicate ID 'p1' in element <key>",
+		},
+		{
+			name:        "Dangling IDREF",
+			xml:         `<assembly><part id="p1" replaces="p9"/></assembly>`,
+			shouldPass:  false,
+			errorString: "IDREF 'p9' in attribute 'replaces' in element <part> does not match any ID in the document",
+		},
+		{
+			name:        "Dangling entry in IDREFS",
+			xml:         `<assembly><part id="p1"><uses>p1 missing</uses></part></assembly>`,
+			shouldPass:  false,
+			errorString: "IDREF 'missing' in element <uses> does not match any ID in the document",
+		},
+		{
+			name:        "Invalid IDREFS lexical value",
+			xml:         `<assembly><part id="p1"><uses>p1 9bad</uses></part></assembly>`,
+			shouldPass:  false,
+			errorString: "'9bad' is not a valid IDREF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package xmlparser
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -462,3 +466,548 @@ func TestCircularReferenceDetection(t *testing.T) {
 		t.Log("⚠ Circular reference not detected - this could potentially cause issues")
 	}
 }
+
+// Test attributes typed with simple types from an imported namespace
+func TestImportedAttributeSimpleTypes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_attr_import_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	commonSchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	targetNamespace="http://example.com/common">
+
+	<xs:simpleType name="CurrencyCode">
+		<xs:restriction base="xs:string">
+			<xs:enumeration value="EUR"/>
+			<xs:enumeration value="USD"/>
+		</xs:restriction>
+	</xs:simpleType>
+
+	<xs:simpleType name="AccountRef">
+		<xs:restriction base="xs:string">
+			<xs:pattern value="^ACC-[0-9]{4}$"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "common.xsd"), []byte(commonSchemaContent), 0644); err != nil {
+		t.Fatalf("Failed to write imported schema file: %v", err)
+	}
+
+	// Two prefixes are bound to the imported namespace; both must resolve
+	mainSchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns:common="http://example.com/common"
+	xmlns:cmn="http://example.com/common">
+
+	<xs:import namespace="http://example.com/common" schemaLocation="common.xsd"/>
+
+	<xs:element name="payment">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="amount" type="xs:decimal"/>
+			</xs:sequence>
+			<xs:attribute name="currency" type="common:CurrencyCode" use="required"/>
+			<xs:attribute name="account" type="cmn:AccountRef"/>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`
+
+	schema, err := ParseXSD([]byte(mainSchemaContent), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to parse schema with imports: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Valid currency and account",
+			xml:        `<payment currency="EUR" account="ACC-1234"><amount>10.50</amount></payment>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Currency not in imported enumeration",
+			xml:         `<payment currency="GBP"><amount>10.50</amount></payment>`,
+			shouldPass:  false,
+			errorString: "not in the list of allowed values",
+		},
+		{
+			name:        "Account does not match imported pattern",
+			xml:         `<payment currency="USD" account="1234"><amount>10.50</amount></payment>`,
+			shouldPass:  false,
+			errorString: "does not match pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test schemas importing a namespace through another import (a chain), and two imports
+// sharing a third schema (a diamond): the shared components are merged once, keyed by
+// their own namespace, whatever prefixes the importing documents bind to it
+func TestNestedImports(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_nested_import_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"codes.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	targetNamespace="http://example.com/codes" elementFormDefault="qualified">
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string">
+			<xs:pattern value="[A-Z]{3}"/>
+		</xs:restriction>
+	</xs:simpleType>
+	<xs:element name="code" type="Code"/>
+	<xs:attribute name="source" type="xs:string"/>
+</xs:schema>`,
+		"items.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns:d="http://example.com/codes"
+	targetNamespace="http://example.com/items" elementFormDefault="qualified">
+	<xs:import namespace="http://example.com/codes" schemaLocation="codes.xsd"/>
+	<xs:element name="item">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element ref="d:code"/>
+				<xs:element name="label" type="d:Code"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+		"parties.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns:cd="http://example.com/codes"
+	targetNamespace="http://example.com/parties" elementFormDefault="qualified">
+	<xs:import namespace="http://example.com/codes" schemaLocation="codes.xsd"/>
+	<xs:element name="party" type="cd:Code"/>
+</xs:schema>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write schema file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{
+			name: "Chain",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns:b="http://example.com/items"
+	targetNamespace="http://example.com/orders" elementFormDefault="qualified">
+	<xs:import namespace="http://example.com/items" schemaLocation="items.xsd"/>
+	<xs:element name="order">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element ref="b:item"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+		},
+		{
+			name: "Diamond",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns:b="http://example.com/items" xmlns:p="http://example.com/parties" xmlns:d="http://example.com/codes"
+	targetNamespace="http://example.com/orders" elementFormDefault="qualified">
+	<xs:import namespace="http://example.com/items" schemaLocation="items.xsd"/>
+	<xs:import namespace="http://example.com/parties" schemaLocation="parties.xsd"/>
+	<xs:import namespace="http://example.com/codes" schemaLocation="codes.xsd"/>
+	<xs:element name="order">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element ref="b:item"/>
+				<xs:element ref="p:party" minOccurs="0"/>
+				<xs:element name="currency" type="d:Code" minOccurs="0"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`,
+		},
+	}
+
+	documents := []struct {
+		name        string
+		xml         string
+		errorString string
+	}{
+		{
+			name: "Valid item",
+			xml: `<order xmlns="http://example.com/orders" xmlns:i="http://example.com/items" xmlns:c="http://example.com/codes">
+	<i:item><c:code>ABC</c:code><i:label>XYZ</i:label></i:item>
+</order>`,
+		},
+		{
+			name: "Code not matching the nested import's pattern",
+			xml: `<order xmlns="http://example.com/orders" xmlns:i="http://example.com/items" xmlns:c="http://example.com/codes">
+	<i:item><c:code>abc</c:code><i:label>XYZ</i:label></i:item>
+</order>`,
+			errorString: "does not match pattern",
+		},
+		{
+			name: "Label not matching the nested import's pattern",
+			xml: `<order xmlns="http://example.com/orders" xmlns:i="http://example.com/items" xmlns:c="http://example.com/codes">
+	<i:item><c:code>ABC</c:code><i:label>X</i:label></i:item>
+</order>`,
+			errorString: "does not match pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseXSD([]byte(tt.schema), tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to parse schema with nested imports: %v", err)
+			}
+			for _, simpleType := range schema.SimpleTypes {
+				if strings.Count(simpleType.Name, ":") > 1 {
+					t.Errorf("Expected the imported type to be prefixed once, got '%s'", simpleType.Name)
+				}
+			}
+
+			for _, document := range documents {
+				doc, err := Parse([]byte(document.xml))
+				if err != nil {
+					t.Fatalf("Failed to parse XML: %v", err)
+				}
+				validationErr := schema.Validate(doc)
+				if document.errorString == "" {
+					if validationErr != nil {
+						t.Errorf("%s: expected validation to pass, but got error: %v", document.name, validationErr)
+					}
+				} else {
+					expectValidationError(t, validationErr, document.errorString)
+				}
+			}
+		})
+	}
+
+	// The types shared through both imports of the diamond are checked from either path
+	schema, err := ParseXSD([]byte(tests[1].schema), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to parse schema with nested imports: %v", err)
+	}
+	doc, err := Parse([]byte(`<order xmlns="http://example.com/orders" xmlns:i="http://example.com/items" xmlns:c="http://example.com/codes" xmlns:p="http://example.com/parties">
+	<i:item><c:code>ABC</c:code><i:label>XYZ</i:label></i:item>
+	<p:party>ab</p:party>
+	<currency>eur</currency>
+</order>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	if err := schema.Validate(doc); err == nil || strings.Count(err.Error(), "does not match pattern") != 2 {
+		t.Errorf("Expected the party and the currency not to match the shared pattern, got: %v", err)
+	}
+}
+
+// Test URI-first resolution of schemaLocation values
+func TestResolveSchemaLocation(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_location_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// A file whose name literally contains a percent sequence exercises the filesystem fallback
+	if err := os.WriteFile(filepath.Join(tmpDir, "literal%41.xsd"), []byte("<xs:schema/>"), 0644); err != nil {
+		t.Fatalf("Failed to write schema file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		location string
+		base     string
+		expected string
+	}{
+		{
+			name:     "Relative path with forward slashes",
+			location: "types/common.xsd",
+			base:     tmpDir,
+			expected: filepath.Join(tmpDir, "types", "common.xsd"),
+		},
+		{
+			name:     "Percent-encoded relative path",
+			location: "my%20types/common%2Bv2.xsd",
+			base:     tmpDir,
+			expected: filepath.Join(tmpDir, "my types", "common+v2.xsd"),
+		},
+		{
+			name:     "Literal percent sequence falls back to existing file",
+			location: "literal%41.xsd",
+			base:     tmpDir,
+			expected: filepath.Join(tmpDir, "literal%41.xsd"),
+		},
+		{
+			name:     "Relative reference against remote base",
+			location: "../common/types.xsd",
+			base:     "https://example.com/schemas/main/order.xsd",
+			expected: "https://example.com/schemas/common/types.xsd",
+		},
+		{
+			name:     "Absolute URL ignores base",
+			location: "http://example.com/a.xsd",
+			base:     tmpDir,
+			expected: "http://example.com/a.xsd",
+		},
+		{
+			name:     "File URI",
+			location: "file:///etc/schemas/a%20b.xsd",
+			base:     tmpDir,
+			expected: "/etc/schemas/a b.xsd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "File URI" && runtime.GOOS == "windows" {
+				t.Skip("POSIX file URI")
+			}
+			resolved, err := resolveSchemaLocation(tt.location, tt.base)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resolved != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, resolved)
+			}
+		})
+	}
+
+	if _, err := resolveSchemaLocation("urn:example:schema", tmpDir); err == nil ||
+		!strings.Contains(err.Error(), "unsupported scheme") {
+		t.Errorf("Expected unsupported scheme error, got: %v", err)
+	}
+}
+
+// Test file URI conversion for both POSIX and Windows conventions
+func TestFileURIToPath(t *testing.T) {
+	tests := []struct {
+		uri      string
+		windows  bool
+		expected string
+	}{
+		{"file:///home/user/schemas/a.xsd", false, "/home/user/schemas/a.xsd"},
+		{"file://localhost/home/user/a%20b.xsd", false, "/home/user/a b.xsd"},
+		{"file:///C:/schemas/a.xsd", true, `C:\schemas\a.xsd`},
+		{"file:///c:/My%20Schemas/a.xsd", true, `c:\My Schemas\a.xsd`},
+		{"file://localhost/C:/schemas/a.xsd", true, `C:\schemas\a.xsd`},
+		{"file://server/share/a.xsd", true, `\\server\share\a.xsd`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			uri, err := url.Parse(tt.uri)
+			if err != nil {
+				t.Fatalf("Failed to parse URI: %v", err)
+			}
+			if path := fileURIToPath(uri, tt.windows); path != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, path)
+			}
+		})
+	}
+}
+
+// Test that include locations with encoded characters are loaded
+func TestIncludeEncodedSchemaLocation(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_encoded_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.Mkdir(filepath.Join(tmpDir, "common types"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	includedSchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="CodeType">
+		<xs:restriction base="xs:string">
+			<xs:maxLength value="3"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`
+	if err := os.WriteFile(filepath.Join(tmpDir, "common types", "code.xsd"), []byte(includedSchemaContent), 0644); err != nil {
+		t.Fatalf("Failed to write included schema file: %v", err)
+	}
+
+	mainSchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="common%20types/code.xsd"/>
+	<xs:element name="code" type="CodeType"/>
+</xs:schema>`
+
+	schema, err := ParseXSD([]byte(mainSchemaContent), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to parse schema with encoded include: %v", err)
+	}
+	if _, exists := schema.SimpleTypeMap["CodeType"]; !exists {
+		t.Error("Expected CodeType from included schema to be available")
+	}
+}
+
+// Test that references inside remote schemas resolve against the remote URL at every depth
+func TestRemoteNestedIncludes(t *testing.T) {
+	documents := map[string]string{
+		"/schemas/main.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="common/types.xsd"/>
+	<xs:element name="order" type="OrderType"/>
+</xs:schema>`,
+		"/schemas/common/types.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="../units/quantity.xsd"/>
+	<xs:complexType name="OrderType">
+		<xs:sequence>
+			<xs:element name="quantity" type="QuantityType"/>
+		</xs:sequence>
+	</xs:complexType>
+</xs:schema>`,
+		"/schemas/units/quantity.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="QuantityType">
+		<xs:restriction base="xs:positiveInteger">
+			<xs:maxInclusive value="100"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, exists := documents[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(document))
+	}))
+	defer server.Close()
+
+	// A local directory with a decoy common/types.xsd, which must not be used for the nested include
+	tmpDir, err := os.MkdirTemp("", "xmlparser_remote_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.Mkdir(filepath.Join(tmpDir, "common"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	decoy := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:complexType name="DecoyType"/></xs:schema>`
+	if err := os.WriteFile(filepath.Join(tmpDir, "common", "types.xsd"), []byte(decoy), 0644); err != nil {
+		t.Fatalf("Failed to write decoy schema file: %v", err)
+	}
+
+	localMain := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="` + server.URL + `/schemas/common/types.xsd"/>
+	<xs:element name="order" type="OrderType"/>
+</xs:schema>`
+
+	parsers := map[string]func() (*Schema, error){
+		"Remote root schema": func() (*Schema, error) {
+			return ParseXSDFromLocation(server.URL+"/schemas/main.xsd", ParseOptions{})
+		},
+		"Local schema including a remote one": func() (*Schema, error) {
+			return ParseXSD([]byte(localMain), tmpDir)
+		},
+		"Remote base path": func() (*Schema, error) {
+			return ParseXSD([]byte(documents["/schemas/main.xsd"]), server.URL+"/schemas/main.xsd")
+		},
+	}
+	for name, parse := range parsers {
+		t.Run(name, func(t *testing.T) {
+			schema, err := parse()
+			if err != nil {
+				t.Fatalf("Failed to parse schema: %v", err)
+			}
+			if _, exists := schema.ComplexTypeMap["DecoyType"]; exists {
+				t.Error("Nested include was resolved against the local base path instead of the remote URL")
+			}
+
+			doc, err := Parse([]byte(`<order><quantity>101</quantity></order>`))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			expectValidationError(t, schema.Validate(doc), "exceeds maximum allowed value 100")
+		})
+	}
+
+	t.Run("Missing nested remote schema", func(t *testing.T) {
+		delete(documents, "/schemas/units/quantity.xsd")
+		_, err := ParseXSDFromLocation(server.URL+"/schemas/main.xsd", ParseOptions{})
+		want := "failed to process include '../units/quantity.xsd' relative to '" + server.URL + "/schemas/common/types.xsd'"
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q, got: %v", want, err)
+		}
+	})
+}
+
+// Test that ParseXSDFile resolves includes next to the file and ParseXSDReader reads a stream
+func TestParseXSDFileAndReader(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	schemaDir := filepath.Join(tmpDir, "schemas")
+	if err := os.MkdirAll(schemaDir, 0755); err != nil {
+		t.Fatalf("Failed to create schema directory: %v", err)
+	}
+	files := map[string]string{
+		"types.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string">
+			<xs:maxLength value="3"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`,
+		"main.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="types.xsd"/>
+	<xs:element name="code" type="Code"/>
+</xs:schema>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(schemaDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	doc, err := Parse([]byte(`<code>ABCD</code>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	schema, err := ParseXSDFile(filepath.Join(schemaDir, "main.xsd"))
+	if err != nil {
+		t.Fatalf("Failed to parse schema file: %v", err)
+	}
+	expectValidationError(t, schema.Validate(doc), "value 'ABCD' is too long")
+
+	schema, err = ParseXSDReader(strings.NewReader(files["main.xsd"]), schemaDir)
+	if err != nil {
+		t.Fatalf("Failed to parse schema from reader: %v", err)
+	}
+	expectValidationError(t, schema.Validate(doc), "value 'ABCD' is too long")
+
+	if _, err := ParseXSDFile(filepath.Join(tmpDir, "missing.xsd")); err == nil ||
+		!strings.Contains(err.Error(), "failed to read schema file") {
+		t.Errorf("Expected error for a missing schema file, got: %v", err)
+	}
+}
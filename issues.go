@@ -0,0 +1,352 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationIssue is a single failure of a ValidationError, with what callers need to act
+// on it without parsing its message: a stable code, the location path of the element or
+// attribute it was found on, and its position in the source.
+type ValidationIssue struct {
+	Code     IssueCode `json:"code" xml:"code,attr"`
+	Message  string    `json:"message" xml:",chardata"`
+	Severity Severity  `json:"severity" xml:"severity,attr"`
+
+	// Rule names the validation rule of the XML Schema specification the issue violates,
+	// e.g. "cvc-minLength-valid" or "cvc-complex-type.2.4.a", as Xerces prefixes its messages
+	// with and libxml2 names its XML_SCHEMAV_CVC_* codes, so handling and fixtures written
+	// for those validators keep working. Empty for issues no rule covers, such as parse
+	// errors and the checks of ValidateOptions.
+	Rule string `json:"rule,omitempty" xml:"rule,attr,omitempty"`
+
+	// XPath locates the element the issue was found on, e.g. "/order/item[2]/price[1]",
+	// with the attribute step for attribute issues, e.g. "/order/@id". Names carry a prefix
+	// the document declares for their namespace, if any; every step but the root's gives
+	// the position among siblings of the same name. Empty for issues of no element.
+	XPath string `json:"xpath,omitempty" xml:"xpath,attr,omitempty"`
+
+	// Source position of the start tag of the element, or of the attribute; zero for
+	// documents not parsed from source
+	Line   int `json:"line,omitempty" xml:"line,attr,omitempty"`
+	Column int `json:"column,omitempty" xml:"column,attr,omitempty"`
+}
+
+// Error returns the message of the issue, so that the issues a ValidationError unwraps
+// into are errors.
+func (i *ValidationIssue) Error() string {
+	return i.Message
+}
+
+// IssueCode classifies a ValidationIssue. New codes may be added; callers should treat
+// codes they do not know as CodeInvalidValue.
+type IssueCode string
+
+// Codes of validation issues.
+const (
+	CodeParseError          IssueCode = "parse-error"          // The document is not well-formed
+	CodeEmptyDocument       IssueCode = "empty-document"       // The document has no root element
+	CodeUndeclaredRoot      IssueCode = "undeclared-root"      // The root element has no global declaration
+	CodeUnexpectedElement   IssueCode = "unexpected-element"   // A child the content model does not allow there
+	CodeMissingElement      IssueCode = "missing-element"      // Fewer occurrences of a child than required
+	CodeTooManyElements     IssueCode = "too-many-elements"    // More occurrences of a child than allowed
+	CodeUnexpectedText      IssueCode = "unexpected-text"      // Text in element-only or empty content
+	CodeEmptyElement        IssueCode = "empty-element"        // An optional element present without a value
+	CodeWrongNamespace      IssueCode = "wrong-namespace"      // An element in another namespace than declared
+	CodeMissingAttribute    IssueCode = "missing-attribute"    // A required attribute is absent
+	CodeUnexpectedAttribute IssueCode = "unexpected-attribute" // An attribute the type does not declare
+	CodeFixedValue          IssueCode = "fixed-value"          // An attribute value other than its declared fixed value
+	CodeInvalidValue        IssueCode = "invalid-value"        // A value its type or facets reject
+	CodeDuplicateID         IssueCode = "duplicate-id"         // An xs:ID value used twice
+	CodeUnresolvedIDREF     IssueCode = "unresolved-idref"     // An xs:IDREF value matching no ID
+	CodeInvalidNil          IssueCode = "invalid-nil"          // A misuse of xsi:nil
+	CodeInvalidXsiType      IssueCode = "invalid-xsi-type"     // An xsi:type that cannot replace the declared type
+	CodeSubtreeBudget       IssueCode = "subtree-budget"       // An element exceeding its subtree budget
+	CodeUnknownMessageType  IssueCode = "unknown-message-type" // A message of a type a Service does not configure
+	CodeNotChecked          IssueCode = "not-checked"          // A warning that validation skipped a constraint or content
+)
+
+// Severity is how serious a ValidationIssue is. Errors make the document invalid; warnings,
+// reported apart from them (see ValidateOptions.OnWarning), tell what validation did not
+// check, so that a valid document can be told apart from one only partly checked.
+type Severity string
+
+// Severities of validation issues.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// newIssue returns the issue of a message reported on node, at path if given, or at that
+// of node. The attribute is that of an attribute issue, if known; otherwise it is taken
+// from the message.
+func newIssue(node *Node, path, attribute, message string) ValidationIssue {
+	issue := ValidationIssue{Code: issueCode(message), Message: message, Severity: SeverityError}
+	issue.Rule = issueRule(issue.Code, message, node != nil && node.Parent == nil)
+	if node == nil {
+		return issue
+	}
+
+	if attribute == "" {
+		attribute = messageAttribute(message, node)
+	}
+	if path == "" {
+		path = nodePath(node)
+	}
+	position := node.Position
+	for i, attr := range node.Attrs {
+		if attribute != "" && attr.Name.Local == attribute {
+			path += "/@" + qualifiedName(node, attr.Name)
+			if i < len(node.AttrPositions) {
+				position = node.AttrPositions[i]
+			}
+			break
+		}
+	}
+	issue.XPath, issue.Line, issue.Column = path, position.Line, position.Column
+	return issue
+}
+
+// located returns the issue of a message reported on node, as newIssue does, with the
+// location path appended to the message, so that plain messages tell which of several
+// elements of the same name they are about.
+func located(node *Node, path, attribute, message string) ValidationIssue {
+	issue := newIssue(node, path, attribute, message)
+	issue.Message += " (at " + issue.XPath + ")"
+	return issue
+}
+
+// parseFailure returns the ValidationError of a document that cannot be parsed.
+func parseFailure(err error) *ValidationError {
+	issue := newIssue(nil, "", "", err.Error())
+	var syntaxErr *xml.SyntaxError
+	var limitErr *LimitError
+	if errors.As(err, &syntaxErr) {
+		issue.Line = syntaxErr.Line
+	} else if errors.As(err, &limitErr) {
+		issue.Line, issue.Column = limitErr.Position.Line, limitErr.Position.Column
+	}
+	return &ValidationError{Issues: []ValidationIssue{issue}, ParseError: err}
+}
+
+// documentFailure returns the ValidationError of a document rejected as a whole, such as
+// one whose root element is not declared.
+func documentFailure(root *Node, message string) *ValidationError {
+	return &ValidationError{Issues: []ValidationIssue{newIssue(root, "", "", message)}}
+}
+
+// messageAttribute returns the name of the attribute of node a message is about, or ""
+// when it is about the element.
+func messageAttribute(message string, node *Node) string {
+	const prefix = "attribute '"
+	suffix := "' in element <" + node.Name.Local + ">"
+	for offset := 0; ; {
+		i := strings.Index(message[offset:], prefix)
+		if i < 0 {
+			return ""
+		}
+		name := message[offset+i+len(prefix):]
+		if end := strings.IndexByte(name, '\''); end >= 0 && strings.HasPrefix(name[end:], suffix) {
+			return name[:end]
+		}
+		offset += i + len(prefix)
+	}
+}
+
+// nodePath returns the location path of an element from the root.
+func nodePath(node *Node) string {
+	var steps []string
+	for ; node != nil; node = node.Parent {
+		step := qualifiedName(node, node.Name)
+		if node.Parent != nil {
+			step += fmt.Sprintf("[%d]", siblingPosition(node))
+		}
+		steps = append(steps, step)
+	}
+
+	var path strings.Builder
+	for i := len(steps) - 1; i >= 0; i-- {
+		path.WriteString("/" + steps[i])
+	}
+	return path.String()
+}
+
+// siblingPosition returns the position of an element among the children of its parent
+// with the same name, starting at 1.
+func siblingPosition(node *Node) int {
+	if node.sibling > 0 {
+		return node.sibling
+	}
+	position := 1
+	for _, sibling := range node.Parent.Children {
+		if sibling == node {
+			break
+		}
+		if sibling.Name == node.Name {
+			position++
+		}
+	}
+	return position
+}
+
+// qualifiedName returns the name of node or of one of its attributes with a prefix the
+// document declares for its namespace, or the local name when there is none.
+func qualifiedName(node *Node, name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	if prefix, ok := declaredPrefix(node, name.Space); ok {
+		return prefix + ":" + name.Local
+	}
+	return name.Local
+}
+
+// issueCode classifies a message by the wording of the check that reported it. Messages
+// start with what they are about, so values quoted further on do not affect the code.
+// TestIssueRuleOfEveryMessageFormat reads the message formats of the checks from their
+// source, so a reworded message fails it until its code and rule are checked again.
+func issueCode(message string) IssueCode {
+	switch {
+	case strings.HasPrefix(message, "in element <"):
+		return CodeInvalidValue
+	case strings.HasPrefix(message, "required attribute "):
+		return CodeMissingAttribute
+	case strings.HasPrefix(message, "unexpected attribute "):
+		return CodeUnexpectedAttribute
+	case strings.HasPrefix(message, "duplicate ID "):
+		return CodeDuplicateID
+	case strings.HasPrefix(message, "IDREF "):
+		return CodeUnresolvedIDREF
+	case strings.HasPrefix(message, "XML parsing error"):
+		return CodeParseError
+	case strings.HasPrefix(message, "XML document is empty"):
+		return CodeEmptyDocument
+	case strings.HasPrefix(message, "root element "), strings.HasPrefix(message, "no schema in the set"):
+		return CodeUndeclaredRoot
+	case strings.HasPrefix(message, "xsi:type "), strings.HasPrefix(message, "invalid xsi:type "):
+		return CodeInvalidXsiType
+	case strings.HasPrefix(message, "attribute '"):
+		switch {
+		case strings.Contains(message, " has fixed value '"):
+			return CodeFixedValue
+		case strings.Contains(message, " matches the attribute wildcard, "):
+			return CodeUnexpectedAttribute
+		}
+		return CodeInvalidValue
+	case strings.HasPrefix(message, "element <"), strings.HasPrefix(message, "optional element <"),
+		strings.HasPrefix(message, "required element <"):
+		return elementIssueCode(message)
+	}
+	return CodeInvalidValue
+}
+
+// elementIssueCode classifies a message about the structure of an element.
+func elementIssueCode(message string) IssueCode {
+	if strings.Contains(message, "> is in ") {
+		return CodeWrongNamespace
+	}
+
+	// Only the part before quoted text is worded by the check
+	if i := strings.IndexByte(message, '\''); i >= 0 {
+		message = message[:i]
+	}
+	switch {
+	case strings.Contains(message, " contains unexpected text "):
+		return CodeUnexpectedText
+	case strings.Contains(message, " exceeds its subtree budget"):
+		return CodeSubtreeBudget
+	case strings.Contains(message, "xsi:nil"):
+		return CodeInvalidNil
+	case strings.Contains(message, "> is empty"):
+		return CodeEmptyElement
+	case strings.Contains(message, " requires at least "), strings.Contains(message, " is missing"),
+		strings.Contains(message, " must contain at least "), strings.Contains(message, " but minimum is "):
+		return CodeMissingElement
+	case strings.Contains(message, " allows at most "), strings.Contains(message, " but maximum is "):
+		return CodeTooManyElements
+	}
+	return CodeUnexpectedElement
+}
+
+// valueRules are the rules of the facets a value message can report, by the wording of
+// the facet's message after the quoted value.
+var valueRules = []struct{ wording, rule string }{
+	{"' is too short (minimum length: ", "cvc-minLength-valid"},
+	{"' is too long (maximum length: ", "cvc-maxLength-valid"},
+	{"' does not match pattern '", "cvc-pattern-valid"},
+	{"' is not in the list of allowed values: ", "cvc-enumeration-valid"},
+	{"' below minimum allowed value ", "cvc-minInclusive-valid"},
+	{"' exceeds maximum allowed value ", "cvc-maxInclusive-valid"},
+	{"' has too many digits (totalDigits: ", "cvc-totalDigits-valid"},
+	{"' has too many fraction digits (fractionDigits: ", "cvc-fractionDigits-valid"},
+}
+
+// issueRule returns the rule of the XML Schema specification an issue of the given code
+// violates; root tells whether the issue is about the root element.
+func issueRule(code IssueCode, message string, root bool) string {
+	switch code {
+	case CodeUndeclaredRoot:
+		return "cvc-elt.1.a"
+	case CodeWrongNamespace:
+		if root {
+			return "cvc-elt.1.a"
+		}
+		return "cvc-complex-type.2.4.a"
+	case CodeUnexpectedElement:
+		if strings.Contains(message, " has a simple type and must not contain child elements") {
+			return "cvc-type.3.1.2"
+		}
+		if strings.Contains(message, " must be empty (content model is empty)") {
+			return "cvc-complex-type.2.1"
+		}
+		return "cvc-complex-type.2.4.a"
+	case CodeMissingElement:
+		return "cvc-complex-type.2.4.b"
+	case CodeTooManyElements:
+		return "cvc-complex-type.2.4.d"
+	case CodeUnexpectedText:
+		if strings.Contains(message, " (content model is empty)") {
+			return "cvc-complex-type.2.1"
+		}
+		return "cvc-complex-type.2.3"
+	case CodeMissingAttribute:
+		return "cvc-complex-type.4"
+	case CodeUnexpectedAttribute:
+		return "cvc-complex-type.3.2.2"
+	case CodeFixedValue:
+		return "cvc-attribute.4"
+	case CodeDuplicateID:
+		return "cvc-id.2"
+	case CodeUnresolvedIDREF:
+		return "cvc-id.1"
+	case CodeInvalidNil:
+		if strings.Contains(message, " is not nillable") {
+			return "cvc-elt.3.1"
+		}
+		return "cvc-elt.3.2.1"
+	case CodeInvalidXsiType:
+		if strings.Contains(message, " is not derived from its declared type ") {
+			return "cvc-elt.4.3"
+		}
+		return "cvc-elt.4.2"
+	case CodeInvalidValue:
+		for _, r := range valueRules {
+			if strings.Contains(message, r.wording) {
+				return r.rule
+			}
+		}
+		return "cvc-datatype-valid.1.2.1"
+	}
+	return ""
+}
+
+// messagesOf returns the messages of issues.
+func messagesOf(issues []ValidationIssue) []string {
+	messages := make([]string, len(issues))
+	for i, issue := range issues {
+		messages[i] = issue.Message
+	}
+	return messages
+}
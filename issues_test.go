@@ -0,0 +1,404 @@
+package xmlparser
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidationIssues(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="item" maxOccurs="unbounded">
+          <xs:complexType>
+            <xs:sequence>
+              <xs:element name="price" type="xs:decimal"/>
+            </xs:sequence>
+            <xs:attribute name="sku" type="xs:positiveInteger"/>
+          </xs:complexType>
+        </xs:element>
+        <xs:element name="parent" type="xs:IDREF" minOccurs="0"/>
+      </xs:sequence>
+      <xs:attribute name="id" type="xs:ID" use="required"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	document := `<order>
+  <item sku="1"><price>1.50</price></item>
+  <item sku="-2"><price>free</price></item>
+  <parent>o-2</parent>
+  <note/>
+</order>`
+	expected := []ValidationIssue{
+		{Code: CodeMissingAttribute, XPath: "/order", Line: 1, Column: 1},
+		{Code: CodeUnexpectedElement, XPath: "/order", Line: 1, Column: 1},
+		{Code: CodeInvalidValue, XPath: "/order/item[2]/@sku", Line: 3, Column: 9},
+		{Code: CodeInvalidValue, XPath: "/order/item[2]/price[1]", Line: 3, Column: 18},
+		{Code: CodeUnresolvedIDREF, XPath: "/order/parent[1]", Line: 4, Column: 3},
+	}
+	checkIssues := func(t *testing.T, err error, ordered bool) {
+		t.Helper()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || len(validationErr.Issues) != len(expected) {
+			t.Fatalf("Expected %d issues, got: %v", len(expected), err)
+		}
+		for i, want := range expected {
+			got := validationErr.Issues[i]
+			if !ordered {
+				for _, issue := range validationErr.Issues {
+					if issue.XPath == want.XPath && issue.Code == want.Code {
+						got = issue
+					}
+				}
+			}
+			if got.Code != want.Code || got.XPath != want.XPath || got.Line != want.Line || got.Column != want.Column ||
+				got.Severity != SeverityError {
+				t.Errorf("Issue %d: expected %s at %s (%d:%d), got %+v", i, want.Code, want.XPath, want.Line, want.Column, got)
+			}
+		}
+		if !strings.Contains(validationErr.Error(), "5 validation errors found") {
+			t.Errorf("Unexpected error text: %v", validationErr)
+		}
+	}
+
+	t.Run("Validate", func(t *testing.T) {
+		checkIssues(t, schema.ValidateBytes([]byte(document)), true)
+	})
+	t.Run("ValidateReader", func(t *testing.T) {
+		checkIssues(t, schema.ValidateReader(strings.NewReader(document)), false)
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		var validationErr *ValidationError
+		err := schema.ValidateBytes([]byte("<order>\n<item>\n</order>"))
+		if !errors.As(err, &validationErr) || validationErr.Issues[0].Code != CodeParseError || validationErr.Issues[0].Line != 3 {
+			t.Errorf("Expected a parse error on line 3, got: %+v", validationErr)
+		}
+	})
+
+	t.Run("undeclared root", func(t *testing.T) {
+		var validationErr *ValidationError
+		err := schema.ValidateBytes([]byte("<invoice/>"))
+		if !errors.As(err, &validationErr) || validationErr.Issues[0].Code != CodeUndeclaredRoot || validationErr.Issues[0].XPath != "/invoice" {
+			t.Errorf("Expected an undeclared root issue, got: %+v", validationErr)
+		}
+	})
+}
+
+func TestIssueCode(t *testing.T) {
+	tests := []struct {
+		message string
+		code    IssueCode
+	}{
+		{"element <order> requires at least 1 <item> child, but found 0", CodeMissingElement},
+		{"element <order> allows at most 1 <note> child, but found 2", CodeTooManyElements},
+		{"element <note> is not a valid child of <order>", CodeUnexpectedElement},
+		{"element <item> is out of order in <order>: it must appear before <note>", CodeUnexpectedElement},
+		{"element <order> contains unexpected text 'requires at least' (content model is element-only)", CodeUnexpectedText},
+		{"element <order> is in namespace 'urn:a', but its declaration requires namespace 'urn:b'", CodeWrongNamespace},
+		{"element <price> has xsi:nil=\"true\", but its declaration is not nillable", CodeInvalidNil},
+		{"optional element <note> is empty; omit it instead of leaving it empty", CodeEmptyElement},
+		{"required element <name> is missing from xs:all group in <person>", CodeMissingElement},
+		{"element <order> exceeds its subtree budget: 12 descendant elements (maximum: 10)", CodeSubtreeBudget},
+		{"in element <price>: value 'free' is not a valid decimal", CodeInvalidValue},
+		{"attribute 'code' in element <item> has fixed value 'A1', but got 'B2'", CodeFixedValue},
+		{"unexpected attribute 'color' in element <item>", CodeUnexpectedAttribute},
+		{"duplicate ID 'a' in attribute 'id' in element <item> (already used by element <order>)", CodeDuplicateID},
+		{"xsi:type 'Car' on element <vehicle> does not resolve to a type in the schema", CodeInvalidXsiType},
+	}
+	for _, tt := range tests {
+		if code := issueCode(tt.message); code != tt.code {
+			t.Errorf("%q: expected %s, got %s", tt.message, tt.code, code)
+		}
+	}
+}
+
+func TestIssueRule(t *testing.T) {
+	tests := []struct {
+		message string
+		root    bool
+		rule    string
+	}{
+		{"in element <name>: value 'A' is too short (minimum length: 2, actual: 1)", false, "cvc-minLength-valid"},
+		{"attribute 'code' in element <item>: value 'ABCD' is too long (maximum length: 3, actual: 4)", false, "cvc-maxLength-valid"},
+		{"in element <zip>: value '1x' does not match pattern '[0-9]+'", false, "cvc-pattern-valid"},
+		{"in element <color>: value 'pink' is not in the list of allowed values: [red, green]", false, "cvc-enumeration-valid"},
+		{"in element <quantity>: value '0' below minimum allowed value 1", false, "cvc-minInclusive-valid"},
+		{"in element <quantity>: value '500' exceeds maximum allowed value 100", false, "cvc-maxInclusive-valid"},
+		{"in element <price>: value '1.234' has too many fraction digits (fractionDigits: 2, actual: 3)", false, "cvc-fractionDigits-valid"},
+		{"in element <price>: value 'is too short (minimum length: ' is not a valid decimal", false, "cvc-datatype-valid.1.2.1"},
+		{"element <order> requires at least 1 <item> child, but found 0", false, "cvc-complex-type.2.4.b"},
+		{"element <order> allows at most 1 <note> child, but found 2", false, "cvc-complex-type.2.4.d"},
+		{"element <note> is not a valid child of <order>", false, "cvc-complex-type.2.4.a"},
+		{"element <order> contains unexpected text 'x' (content model is element-only)", false, "cvc-complex-type.2.3"},
+		{"element <flag> contains unexpected text 'x' (content model is empty)", false, "cvc-complex-type.2.1"},
+		{"element <price> has a simple type and must not contain child elements, but found <amount>", false, "cvc-type.3.1.2"},
+		{"element <order> is in no namespace, but its declaration requires namespace 'urn:a'", true, "cvc-elt.1.a"},
+		{"element <item> is in no namespace, but its declaration requires namespace 'urn:a'", false, "cvc-complex-type.2.4.a"},
+		{"root element <invoice> is not defined in the schema", true, "cvc-elt.1.a"},
+		{"required attribute 'id' is missing from element <order>", false, "cvc-complex-type.4"},
+		{"unexpected attribute 'color' in element <item>", false, "cvc-complex-type.3.2.2"},
+		{"attribute 'code' in element <item> has fixed value 'A1', but got 'B2'", false, "cvc-attribute.4"},
+		{"duplicate ID 'a' in attribute 'id' in element <item> (already used by element <order>)", false, "cvc-id.2"},
+		{"IDREF 'o-2' in element <parent> does not match any ID in the document", false, "cvc-id.1"},
+		{"element <price> has xsi:nil=\"true\", but its declaration is not nillable", false, "cvc-elt.3.1"},
+		{"element <price> has xsi:nil=\"true\" and must be empty, but contains text '1'", false, "cvc-elt.3.2.1"},
+		{"xsi:type 'Car' on element <vehicle> does not resolve to a type in the schema", false, "cvc-elt.4.2"},
+		{"xsi:type 'Car' on element <vehicle> is not derived from its declared type 'Animal'", false, "cvc-elt.4.3"},
+		{"optional element <note> is empty; omit it instead of leaving it empty", false, ""},
+		{"XML parsing error: unexpected EOF", true, ""},
+	}
+	for _, tt := range tests {
+		if rule := issueRule(issueCode(tt.message), tt.message, tt.root); rule != tt.rule {
+			t.Errorf("%q: expected %q, got %q", tt.message, tt.rule, rule)
+		}
+	}
+}
+
+// Test the code and rule of every message format of the validation checks, read from their
+// source, so that rewording a message cannot silently change or drop its rule
+func TestIssueRuleOfEveryMessageFormat(t *testing.T) {
+	const (
+		inElement   = "in element <e>: "
+		inAttribute = "attribute 'a' in element <e>: "
+		inDate      = inElement + "value '2024-13-01' is not a valid date: "
+	)
+	tests := []struct {
+		prefix string // Text the check's message is wrapped in
+		format string
+		args   []any
+		code   IssueCode
+		rule   string
+	}{
+		// binary.go
+		{inElement, "value '%s' is not valid base64Binary: %v", []any{"A", "illegal data"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not valid hexBinary: odd number of hex digits", []any{"A"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not valid hexBinary: %v", []any{"G", "invalid byte"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+
+		// budget.go
+		{"", "element <%s> exceeds its subtree budget: %d descendant elements (maximum: %d)", []any{"e", 2, 1}, CodeSubtreeBudget, ""},
+		{"", "element <%s> exceeds its subtree budget: %d bytes of text (maximum: %d)", []any{"e", 2, 1}, CodeSubtreeBudget, ""},
+
+		// content_model.go
+		{"", "element <%s> is not a valid choice for <%s>", []any{"c", "e"}, CodeUnexpectedElement, "cvc-complex-type.2.4.a"},
+		{"", "element <%s> is not a valid child of <%s>", []any{"c", "e"}, CodeUnexpectedElement, "cvc-complex-type.2.4.a"},
+		{"", "element <%s> allows at most %d <%s> child, but found %d", []any{"e", 1, "c", 2}, CodeTooManyElements, "cvc-complex-type.2.4.d"},
+		{"", "element <%s> is out of order in <%s>", []any{"c", "e"}, CodeUnexpectedElement, "cvc-complex-type.2.4.a"},
+		{"", "element <%s> is out of order in <%s>: it must appear before <%s>", []any{"c", "e", "d"}, CodeUnexpectedElement, "cvc-complex-type.2.4.a"},
+		{"", "element <%s> requires at least %d <%s> child, but found %d", []any{"e", 1, "c", 0}, CodeMissingElement, "cvc-complex-type.2.4.b"},
+		{"", "element <%s> requires at least %d occurrences of its sequence group, but found %d", []any{"e", 2, 1}, CodeMissingElement, "cvc-complex-type.2.4.b"},
+		{"", "element <%s> allows at most %d occurrences of its sequence group, but found %d", []any{"e", 2, 3}, CodeTooManyElements, "cvc-complex-type.2.4.d"},
+		{"", "element <%s> must contain at least one choice element", []any{"e"}, CodeMissingElement, "cvc-complex-type.2.4.b"},
+		{"", "element <%s> choice requires at least %d selections, but found %d", []any{"e", 2, 1}, CodeMissingElement, "cvc-complex-type.2.4.b"},
+		{"", "element <%s> choice allows at most %d selections, but found %d", []any{"e", 2, 3}, CodeTooManyElements, "cvc-complex-type.2.4.d"},
+
+		// datetime.go
+		{inElement, "value '%s' is not a valid date (expected format: YYYY-MM-DD)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid dateTimeStamp (expected format: YYYY-MM-DDTHH:mm:ss with timezone)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid dateTime (expected format: YYYY-MM-DDTHH:mm:ss)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid time (expected format: HH:mm:ss)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid gYear (expected format: YYYY)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid gYearMonth (expected format: YYYY-MM)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid gMonth (expected format: --MM)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid gMonthDay (expected format: --MM-DD)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid gDay (expected format: ---DD)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid %s: %v", []any{"2024-13-01", "date", "month 13 is out of range"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "year -0000 is not allowed", nil, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "year 0000 is not allowed in XSD 1.0", nil, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "%v for %s-%s", []any{"day 31 is out of range", "2024", "04"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "month %s is out of range", []any{"13"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "day %s is out of range", []any{"32"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "hour 24 is only allowed as 24:00:00", nil, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "hour %s is out of range", []any{"25"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "minute %s is out of range", []any{"60"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "second %s is out of range", []any{"61"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inDate, "timezone %s is out of range", []any{"+15:00"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+
+		// decimal.go
+		{inElement, "value '%s' is not a valid integer", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid decimal number", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "invalid limit value in schema: %s", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "invalid totalDigits value in schema: %s", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' has too many digits (totalDigits: %d, actual: %d)", []any{"123", 2, 3}, CodeInvalidValue, "cvc-totalDigits-valid"},
+		{inElement, "invalid fractionDigits value in schema: %s", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' has too many fraction digits (fractionDigits: %d, actual: %d)", []any{"1.23", 1, 2}, CodeInvalidValue, "cvc-fractionDigits-valid"},
+
+		// element_forms.go
+		{"", "root element <%s> is not defined in the schema", []any{"e"}, CodeUndeclaredRoot, "cvc-elt.1.a"},
+		{"", "element <%s> is in %s, but its declaration requires %s", []any{"c", "no namespace", "namespace 'urn:a'"}, CodeWrongNamespace, "cvc-complex-type.2.4.a"},
+
+		// id_integrity.go
+		{"", "IDREF '%s' in %s does not match any ID in the document", []any{"r", "element <e>"}, CodeUnresolvedIDREF, "cvc-id.1"},
+		{"", "duplicate ID '%s' in %s (already used by element <%s>)", []any{"i", "attribute 'a' in element <e>", "d"}, CodeDuplicateID, "cvc-id.2"},
+
+		// namespaces.go
+		{inElement, "'%s' is not a valid QName", []any{"a:"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "QName '%s' uses undeclared namespace prefix '%s'%s", []any{"p:n", "p", ""}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+
+		// options.go
+		{"", "optional element <%s> is empty; omit it instead of leaving it empty", []any{"e"}, CodeEmptyElement, ""},
+
+		// validation_helpers.go
+		{inElement, "invalid pattern in schema: %s", []any{"["}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' does not match pattern '%s'", []any{"x", "[0-9]+"}, CodeInvalidValue, "cvc-pattern-valid"},
+		{inElement, "value '%s' is not in the list of allowed values: [%s, ...] (%d more not shown)", []any{"x", "a", 1}, CodeInvalidValue, "cvc-enumeration-valid"},
+		{inElement, "value '%s' is not in the list of allowed values: [%s]", []any{"x", "a"}, CodeInvalidValue, "cvc-enumeration-valid"},
+		{inElement, "invalid minLength value in schema: %s", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inAttribute, "value '%s' is too short (minimum length: %d, actual: %d)", []any{"x", 2, 1}, CodeInvalidValue, "cvc-minLength-valid"},
+		{inElement, "invalid maxLength value in schema: %s", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inAttribute, "value '%s' is too long (maximum length: %d, actual: %d)", []any{"xyz", 2, 3}, CodeInvalidValue, "cvc-maxLength-valid"},
+		{inElement, "value '%s' %s allowed value %s", []any{"0", "below minimum", "1"}, CodeInvalidValue, "cvc-minInclusive-valid"},
+		{inElement, "value '%s' %s allowed value %s", []any{"9", "exceeds maximum", "1"}, CodeInvalidValue, "cvc-maxInclusive-valid"},
+		{inElement, "value '%s' is not a valid int", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is out of range for int", []any{"9"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid long", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid short", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is out of range for short", []any{"9"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid byte", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is out of range for byte", []any{"9"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid nonNegativeInteger", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' must be non-negative", []any{"-1"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid positiveInteger", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' must be positive", []any{"0"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid nonPositiveInteger", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' must be non-positive", []any{"1"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid negativeInteger", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' must be negative", []any{"0"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid decimal", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid double", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid float", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid boolean (expected: true, false, 1, or 0)", []any{"x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid duration (expected format: PnYnMnDTnHnMnS)", []any{"P"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid dayTimeDuration (expected format: PnDTnHnMnS)", []any{"P"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid yearMonthDuration (expected format: PnYnM)", []any{"P"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid token (no leading/trailing/consecutive whitespace allowed)", []any{" x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid Name", []any{"1"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid NCName (no colons allowed)", []any{"a:b"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid %s", []any{"1", "ID"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid IDREFS (expected at least one IDREF)", []any{""}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid IDREFS ('%s' is not a valid IDREF)", []any{"1", "1"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid ENTITY (expected an NCName)", []any{"1"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid ENTITIES (expected at least one ENTITY)", []any{""}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid ENTITIES ('%s' is not a valid ENTITY)", []any{"1", "1"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid %s (expected a name with an optional prefix, such as tns:Name)", []any{"1", "QName"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid NMTOKEN", []any{"a b"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid NMTOKENS (expected at least one NMTOKEN)", []any{""}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid NMTOKENS ('%s' is not a valid NMTOKEN)", []any{"a ,", ","}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid language (expected a tag such as en or en-US)", []any{"1"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "URI cannot be empty", nil, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is not a valid URI (contains spaces)", []any{"a b"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{inElement, "value '%s' is out of range for %s", []any{"256", "unsignedByte"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+
+		// validations.go
+		{"", "element <%s> contains unexpected text '%s' (content model is empty)", []any{"e", "x"}, CodeUnexpectedText, "cvc-complex-type.2.1"},
+		{"", "element <%s> contains unexpected text '%s' (content model is element-only)", []any{"e", "x"}, CodeUnexpectedText, "cvc-complex-type.2.3"},
+		{"", "element <%s> has a simple type and must not contain child elements, but found <%s>", []any{"e", "c"}, CodeUnexpectedElement, "cvc-type.3.1.2"},
+		{"", "in element <%s>: %v", []any{"e", "value 'x' is not a valid int"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{"", "element <%s> must be empty (content model is empty), but found child element <%s>", []any{"e", "c"}, CodeUnexpectedElement, "cvc-complex-type.2.1"},
+		{inElement, "type definition '%s' not found in schema", []any{"T"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{"", "element <%s> is not allowed in xs:all group of <%s>", []any{"c", "e"}, CodeUnexpectedElement, "cvc-complex-type.2.4.a"},
+		{"", "required element <%s> is missing from xs:all group in <%s>", []any{"c", "e"}, CodeMissingElement, "cvc-complex-type.2.4.b"},
+		{"", "element <%s> appears %d times in xs:all group, but minimum is %d", []any{"c", 1, 2}, CodeMissingElement, "cvc-complex-type.2.4.b"},
+		{"", "element <%s> appears %d times in xs:all group, but maximum is %d", []any{"c", 2, 1}, CodeTooManyElements, "cvc-complex-type.2.4.d"},
+		{"", "required attribute '%s' is missing from element <%s>", []any{"a", "e"}, CodeMissingAttribute, "cvc-complex-type.4"},
+		{"", "%s has fixed value '%s', but got '%s'", []any{"attribute 'a' in element <e>", "x", "y"}, CodeFixedValue, "cvc-attribute.4"},
+		{"", "%s: type definition '%s' not found in schema", []any{"attribute 'a' in element <e>", "T"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+
+		// wildcard.go
+		{"", "unexpected %s: %s", []any{"attribute 'a' in element <e>", "it must not be namespace-qualified"}, CodeUnexpectedAttribute, "cvc-complex-type.3.2.2"},
+		{"", "unexpected %s: %s is not allowed by the attribute wildcard", []any{"attribute 'a' in element <e>", "namespace 'urn:a'"}, CodeUnexpectedAttribute, "cvc-complex-type.3.2.2"},
+		{"", "unexpected %s", []any{"attribute 'a' in element <e>"}, CodeUnexpectedAttribute, "cvc-complex-type.3.2.2"},
+		{"", "%s matches the attribute wildcard, but no global declaration of it is found (processContents is strict)", []any{"attribute 'a' in element <e>"}, CodeUnexpectedAttribute, "cvc-complex-type.3.2.2"},
+
+		// xsi.go
+		{"", "%s must list pairs of a namespace and a schema location, but got '%s'", []any{"attribute 'schemaLocation' in element <e>", "urn:a"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{"", "unexpected %s: the XMLSchema-instance namespace has no such attribute", []any{"attribute 'a' in element <e>"}, CodeUnexpectedAttribute, "cvc-complex-type.3.2.2"},
+		{"", "%s must be a boolean, but got '%s'", []any{"attribute 'nil' in element <e>", "x"}, CodeInvalidValue, "cvc-datatype-valid.1.2.1"},
+		{"", "element <%s> has xsi:nil=\"true\", but its declaration is not nillable", []any{"e"}, CodeInvalidNil, "cvc-elt.3.1"},
+		{"", "element <%s> has xsi:nil=\"true\" and must be empty, but found child element <%s>", []any{"e", "c"}, CodeInvalidNil, "cvc-elt.3.2.1"},
+		{"", "element <%s> has xsi:nil=\"true\" and must be empty, but contains text '%s'", []any{"e", "x"}, CodeInvalidNil, "cvc-elt.3.2.1"},
+
+		// xsi_type.go
+		{"", "invalid xsi:type on element <%s>: %v", []any{"e", "'a:' is not a valid QName"}, CodeInvalidXsiType, "cvc-elt.4.2"},
+		{"", "xsi:type '%s' on element <%s> does not resolve to a type in the schema", []any{"T", "e"}, CodeInvalidXsiType, "cvc-elt.4.2"},
+		{"", "xsi:type '%s' on element <%s> is not derived from its declared type '%s'", []any{"T", "e", "D"}, CodeInvalidXsiType, "cvc-elt.4.3"},
+	}
+
+	// Formats of the checks' sources that are not messages of their own: parts of other
+	// messages, and warnings, which have no rule
+	fragments := map[string]bool{
+		"namespace '%s'":                     true,
+		"attribute '%s' in element <%s>":     true,
+		"element <%s>":                       true,
+		" (line %d, column %d)":              true,
+		"%d validation errors found:\n - %s": true,
+		"\n - ... and %d more":               true,
+		"... (%d bytes total)":               true,
+		"EmptyElementPolicy(%d)":             true,
+		"attribute '%s' in element <%s> is not checked: it matches an attribute wildcard with processContents skip":                    true,
+		"attribute '%s' in element <%s> is not checked: it matches a lax attribute wildcard, but no global declaration of it is found": true,
+	}
+
+	listed := make(map[string]bool)
+	for _, tt := range tests {
+		listed[tt.format] = true
+		message := tt.prefix + fmt.Sprintf(tt.format, tt.args...)
+		code := issueCode(message)
+		if rule := issueRule(code, message, false); code != tt.code || rule != tt.rule {
+			t.Errorf("%q: expected %s and rule %q, got %s and rule %q", message, tt.code, tt.rule, code, rule)
+		}
+	}
+
+	sources := []string{"binary.go", "budget.go", "content_model.go", "datetime.go", "decimal.go", "element_forms.go",
+		"id_integrity.go", "namespaces.go", "options.go", "validation_helpers.go", "validations.go", "wildcard.go",
+		"xsi.go", "xsi_type.go"}
+	for _, source := range sources {
+		for _, format := range messageFormats(t, source) {
+			if !listed[format] && !fragments[format] {
+				t.Errorf("%s: message format %q has no expected rule", source, format)
+			}
+		}
+	}
+}
+
+// messageFormats returns the format strings of the fmt.Sprintf and fmt.Errorf calls of a
+// source file of the package.
+func messageFormats(t *testing.T, source string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), source, nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", source, err)
+	}
+	var formats []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || selector.Sel.Name != "Sprintf" && selector.Sel.Name != "Errorf" {
+			return true
+		}
+		if pkg, ok := selector.X.(*ast.Ident); !ok || pkg.Name != "fmt" {
+			return true
+		}
+		if literal, ok := call.Args[0].(*ast.BasicLit); ok && literal.Kind == token.STRING {
+			if format, err := strconv.Unquote(literal.Value); err == nil {
+				formats = append(formats, format)
+			}
+		}
+		return true
+	})
+	return formats
+}
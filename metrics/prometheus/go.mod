@@ -0,0 +1,23 @@
+module github.com/moolekkari/validatexml-go/metrics/prometheus
+
+go 1.20
+
+require (
+	github.com/moolekkari/validatexml-go v0.2.0
+	github.com/prometheus/client_golang v1.19.1
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+// The replace directive builds against the working tree within this repository only;
+// importers resolve the version required above, the first with ServiceConfig.OnValidate.
+replace github.com/moolekkari/validatexml-go => ../..
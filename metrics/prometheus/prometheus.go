@@ -0,0 +1,127 @@
+// Package prometheus exposes the validations of an xmlparser.Service as Prometheus metrics.
+// It is a module of its own, so that the validatexml-go module stays free of dependencies.
+//
+//	metrics := prometheus.NewMetrics()
+//	registry.MustRegister(metrics)
+//	service := xmlparser.NewService(xmlparser.ServiceConfig{
+//		Bundles:    bundles,
+//		OnValidate: metrics.Observe,
+//	})
+//
+// Every metric is labeled with the message type, or with UnknownMessageType for the message
+// types the service does not configure, which clients choose:
+//
+//   - validatexml_validations_total counts validations by outcome: "valid", "invalid",
+//     "malformed" (not well-formed XML) or "error" (unknown message type or schema not
+//     loaded)
+//   - validatexml_issues_total counts the issues of invalid messages by issue code
+//   - validatexml_document_issues is a histogram of the number of issues of invalid messages
+//   - validatexml_validation_duration_seconds is a histogram of the time spent parsing and
+//     validating messages
+//   - validatexml_document_bytes is a histogram of the size of messages
+package prometheus
+
+import (
+	"errors"
+
+	xmlparser "github.com/moolekkari/validatexml-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Outcomes of validations, the values of the outcome label.
+const (
+	OutcomeValid     = "valid"
+	OutcomeInvalid   = "invalid"
+	OutcomeMalformed = "malformed"
+	OutcomeError     = "error"
+)
+
+// UnknownMessageType is the message_type label of validations of message types the service
+// does not configure, so that clients cannot create a series per name they send.
+const UnknownMessageType = "unknown"
+
+// Metrics is a prometheus.Collector of the metrics of the validations it observes.
+type Metrics struct {
+	validations *prometheus.CounterVec
+	issues      *prometheus.CounterVec
+	perDocument *prometheus.HistogramVec
+	duration    *prometheus.HistogramVec
+	size        *prometheus.HistogramVec
+}
+
+// NewMetrics returns the metrics of no validations yet. Register them with a
+// prometheus.Registerer, and set Observe as the OnValidate hook of the services to measure.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		validations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "validatexml_validations_total",
+			Help: "Messages validated, by message type and outcome.",
+		}, []string{"message_type", "outcome"}),
+		issues: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "validatexml_issues_total",
+			Help: "Issues of invalid messages, by message type and issue code.",
+		}, []string{"message_type", "code"}),
+		perDocument: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "validatexml_document_issues",
+			Help:    "Issues per invalid message, by message type.",
+			Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000},
+		}, []string{"message_type"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "validatexml_validation_duration_seconds",
+			Help:    "Time spent parsing and validating messages, by message type.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"message_type"}),
+		size: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "validatexml_document_bytes",
+			Help:    "Size of validated messages, by message type.",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+		}, []string{"message_type"}),
+	}
+}
+
+// Observe records a validation. It has the signature of xmlparser.ServiceConfig.OnValidate.
+func (m *Metrics) Observe(event xmlparser.ValidationEvent) {
+	messageType := event.MessageType
+	if !event.KnownMessageType {
+		messageType = UnknownMessageType
+	}
+
+	outcome := OutcomeValid
+	var validationErr *xmlparser.ValidationError
+	switch {
+	case event.Err == nil:
+	case !errors.As(event.Err, &validationErr):
+		outcome = OutcomeError
+	case validationErr.ParseError != nil:
+		outcome = OutcomeMalformed
+	default:
+		outcome = OutcomeInvalid
+		for _, issue := range validationErr.Issues {
+			m.issues.WithLabelValues(messageType, string(issue.Code)).Inc()
+		}
+		m.perDocument.WithLabelValues(messageType).Observe(float64(len(validationErr.Issues) + validationErr.Omitted))
+	}
+	m.validations.WithLabelValues(messageType, outcome).Inc()
+	if outcome != OutcomeError {
+		m.duration.WithLabelValues(messageType).Observe(event.Duration.Seconds())
+		m.size.WithLabelValues(messageType).Observe(float64(event.Bytes))
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.validations.Describe(ch)
+	m.issues.Describe(ch)
+	m.perDocument.Describe(ch)
+	m.duration.Describe(ch)
+	m.size.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.validations.Collect(ch)
+	m.issues.Collect(ch)
+	m.perDocument.Collect(ch)
+	m.duration.Collect(ch)
+	m.size.Collect(ch)
+}
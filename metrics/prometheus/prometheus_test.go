@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	xmlparser "github.com/moolekkari/validatexml-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	dir := t.TempDir()
+	location := filepath.Join(dir, "orders.xsd")
+	err := os.WriteFile(location, []byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger" maxOccurs="unbounded"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	metrics := NewMetrics()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics)
+	service := xmlparser.NewService(xmlparser.ServiceConfig{
+		Bundles:    map[string]xmlparser.SchemaBundle{"order": {Location: location}},
+		OnValidate: metrics.Observe,
+	})
+	if err := service.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	service.Validate("order", []byte(`<order><quantity>1</quantity></order>`))
+	service.Validate("order", []byte(`<order><quantity>0</quantity><quantity>-1</quantity><note/></order>`))
+	service.Validate("order", []byte(`<order>`))
+	service.Validate("refund", []byte(`<refund/>`))
+	service.Validate("refund-v2", []byte(`<refund/>`))
+
+	// The unknown message types share a series
+	if count := testutil.CollectAndCount(metrics.validations); count != 4 {
+		t.Errorf("Expected 4 validation series, got %d", count)
+	}
+
+	counters := []struct {
+		counter prometheus.Collector
+		value   float64
+	}{
+		{metrics.validations.WithLabelValues("order", OutcomeValid), 1},
+		{metrics.validations.WithLabelValues("order", OutcomeInvalid), 1},
+		{metrics.validations.WithLabelValues("order", OutcomeMalformed), 1},
+		{metrics.validations.WithLabelValues(UnknownMessageType, OutcomeError), 2},
+		{metrics.issues.WithLabelValues("order", string(xmlparser.CodeInvalidValue)), 2},
+		{metrics.issues.WithLabelValues("order", string(xmlparser.CodeUnexpectedElement)), 1},
+	}
+	for i, tt := range counters {
+		if value := testutil.ToFloat64(tt.counter); value != tt.value {
+			t.Errorf("Counter %d: expected %v, got %v", i, tt.value, value)
+		}
+	}
+
+	// Durations and sizes of the order messages, and the issues of the invalid one
+	if count, err := testutil.GatherAndCount(registry, "validatexml_validation_duration_seconds",
+		"validatexml_document_bytes", "validatexml_document_issues"); err != nil || count != 3 {
+		t.Errorf("Expected 3 histograms, got %d (%v)", count, err)
+	}
+	if problems, err := testutil.GatherAndLint(registry); err != nil || len(problems) > 0 {
+		t.Errorf("Unexpected lint problems: %v (%v)", problems, err)
+	}
+}
@@ -0,0 +1,170 @@
+package xmlparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes is the size of the largest request body Middleware validates, unless
+// MiddlewareOptions.MaxBodyBytes says otherwise.
+const DefaultMaxBodyBytes = 10 << 20
+
+// MiddlewareOptions configures Service.Middleware: which requests are validated, under
+// which message type, and how invalid ones are rejected.
+type MiddlewareOptions struct {
+	// Routes maps URL paths to the message type of the bodies sent to them. A path ending
+	// in "/" also matches the paths below it, as with http.ServeMux; the longest match wins.
+	Routes map[string]string
+
+	// ContentTypes maps the media type of request bodies, such as
+	// "application/vnd.acme.order+xml", or the profile parameter of their Content-Type,
+	// such as "urn:acme:order", to their message type. They take precedence over Routes,
+	// and profiles over media types.
+	ContentTypes map[string]string
+
+	// MaxBodyBytes is the size of the largest body read for validation; larger ones are
+	// rejected with 413 Request Entity Too Large. DefaultMaxBodyBytes when zero.
+	MaxBodyBytes int64
+
+	// Format of the report rejecting an invalid body. When empty, it is XML for requests
+	// whose Accept header names XML but not JSON media types, and JSON otherwise.
+	Format ReportFormat
+
+	// Status of the response rejecting an invalid body; 400 Bad Request when zero.
+	Status int
+}
+
+// ReportFormat is the format of a ValidationReport in a response.
+type ReportFormat string
+
+// Formats of validation reports.
+const (
+	ReportJSON ReportFormat = "json" // application/json
+	ReportXML  ReportFormat = "xml"  // application/xml, with a <validationReport> root
+)
+
+// ValidationReport is the outcome of validating a message: the body of the response
+// Middleware rejects an invalid request with, and the response of Service.TwirpHandler.
+type ValidationReport struct {
+	XMLName     xml.Name          `json:"-" xml:"validationReport"`
+	MessageType string            `json:"messageType" xml:"messageType,attr"`
+	Valid       bool              `json:"valid" xml:"valid,attr"`
+	Issues      []ValidationIssue `json:"issues" xml:"issue"`
+	Omitted     int               `json:"omitted,omitempty" xml:"omitted,attr,omitempty"` // See ValidationError
+	Warnings    []ValidationIssue `json:"warnings,omitempty" xml:"warning"`               // See Result.Warnings
+}
+
+// Middleware returns HTTP middleware validating the bodies of POST, PUT and PATCH requests
+// against the schema of their message type, selected by content type or route, before the
+// wrapped handler runs. Invalid bodies, malformed ones included, are rejected with a
+// ValidationReport; valid ones are passed on to the handler, which can read the body
+// again. Requests whose message type cannot be told pass through unvalidated.
+//
+// A request of a message type whose schema is not loaded is rejected with 503 Service
+// Unavailable, and one of a message type the service does not configure with 500 Internal
+// Server Error.
+func (s *Service) Middleware(opts MiddlewareOptions) func(http.Handler) http.Handler {
+	if opts.MaxBodyBytes == 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.Status == 0 {
+		opts.Status = http.StatusBadRequest
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			messageType, ok := opts.messageType(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+				} else {
+					http.Error(w, "failed to read request body", http.StatusBadRequest)
+				}
+				return
+			}
+
+			if err := s.ValidateContext(r.Context(), messageType, body); err != nil {
+				var validationErr *ValidationError
+				if errors.As(err, &validationErr) {
+					opts.reject(w, r, validationErr.Report(messageType))
+				} else if _, exists := s.config.Bundles[messageType]; exists {
+					http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				} else {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+
+			r.Body, r.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// messageType returns the message type of a request body, and false when the request is
+// not validated.
+func (opts *MiddlewareOptions) messageType(r *http.Request) (string, bool) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch {
+		return "", false
+	}
+
+	if mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+		if profile := params["profile"]; profile != "" {
+			if messageType, ok := opts.ContentTypes[profile]; ok {
+				return messageType, true
+			}
+		}
+		if messageType, ok := opts.ContentTypes[mediaType]; ok {
+			return messageType, true
+		}
+	}
+
+	var match string
+	for route := range opts.Routes {
+		if (route == r.URL.Path || strings.HasSuffix(route, "/") && strings.HasPrefix(r.URL.Path, route)) &&
+			len(route) > len(match) {
+			match = route
+		}
+	}
+	if match == "" {
+		return "", false
+	}
+	return opts.Routes[match], true
+}
+
+// reject writes the response rejecting an invalid request body.
+func (opts *MiddlewareOptions) reject(w http.ResponseWriter, r *http.Request, report ValidationReport) {
+	format := opts.Format
+	if format == "" {
+		format = ReportJSON
+		if accept := r.Header.Get("Accept"); strings.Contains(accept, "xml") && !strings.Contains(accept, "json") {
+			format = ReportXML
+		}
+	}
+
+	var body []byte
+	if format == ReportXML {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		encoded, _ := xml.MarshalIndent(report, "", "  ")
+		body = append([]byte(xml.Header), encoded...)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ = json.Marshal(report)
+	}
+	w.WriteHeader(opts.Status)
+	w.Write(append(body, '\n'))
+}
@@ -0,0 +1,130 @@
+package xmlparser
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	dir, err := os.MkdirTemp("", "middleware")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = os.WriteFile(filepath.Join(dir, "orders.xsd"), []byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+  <xs:element name="cancellation" type="xs:string"/>
+</xs:schema>`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	service := NewService(ServiceConfig{Bundles: map[string]SchemaBundle{
+		"order":        {Location: filepath.Join(dir, "orders.xsd"), RootElement: "order"},
+		"cancellation": {Location: filepath.Join(dir, "orders.xsd"), RootElement: "cancellation"},
+		"invoice":      {Location: filepath.Join(dir, "invoices.xsd")},
+	}})
+	service.Start(context.Background())
+
+	middleware := service.Middleware(MiddlewareOptions{
+		Routes: map[string]string{
+			"/orders":         "order",
+			"/orders/cancel/": "cancellation",
+			"/invoices":       "invoice",
+			"/refunds":        "refund",
+		},
+		ContentTypes: map[string]string{
+			"application/vnd.acme.cancellation+xml": "cancellation",
+			"urn:acme:order":                        "order",
+		},
+		MaxBodyBytes: 100,
+	})
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		accept      string
+		body        string
+		status      int
+		contains    string // Expected in the response body
+	}{
+		{"valid", "POST", "/orders", "application/xml", "", `<order><quantity>1</quantity></order>`, 200, "<quantity>1</quantity>"},
+		{"invalid", "POST", "/orders", "application/xml", "", `<order><quantity>0</quantity></order>`, 400, `"code":"invalid-value"`},
+		{"malformed", "PUT", "/orders", "application/xml", "", `<order>`, 400, `"code":"parse-error"`},
+		{"XML report", "POST", "/orders", "application/xml", "application/xml", `<order/>`, 400, `<validationReport messageType="order" valid="false">`},
+		{"subtree route", "POST", "/orders/cancel/17", "application/xml", "", `<order><quantity>1</quantity></order>`, 400, "not allowed for message type 'cancellation'"},
+		{"media type", "POST", "/inbox", "application/vnd.acme.cancellation+xml", "", `<cancellation>late</cancellation>`, 200, "late"},
+		{"profile", "POST", "/orders/cancel/17", `application/xml; profile="urn:acme:order"`, "", `<order><quantity>2</quantity></order>`, 200, "<quantity>2</quantity>"},
+		{"unrouted", "POST", "/inbox", "application/xml", "", `<anything/>`, 200, "<anything/>"},
+		{"not a body method", "GET", "/orders", "", "", "", 200, ""},
+		{"too large", "POST", "/orders", "application/xml", "", "<order>" + strings.Repeat(" ", 100) + "</order>", 413, "exceeds 100 bytes"},
+		{"schema not loaded", "POST", "/invoices", "application/xml", "", `<invoice/>`, 503, "not loaded"},
+		{"unknown message type", "POST", "/refunds", "application/xml", "", `<refund/>`, 500, "unknown message type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			request.Header.Set("Accept", tt.accept)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.status || !strings.Contains(recorder.Body.String(), tt.contains) {
+				t.Errorf("Expected status %d with %q, got %d: %s", tt.status, tt.contains, recorder.Code, recorder.Body)
+			}
+		})
+	}
+
+	t.Run("report", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/orders", strings.NewReader(`<order><quantity>0</quantity></order>`))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		var report ValidationReport
+		if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to decode the report: %v", err)
+		}
+		if report.MessageType != "order" || len(report.Issues) != 1 || report.Issues[0].XPath != "/order/quantity[1]" ||
+			report.Issues[0].Line != 1 || report.Issues[0].Column != 8 {
+			t.Errorf("Unexpected report: %+v", report)
+		}
+		if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected a JSON report, got %s", contentType)
+		}
+
+		request = httptest.NewRequest("POST", "/orders", strings.NewReader(`<order><quantity>0</quantity></order>`))
+		recorder = httptest.NewRecorder()
+		service.Middleware(MiddlewareOptions{Routes: map[string]string{"/orders": "order"}, Format: ReportXML,
+			Status: http.StatusUnprocessableEntity})(handler).ServeHTTP(recorder, request)
+		report = ValidationReport{}
+		if err := xml.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to decode the report: %v", err)
+		}
+		if recorder.Code != http.StatusUnprocessableEntity || len(report.Issues) != 1 || report.Issues[0].Code != CodeInvalidValue ||
+			!strings.Contains(report.Issues[0].Message, "must be positive") {
+			t.Errorf("Unexpected report (status %d): %+v", recorder.Code, report)
+		}
+	})
+}
@@ -5,23 +5,39 @@ package xmlparser
 
 import (
 	"encoding/xml"
+	"fmt"
 	"strings"
 )
 
 // Schema represents a parsed XML Schema Definition (XSD).
 // It contains all the type definitions and validation rules from the schema.
+//
+// A parsed Schema is safe for concurrent use: everything validation derives from the
+// components, such as lookup maps and compiled content models, is built when it is
+// parsed, and validating, annotating, filtering or generating documents only reads it.
+// Compile, Prune and RegisterShardKey modify the schema, so they must be called before it
+// is shared, as must any change to its exported fields.
 type Schema struct {
-	XMLName            xml.Name `xml:"http://www.w3.org/2001/XMLSchema schema"`
-	TargetNamespace    string   `xml:"targetNamespace,attr"`
-	ElementFormDefault string   `xml:"elementFormDefault,attr"`
+	XMLName              xml.Name `xml:"http://www.w3.org/2001/XMLSchema schema"`
+	TargetNamespace      string   `xml:"targetNamespace,attr"`
+	ElementFormDefault   string   `xml:"elementFormDefault,attr"`
+	AttributeFormDefault string   `xml:"attributeFormDefault,attr"`
 
 	// Namespace declarations
 	Xmlns map[string]string `xml:"-"` // Namespace prefix mappings
 
+	// Specification edition the schema is validated under (see ParseOptions)
+	Version SchemaVersion `xml:"-"`
+
+	// Options and schema documents the schema was parsed from, for replay bundles
+	options ParseOptions
+	sources []SchemaSource
+
 	// XSD definitions
 	Elements     []Element     `xml:"element"`
 	ComplexTypes []ComplexType `xml:"complexType"`
 	SimpleTypes  []SimpleType  `xml:"simpleType"`
+	Attributes   []Attribute   `xml:"attribute"` // Global attributes, referenced with ref
 	Imports      []Import      `xml:"import"`
 	Includes     []Include     `xml:"include"`
 
@@ -29,36 +45,107 @@ type Schema struct {
 	ElementMap     map[string]*Element
 	ComplexTypeMap map[string]*ComplexType
 	SimpleTypeMap  map[string]*SimpleType
+
+	// Namespace-qualified lookup maps, keyed by the defining schema's target namespace
+	elementNSMap     map[xml.Name]*Element
+	simpleTypeNSMap  map[xml.Name]*SimpleType
+	complexTypeNSMap map[xml.Name]*ComplexType
+	attributeNSMap   map[xml.Name]*Attribute
+
+	// Content models, enumeration sets and patterns compiled when the schema is parsed
+	compiled compiledSchema
+
+	// Shard key paths registered with RegisterShardKey, by root element declaration
+	shardKeys map[*Element]*shardKey
 }
 
 // Element represents an XSD element definition.
 // Elements define the structure and constraints for XML elements.
 type Element struct {
 	Name      string `xml:"name,attr"`
+	Ref       string `xml:"ref,attr"`       // Reference to a global element (e.g., "addr:address")
 	Type      string `xml:"type,attr"`      // Reference to a type (e.g., "xs:string")
 	MinOccurs string `xml:"minOccurs,attr"` // Minimum occurrences (default: 1)
-	MaxOccurs string `xml:"maxOccurs,attr"` // Maximum occurrences ("unbounded" or number)
+	MaxOccurs string `xml:"maxOccurs,attr"` // Maximum occurrences ("unbounded" or number, default: 1)
+	Nillable  bool   `xml:"nillable,attr"`  // Whether instances may be empty with xsi:nil="true"
+	Form      string `xml:"form,attr"`      // qualified or unqualified (default: elementFormDefault)
 
 	// Inline type definitions (alternative to Type reference)
 	ComplexType *ComplexType `xml:"complexType"`
 	SimpleType  *SimpleType  `xml:"simpleType"`
+
+	Annotation *Annotation `xml:"annotation"` // Human-readable documentation
+
+	Unchecked []UncheckedConstruct `xml:",any"` // Identity constraints and other unsupported children
+
+	qualified bool     // Whether instance elements must be namespace-qualified
+	namespace string   // Namespace of instance elements; empty when unqualified
+	typeRef   xml.Name // Type resolved with the defining document's namespace declarations
+	ref       xml.Name // Global element resolved from Ref
+
+	// Types the declaration was linked to by Compile, when linked
+	linked            bool
+	linkedComplexType *ComplexType
+	linkedSimpleType  *SimpleType
 }
 
 // ComplexType represents an XSD complex type definition.
 // Complex types define elements that can contain other elements or attributes.
 type ComplexType struct {
 	Name       string      `xml:"name,attr"`
-	Sequence   *Sequence   `xml:"sequence"`  // Ordered sequence of child elements
-	Choice     *Choice     `xml:"choice"`    // Choice between alternative elements
-	All        *All        `xml:"all"`       // Unordered group of elements
-	Attributes []Attribute `xml:"attribute"` // Element attributes
+	Mixed      bool        `xml:"mixed,attr"` // Whether text may be interleaved with child elements
+	Sequence   *Sequence   `xml:"sequence"`   // Ordered sequence of child elements
+	Choice     *Choice     `xml:"choice"`     // Choice between alternative elements
+	All        *All        `xml:"all"`        // Unordered group of elements
+	Attributes []Attribute `xml:"attribute"`  // Element attributes
+	Assertions []Assertion `xml:"assert"`     // XSD 1.1 assertions (accepted, not evaluated)
+
+	AnyAttribute   *AnyAttribute   `xml:"anyAttribute"`   // Attribute wildcard
+	ComplexContent *ComplexContent `xml:"complexContent"` // Derivation from a base complex type
+
+	namespace string   // Target namespace of the schema document defining this type
+	base      xml.Name // Base type of a complexContent derivation, resolved during parsing
+	inherited bool     // Whether the derivation's content and attributes have been applied
+}
+
+// ComplexContent derives a complex type from a base complex type. When parsing completes,
+// the derived type's Sequence, Choice, All and Attributes hold its effective content model
+// and attribute uses, including those inherited from the base type.
+type ComplexContent struct {
+	Extension   *ComplexDerivation `xml:"extension"`
+	Restriction *ComplexDerivation `xml:"restriction"`
+}
+
+// ComplexDerivation is the xs:extension or xs:restriction of complex content: the base type
+// and the particles and attributes the derived type appends (extension) or keeps (restriction).
+type ComplexDerivation struct {
+	Base         string        `xml:"base,attr"`
+	Sequence     *Sequence     `xml:"sequence"`
+	Choice       *Choice       `xml:"choice"`
+	All          *All          `xml:"all"`
+	Attributes   []Attribute   `xml:"attribute"`
+	AnyAttribute *AnyAttribute `xml:"anyAttribute"`
+}
+
+// AnyAttribute represents an xs:anyAttribute wildcard, which allows attributes that are
+// not declared by the complex type.
+type AnyAttribute struct {
+	Namespace       string `xml:"namespace,attr"`       // ##any (default), ##other, or a list of URIs, ##targetNamespace and ##local
+	ProcessContents string `xml:"processContents,attr"` // strict (default), lax or skip
+
+	namespace string // Target namespace of the schema document defining the wildcard
 }
 
 // Sequence represents an ordered sequence of elements in a complex type.
+// Sequences may nest further sequence and choice groups, each with its own occurrence bounds.
 type Sequence struct {
-	Elements  []Element `xml:"element"`
-	MinOccurs string    `xml:"minOccurs,attr"`
-	MaxOccurs string    `xml:"maxOccurs,attr"`
+	Elements  []Element  `xml:"element"`
+	Sequences []Sequence `xml:"sequence"`
+	Choices   []Choice   `xml:"choice"`
+	MinOccurs string     `xml:"minOccurs,attr"`
+	MaxOccurs string     `xml:"maxOccurs,attr"`
+
+	order []particleRef // Document order of the particles above (set during parsing)
 }
 
 // Choice represents a choice between alternative elements.
@@ -70,7 +157,7 @@ type Choice struct {
 	MaxOccurs string     `xml:"maxOccurs,attr"`
 }
 
-// All represents an unordered group of elements (each appears 0 or 1 times).
+// All represents an unordered group of elements (each appears 0 or 1 times; more with maxOccurs in XSD 1.1).
 type All struct {
 	Elements  []Element `xml:"element"`
 	MinOccurs string    `xml:"minOccurs,attr"`
@@ -81,6 +168,10 @@ type All struct {
 type SimpleType struct {
 	Name        string       `xml:"name,attr"`
 	Restriction *Restriction `xml:"restriction"` // Value restrictions/constraints
+
+	Unchecked []UncheckedConstruct `xml:",any"` // xs:list, xs:union and other unsupported children
+
+	namespace string // Target namespace of the schema document defining this type
 	// TODO: Add support for List and Union types
 }
 
@@ -97,8 +188,33 @@ type Restriction struct {
 	MinInclusive *Facet `xml:"minInclusive"`
 	MaxInclusive *Facet `xml:"maxInclusive"`
 
+	// Decimal digit constraints
+	TotalDigits    *Facet `xml:"totalDigits"`
+	FractionDigits *Facet `xml:"fractionDigits"`
+
 	// Enumeration constraints
 	Enumeration []*Facet `xml:"enumeration"`
+
+	// XSD 1.1 assertion facets (accepted, not evaluated)
+	Assertions []*Assertion `xml:"assertion"`
+
+	// Facets not enforced, such as xs:length and xs:minExclusive, and other unsupported children
+	Unchecked []UncheckedConstruct `xml:",any"`
+
+	baseRef xml.Name // Base resolved with the defining document's namespace declarations
+
+	// Base type and built-in ancestor the restriction was linked to by Compile; builtIn is
+	// empty until it is linked
+	linkedBase *SimpleType
+	builtIn    string
+}
+
+// UncheckedConstruct is a child of a schema component that validation does not check, such
+// as an xs:length facet or an xs:key identity constraint. Instances of the component are
+// reported with a CodeNotChecked warning (see ValidateOptions.OnWarning) rather than
+// passing silently. xs:annotation children are collected too, and not reported.
+type UncheckedConstruct struct {
+	XMLName xml.Name
 }
 
 // Facet represents a single validation constraint with its value.
@@ -106,28 +222,127 @@ type Facet struct {
 	Value string `xml:"value,attr"`
 }
 
+// Assertion represents an XSD 1.1 xs:assert or xs:assertion with its XPath test.
+type Assertion struct {
+	Test string `xml:"test,attr"`
+}
+
 // Attribute represents an XSD attribute definition.
 type Attribute struct {
 	Name       string      `xml:"name,attr"`
+	Ref        string      `xml:"ref,attr"` // Reference to a global attribute (e.g., "xml:lang")
 	Type       string      `xml:"type,attr"`
-	Use        string      `xml:"use,attr"` // required, optional, prohibited
+	Use        string      `xml:"use,attr"`  // required, optional, prohibited
+	Form       string      `xml:"form,attr"` // qualified or unqualified (default: attributeFormDefault)
 	Default    string      `xml:"default,attr"`
 	Fixed      string      `xml:"fixed,attr"`
 	SimpleType *SimpleType `xml:"simpleType"` // Inline simple type definition
+	Annotation *Annotation `xml:"annotation"` // Human-readable documentation
+
+	qualified bool     // Whether instance attributes must be namespace-qualified
+	namespace string   // Namespace of instance attributes; empty when unqualified
+	typeRef   xml.Name // Type resolved with the defining document's namespace declarations
+
+	// Simple type the declaration was linked to by Compile, when linked
+	linked           bool
+	linkedSimpleType *SimpleType
+}
+
+// Annotation represents an xs:annotation with its xs:documentation and xs:appinfo children.
+type Annotation struct {
+	Documentation []Documentation `xml:"documentation"`
+	AppInfo       []AppInfo       `xml:"appinfo"`
+}
+
+// Documentation represents an xs:documentation entry with its optional xml:lang.
+type Documentation struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Text string `xml:",chardata"`
+}
+
+// AppInfo represents an xs:appinfo entry: application data identified by its source URI,
+// such as SensitiveAppInfo.
+type AppInfo struct {
+	Source  string `xml:"source,attr"`
+	Content string `xml:",innerxml"` // Raw XML content
 }
 
 // Document represents a parsed XML document as a tree structure.
 type Document struct {
 	Root *Node // Root element of the document
+
+	// The comments and processing instructions around the root element and the root
+	// itself, in document order, when parsed with DocumentOptions.PreserveNodes; nil
+	// otherwise. The XML declaration is not included.
+	Nodes []*Node
 }
 
-// Node represents a single XML element in the document tree.
+// Node represents a single XML element in the document tree. Documents parsed with
+// DocumentOptions.PreserveNodes also have nodes of the other kinds, listed in the Nodes
+// of their parent element.
 type Node struct {
 	Parent   *Node      // Parent node (nil for root)
-	Name     xml.Name   // Element name with namespace
+	Name     xml.Name   // Element name with namespace; the target of a processing instruction
 	Attrs    []xml.Attr // Element attributes
 	Children []*Node    // Child elements
-	Content  string     // Text content (for leaf nodes)
+	Content  string     // Text content (for leaf nodes); the data of the other kinds of node
+	Position Position   // Source position of the start tag; zero for documents not parsed from source
+
+	// Source position of each attribute in Attrs; nil for documents not parsed from source
+	AttrPositions []Position
+
+	// Kind of node; validation only sees elements, whose Content joins the text and CDATA
+	// sections within them
+	Kind NodeKind
+
+	// The content of an element in document order, the child elements of Children among
+	// its text, CDATA sections, comments and processing instructions, when parsed with
+	// DocumentOptions.PreserveNodes; nil otherwise
+	Nodes []*Node
+
+	scope   *namespaceScope // Namespace bindings in scope, recorded when parsed (see Namespaces)
+	sibling int             // Position among same-named siblings, set by streaming validation, which drops them
+}
+
+// NodeKind is the kind of a node of the document tree.
+type NodeKind int
+
+const (
+	// ElementNode is an element, the only kind of node of documents parsed by default.
+	ElementNode NodeKind = iota
+	// TextNode is character data outside CDATA sections, with its references resolved.
+	TextNode
+	// CDATANode is the text of a CDATA section.
+	CDATANode
+	// CommentNode is a comment.
+	CommentNode
+	// ProcInstNode is a processing instruction.
+	ProcInstNode
+)
+
+// String returns the name of the kind.
+func (k NodeKind) String() string {
+	switch k {
+	case ElementNode:
+		return "ElementNode"
+	case TextNode:
+		return "TextNode"
+	case CDATANode:
+		return "CDATANode"
+	case CommentNode:
+		return "CommentNode"
+	case ProcInstNode:
+		return "ProcInstNode"
+	default:
+		return fmt.Sprintf("NodeKind(%d)", int(k))
+	}
+}
+
+// Position is a location in the source document. Lines and columns start at 1;
+// columns count characters, not bytes.
+type Position struct {
+	Line   int
+	Column int
 }
 
 // QName represents a qualified name with namespace prefix and local name.
@@ -171,14 +386,20 @@ func (s *Schema) IsQualified(elementName string) bool {
 
 // GetElementKey returns the appropriate key for element lookup based on namespace rules.
 func (s *Schema) GetElementKey(name xml.Name) string {
-	// For qualified elements, use the full name with namespace
-	if s.IsQualified(name.Local) && name.Space != "" {
-		if name.Space == s.TargetNamespace {
-			return name.Local // Use local name for target namespace elements
+	if name.Space != "" && name.Space != s.TargetNamespace {
+		// Elements from imported namespaces are merged under a prefix bound to their namespace,
+		// whatever prefix (or default namespace) the instance document uses for them
+		for prefix, namespace := range s.Xmlns {
+			key := prefix + ":" + name.Local
+			if prefix != "" && namespace == name.Space && s.ElementMap[key] != nil {
+				return key
+			}
+		}
+		if s.IsQualified(name.Local) {
+			return name.Space + ":" + name.Local // Use full qualified name for other namespaces
 		}
-		return name.Space + ":" + name.Local // Use full qualified name for other namespaces
 	}
-	return name.Local // Use local name for unqualified elements
+	return name.Local // Use local name for target namespace and unqualified elements
 }
 
 // Import represents an xs:import element for including external schemas from different namespaces.
@@ -0,0 +1,194 @@
+package xmlparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test a schema set spanning three target namespaces, whose documents bind other prefixes
+// than the instances and define components with the same local names
+func TestMultiNamespaceSchemaSet(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_multi_namespace_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	addressSchemaContent := `<xsd:schema xmlns:xsd="http://www.w3.org/2001/XMLSchema"
+	xmlns:a="urn:example:address"
+	targetNamespace="urn:example:address"
+	elementFormDefault="qualified">
+
+	<xsd:simpleType name="Zip">
+		<xsd:restriction base="xsd:string">
+			<xsd:maxLength value="5"/>
+		</xsd:restriction>
+	</xsd:simpleType>
+
+	<xsd:complexType name="AddressType">
+		<xsd:sequence>
+			<xsd:element name="street" type="xsd:string"/>
+			<xsd:element name="zip" type="a:Zip"/>
+		</xsd:sequence>
+	</xsd:complexType>
+
+	<xsd:element name="address" type="a:AddressType"/>
+</xsd:schema>`
+
+	partySchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns="urn:example:party"
+	targetNamespace="urn:example:party"
+	elementFormDefault="qualified">
+
+	<xs:simpleType name="Zip">
+		<xs:restriction base="xs:string">
+			<xs:pattern value="[A-Z]{2}-[0-9]{3}"/>
+		</xs:restriction>
+	</xs:simpleType>
+
+	<xs:complexType name="AddressType">
+		<xs:sequence>
+			<xs:element name="email" type="xs:string"/>
+			<xs:element name="zip" type="Zip" minOccurs="0"/>
+		</xs:sequence>
+	</xs:complexType>
+</xs:schema>`
+
+	files := map[string]string{"address.xsd": addressSchemaContent, "party.xsd": partySchemaContent}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write schema file %s: %v", name, err)
+		}
+	}
+
+	mainSchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns="urn:example:order"
+	xmlns:addr="urn:example:address"
+	xmlns:party="urn:example:party"
+	targetNamespace="urn:example:order"
+	elementFormDefault="qualified">
+
+	<xs:import namespace="urn:example:address" schemaLocation="address.xsd"/>
+	<xs:import namespace="urn:example:party" schemaLocation="party.xsd"/>
+
+	<xs:element name="order">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element ref="addr:address"/>
+				<xs:element name="contact" type="party:AddressType" maxOccurs="2"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`
+
+	schema, err := ParseXSD([]byte(mainSchemaContent), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to parse schema set: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		strict      bool
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name: "Valid document with other prefixes",
+			xml: `<order xmlns="urn:example:order" xmlns:x="urn:example:address" xmlns:y="urn:example:party">
+				<x:address><x:street>Main St</x:street><x:zip>12345</x:zip></x:address>
+				<contact><y:email>a@example.com</y:email><y:zip>NL-123</y:zip></contact>
+			</order>`,
+			shouldPass: true,
+		},
+		{
+			name: "Valid document with the schema's own prefixes",
+			xml: `<o:order xmlns:o="urn:example:order" xmlns:addr="urn:example:address" xmlns:party="urn:example:party">
+				<addr:address><addr:street>Main St</addr:street><addr:zip>12345</addr:zip></addr:address>
+				<o:contact><party:email>a@example.com</party:email></o:contact>
+			</o:order>`,
+			strict:     true,
+			shouldPass: true,
+		},
+		{
+			name:       "Global element of an imported namespace as root",
+			xml:        `<x:address xmlns:x="urn:example:address"><x:street>Main St</x:street><x:zip>12345</x:zip></x:address>`,
+			strict:     true,
+			shouldPass: true,
+		},
+		{
+			name: "Facet of the address namespace's Zip",
+			xml: `<order xmlns="urn:example:order" xmlns:x="urn:example:address" xmlns:y="urn:example:party">
+				<x:address><x:street>Main St</x:street><x:zip>123456</x:zip></x:address>
+				<contact><y:email>a@example.com</y:email></contact>
+			</order>`,
+			shouldPass:  false,
+			errorString: "value '123456' is too long (maximum length: 5, actual: 6)",
+		},
+		{
+			name: "Facet of the party namespace's Zip",
+			xml: `<order xmlns="urn:example:order" xmlns:x="urn:example:address" xmlns:y="urn:example:party">
+				<x:address><x:street>Main St</x:street><x:zip>12345</x:zip></x:address>
+				<contact><y:email>a@example.com</y:email><y:zip>12345</y:zip></contact>
+			</order>`,
+			shouldPass:  false,
+			errorString: "does not match pattern",
+		},
+		{
+			name: "Content of the party namespace's AddressType",
+			xml: `<order xmlns="urn:example:order" xmlns:x="urn:example:address" xmlns:y="urn:example:party">
+				<x:address><x:street>Main St</x:street><x:zip>12345</x:zip></x:address>
+				<contact><y:street>Main St</y:street></contact>
+			</order>`,
+			shouldPass:  false,
+			errorString: "element <street> is not a valid child of <contact>",
+		},
+		{
+			name: "Referenced element in the wrong namespace",
+			xml: `<order xmlns="urn:example:order" xmlns:y="urn:example:party">
+				<y:address><y:street>Main St</y:street><y:zip>12345</y:zip></y:address>
+				<contact><y:email>a@example.com</y:email></contact>
+			</order>`,
+			strict:      true,
+			shouldPass:  false,
+			errorString: "element <address> is in namespace 'urn:example:party', but its declaration requires namespace 'urn:example:address'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.ValidateWithOptions(doc, ValidateOptions{StrictNamespaces: tt.strict})
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test that element references must name a global element of the schema set
+func TestUnresolvedElementReference(t *testing.T) {
+	schemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="order">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element ref="address"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`
+
+	_, err := ParseXSD([]byte(schemaContent))
+	if err == nil || err.Error() != "element reference 'address' does not match a global element declaration" {
+		t.Errorf("Expected unresolved reference error, got: %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package xmlparser
 
 import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -151,4 +154,313 @@ func TestUnqualifiedElements(t *testing.T) {
 	} else {
 		t.Log("✓ Unqualified element validation passed")
 	}
-}
\ No newline at end of file
+}
+
+// Test namespace bindings that are rebound within a subtree
+func TestNamespaceRebindingInSubtree(t *testing.T) {
+	doc, err := Parse([]byte(`<a xmlns="urn:one" xmlns:p="urn:p1">
+		<b xmlns="urn:two"><c xmlns:p="urn:p2"/></b>
+		<d xmlns=""/>
+	</a>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	b := doc.Root.Children[0]
+	c := b.Children[0]
+	d := doc.Root.Children[1]
+
+	lookups := []struct {
+		node      *Node
+		prefix    string
+		namespace string
+		found     bool
+	}{
+		{doc.Root, "", "urn:one", true},
+		{b, "", "urn:two", true},
+		{c, "", "urn:two", true},
+		{c, "p", "urn:p2", true},
+		{b, "p", "urn:p1", true},
+		{d, "", "", true},
+		{d, "q", "", false},
+		{d, "xml", "http://www.w3.org/XML/1998/namespace", true},
+	}
+	for _, tt := range lookups {
+		namespace, found := tt.node.LookupNamespace(tt.prefix)
+		if namespace != tt.namespace || found != tt.found {
+			t.Errorf("LookupNamespace(%q) on <%s> = %q, %v; expected %q, %v",
+				tt.prefix, tt.node.Name.Local, namespace, found, tt.namespace, tt.found)
+		}
+	}
+
+	if name, err := c.ResolveQName("p:Type"); err != nil || name.Space != "urn:p2" || name.Local != "Type" {
+		t.Errorf("Expected p:Type to resolve to urn:p2 at <c>, got %v (%v)", name, err)
+	}
+	if name, err := c.ResolveQName("Type"); err != nil || name.Space != "urn:two" {
+		t.Errorf("Expected unprefixed QName to take the default namespace, got %v (%v)", name, err)
+	}
+	if _, err := d.ResolveQName("q:Type"); err == nil || !strings.Contains(err.Error(), "undeclared namespace prefix 'q' (declared prefixes: p)") {
+		t.Errorf("Expected undeclared prefix error, got: %v", err)
+	}
+
+	// The bindings in scope are recorded while parsing, and shared by elements that
+	// declare none
+	namespaces := c.Namespaces()
+	if len(namespaces) != 3 || namespaces[""] != "urn:two" || namespaces["p"] != "urn:p2" || namespaces["xml"] == "" {
+		t.Errorf("Unexpected namespaces in scope at <c>: %v", namespaces)
+	}
+	if namespaces := d.Namespaces(); len(namespaces) != 2 || namespaces["p"] != "urn:p1" {
+		t.Errorf("Expected the undeclared default namespace to be left out at <d>, got %v", namespaces)
+	}
+	e, err := Parse([]byte(`<a xmlns:p="urn:p1"><b><c/></b></a>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	if leaf := e.Root.Children[0].Children[0]; leaf.scope == nil || leaf.scope != e.Root.scope {
+		t.Errorf("Expected elements without declarations to share the scope of their parent")
+	}
+
+	// Nodes built in code are resolved from their xmlns attributes
+	built := &Node{Name: xml.Name{Local: "c"}, Parent: &Node{
+		Name:  xml.Name{Local: "b"},
+		Attrs: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: "urn:two"}, {Name: xml.Name{Space: "xmlns", Local: "p"}, Value: "urn:p2"}},
+	}}
+	if namespace, _ := built.LookupNamespace("p"); namespace != "urn:p2" || len(built.Namespaces()) != 3 {
+		t.Errorf("Expected built nodes to resolve their ancestors' declarations, got %q and %v", namespace, built.Namespaces())
+	}
+}
+
+// Test that xs:QName and xs:NOTATION values resolve their prefix with the namespace
+// bindings in scope at their element
+func TestQNameValues(t *testing.T) {
+	xsdBytes := []byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="Reference">
+        <xs:restriction base="xs:QName">
+            <xs:maxLength value="20"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:element name="refs">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="ref" type="Reference" maxOccurs="unbounded"/>
+            </xs:sequence>
+            <xs:attribute name="format" type="xs:NOTATION"/>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+	schema, err := ParseXSD(xsdBytes, "")
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xmlData     string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Prefixes declared on the element and its ancestors",
+			xmlData:    `<refs xmlns:a="urn:a" format="a:png"><ref>a:One</ref><ref xmlns:b="urn:b">b:Two</ref><ref>Three</ref></refs>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Prefix declared on a sibling only",
+			xmlData:     `<refs xmlns:a="urn:a"><ref xmlns:b="urn:b">b:One</ref><ref>b:Two</ref></refs>`,
+			errorString: "in element <ref>: QName 'b:Two' uses undeclared namespace prefix 'b' (declared prefixes: a)",
+		},
+		{
+			name:        "Undeclared prefix in a NOTATION attribute",
+			xmlData:     `<refs format="img:png"><ref>One</ref></refs>`,
+			errorString: "attribute 'format' in element <refs> (line 1, column 7): QName 'img:png' uses undeclared namespace prefix 'img'",
+		},
+		{
+			name:        "Value that is not a QName",
+			xmlData:     `<refs><ref>a:b:c</ref></refs>`,
+			errorString: "value 'a:b:c' is not a valid QName",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xmlData))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test xsi:type resolution using the nearest in-scope namespace bindings
+func TestXsiTypeResolution(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+           xmlns:tns="urn:shapes"
+           targetNamespace="urn:shapes"
+           elementFormDefault="qualified">
+    <xs:complexType name="PointType">
+        <xs:sequence>
+            <xs:element name="x" type="xs:integer"/>
+            <xs:element name="y" type="xs:integer"/>
+        </xs:sequence>
+    </xs:complexType>
+    <xs:simpleType name="Percent">
+        <xs:restriction base="xs:integer">
+            <xs:minInclusive value="0"/>
+            <xs:maxInclusive value="100"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:element name="shapes">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="value" maxOccurs="unbounded"/>
+                <xs:element name="amount" type="xs:decimal" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	wrap := func(body string) string {
+		return `<shapes xmlns="urn:shapes" xmlns:s="urn:shapes" xmlns:xs="http://www.w3.org/2001/XMLSchema"
+			xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` + body + `</shapes>`
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Unprefixed type in default namespace",
+			xml:        wrap(`<value xsi:type="PointType"><x>1</x><y>2</y></value>`),
+			shouldPass: true,
+		},
+		{
+			name:       "Prefixed simple type",
+			xml:        wrap(`<value xsi:type="s:Percent">50</value>`),
+			shouldPass: true,
+		},
+		{
+			name:        "Substituted type is validated",
+			xml:         wrap(`<value xsi:type="s:Percent">150</value>`),
+			shouldPass:  false,
+			errorString: "exceeds maximum",
+		},
+		{
+			name:        "Substituted complex type is validated",
+			xml:         wrap(`<value xsi:type="PointType"><x>1</x></value>`),
+			shouldPass:  false,
+			errorString: "requires at least 1 <y> child",
+		},
+		{
+			name:       "Built-in type",
+			xml:        wrap(`<value xsi:type="xs:date">2024-01-31</value>`),
+			shouldPass: true,
+		},
+		{
+			name:        "Default namespace rebound in subtree",
+			xml:         wrap(`<value xmlns="urn:shapes" xsi:type="PointType"><x>1</x><y>2</y></value><value xmlns="urn:other" xsi:type="PointType"/>`),
+			shouldPass:  false,
+			errorString: "xsi:type 'PointType' on element <value> does not resolve to a type in the schema",
+		},
+		{
+			name:        "Prefix rebound in subtree",
+			xml:         wrap(`<value xmlns:s="urn:other" xsi:type="s:Percent">50</value>`),
+			shouldPass:  false,
+			errorString: "xsi:type 's:Percent' on element <value> does not resolve",
+		},
+		{
+			name:        "Undeclared prefix",
+			xml:         wrap(`<value xsi:type="q:Percent">50</value>`),
+			shouldPass:  false,
+			errorString: "undeclared namespace prefix 'q'",
+		},
+		{
+			name:       "Type derived from declared type",
+			xml:        wrap(`<value/><amount xsi:type="xs:int">42</amount>`),
+			shouldPass: true,
+		},
+		{
+			name:        "Type not derived from declared type",
+			xml:         wrap(`<value/><amount xsi:type="xs:string">42</amount>`),
+			shouldPass:  false,
+			errorString: "is not derived from its declared type 'xs:decimal'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test a root element from an imported namespace bound as the default namespace
+func TestImportedRootWithDefaultNamespace(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	commonSchema := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	targetNamespace="urn:common" elementFormDefault="qualified">
+	<xs:element name="note" type="xs:string"/>
+</xs:schema>`
+	if err := os.WriteFile(filepath.Join(tmpDir, "common.xsd"), []byte(commonSchema), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	schema, err := ParseXSD([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns:common="urn:common" targetNamespace="urn:main" elementFormDefault="qualified">
+	<xs:import namespace="urn:common" schemaLocation="common.xsd"/>
+	<xs:element name="note">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="text" type="xs:string"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`), tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	// The imported note has simple content; the local note would require a <text> child
+	for _, xml := range []string{`<note xmlns="urn:common">hello</note>`, `<c:note xmlns:c="urn:common">hello</c:note>`} {
+		doc, err := Parse([]byte(xml))
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+		if err := schema.Validate(doc); err != nil {
+			t.Errorf("Expected %s to validate against the imported element, got: %v", xml, err)
+		}
+	}
+}
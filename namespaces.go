@@ -0,0 +1,173 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Well-known namespaces.
+const (
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+	xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+	xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// namespaceScope holds the namespace declarations of one element, chained to the scope of
+// its parent. Elements without declarations share the scope of their parent, so a parsed
+// document has one scope per element that declares namespaces.
+type namespaceScope struct {
+	parent   *namespaceScope
+	bindings map[string]string // Namespace by prefix, "" for the default namespace
+}
+
+// emptyNamespaceScope is the scope above the root element of parsed documents.
+var emptyNamespaceScope = &namespaceScope{}
+
+// newNamespaceScope returns the scope of an element with the given attributes, whose
+// parent element has the scope parent.
+func newNamespaceScope(parent *namespaceScope, attrs []xml.Attr) *namespaceScope {
+	bindings := namespaceBindings(attrs)
+	if bindings == nil {
+		return parent
+	}
+	return &namespaceScope{parent: parent, bindings: bindings}
+}
+
+// namespaceBindings returns the namespaces declared by the xmlns attributes of an element,
+// by prefix, or nil if it declares none.
+func namespaceBindings(attrs []xml.Attr) map[string]string {
+	var bindings map[string]string
+	for _, attr := range attrs {
+		prefix, ok := "", false
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			ok = true
+		case attr.Name.Space == "xmlns":
+			prefix, ok = attr.Name.Local, true
+		}
+		if ok {
+			if bindings == nil {
+				bindings = make(map[string]string)
+			}
+			bindings[prefix] = attr.Value
+		}
+	}
+	return bindings
+}
+
+// Namespaces returns the namespace bindings in scope at this node, by prefix: those it
+// declares and those it inherits from its ancestors, the nearest declaration of a prefix
+// winning. The default namespace has the empty prefix and is left out when it is not
+// declared, or undeclared with xmlns=""; the "xml" prefix is always bound. The bindings of
+// parsed documents are recorded as they are parsed; those of nodes built in code are found
+// from the xmlns attributes of the node and its ancestors.
+func (n *Node) Namespaces() map[string]string {
+	namespaces := map[string]string{"xml": xmlNamespace}
+	if n.scope != nil {
+		for scope := n.scope; scope != nil; scope = scope.parent {
+			for prefix, namespace := range scope.bindings {
+				if _, ok := namespaces[prefix]; !ok {
+					namespaces[prefix] = namespace
+				}
+			}
+		}
+	} else {
+		for node := n; node != nil; node = node.Parent {
+			for prefix, namespace := range namespaceBindings(node.Attrs) {
+				if _, ok := namespaces[prefix]; !ok {
+					namespaces[prefix] = namespace
+				}
+			}
+		}
+	}
+	if namespaces[""] == "" {
+		delete(namespaces, "")
+	}
+	return namespaces
+}
+
+// LookupNamespace returns the namespace bound to prefix at this node, using the nearest
+// declaration on the node or its ancestors. The empty prefix looks up the default namespace;
+// an xmlns="" undeclaration yields an empty namespace. The "xml" prefix is always bound.
+func (n *Node) LookupNamespace(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return xmlNamespace, true
+	}
+
+	if n.scope != nil {
+		for scope := n.scope; scope != nil; scope = scope.parent {
+			if namespace, ok := scope.bindings[prefix]; ok {
+				return namespace, true
+			}
+		}
+		return "", prefix == ""
+	}
+	for node := n; node != nil; node = node.Parent {
+		for _, attr := range node.Attrs {
+			if prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+				return attr.Value, true
+			}
+			if prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+				return attr.Value, true
+			}
+		}
+	}
+
+	// The default namespace is empty unless declared
+	return "", prefix == ""
+}
+
+// ResolveQName resolves a QName-valued text or attribute value, such as an xsi:type value,
+// against the namespace bindings in scope at this node. Unprefixed names take the default
+// namespace, as the XSD QName type requires.
+func (n *Node) ResolveQName(value string) (xml.Name, error) {
+	value = strings.TrimSpace(value)
+	qname := ParseQName(value)
+	if qname.LocalName == "" || (strings.Contains(value, ":") && qname.Prefix == "") {
+		return xml.Name{}, fmt.Errorf("'%s' is not a valid QName", excerpt(value))
+	}
+
+	namespace, ok := n.LookupNamespace(qname.Prefix)
+	if !ok {
+		return xml.Name{}, fmt.Errorf("QName '%s' uses undeclared namespace prefix '%s'%s", excerpt(value), qname.Prefix,
+			declaredPrefixes(n))
+	}
+	return xml.Name{Space: namespace, Local: qname.LocalName}, nil
+}
+
+// checkQNamePrefix checks that an xs:QName or xs:NOTATION value, or a value of a type
+// derived from them, uses a namespace prefix in scope at node. The built-in type check
+// reports values that are not QNames.
+func (v *validator) checkQNamePrefix(value, typeName string, simpleType *SimpleType, node *Node) []string {
+	switch v.builtInBase(typeName, simpleType) {
+	case "xs:QName", "xs:NOTATION":
+	default:
+		return nil
+	}
+	value = strings.TrimSpace(value)
+	if !qNameRegexp.MatchString(value) {
+		return nil
+	}
+	if _, err := node.ResolveQName(value); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// declaredPrefixes describes the namespace prefixes declared in scope at a node, for
+// errors about undeclared prefixes; it is empty when there are none.
+func declaredPrefixes(n *Node) string {
+	var prefixes []string
+	for prefix := range n.Namespaces() {
+		if prefix != "" && prefix != "xml" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return ""
+	}
+	sort.Strings(prefixes)
+	return " (declared prefixes: " + strings.Join(prefixes, ", ") + ")"
+}
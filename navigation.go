@@ -0,0 +1,164 @@
+package xmlparser
+
+import "strings"
+
+// Attr returns the value of the attribute of the element with the given local name, and
+// whether it is present. A prefix in name is ignored, as in paths; namespace declarations
+// are not attributes.
+func (n *Node) Attr(name string) (string, bool) {
+	local := ParseQName(name).LocalName
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == local && attr.Name.Space != "xmlns" && !(attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// ChildrenNamed returns the child elements with the given local name, in document order.
+// A prefix in name is ignored, and "*" matches every child.
+func (n *Node) ChildrenNamed(name string) []*Node {
+	local := ParseQName(name).LocalName
+	var children []*Node
+	for _, child := range n.Children {
+		if local == "*" || child.Name.Local == local {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// FirstChild returns the first child element with the given local name, or nil. A prefix
+// in name is ignored, and "*" matches any child.
+func (n *Node) FirstChild(name string) *Node {
+	local := ParseQName(name).LocalName
+	for _, child := range n.Children {
+		if local == "*" || child.Name.Local == local {
+			return child
+		}
+	}
+	return nil
+}
+
+// FindAll returns the elements a location path selects from the element. The path is a
+// list of child steps separated by "/", such as "line/sku"; "*" matches any name, "//"
+// selects descendants at any depth instead of children ("//sku", "lines//sku"), and a path
+// starting with "/" starts at the root element of the document ("/invoice/lines/line").
+// Prefixes of steps are ignored, as in ValidateOptions.SensitivePaths. A final attribute
+// step such as "@id" keeps the elements that have the attribute; see Value for its value.
+func (n *Node) FindAll(path string) []*Node {
+	path = strings.TrimSpace(path)
+	start := n
+	if strings.HasPrefix(path, "/") {
+		// Absolute paths start above the root element, which their first step selects
+		root := n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		start = &Node{Children: []*Node{root}}
+	}
+	return findPath(start, path)
+}
+
+// Find returns the first element FindAll selects, or nil.
+func (n *Node) Find(path string) *Node {
+	if found := n.FindAll(path); len(found) > 0 {
+		return found[0]
+	}
+	return nil
+}
+
+// Value returns the text content of the first element a path selects, or the value of its
+// attribute if the path ends with an attribute step, and whether one was found.
+func (n *Node) Value(path string) (string, bool) {
+	node := n.Find(path)
+	if node == nil {
+		return "", false
+	}
+	if attribute, ok := attributeStep(path); ok {
+		return node.Attr(attribute)
+	}
+	return node.Content, true
+}
+
+// FindAll returns the elements a location path selects, relative to the root element
+// unless it starts with "/" (see Node.FindAll).
+func (d *Document) FindAll(path string) []*Node {
+	if d.Root == nil {
+		return nil
+	}
+	return d.Root.FindAll(path)
+}
+
+// Find returns the first element FindAll selects, or nil.
+func (d *Document) Find(path string) *Node {
+	if d.Root == nil {
+		return nil
+	}
+	return d.Root.Find(path)
+}
+
+// Value returns the text content or attribute value a location path selects (see Node.Value).
+func (d *Document) Value(path string) (string, bool) {
+	if d.Root == nil {
+		return "", false
+	}
+	return d.Root.Value(path)
+}
+
+// findPath evaluates a location path from start, whose children the first step selects.
+func findPath(start *Node, path string) []*Node {
+	nodes := []*Node{start}
+	descendants := false
+	for _, step := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		if step == "" {
+			// The empty step of "//" makes the next step select descendants
+			descendants = true
+			continue
+		}
+		if strings.HasPrefix(step, "@") {
+			// Only a final attribute step is meaningful
+			var selected []*Node
+			for _, node := range nodes {
+				if _, ok := node.Attr(step[1:]); ok {
+					selected = append(selected, node)
+				}
+			}
+			return selected
+		}
+
+		local := ParseQName(step).LocalName
+		var selected []*Node
+		seen := make(map[*Node]bool)
+		var visit func(node *Node)
+		visit = func(node *Node) {
+			for _, child := range node.Children {
+				if (local == "*" || child.Name.Local == local) && !seen[child] {
+					seen[child] = true
+					selected = append(selected, child)
+				}
+				if descendants {
+					visit(child)
+				}
+			}
+		}
+		for _, node := range nodes {
+			visit(node)
+		}
+		nodes, descendants = selected, false
+	}
+	if len(nodes) == 1 && nodes[0] == start {
+		return nil // An empty path selects nothing
+	}
+	return nodes
+}
+
+// attributeStep returns the name of the final attribute step of a path, if it has one.
+func attributeStep(path string) (string, bool) {
+	path = strings.TrimSpace(path)
+	last := path[strings.LastIndex(path, "/")+1:]
+	if strings.HasPrefix(last, "@") {
+		return last[1:], true
+	}
+	return "", false
+}
@@ -0,0 +1,91 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNodeNavigation(t *testing.T) {
+	doc, err := Parse([]byte(`<inv:invoice xmlns:inv="urn:example:invoice" xmlns:x="urn:x" currency="EUR">
+  <inv:seller id="s1"><inv:name>Widgets</inv:name></inv:seller>
+  <inv:lines>
+    <inv:line number="1" x:batch="7"><inv:sku>WID-0001</inv:sku></inv:line>
+    <inv:line number="2"><inv:sku>DOC-0002</inv:sku><inv:part><inv:sku>DOC-0003</inv:sku></inv:part></inv:line>
+  </inv:lines>
+</inv:invoice>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	root := doc.Root
+
+	if value, ok := root.Attr("currency"); !ok || value != "EUR" {
+		t.Errorf("Expected currency EUR, got %q %v", value, ok)
+	}
+	if _, ok := root.Attr("inv"); ok {
+		t.Error("Expected namespace declarations not to be attributes")
+	}
+	line := root.FirstChild("lines").FirstChild("line")
+	if value, ok := line.Attr("x:batch"); !ok || value != "7" {
+		t.Errorf("Expected batch 7, got %q %v", value, ok)
+	}
+	if lines := root.FirstChild("inv:lines").ChildrenNamed("line"); len(lines) != 2 || lines[0] != line {
+		t.Errorf("Expected the two lines, got %d", len(lines))
+	}
+	if root.FirstChild("missing") != nil || len(root.ChildrenNamed("*")) != 2 {
+		t.Error("Unexpected children")
+	}
+
+	skus := func(nodes []*Node) string {
+		var values []string
+		for _, node := range nodes {
+			values = append(values, node.Content)
+		}
+		return strings.Join(values, ",")
+	}
+	tests := []struct {
+		path     string
+		from     *Node
+		expected string
+	}{
+		{"lines/line/sku", root, "WID-0001,DOC-0002"},
+		{"/invoice/lines/line/sku", line, "WID-0001,DOC-0002"},
+		{"//sku", line, "WID-0001,DOC-0002,DOC-0003"},
+		{"lines//sku", root, "WID-0001,DOC-0002,DOC-0003"},
+		{"inv:lines/*/sku", root, "WID-0001,DOC-0002"},
+		{"sku", line, "WID-0001"},
+		{"/lines", root, ""},
+		{"", root, ""},
+	}
+	for _, tt := range tests {
+		if actual := skus(tt.from.FindAll(tt.path)); actual != tt.expected {
+			t.Errorf("FindAll(%q): expected %q, got %q", tt.path, tt.expected, actual)
+		}
+	}
+	if found := doc.FindAll("lines/line/@x:batch"); len(found) != 1 || found[0] != line {
+		t.Errorf("Expected the line with a batch, got %v", found)
+	}
+	if doc.Find("//part/sku").Content != "DOC-0003" || doc.Find("//missing") != nil {
+		t.Error("Unexpected result of Find")
+	}
+
+	values := []struct {
+		path     string
+		expected string
+		found    bool
+	}{
+		{"seller/name", "Widgets", true},
+		{"seller/@id", "s1", true},
+		{"//line/@number", "1", true},
+		{"@currency", "EUR", true},
+		{"seller/@missing", "", false},
+		{"buyer/name", "", false},
+	}
+	for _, tt := range values {
+		if value, found := doc.Value(tt.path); value != tt.expected || found != tt.found {
+			t.Errorf("Value(%q): expected %q %v, got %q %v", tt.path, tt.expected, tt.found, value, found)
+		}
+	}
+	if _, found := (&Document{}).Value("a"); found || (&Document{}).FindAll("a") != nil {
+		t.Error("Expected an empty document to have no values")
+	}
+}
@@ -0,0 +1,69 @@
+package xmlparser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// occurrenceParticles lists the schema elements that accept minOccurs and maxOccurs.
+var occurrenceParticles = map[string]bool{
+	"element": true, "sequence": true, "choice": true, "all": true, "any": true, "group": true,
+}
+
+// checkOccurrenceAttributes checks every minOccurs and maxOccurs attribute in a schema
+// document: values must be non-negative integers (maxOccurs may also be "unbounded") and
+// minOccurs may not exceed maxOccurs. Errors include the attribute's line and column.
+func checkOccurrenceAttributes(xsdBytes []byte) error {
+	doc, err := Parse(xsdBytes)
+	if err != nil {
+		return nil // Malformed documents are reported by the schema decoder
+	}
+	return checkNodeOccurrences(doc.Root)
+}
+
+// checkNodeOccurrences checks the occurrence attributes of a schema node and its descendants.
+func checkNodeOccurrences(node *Node) error {
+	if node.Name.Space == xsdNamespace && occurrenceParticles[node.Name.Local] {
+		min, max := defaultOccurs, defaultOccurs
+		minIndex, maxIndex := -1, -1
+
+		for i, attr := range node.Attrs {
+			if attr.Name.Space != "" {
+				continue
+			}
+			switch attr.Name.Local {
+			case "minOccurs":
+				value, err := strconv.Atoi(attr.Value)
+				if err != nil || value < 0 {
+					return fmt.Errorf("%s must be a non-negative integer, got '%s'", attributeLocation(node, i), attr.Value)
+				}
+				min, minIndex = value, i
+			case "maxOccurs":
+				if attr.Value == "unbounded" {
+					max, maxIndex = -1, i
+					continue
+				}
+				value, err := strconv.Atoi(attr.Value)
+				if err != nil || value < 0 {
+					return fmt.Errorf("%s must be a non-negative integer or 'unbounded', got '%s'", attributeLocation(node, i), attr.Value)
+				}
+				max, maxIndex = value, i
+			}
+		}
+
+		if max >= 0 && min > max {
+			index := minIndex
+			if index < 0 {
+				index = maxIndex
+			}
+			return fmt.Errorf("%s: minOccurs %d is greater than maxOccurs %d", attributeLocation(node, index), min, max)
+		}
+	}
+
+	for _, child := range node.Children {
+		if err := checkNodeOccurrences(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test that malformed occurrence bounds are rejected when the schema is parsed
+func TestOccurrenceAttributes(t *testing.T) {
+	tests := []struct {
+		name        string
+		particles   string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Valid bounds",
+			particles:  `<xs:element name="a" minOccurs="0" maxOccurs="unbounded"/><xs:element name="b" minOccurs="2" maxOccurs="2"/>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Zero maxOccurs",
+			particles:  `<xs:element name="a" minOccurs="0" maxOccurs="0"/>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Non-numeric minOccurs",
+			particles:   `<xs:element name="a" minOccurs="abc"/>`,
+			errorString: "attribute 'minOccurs' in element <element> (line 5, column 38) must be a non-negative integer, got 'abc'",
+		},
+		{
+			name:        "Negative minOccurs",
+			particles:   `<xs:element name="a" minOccurs="-1"/>`,
+			errorString: "must be a non-negative integer, got '-1'",
+		},
+		{
+			name:        "Unbounded minOccurs",
+			particles:   `<xs:element name="a" minOccurs="unbounded"/>`,
+			errorString: "must be a non-negative integer, got 'unbounded'",
+		},
+		{
+			name:        "Malformed maxOccurs",
+			particles:   `<xs:element name="a" maxOccurs="many"/>`,
+			errorString: "attribute 'maxOccurs' in element <element> (line 5, column 38) must be a non-negative integer or 'unbounded', got 'many'",
+		},
+		{
+			name:        "minOccurs greater than maxOccurs",
+			particles:   `<xs:element name="a" minOccurs="3" maxOccurs="2"/>`,
+			errorString: "attribute 'minOccurs' in element <element> (line 5, column 38): minOccurs 3 is greater than maxOccurs 2",
+		},
+		{
+			name:        "minOccurs greater than default maxOccurs",
+			particles:   `<xs:element name="a" minOccurs="2"/>`,
+			errorString: "minOccurs 2 is greater than maxOccurs 1",
+		},
+		{
+			name:        "Nested group",
+			particles:   `<xs:choice minOccurs="1" maxOccurs="-5"><xs:element name="a"/></xs:choice>`,
+			errorString: "attribute 'maxOccurs' in element <choice> (line 5, column 42)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xsd := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="root">
+        <xs:complexType>
+            <xs:sequence>
+                ` + tt.particles + `
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`
+			_, err := ParseXSD([]byte(xsd))
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected schema to parse, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorString) {
+				t.Errorf("Expected error containing '%s', got: %v", tt.errorString, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// ValidateOptions configures ValidateWithOptions. The zero value gives the behavior of Validate.
+type ValidateOptions struct {
+	// EmptyOptionalElements selects how empty optional elements of simple type, such as
+	// <middleName/> declared with minOccurs="0", are treated. Empty required elements always
+	// have the empty string validated against their type.
+	EmptyOptionalElements EmptyElementPolicy
+
+	// StrictNamespaces requires every element to be in the namespace its declaration implies:
+	// the target namespace for global elements and for local elements whose form is qualified
+	// (elementFormDefault="qualified"), no namespace otherwise. By default elements are also
+	// matched by local name, so documents that omit the namespace are still validated.
+	StrictNamespaces bool
+
+	// FacetOrder is the order in which the facets of a simple value are checked, by kind.
+	// Kinds left out are checked after the listed ones. The default checks the cheap facets
+	// first: length, enumeration, digits, range, and pattern last.
+	FacetOrder []FacetKind
+
+	// AllFacetDiagnostics checks every facet of a value and reports each failure, including
+	// after the value failed its built-in type. By default checking stops at the first
+	// failure, sparing the remaining facets (often a costly pattern) of an invalid value.
+	AllFacetDiagnostics bool
+
+	// SubtreeBudgets bounds the size of the subtrees rooted at elements with the given names,
+	// so that a known extension point, such as free-text notes, can be held to tighter
+	// limits than the rest of the document. A name with an empty Space matches the local
+	// name in any namespace. Budgets of nested subtrees all apply. Budgets are checked on
+	// elements validated against a declaration.
+	SubtreeBudgets map[xml.Name]SubtreeBudget
+
+	// SensitivePaths marks elements and attributes as holding sensitive data, like the
+	// declarations annotated with SensitiveAppInfo: their values are masked as
+	// DefaultRedaction in issues, so that personal data does not leak into logs. A path is
+	// a location path of child steps, written from the root element as
+	// "/customer/card/number", relative to it as "card/@holder", or as "//ssn" to match at
+	// any depth. Prefixes of steps are ignored.
+	SensitivePaths []string
+
+	// MaxErrors stops validation once that many issues have been found, so that a badly
+	// broken document does not make validation build an issue for every element of it. The
+	// ValidationError then has Stopped set, and IDREFs are not checked, as the IDs of the
+	// rest of the document are not known. Zero does not limit the number of issues found;
+	// at most 1000 are stored regardless. ValidateRecords and ValidateRecordsAt ignore it,
+	// as every record is reported on.
+	MaxErrors int
+
+	// FailFast stops validation at the first issue, as MaxErrors: 1 does.
+	FailFast bool
+
+	// Tracer, if set, traces the validation of each document with a span, in the span of
+	// the context given to ValidateContext (see Tracer).
+	Tracer Tracer
+
+	// TraceChildren adds a span for each child of the root element to the span of the
+	// document, for telling which parts of large documents validation spends its time on.
+	TraceChildren bool
+
+	// OnWarning, if set, is called with each CodeNotChecked warning validation reports: a
+	// constraint of the schema it does not check, such as an xs:length facet or an xs:key
+	// identity constraint, or an attribute an attribute wildcard lets through unvalidated.
+	// Each warning is reported once per document, located on the first element or attribute
+	// it applies to. ValidateAndAnnotate also returns them in Result.Warnings.
+	OnWarning func(ValidationIssue)
+
+	// OnIssue, if set, is called with each issue as validation finds it, before Validate
+	// returns them all, for streaming error handling and progress reporting. Issues come
+	// in the order they are found, which can differ from that of ValidationError.Issues:
+	// an element's own issues follow those of its children. Only the issues within
+	// MaxErrors are passed. To stop early, validate with ValidateContext and cancel its
+	// context, from OnIssue if need be: validation stops at the next element and returns
+	// the context's error.
+	OnIssue func(ValidationIssue)
+}
+
+// SubtreeBudget is the size allowed for the subtree of an element. Zero fields are not limited.
+type SubtreeBudget struct {
+	// MaxElements is the number of elements allowed below the root of the subtree, at any depth.
+	MaxElements int
+
+	// MaxTextLength is the number of bytes of text allowed in the subtree, root included,
+	// not counting the leading and trailing whitespace of each element's text.
+	MaxTextLength int
+}
+
+// EmptyElementPolicy is the treatment of empty optional elements of simple type. Producers
+// disagree on whether an empty tag means "no value" or "the empty string", so the policy
+// can be chosen per validation.
+type EmptyElementPolicy int
+
+const (
+	// EmptyAsAbsent skips the type checks of empty optional elements, as if they were omitted.
+	EmptyAsAbsent EmptyElementPolicy = iota
+	// EmptyAsValue validates the empty string against the element's type and facets.
+	EmptyAsValue
+	// EmptyAsError reports empty optional elements; they must be omitted instead.
+	EmptyAsError
+)
+
+// String returns the name of the policy.
+func (p EmptyElementPolicy) String() string {
+	switch p {
+	case EmptyAsAbsent:
+		return "EmptyAsAbsent"
+	case EmptyAsValue:
+		return "EmptyAsValue"
+	case EmptyAsError:
+		return "EmptyAsError"
+	default:
+		return fmt.Sprintf("EmptyElementPolicy(%d)", int(p))
+	}
+}
+
+// validateEmptyOptional applies the empty element policy to an empty optional element of simple type.
+func (v *validator) validateEmptyOptional(node *Node, def *Element) []string {
+	switch v.opts.EmptyOptionalElements {
+	case EmptyAsValue:
+		return v.validateTextContent(node, def)
+	case EmptyAsError:
+		return []string{fmt.Sprintf("optional element <%s> is empty; omit it instead of leaving it empty", node.Name.Local)}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,318 @@
+package xmlparser
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Test the policies for empty optional elements of simple type, and the validation of
+// empty required ones
+func TestEmptyOptionalElementPolicy(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="person">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="firstName" type="xs:string"/>
+                <xs:element name="middleName" type="xs:string" minOccurs="0"/>
+                <xs:element name="age" type="xs:integer" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+    <xs:element name="badge">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="number" type="xs:int"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		policy      EmptyElementPolicy
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Absent policy skips empty optional elements",
+			policy:     EmptyAsAbsent,
+			xml:        `<person><firstName>Ada</firstName><middleName/><age></age></person>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Value policy accepts an empty string",
+			policy:     EmptyAsValue,
+			xml:        `<person><firstName>Ada</firstName><middleName/></person>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Value policy validates the empty value against the type",
+			policy:      EmptyAsValue,
+			xml:         `<person><firstName>Ada</firstName><age/></person>`,
+			shouldPass:  false,
+			errorString: "in element <age>: value '' is not a valid integer",
+		},
+		{
+			name:        "Error policy rejects empty optional elements",
+			policy:      EmptyAsError,
+			xml:         `<person><firstName>Ada</firstName><middleName/></person>`,
+			shouldPass:  false,
+			errorString: "optional element <middleName> is empty; omit it instead of leaving it empty",
+		},
+		{
+			name:       "Error policy ignores empty required elements",
+			policy:     EmptyAsError,
+			xml:        `<person><firstName/><age>36</age></person>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Empty required elements are validated against the type",
+			policy:      EmptyAsAbsent,
+			xml:         `<badge><number/></badge>`,
+			shouldPass:  false,
+			errorString: "in element <number>: value '' is not a valid int",
+		},
+		{
+			name:        "Empty required elements are validated whatever the policy",
+			policy:      EmptyAsError,
+			xml:         `<badge><number></number></badge>`,
+			shouldPass:  false,
+			errorString: "in element <number>: value '' is not a valid int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.ValidateWithOptions(doc, ValidateOptions{EmptyOptionalElements: tt.policy})
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test that subtree budgets bound the elements and text below the configured elements
+func TestSubtreeBudgets(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:example:order" xmlns="urn:example:order">
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="id" type="xs:string"/>
+                <xs:element name="notes" minOccurs="0">
+                    <xs:complexType mixed="true">
+                        <xs:sequence>
+                            <xs:element name="p" type="xs:string" minOccurs="0" maxOccurs="unbounded"/>
+                        </xs:sequence>
+                    </xs:complexType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	budgets := map[xml.Name]SubtreeBudget{
+		{Local: "notes"}: {MaxElements: 2, MaxTextLength: 10},
+		{Space: "urn:example:order", Local: "order"}: {MaxElements: 5},
+		{Space: "urn:example:other", Local: "id"}:    {MaxTextLength: 1},
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name: "Within budget",
+			xml: `<order xmlns="urn:example:order"><id>a-long-identifier</id><notes>
+    Fragile <p>top</p>
+</notes></order>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Too many elements",
+			xml:         `<order xmlns="urn:example:order"><id>1</id><notes><p/><p/><p/></notes></order>`,
+			shouldPass:  false,
+			errorString: "element <notes> exceeds its subtree budget: 3 descendant elements (maximum: 2)",
+		},
+		{
+			name:        "Too much text",
+			xml:         `<order xmlns="urn:example:order"><id>1</id><notes>Keep dry<p>and cool</p></notes></order>`,
+			shouldPass:  false,
+			errorString: "element <notes> exceeds its subtree budget: 16 bytes of text (maximum: 10)",
+		},
+		{
+			name:        "Budget of an enclosing subtree",
+			xml:         `<order xmlns="urn:example:order"><id>1</id><notes><p/><p/></notes><id>2</id><id>3</id></order>`,
+			shouldPass:  false,
+			errorString: "element <order> exceeds its subtree budget: 6 descendant elements (maximum: 5)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.ValidateWithOptions(doc, ValidateOptions{SubtreeBudgets: budgets})
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test that validation stops at the error limit
+func TestErrorLimit(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger" maxOccurs="unbounded"/>
+        <xs:element name="parent" type="xs:IDREF" minOccurs="0"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+	doc, err := Parse([]byte(`<order><quantity>0</quantity><quantity>1</quantity><quantity>-1</quantity><quantity>x</quantity><parent>o-1</parent></order>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    ValidateOptions
+		issues  int
+		stopped bool
+	}{
+		{"No limit", ValidateOptions{}, 4, false},
+		{"MaxErrors", ValidateOptions{MaxErrors: 2}, 2, true},
+		{"FailFast", ValidateOptions{FailFast: true}, 1, true},
+		{"Limit not reached", ValidateOptions{MaxErrors: 10}, 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *ValidationError
+			if !errors.As(schema.ValidateWithOptions(doc, tt.opts), &validationErr) {
+				t.Fatalf("Expected a ValidationError")
+			}
+			if len(validationErr.Issues) != tt.issues || validationErr.Stopped != tt.stopped {
+				t.Errorf("Expected %d issues (stopped: %t), got %d (stopped: %t): %v",
+					tt.issues, tt.stopped, len(validationErr.Issues), validationErr.Stopped, validationErr)
+			}
+			if !strings.Contains(validationErr.Issues[0].Message, "value '0' must be positive") {
+				t.Errorf("Expected the first issue in document order, got: %v", validationErr.Issues[0].Message)
+			}
+			if tt.stopped != strings.Contains(validationErr.Error(), "validation stopped at the error limit") {
+				t.Errorf("Unexpected error text: %v", validationErr)
+			}
+		})
+	}
+
+	t.Run("Validator reuse", func(t *testing.T) {
+		validator := schema.NewValidator(ValidateOptions{FailFast: true})
+		if err := validator.Validate(doc); err == nil {
+			t.Fatalf("Expected validation to fail")
+		}
+		valid, _ := Parse([]byte(`<order><quantity>1</quantity></order>`))
+		if err := validator.Validate(valid); err != nil {
+			t.Errorf("Expected validation to pass, but got error: %v", err)
+		}
+	})
+}
+
+func TestOnIssue(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger" maxOccurs="unbounded"/>
+        <xs:element name="parent" type="xs:IDREF" minOccurs="0"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+	doc, err := Parse([]byte(`<order><quantity>0</quantity><quantity>1</quantity><quantity>-1</quantity><quantity>x</quantity><parent>o-1</parent></order>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	var found []ValidationIssue
+	opts := ValidateOptions{OnIssue: func(issue ValidationIssue) { found = append(found, issue) }}
+	var validationErr *ValidationError
+	if !errors.As(schema.ValidateWithOptions(doc, opts), &validationErr) {
+		t.Fatalf("Expected a ValidationError")
+	}
+	if len(found) != len(validationErr.Issues) {
+		t.Fatalf("Expected the %d issues of the error, got %d: %+v", len(validationErr.Issues), len(found), found)
+	}
+	for i, issue := range validationErr.Issues {
+		if found[i] != issue {
+			t.Errorf("Issue %d: expected %+v, got %+v", i, issue, found[i])
+		}
+	}
+
+	found = nil
+	opts.MaxErrors = 2
+	schema.ValidateWithOptions(doc, opts)
+	if len(found) != 2 {
+		t.Errorf("Expected the 2 issues within MaxErrors, got %d", len(found))
+	}
+
+	// Canceling the context from the callback stops validation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	found = nil
+	err = schema.ValidateContext(ctx, doc, ValidateOptions{OnIssue: func(issue ValidationIssue) {
+		found = append(found, issue)
+		cancel()
+	}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context's error, got: %v", err)
+	}
+	if len(found) != 1 || !strings.Contains(found[0].Message, "value '0' must be positive") {
+		t.Errorf("Expected validation to stop after the first issue, got: %+v", found)
+	}
+
+	// Documents that cannot be validated report their failure too
+	found = nil
+	other, _ := Parse([]byte(`<invoice/>`))
+	if schema.ValidateWithOptions(other, ValidateOptions{OnIssue: opts.OnIssue}) == nil || len(found) != 1 {
+		t.Errorf("Expected the undeclared root as the only issue, got: %+v", found)
+	}
+}
@@ -0,0 +1,458 @@
+package xmlparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ParallelOptions configures ValidateRecordsAt and ValidateParallel.
+type ParallelOptions struct {
+	ValidateOptions
+
+	// Workers is the number of records or subtrees validated concurrently (defaults to GOMAXPROCS).
+	Workers int
+}
+
+// elementSpan locates a child element of the root in the source document.
+type elementSpan struct {
+	start  int64    // Offset of the element's start tag
+	tagEnd int64    // Offset just past its start tag
+	end    int64    // Offset just past its end tag
+	line   int      // Line of the start tag
+	column int      // Byte column of the start tag
+	lines  int      // Line breaks after the start tag, up to the end of the element
+	name   xml.Name // Element name
+}
+
+// documentLayout is the root start tag and the location of the root's children, found by
+// tokenizing a document without building its tree.
+type documentLayout struct {
+	root     xml.StartElement
+	children []elementSpan
+	charset  string // Encoding the document declares, or "" for UTF-8
+}
+
+// ValidateRecordsAt validates a batch document like ValidateRecords, parsing and validating
+// its records in parallel. It is meant for very large documents: only the envelope (the
+// root element and its children that are not records) is held in memory as a whole, while
+// each record is read from r, parsed and validated by one of opts.Workers workers.
+//
+// The document is tokenized once to find the records, then the envelope is validated with
+// each record standing in for itself as an empty element; this finds the declaration of
+// every record and reports the issues of the envelope. Records are validated against their
+// declaration with the namespace declarations of the root in scope, and the document-wide
+// ID/IDREF checks run once all records are done. The result has the same issues as
+// ValidateRecords on the parsed document, except that an ID already used by an earlier
+// record is reported after the other issues of the record that uses it again.
+//
+// The content of each record is released once it is validated, so the Node of a
+// RecordResult is an empty element. Split reads the records from r again, which must
+// therefore stay readable and unchanged while the result is in use.
+func (s *Schema) ValidateRecordsAt(r io.ReaderAt, size int64, recordElement string, opts ParallelOptions) (*BatchResult, error) {
+	layout, err := scanLayout(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if recordElement == "" {
+		recordElement = layout.mostFrequentChild()
+	}
+
+	envelope, err := layout.envelope(r, size, recordElement)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(envelope)
+	if err != nil {
+		return nil, err
+	}
+	rootDef, rootErr := s.rootDeclaration(doc, opts.ValidateOptions)
+	if rootErr != nil {
+		return nil, rootErr
+	}
+
+	// Validate the envelope, collecting the declaration of each record
+	result := &BatchResult{RecordElement: recordElement, root: doc.Root}
+	source := &recordSource{r: r, root: layout.root, charset: layout.charset}
+	for i, child := range doc.Root.Children {
+		if child.Name.Local == recordElement {
+			result.Records = append(result.Records, RecordResult{Index: len(result.Records) + 1, Node: child})
+			source.spans = append(source.spans, layout.children[i])
+		}
+	}
+	result.source = source
+
+	opts.MaxErrors, opts.FailFast = 0, false // Every record is reported on
+	opts.OnIssue = nil                       // Record issues are reported in the result only
+	v := newValidator(s, opts.ValidateOptions)
+	v.deferred = make(map[*Node]*Element, len(result.Records))
+	for i := range result.Records {
+		v.deferred[result.Records[i].Node] = nil
+	}
+	errors := v.validateNode(doc.Root, rootDef)
+
+	identities, err := s.validateRecordsParallel(source, v.deferred, result.Records, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// IDs are unique across the document: an ID already used by the envelope or an earlier
+	// record is reported in the record that uses it again
+	for i, identity := range identities {
+		values := make([]string, 0, len(identity.ids))
+		for value := range identity.ids {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			if first, exists := v.ids[value]; exists {
+				id := identity.ids[value]
+				result.Records[i].Errors = append(result.Records[i].Errors, located(id.node, id.path, id.attribute, duplicateID(id, first)).Message)
+			} else {
+				v.ids[value] = identity.ids[value]
+			}
+		}
+		v.idrefs = append(v.idrefs, identity.idrefs...)
+	}
+
+	errors = append(errors, messagesOf(v.checkIDReferences())...)
+	if len(errors) > 0 {
+		result.Errors = storedMessages(errors)
+	}
+	for i := range result.Records {
+		if len(result.Records[i].Errors) > 0 {
+			result.Records[i].Errors = storedMessages(result.Records[i].Errors)
+		}
+	}
+	return result, nil
+}
+
+// recordIdentities are the IDs and IDREFs of one record, merged once all records are validated.
+type recordIdentities struct {
+	ids    map[string]idReference
+	idrefs []idReference
+}
+
+// recordSource locates the records of a batch document validated by ValidateRecordsAt.
+type recordSource struct {
+	r       io.ReaderAt
+	root    xml.StartElement // Start tag of the root, whose namespace declarations records use
+	charset string           // Encoding the document declares, or "" for UTF-8
+	spans   []elementSpan    // Location of each record, in the order of the results
+}
+
+// read parses record i again.
+func (rs *recordSource) read(i int) (*Node, error) {
+	return parseRecord(rs.r, rs.root, rs.charset, rs.spans[i])
+}
+
+// validateRecordsParallel parses and validates the records of source in parallel, storing
+// their issues in records, and releases the content of each record once validated. Records
+// the envelope's content model did not match with a declaration are parsed but not
+// validated: the envelope reports them.
+func (s *Schema) validateRecordsParallel(source *recordSource, declarations map[*Node]*Element,
+	records []RecordResult, opts ParallelOptions) ([]recordIdentities, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	identities := make([]recordIdentities, len(records))
+	parseErrors := make([]error, len(records))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := newValidator(s, opts.ValidateOptions)
+			for i := range jobs {
+				def := declarations[records[i].Node]
+				node, err := source.read(i)
+				if err != nil {
+					parseErrors[i] = err
+					continue
+				}
+				if def == nil {
+					continue
+				}
+				// The record's element in the envelope takes the parsed content, so that the
+				// positions of its siblings are those of the document
+				stub := records[i].Node
+				stub.Children, stub.Content = node.Children, node.Content
+				for _, child := range stub.Children {
+					child.Parent = stub
+				}
+
+				v.ids, v.idrefs = make(map[string]idReference), nil
+				records[i].Errors = v.validateNode(stub, def)
+				v.issues = v.issues[:0]
+				identities[i] = recordIdentities{ids: v.ids, idrefs: v.idrefs}
+				identities[i].locate()
+				releaseContent(stub)
+			}
+		}()
+	}
+	for i := range records {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range parseErrors {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return identities, nil
+}
+
+// locate stores the location path of every ID and IDREF, which their elements no longer
+// tell once the record's content is released.
+func (ri *recordIdentities) locate() {
+	for value, id := range ri.ids {
+		id.path = nodePath(id.node)
+		ri.ids[value] = id
+	}
+	for i := range ri.idrefs {
+		ri.idrefs[i].path = nodePath(ri.idrefs[i].node)
+	}
+}
+
+// releaseContent drops the content of an element and of its descendants, which the IDs and
+// IDREFs of its record would otherwise keep reachable through their ancestors.
+func releaseContent(node *Node) {
+	stack := []*Node{node}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = append(stack[:len(stack)-1], node.Children...)
+		node.Children, node.Nodes, node.Content = nil, nil, ""
+	}
+}
+
+// scanLayout tokenizes a document to find its root start tag and the location of each
+// child of the root, skipping over their content. A document declared in one of the
+// encodings of CharsetReader is tokenized converted to UTF-8, with the offsets of the
+// spans mapped back to those of the document.
+func scanLayout(r io.ReaderAt, size int64) (*documentLayout, error) {
+	declaration := make([]byte, maxDeclaration)
+	n, err := io.NewSectionReader(r, 0, size).ReadAt(declaration, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	layout := &documentLayout{charset: declaredCharset(declaration[:n])}
+
+	var decoder *xml.Decoder
+	sourceOffset := func(offset int64) int64 { return offset }
+	if layout.charset == "" {
+		decoder = xml.NewDecoder(io.NewSectionReader(r, 0, size))
+	} else {
+		converted, err := CharsetReader(layout.charset, io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, fmt.Errorf("XML parsing error: %w", err)
+		}
+		input := &offsetReader{input: converted}
+		decoder = xml.NewDecoder(input)
+		decoder.CharsetReader = passThroughCharset(layout.charset, CharsetReader)
+		sourceOffset = input.sourceOffset
+	}
+	rootFound, depth := false, 0
+
+	for {
+		start := sourceOffset(decoder.InputOffset())
+		line, column := decoder.InputPos()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("XML parsing error: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 1 {
+				span := elementSpan{start: start, tagEnd: sourceOffset(decoder.InputOffset()), line: line, column: column, name: t.Name}
+				tagLine, _ := decoder.InputPos()
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("XML parsing error: %w", err)
+				}
+				span.end = sourceOffset(decoder.InputOffset())
+				endLine, _ := decoder.InputPos()
+				span.lines = endLine - tagLine
+				layout.children = append(layout.children, span)
+				continue
+			}
+			if !rootFound {
+				layout.root, rootFound = t.Copy(), true
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	if !rootFound {
+		return nil, fmt.Errorf("XML document is empty or contains no root element")
+	}
+	return layout, nil
+}
+
+// mostFrequentChild returns the most frequent local name among the root's children,
+// preferring the name that appears first on ties.
+func (l *documentLayout) mostFrequentChild() string {
+	names := make([]string, len(l.children))
+	for i, child := range l.children {
+		names[i] = child.name.Local
+	}
+	return mostFrequent(names)
+}
+
+// envelope returns the document with the content of every record removed: each record is
+// reduced to its start tag, closed at once. Line breaks are kept, so that the positions of
+// the elements after a record are the same as in the document.
+func (l *documentLayout) envelope(r io.ReaderAt, size int64, recordElement string) ([]byte, error) {
+	var envelope bytes.Buffer
+	offset := int64(0)
+	for _, span := range l.children {
+		if span.name.Local != recordElement {
+			continue
+		}
+		if err := copySection(&envelope, r, offset, span.tagEnd); err != nil {
+			return nil, err
+		}
+		tag := envelope.Bytes()[envelope.Len()-int(span.tagEnd-span.start):]
+		if !bytes.HasSuffix(tag, []byte("/>")) {
+			envelope.WriteString("</" + rawTagName(tag) + ">")
+		}
+		envelope.WriteString(strings.Repeat("\n", span.lines))
+		offset = span.end
+	}
+	if err := copySection(&envelope, r, offset, size); err != nil {
+		return nil, err
+	}
+	return envelope.Bytes(), nil
+}
+
+// copySection appends the bytes of r from offset start up to end to buf.
+func copySection(buf *bytes.Buffer, r io.ReaderAt, start, end int64) error {
+	if _, err := io.Copy(buf, io.NewSectionReader(r, start, end-start)); err != nil {
+		return fmt.Errorf("failed to read document: %w", err)
+	}
+	return nil
+}
+
+// rawTagName returns the element name of a raw start tag as written, with its prefix.
+func rawTagName(tag []byte) string {
+	i := 1
+	for i < len(tag) && !isXMLSpace(tag[i]) && tag[i] != '>' && tag[i] != '/' {
+		i++
+	}
+	return string(tag[1:i])
+}
+
+// parseRecord parses the record element at span with the root's namespace declarations in
+// scope, and moves its attribute positions to those in the whole document. The record is
+// converted to UTF-8 first when the document declares another charset.
+func parseRecord(r io.ReaderAt, root xml.StartElement, charset string, span elementSpan) (*Node, error) {
+	wrapper := namespaceWrapper(root)
+	source := make([]byte, 0, len(wrapper)+int(span.end-span.start)+len("</envelope>"))
+	source = append(source, wrapper...)
+	record := make([]byte, span.end-span.start)
+	if _, err := r.ReadAt(record, span.start); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	record, err := convertCharset(record, charset)
+	if err != nil {
+		return nil, err
+	}
+	source = append(append(source, record...), "</envelope>"...)
+
+	doc, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	node := doc.Root.Children[0]
+
+	// The decoder counts columns in bytes of UTF-8; positions count characters. A line
+	// start in another charset is no longer than in UTF-8, so it ends what is read
+	length := int64(span.column - 1)
+	if length > span.start {
+		length = span.start
+	}
+	lineStart := make([]byte, length)
+	if _, err := r.ReadAt(lineStart, span.start-int64(len(lineStart))); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	if charset != "" {
+		lineStart = lineStart[bytes.LastIndexByte(lineStart, '\n')+1:]
+		if lineStart, err = convertCharset(lineStart, charset); err != nil {
+			return nil, err
+		}
+	}
+	shiftPositions(node, span.line, utf8.RuneCount(lineStart)+1, utf8.RuneCountInString(wrapper))
+	return node, nil
+}
+
+// convertCharset converts data from charset to UTF-8 with CharsetReader, or returns it as
+// it is when charset is "".
+func convertCharset(data []byte, charset string) ([]byte, error) {
+	if charset == "" {
+		return data, nil
+	}
+	converted, err := CharsetReader(charset, bytes.NewReader(data))
+	if err == nil {
+		data, err = io.ReadAll(converted)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("XML parsing error: %w", err)
+	}
+	return data, nil
+}
+
+// namespaceWrapper returns a start tag declaring the same namespaces as the root element.
+func namespaceWrapper(root xml.StartElement) string {
+	var wrapper strings.Builder
+	wrapper.WriteString("<envelope")
+	for _, attr := range root.Attr {
+		switch {
+		case attr.Name.Space == "xmlns":
+			wrapper.WriteString(" xmlns:" + attr.Name.Local + `="`)
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			wrapper.WriteString(` xmlns="`)
+		default:
+			continue
+		}
+		xml.EscapeText(&wrapper, []byte(attr.Value))
+		wrapper.WriteString(`"`)
+	}
+	wrapper.WriteString(">")
+	return wrapper.String()
+}
+
+// shiftPositions moves the source positions of a record parsed after a wrapper of
+// prefix characters on its first line to those in a document where the record starts at
+// line and column.
+func shiftPositions(node *Node, line, column, prefix int) {
+	shift := func(position *Position) {
+		if position.Line == 1 {
+			position.Column += column - 1 - prefix
+		}
+		position.Line += line - 1
+	}
+	if node.Position.Line > 0 {
+		shift(&node.Position)
+	}
+	for i := range node.AttrPositions {
+		shift(&node.AttrPositions[i])
+	}
+	for _, child := range node.Children {
+		shiftPositions(child, line, column, prefix)
+	}
+}
@@ -0,0 +1,168 @@
+package xmlparser
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// subtreeResult holds what validating one subtree of ValidateParallel found, merged once
+// all subtrees are validated.
+type subtreeResult struct {
+	issues   []ValidationIssue
+	warnings []ValidationIssue
+	ids      []idReference // Sorted by value
+	idrefs   []idReference
+}
+
+// ValidateParallel checks if the XML document conforms to the schema, like
+// ValidateWithOptions, validating the subtrees of the element with the most children
+// across opts.Workers workers. It is meant for documents with thousands of repeated
+// children under one parent, such as the <record> elements of a <records> element; a
+// document whose elements all have fewer than two children is validated sequentially.
+//
+// The rest of the document is validated first, matching each child of the parent with
+// its declaration; the children's subtrees are then validated in parallel, and the
+// document-wide ID/IDREF checks run once all of them are done. Issues are merged in the
+// same order for any number of workers: those of the rest of the document, then those of
+// each subtree in document order, then unresolved IDREFs. An ID already used elsewhere is
+// reported after the other issues of the subtree that uses it again. MaxErrors and FailFast
+// apply to the merged issues, and OnIssue and OnWarning are called in the merged order.
+func (s *Schema) ValidateParallel(doc *Document, opts ParallelOptions) error {
+	var parent *Node
+	if doc != nil && doc.Root != nil {
+		parent = widestElement(doc.Root)
+	}
+	if parent == nil || len(parent.Children) < 2 {
+		return s.ValidateWithOptions(doc, opts.ValidateOptions)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	v := newValidator(s, opts.ValidateOptions)
+	rootDef, rootErr := v.rootDeclaration(doc, v.opts)
+	if rootErr != nil {
+		v.found(rootErr.Issues...)
+		return rootErr
+	}
+
+	// Validate the rest of the document, collecting the declaration of each child
+	v.deferred = make(map[*Node]*Element, len(parent.Children))
+	for _, child := range parent.Children {
+		v.deferred[child] = nil
+	}
+	v.validateNode(doc.Root, rootDef)
+	issues := v.issues
+	stopped := v.stopped
+
+	if !stopped {
+		subtrees := s.validateSubtrees(parent.Children, v.deferred, workers, opts.ValidateOptions)
+
+		// IDs are unique across the document: an ID already used by the rest of the document
+		// or an earlier subtree is reported in the subtree that uses it again
+		for i := range subtrees {
+			subtree := &subtrees[i]
+			for _, id := range subtree.ids {
+				if first, exists := v.ids[id.value]; exists {
+					subtree.issues = append(subtree.issues, located(id.node, "", id.attribute, duplicateID(id, first)))
+				} else {
+					v.ids[id.value] = id
+				}
+			}
+			v.idrefs = append(v.idrefs, subtree.idrefs...)
+
+			v.found(subtree.issues...)
+			issues = append(issues, subtree.issues...)
+			for _, warning := range subtree.warnings {
+				v.warnLocated(warning)
+			}
+		}
+
+		references := v.checkIDReferences()
+		v.found(references...)
+		issues = append(issues, references...)
+	}
+	if limit := v.errorLimit(); limit > 0 && len(issues) > limit {
+		issues, stopped = issues[:limit], true
+	}
+	if len(issues) > 0 {
+		validationErr := newValidationError(issues)
+		validationErr.Stopped = stopped
+		return validationErr
+	}
+	return nil
+}
+
+// validateSubtrees validates the subtrees of nodes against the declarations the content
+// model of their parent matched them with, in parallel. Nodes without a declaration are
+// not validated: their parent reports them.
+func (s *Schema) validateSubtrees(nodes []*Node, declarations map[*Node]*Element, workers int, opts ValidateOptions) []subtreeResult {
+	// Each subtree is validated in full and its issues and warnings reported once merged
+	opts.MaxErrors, opts.FailFast = 0, false
+	opts.OnIssue = nil
+	collectWarnings := opts.OnWarning != nil
+
+	subtrees := make([]subtreeResult, len(nodes))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var warnings []ValidationIssue
+			workerOpts := opts
+			if collectWarnings {
+				workerOpts.OnWarning = func(issue ValidationIssue) {
+					warnings = append(warnings, issue)
+				}
+			}
+			v := newValidator(s, workerOpts)
+			for i := range jobs {
+				def := declarations[nodes[i]]
+				if def == nil {
+					continue
+				}
+
+				// The IDREFs of the previous subtree are handed over, not cleared
+				v.idrefs = nil
+				v.reset()
+				warnings = nil
+				v.validateNode(nodes[i], def)
+				subtree := subtreeResult{issues: append([]ValidationIssue(nil), v.issues...), warnings: warnings, idrefs: v.idrefs}
+				if len(v.ids) > 0 {
+					subtree.ids = make([]idReference, 0, len(v.ids))
+					for _, id := range v.ids {
+						subtree.ids = append(subtree.ids, id)
+					}
+					sort.Slice(subtree.ids, func(a, b int) bool { return subtree.ids[a].value < subtree.ids[b].value })
+				}
+				subtrees[i] = subtree
+			}
+		}()
+	}
+	for i := range nodes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return subtrees
+}
+
+// widestElement returns the first element, in document order, with the most children.
+func widestElement(root *Node) *Node {
+	widest := root
+	stack := []*Node{root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(node.Children) > len(widest.Children) {
+			widest = node
+		}
+		for i := len(node.Children) - 1; i >= 0; i-- {
+			stack = append(stack, node.Children[i])
+		}
+	}
+	return widest
+}
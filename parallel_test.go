@@ -0,0 +1,220 @@
+package xmlparser
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Test that parallel validation from an io.ReaderAt reports the same issues as ValidateRecords
+func TestValidateRecordsAt(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+    targetNamespace="urn:example:batch"
+    xmlns:b="urn:example:batch"
+    elementFormDefault="qualified">
+    <xs:element name="batch">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="header" type="xs:string"/>
+                <xs:element name="order" maxOccurs="unbounded">
+                    <xs:complexType>
+                        <xs:sequence>
+                            <xs:element name="quantity" type="xs:positiveInteger"/>
+                            <xs:element name="parent" type="xs:IDREF" minOccurs="0"/>
+                            <xs:element name="tag" minOccurs="0">
+                                <xs:complexType>
+                                    <xs:attribute name="code" type="xs:NCName"/>
+                                </xs:complexType>
+                            </xs:element>
+                        </xs:sequence>
+                        <xs:attribute name="id" type="xs:ID" use="required"/>
+                        <xs:attribute name="code" type="xs:NCName"/>
+                    </xs:complexType>
+                </xs:element>
+                <xs:element name="trailer" type="xs:string"/>
+            </xs:sequence>
+            <xs:attribute name="count" type="xs:integer" use="required"/>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	source := `<?xml version="1.0"?>
+<b:batch xmlns:b="urn:example:batch" count="x">
+    <b:header>daily</b:header>
+    <b:order id="o1"><b:quantity>5</b:quantity></b:order>
+    <b:order id="o2" code="1x">
+        <b:quantity>0</b:quantity>
+    </b:order>
+    <b:order id="o3">
+        <b:quantity>2</b:quantity>
+        <b:parent>o1</b:parent>
+    </b:order>
+    <b:order id="o1"><b:quantity>1</b:quantity><b:note/></b:order>
+    <b:order id="ö4" code="2y"><b:quantity>3</b:quantity><b:parent>o9</b:parent></b:order>
+    <b:order id="o5" code="-é"/><b:order id="o6"><b:quantity>1</b:quantity><b:tag code="-1"/></b:order>
+    <b:trailer>end</b:trailer>
+</b:batch>`
+
+	doc, err := Parse([]byte(source))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	expected, err := schema.ValidateRecords(doc, "", ValidateOptions{})
+	if err != nil {
+		t.Fatalf("ValidateRecords failed: %v", err)
+	}
+
+	// The same document in ISO-8859-1 has the same issues at the same positions
+	var latin1 strings.Builder
+	for _, r := range strings.Replace(source, `version="1.0"`, `version="1.0" encoding="ISO-8859-1"`, 1) {
+		latin1.WriteByte(byte(r))
+	}
+	latin1Doc, err := Parse([]byte(latin1.String()))
+	if err != nil {
+		t.Fatalf("Failed to parse ISO-8859-1 XML: %v", err)
+	}
+	expectedLatin1, err := schema.ValidateRecords(latin1Doc, "", ValidateOptions{})
+	if err != nil {
+		t.Fatalf("ValidateRecords failed: %v", err)
+	}
+	for i := range expected.Records {
+		if !reflect.DeepEqual(expectedLatin1.Records[i].Errors, expected.Records[i].Errors) {
+			t.Fatalf("Record %d: expected issues %q in ISO-8859-1, got %q", i+1, expected.Records[i].Errors, expectedLatin1.Records[i].Errors)
+		}
+	}
+
+	for _, test := range []struct {
+		source  string
+		workers int
+	}{{source, 0}, {source, 1}, {source, 3}, {latin1.String(), 0}, {latin1.String(), 3}} {
+		source, workers := test.source, test.workers
+		result, err := schema.ValidateRecordsAt(strings.NewReader(source), int64(len(source)), "",
+			ParallelOptions{Workers: workers})
+		if err != nil {
+			t.Fatalf("ValidateRecordsAt failed with %d workers: %v", workers, err)
+		}
+
+		if result.Summary() != expected.Summary() {
+			t.Errorf("Expected summary %q, got %q", expected.Summary(), result.Summary())
+		}
+		if !reflect.DeepEqual(result.Errors, expected.Errors) {
+			t.Errorf("Expected envelope issues %q, got %q", expected.Errors, result.Errors)
+		}
+		// An ID already used by an earlier record is reported after the record's other issues
+		for i := range expected.Records {
+			got := append([]string(nil), result.Records[i].Errors...)
+			want := append([]string(nil), expected.Records[i].Errors...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Record %d: expected issues %q, got %q", i+1, expected.Records[i].Errors, result.Records[i].Errors)
+			}
+		}
+
+		// Records hold no content once validated; Split reads them again
+		for _, record := range result.Records {
+			if len(record.Node.Children) > 0 || record.Node.Content != "" {
+				t.Errorf("Expected record %d to be released once validated", record.Index)
+			}
+		}
+
+		accepted, rejected := result.Split()
+		expectedAccepted, expectedRejected := expected.Split()
+		for _, pair := range [][2]*Document{{accepted, expectedAccepted}, {rejected, expectedRejected}} {
+			got, err := pair[0].Marshal()
+			if err != nil {
+				t.Fatalf("Failed to marshal split document: %v", err)
+			}
+			want, _ := pair[1].Marshal()
+			if string(got) != string(want) {
+				t.Errorf("Expected split document:\n%s\ngot:\n%s", want, got)
+			}
+		}
+	}
+
+	if _, err := schema.ValidateRecordsAt(strings.NewReader("<!-- empty -->"), 14, "", ParallelOptions{}); err == nil ||
+		err.Error() != "XML document is empty or contains no root element" {
+		t.Errorf("Expected error for a document without root element, got: %v", err)
+	}
+	undeclared := `<orders/>`
+	if _, err := schema.ValidateRecordsAt(strings.NewReader(undeclared), int64(len(undeclared)), "", ParallelOptions{}); err == nil {
+		t.Error("Expected error for an undeclared root element")
+	}
+}
+
+// Test that parallel validation of sibling subtrees reports the issues of sequential
+// validation, in the same order for any number of workers
+func TestValidateParallel(t *testing.T) {
+	schema, err := ParseXSD([]byte(concurrencyTestSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	doc, err := Parse([]byte(strings.Replace(concurrencyTestDocument(1, 200), "<seller>p1<", "<seller>p9<", 1)))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	messages := func(err error) []string {
+		validationErr, ok := err.(*ValidationError)
+		if !ok {
+			t.Fatalf("Expected a ValidationError, got %v", err)
+		}
+		return messagesOf(validationErr.Issues)
+	}
+	sequential := messages(schema.Validate(doc))
+	sort.Strings(sequential)
+
+	var expected []ValidationIssue
+	for _, workers := range []int{1, 4, 0} {
+		var reported []ValidationIssue
+		err := schema.ValidateParallel(doc, ParallelOptions{Workers: workers, ValidateOptions: ValidateOptions{
+			OnIssue: func(issue ValidationIssue) { reported = append(reported, issue) },
+		}})
+		issues := err.(*ValidationError).Issues
+		if !reflect.DeepEqual(reported, issues) {
+			t.Errorf("Expected OnIssue to be called with the issues in order, got %v", reported)
+		}
+		if expected == nil {
+			expected = issues
+			parallel := messages(err)
+			sort.Strings(parallel)
+			if !reflect.DeepEqual(parallel, sequential) {
+				t.Fatalf("Expected the issues of sequential validation %q, got %q", sequential, parallel)
+			}
+			// The unresolved IDREF is reported last
+			if last := issues[len(issues)-1]; !strings.Contains(last.Message, "IDREF 'p9'") {
+				t.Errorf("Expected the IDREF issue last, got %v", last)
+			}
+		} else if !reflect.DeepEqual(issues, expected) {
+			t.Errorf("Expected the same issues with %d workers, got %v", workers, issues)
+		}
+	}
+
+	err = schema.ValidateParallel(doc, ParallelOptions{Workers: 4, ValidateOptions: ValidateOptions{MaxErrors: 3}})
+	if validationErr := err.(*ValidationError); !validationErr.Stopped || !reflect.DeepEqual(validationErr.Issues, expected[:3]) {
+		t.Errorf("Expected the first 3 issues, stopped, got %+v", validationErr)
+	}
+
+	// Warnings are reported once per document, as sequential validation reports them
+	var sequentialWarnings, parallelWarnings []ValidationIssue
+	schema.ValidateWithOptions(doc, ValidateOptions{OnWarning: func(issue ValidationIssue) {
+		sequentialWarnings = append(sequentialWarnings, issue)
+	}})
+	schema.ValidateParallel(doc, ParallelOptions{Workers: 4, ValidateOptions: ValidateOptions{OnWarning: func(issue ValidationIssue) {
+		parallelWarnings = append(parallelWarnings, issue)
+	}}})
+	if len(sequentialWarnings) == 0 || !reflect.DeepEqual(parallelWarnings, sequentialWarnings) {
+		t.Errorf("Expected warnings %+v, got %+v", sequentialWarnings, parallelWarnings)
+	}
+
+	// Small documents get the issues of Validate
+	small, _ := Parse([]byte(`<order><buyer id="a"><name>A</name><country>XX</country></buyer></order>`))
+	if err := schema.ValidateParallel(small, ParallelOptions{}); err == nil || err.Error() != schema.Validate(small).Error() {
+		t.Errorf("Expected the issues of Validate, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package xmlparser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The Twirp service exchanges messages in the protocol buffers wire format without
+// depending on a protobuf library: its messages (see proto/validatexml/v1) only have
+// string, bytes, bool, int32 and embedded message fields, which take a few lines to
+// encode and decode.
+
+// Wire types of protobuf fields.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// protoField is a field read from an encoded message.
+type protoField struct {
+	number   int
+	wireType int
+	varint   uint64 // Value of varint fields
+	bytes    []byte // Value of length-delimited fields
+}
+
+// parseProtoFields splits an encoded message into its fields, in order.
+func parseProtoFields(data []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := protoUvarint(data)
+		if n == 0 {
+			return nil, errors.New("truncated field key")
+		}
+		data = data[n:]
+
+		field := protoField{number: int(key >> 3), wireType: int(key & 7)}
+		switch field.wireType {
+		case protoVarint:
+			if field.varint, n = protoUvarint(data); n == 0 {
+				return nil, fmt.Errorf("truncated varint in field %d", field.number)
+			}
+		case protoBytes:
+			length, m := protoUvarint(data)
+			if m == 0 || length > uint64(len(data)-m) {
+				return nil, fmt.Errorf("truncated bytes in field %d", field.number)
+			}
+			field.bytes, n = data[m:m+int(length)], m+int(length)
+		case protoFixed64, protoFixed32:
+			if n = 8; field.wireType == protoFixed32 {
+				n = 4
+			}
+			if len(data) < n {
+				return nil, fmt.Errorf("truncated fixed-size value in field %d", field.number)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", field.wireType, field.number)
+		}
+		data = data[n:]
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// protoUvarint decodes a varint, returning the number of bytes read, 0 if it is truncated.
+func protoUvarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// appendProtoVarint appends a varint.
+func appendProtoVarint(b []byte, value uint64) []byte {
+	for value >= 0x80 {
+		b = append(b, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(b, byte(value))
+}
+
+// appendProtoBytes appends a length-delimited field, omitted when empty as in proto3.
+func appendProtoBytes(b []byte, number int, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+	b = appendProtoVarint(b, uint64(number)<<3|protoBytes)
+	b = appendProtoVarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// appendProtoString appends a string field, omitted when empty.
+func appendProtoString(b []byte, number int, value string) []byte {
+	return appendProtoBytes(b, number, []byte(value))
+}
+
+// appendProtoInt appends an int32 or bool field, omitted when zero.
+func appendProtoInt(b []byte, number int, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = appendProtoVarint(b, uint64(number)<<3|protoVarint)
+	return appendProtoVarint(b, uint64(value))
+}
@@ -0,0 +1,166 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Prune removes all global elements and named types that are not reachable from the
+// given root elements. It is intended for large schema sets, composed from many imports
+// and includes, of which a service only validates a handful of message types: pruning
+// right after ParseXSD releases the unused components and shrinks the lookup maps.
+//
+// Root elements are given by the names used in ElementMap (imported elements carry their
+// namespace prefix, e.g. "common:email"). After pruning, documents whose root is not one of
+// the selected elements are rejected by Validate.
+func (s *Schema) Prune(rootElements ...string) error {
+	if len(rootElements) == 0 {
+		return fmt.Errorf("prune requires at least one root element")
+	}
+
+	pruner := &schemaPruner{
+		schema:       s,
+		elements:     make(map[*Element]bool),
+		complexTypes: make(map[*ComplexType]bool),
+		simpleTypes:  make(map[*SimpleType]bool),
+	}
+
+	for _, name := range rootElements {
+		element, exists := s.ElementMap[name]
+		if !exists {
+			return fmt.Errorf("root element '%s' is not defined in the schema", name)
+		}
+		pruner.elements[element] = true
+		pruner.markElement(element)
+	}
+
+	// Collect the reachable components before the slices are replaced
+	elements := make([]Element, 0, len(pruner.elements))
+	for i := range s.Elements {
+		if pruner.elements[&s.Elements[i]] {
+			elements = append(elements, s.Elements[i])
+		}
+	}
+	complexTypes := make([]ComplexType, 0, len(pruner.complexTypes))
+	for i := range s.ComplexTypes {
+		if pruner.complexTypes[&s.ComplexTypes[i]] {
+			complexTypes = append(complexTypes, s.ComplexTypes[i])
+		}
+	}
+	simpleTypes := make([]SimpleType, 0, len(pruner.simpleTypes))
+	for i := range s.SimpleTypes {
+		if pruner.simpleTypes[&s.SimpleTypes[i]] {
+			simpleTypes = append(simpleTypes, s.SimpleTypes[i])
+		}
+	}
+
+	s.Elements = elements
+	s.ComplexTypes = complexTypes
+	s.SimpleTypes = simpleTypes
+
+	if err := s.buildLookupMaps(); err != nil {
+		return fmt.Errorf("failed to rebuild lookup maps after pruning: %w", err)
+	}
+	return s.compile()
+}
+
+// schemaPruner tracks the components reachable from a set of root elements.
+type schemaPruner struct {
+	schema       *Schema
+	elements     map[*Element]bool
+	complexTypes map[*ComplexType]bool
+	simpleTypes  map[*SimpleType]bool
+}
+
+// markElement marks the types used by an element declaration as reachable.
+func (p *schemaPruner) markElement(element *Element) {
+	if element.ComplexType != nil {
+		p.markComplexTypeContent(element.ComplexType)
+	}
+	if element.SimpleType != nil {
+		p.markSimpleTypeContent(element.SimpleType)
+	}
+	p.markTypeReference(element.Type, element.typeRef)
+}
+
+// markTypeReference marks a named complex or simple type as reachable.
+func (p *schemaPruner) markTypeReference(typeName string, ref xml.Name) {
+	if typeName == "" || strings.HasPrefix(typeName, "xs:") {
+		return
+	}
+
+	if complexType := p.schema.lookupComplexType(typeName, ref); complexType != nil {
+		if !p.complexTypes[complexType] {
+			p.complexTypes[complexType] = true
+			p.markComplexTypeContent(complexType)
+		}
+		return
+	}
+
+	if simpleType := p.schema.lookupSimpleType(typeName, ref); simpleType != nil && !p.simpleTypes[simpleType] {
+		p.simpleTypes[simpleType] = true
+		p.markSimpleTypeContent(simpleType)
+	}
+}
+
+// markComplexTypeContent marks everything referenced from a complex type's base type, particles and attributes.
+func (p *schemaPruner) markComplexTypeContent(complexType *ComplexType) {
+	if complexType.ComplexContent != nil {
+		if base := p.schema.complexTypeNSMap[complexType.base]; base != nil && !p.complexTypes[base] {
+			p.complexTypes[base] = true
+			p.markComplexTypeContent(base)
+		}
+	}
+	if complexType.Sequence != nil {
+		p.markSequence(complexType.Sequence)
+	}
+	if complexType.Choice != nil {
+		p.markChoice(complexType.Choice)
+	}
+	if complexType.All != nil {
+		for i := range complexType.All.Elements {
+			p.markElement(&complexType.All.Elements[i])
+		}
+	}
+	for i := range complexType.Attributes {
+		attribute := &complexType.Attributes[i]
+		if attribute.SimpleType != nil {
+			p.markSimpleTypeContent(attribute.SimpleType)
+		}
+		p.markTypeReference(attribute.Type, attribute.typeRef)
+	}
+}
+
+// markSequence marks the element, sequence, and choice particles of an xs:sequence.
+func (p *schemaPruner) markSequence(sequence *Sequence) {
+	for i := range sequence.Elements {
+		p.markElement(&sequence.Elements[i])
+	}
+	for i := range sequence.Sequences {
+		p.markSequence(&sequence.Sequences[i])
+	}
+	for i := range sequence.Choices {
+		p.markChoice(&sequence.Choices[i])
+	}
+}
+
+// markChoice marks the element, sequence, and nested choice particles of an xs:choice.
+func (p *schemaPruner) markChoice(choice *Choice) {
+	for i := range choice.Elements {
+		p.markElement(&choice.Elements[i])
+	}
+	for i := range choice.Sequences {
+		p.markSequence(&choice.Sequences[i])
+	}
+	for i := range choice.Choices {
+		p.markChoice(&choice.Choices[i])
+	}
+}
+
+// markSimpleTypeContent marks the base type of a simple type restriction.
+func (p *schemaPruner) markSimpleTypeContent(simpleType *SimpleType) {
+	if simpleType.Restriction != nil {
+		p.markTypeReference(simpleType.Restriction.Base, simpleType.Restriction.baseRef)
+	}
+}
@@ -0,0 +1,108 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test pruning of components unreachable from the selected root elements
+func TestPruneUnusedComponents(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="CodeBase">
+        <xs:restriction base="xs:string">
+            <xs:maxLength value="3"/>
+        </xs:restriction>
+    </xs:simpleType>
+
+    <xs:simpleType name="CurrencyCode">
+        <xs:restriction base="CodeBase">
+            <xs:enumeration value="EUR"/>
+            <xs:enumeration value="USD"/>
+        </xs:restriction>
+    </xs:simpleType>
+
+    <xs:simpleType name="ReportTitle">
+        <xs:restriction base="xs:string"/>
+    </xs:simpleType>
+
+    <xs:complexType name="MoneyType">
+        <xs:sequence>
+            <xs:element name="amount" type="xs:decimal"/>
+        </xs:sequence>
+        <xs:attribute name="currency" type="CurrencyCode" use="required"/>
+    </xs:complexType>
+
+    <xs:complexType name="ReportType">
+        <xs:sequence>
+            <xs:element name="title" type="ReportTitle"/>
+        </xs:sequence>
+    </xs:complexType>
+
+    <xs:element name="payment">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="total" type="MoneyType"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+
+    <xs:element name="report" type="ReportType"/>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	if err := schema.Prune("payment"); err != nil {
+		t.Fatalf("Failed to prune schema: %v", err)
+	}
+
+	if _, exists := schema.ElementMap["report"]; exists {
+		t.Error("Expected unused root element 'report' to be pruned")
+	}
+	if _, exists := schema.ComplexTypeMap["ReportType"]; exists {
+		t.Error("Expected unused complex type 'ReportType' to be pruned")
+	}
+	if _, exists := schema.SimpleTypeMap["ReportTitle"]; exists {
+		t.Error("Expected unused simple type 'ReportTitle' to be pruned")
+	}
+	for _, name := range []string{"CurrencyCode", "CodeBase"} {
+		if _, exists := schema.SimpleTypeMap[name]; !exists {
+			t.Errorf("Expected reachable simple type '%s' to be kept", name)
+		}
+	}
+	if _, exists := schema.ComplexTypeMap["MoneyType"]; !exists {
+		t.Error("Expected reachable complex type 'MoneyType' to be kept")
+	}
+
+	doc, err := Parse([]byte(`<payment><total currency="GBP"><amount>1.00</amount></total></payment>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	expectValidationError(t, schema.Validate(doc), "not in the list of allowed values")
+
+	doc, err = Parse([]byte(`<report><title>Q3</title></report>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	expectValidationError(t, schema.Validate(doc), "root element <report> is not defined")
+}
+
+func TestPruneErrors(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="test" type="xs:string"/>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	if err := schema.Prune(); err == nil || !strings.Contains(err.Error(), "at least one root element") {
+		t.Errorf("Expected error for missing root elements, got: %v", err)
+	}
+	if err := schema.Prune("missing"); err == nil || !strings.Contains(err.Error(), "root element 'missing' is not defined") {
+		t.Errorf("Expected error for unknown root element, got: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// qualifiedName resolves a QName written in the schema document with the document's own
+// namespace declarations; unprefixed names without a default namespace are in the target
+// namespace. It reports false when the prefix is not declared.
+func (s *Schema) qualifiedName(qname string) (xml.Name, bool) {
+	resolved := s.ResolveQName(qname)
+	if resolved.Namespace == "" {
+		if resolved.Prefix != "" {
+			return xml.Name{}, false
+		}
+		resolved.Namespace = s.TargetNamespace
+	}
+	return xml.Name{Space: resolved.Namespace, Local: resolved.LocalName}, true
+}
+
+// typeReference resolves a type reference of the schema document. References to built-in
+// types are returned with the xs: prefix the validator recognizes, whatever prefix the
+// document binds to the XML Schema namespace. Other references are returned unchanged
+// together with their resolved name, which is the zero Name when the prefix is not declared.
+func (s *Schema) typeReference(typeName string) (string, xml.Name) {
+	if typeName == "" {
+		return "", xml.Name{}
+	}
+	name, ok := s.qualifiedName(typeName)
+	switch {
+	case !ok:
+		return typeName, xml.Name{}
+	case name.Space == xsdNamespace:
+		return "xs:" + name.Local, xml.Name{}
+	default:
+		return typeName, name
+	}
+}
+
+// resolveReferences resolves the type, base and element references of a schema document
+// with its namespace declarations. Once documents are merged, prefixes only have meaning in
+// the document that declares them: each reference is looked up by namespace and local name,
+// so components of every target namespace in the schema set are found whatever prefixes
+// the documents and instances use.
+func (s *Schema) resolveReferences() {
+	s.forEachElement(func(element *Element, global bool) {
+		element.Type, element.typeRef = s.typeReference(element.Type)
+		if element.Ref != "" {
+			element.ref, _ = s.qualifiedName(element.Ref)
+		}
+		if element.SimpleType != nil {
+			s.resolveRestrictionBase(element.SimpleType)
+		}
+	})
+	s.forEachAttribute(func(attribute *Attribute, global bool) {
+		attribute.Type, attribute.typeRef = s.typeReference(attribute.Type)
+		if attribute.SimpleType != nil {
+			s.resolveRestrictionBase(attribute.SimpleType)
+		}
+	})
+	for i := range s.SimpleTypes {
+		s.resolveRestrictionBase(&s.SimpleTypes[i])
+	}
+	s.resolveComplexBases()
+}
+
+// resolveRestrictionBase resolves the base type reference of a simple type restriction.
+func (s *Schema) resolveRestrictionBase(simpleType *SimpleType) {
+	if restriction := simpleType.Restriction; restriction != nil {
+		restriction.Base, restriction.baseRef = s.typeReference(restriction.Base)
+	}
+}
+
+// resolveElementRefs completes element declarations given as ref="prefix:name" with the
+// global element they reference, which may be defined in any document of the schema set.
+// The occurrence bounds stay those of the reference.
+func (s *Schema) resolveElementRefs() error {
+	var err error
+	s.forEachElement(func(element *Element, global bool) {
+		if element.Ref == "" || element.Name != "" || err != nil {
+			return
+		}
+
+		target := s.elementNSMap[element.ref]
+		if target == nil {
+			err = fmt.Errorf("element reference '%s' does not match a global element declaration", element.Ref)
+			return
+		}
+		element.Name = ParseQName(target.Name).LocalName
+		element.Type, element.typeRef = target.Type, target.typeRef
+		element.ComplexType, element.SimpleType = target.ComplexType, target.SimpleType
+		element.Nillable = target.Nillable
+		element.qualified, element.namespace = true, target.namespace
+		if element.Annotation == nil {
+			element.Annotation = target.Annotation
+		}
+	})
+	return err
+}
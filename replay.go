@@ -0,0 +1,241 @@
+package xmlparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// modulePath is the import path of this package, used to look up its version in the build info.
+const modulePath = "github.com/moolekkari/validatexml-go"
+
+// SchemaSource is one schema document a Schema was parsed from.
+type SchemaSource struct {
+	Location string // Resolved location of a referenced schema; empty for the main schema
+	Data     []byte
+}
+
+// ReplayBundle captures everything needed to reproduce a validation: the schema documents
+// exactly as loaded (including imports and includes fetched from disk or the network), the
+// parse options, the instance document and the outcome observed. Bundles are written as a
+// single zip archive that can be attached to bug reports and replayed without access to the
+// original files. Changes made after parsing, such as Prune, are not recorded.
+type ReplayBundle struct {
+	LibraryVersion string         // Version of this package that produced the bundle
+	GoVersion      string         // Go runtime that produced the bundle
+	SchemaVersion  SchemaVersion  // ParseOptions.Version
+	BasePath       string         // ParseOptions.BasePath
+	Schemas        []SchemaSource // Main schema first, then referenced schemas in load order
+	Document       []byte         // Instance document
+	Outcome        string         // Error observed when the bundle was created; empty if the document was valid
+}
+
+// replayManifest is the JSON index stored as manifest.json in a bundle archive.
+type replayManifest struct {
+	LibraryVersion string         `json:"libraryVersion"`
+	GoVersion      string         `json:"goVersion"`
+	SchemaVersion  string         `json:"schemaVersion"`
+	BasePath       string         `json:"basePath,omitempty"`
+	Schemas        []replaySchema `json:"schemas"`
+	Document       string         `json:"document"`
+	Outcome        string         `json:"outcome,omitempty"`
+}
+
+// replaySchema maps a schema file in a bundle archive to the location it was loaded from.
+type replaySchema struct {
+	File     string `json:"file"`
+	Location string `json:"location,omitempty"`
+}
+
+// bundledSources serves referenced schemas from a replay bundle, keyed by resolved location.
+type bundledSources map[string][]byte
+
+// Resolve returns the bundled content of a resolved location.
+func (b bundledSources) Resolve(namespace, location string) (io.ReadCloser, error) {
+	data, exists := b[location]
+	if !exists {
+		return nil, fmt.Errorf("schema '%s' is not part of the replay bundle", location)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// NewReplayBundle validates document against the schema and captures the validation in a
+// bundle. The schema must have been parsed with ParseXSD or ParseXSDWithOptions.
+func (s *Schema) NewReplayBundle(document []byte) (*ReplayBundle, error) {
+	if len(s.sources) == 0 {
+		return nil, fmt.Errorf("schema was not parsed from source and cannot be bundled")
+	}
+
+	bundle := &ReplayBundle{
+		LibraryVersion: libraryVersion(),
+		GoVersion:      runtime.Version(),
+		SchemaVersion:  s.options.Version,
+		BasePath:       s.options.BasePath,
+		Schemas:        s.sources,
+		Document:       document,
+	}
+	if err := validateDocument(s, document); err != nil {
+		bundle.Outcome = err.Error()
+	}
+	return bundle, nil
+}
+
+// Write writes the bundle as a zip archive.
+func (b *ReplayBundle) Write(w io.Writer) error {
+	manifest := replayManifest{
+		LibraryVersion: b.LibraryVersion,
+		GoVersion:      b.GoVersion,
+		SchemaVersion:  b.SchemaVersion.String(),
+		BasePath:       b.BasePath,
+		Document:       "document.xml",
+		Outcome:        b.Outcome,
+	}
+
+	archive := zip.NewWriter(w)
+	for i, source := range b.Schemas {
+		file := fmt.Sprintf("schemas/%03d.xsd", i)
+		manifest.Schemas = append(manifest.Schemas, replaySchema{File: file, Location: source.Location})
+		if err := writeZipFile(archive, file, source.Data); err != nil {
+			return err
+		}
+	}
+	if err := writeZipFile(archive, manifest.Document, b.Document); err != nil {
+		return err
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(archive, "manifest.json", manifestData); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// ReadReplayBundle reads a bundle archive written by ReplayBundle.Write.
+func ReadReplayBundle(data []byte) (*ReplayBundle, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay bundle: %w", err)
+	}
+
+	manifestData, err := readZipFile(archive, "manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	var manifest replayManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid replay bundle manifest: %w", err)
+	}
+
+	bundle := &ReplayBundle{
+		LibraryVersion: manifest.LibraryVersion,
+		GoVersion:      manifest.GoVersion,
+		BasePath:       manifest.BasePath,
+		Outcome:        manifest.Outcome,
+	}
+	switch manifest.SchemaVersion {
+	case XSD10.String():
+		bundle.SchemaVersion = XSD10
+	case XSD11.String():
+		bundle.SchemaVersion = XSD11
+	default:
+		return nil, fmt.Errorf("unsupported schema version '%s' in replay bundle", manifest.SchemaVersion)
+	}
+
+	if len(manifest.Schemas) == 0 {
+		return nil, fmt.Errorf("replay bundle contains no schemas")
+	}
+	for _, schema := range manifest.Schemas {
+		schemaData, err := readZipFile(archive, schema.File)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Schemas = append(bundle.Schemas, SchemaSource{Location: schema.Location, Data: schemaData})
+	}
+	if bundle.Document, err = readZipFile(archive, manifest.Document); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// Schema parses the bundled schema with the recorded options. Imports and includes are
+// served from the bundle; nothing is read from disk or fetched from the network.
+func (b *ReplayBundle) Schema() (*Schema, error) {
+	if len(b.Schemas) == 0 {
+		return nil, fmt.Errorf("replay bundle contains no schemas")
+	}
+	sources := make(bundledSources, len(b.Schemas)-1)
+	for _, source := range b.Schemas[1:] {
+		sources[source.Location] = source.Data
+	}
+	return ParseXSDWithOptions(b.Schemas[0].Data, ParseOptions{
+		BasePath: b.BasePath,
+		Version:  b.SchemaVersion,
+		Resolver: sources,
+	})
+}
+
+// Validate replays the validation and returns its result. The replay reproduces the
+// recorded behavior when the error message equals Outcome (or both are empty).
+func (b *ReplayBundle) Validate() error {
+	schema, err := b.Schema()
+	if err != nil {
+		return err
+	}
+	return validateDocument(schema, b.Document)
+}
+
+// validateDocument parses and validates a document, as a replay bundle records it.
+func validateDocument(schema *Schema, document []byte) error {
+	doc, err := Parse(document)
+	if err != nil {
+		return fmt.Errorf("failed to parse document: %w", err)
+	}
+	return schema.Validate(doc)
+}
+
+// libraryVersion returns the module version of this package from the build info.
+func libraryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return dep.Version + " => " + dep.Replace.Path + " " + dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// writeZipFile adds a file to a zip archive.
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// readZipFile reads a file from a zip archive.
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	f, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("replay bundle is missing '%s': %w", name, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
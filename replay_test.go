@@ -0,0 +1,97 @@
+package xmlparser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that a replay bundle reproduces a validation without the original schema files
+func TestReplayBundle(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	commonSchema := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+           targetNamespace="http://example.com/common">
+    <xs:simpleType name="CurrencyCode">
+        <xs:restriction base="xs:string">
+            <xs:pattern value="[A-Z]{3}"/>
+        </xs:restriction>
+    </xs:simpleType>
+</xs:schema>`
+	addressSchema := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:complexType name="AddressType">
+        <xs:sequence>
+            <xs:element name="city" type="xs:string"/>
+        </xs:sequence>
+    </xs:complexType>
+</xs:schema>`
+	if err := os.MkdirAll(filepath.Join(tmpDir, "common"), 0755); err != nil {
+		t.Fatalf("Failed to create schema directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "common", "common.xsd"), []byte(commonSchema), 0644); err != nil {
+		t.Fatalf("Failed to write schema file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "address.xsd"), []byte(addressSchema), 0644); err != nil {
+		t.Fatalf("Failed to write schema file: %v", err)
+	}
+
+	mainSchema := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+           xmlns:common="http://example.com/common">
+    <xs:import namespace="http://example.com/common" schemaLocation="common/common.xsd"/>
+    <xs:include schemaLocation="address.xsd"/>
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="currency" type="common:CurrencyCode"/>
+                <xs:element name="address" type="AddressType"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`
+
+	schema, err := ParseXSDWithOptions([]byte(mainSchema), ParseOptions{BasePath: tmpDir, Version: XSD11})
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	document := []byte(`<order><currency>euro</currency><address><city>Oslo</city></address></order>`)
+	bundle, err := schema.NewReplayBundle(document)
+	if err != nil {
+		t.Fatalf("NewReplayBundle failed: %v", err)
+	}
+	if bundle.Outcome == "" || len(bundle.Schemas) != 3 || bundle.GoVersion == "" {
+		t.Fatalf("Expected a failed outcome and three schemas, got outcome %q and %d schemas",
+			bundle.Outcome, len(bundle.Schemas))
+	}
+
+	var archive bytes.Buffer
+	if err := bundle.Write(&archive); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+
+	// The original schema files are no longer needed
+	os.RemoveAll(tmpDir)
+
+	replayed, err := ReadReplayBundle(archive.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to read bundle: %v", err)
+	}
+	if replayed.SchemaVersion != XSD11 || !bytes.Equal(replayed.Document, document) {
+		t.Errorf("Bundle did not round trip: version %s, document %q", replayed.SchemaVersion, replayed.Document)
+	}
+
+	replayErr := replayed.Validate()
+	if replayErr == nil || replayErr.Error() != bundle.Outcome {
+		t.Errorf("Expected replay to reproduce %q, got: %v", bundle.Outcome, replayErr)
+	}
+	expectValidationError(t, replayErr, "does not match pattern")
+
+	if _, err := ReadReplayBundle([]byte("not a zip archive")); err == nil {
+		t.Error("Expected error reading an invalid bundle")
+	}
+}
@@ -0,0 +1,132 @@
+package xmlparser
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Report returns the issues of the error as a ValidationReport for messages of the given
+// type, to return to clients as JSON or XML, or as SARIF with MarshalSARIF. A nil error
+// gives the report of a valid message.
+func (e *ValidationError) Report(messageType string) ValidationReport {
+	if e == nil {
+		return ValidationReport{MessageType: messageType, Valid: true}
+	}
+	return ValidationReport{MessageType: messageType, Issues: e.Issues, Omitted: e.Omitted}
+}
+
+// Report returns the issues and warnings of the result as a ValidationReport.
+func (r *Result) Report(messageType string) ValidationReport {
+	report := ValidationReport{MessageType: messageType, Valid: true, Warnings: r.Warnings}
+	if err, ok := r.Err().(*ValidationError); ok {
+		report.Valid, report.Issues, report.Omitted = false, err.Issues, err.Omitted
+	}
+	return report
+}
+
+// SARIFVersion is the version of the Static Analysis Results Interchange Format (SARIF)
+// MarshalSARIF writes.
+const SARIFVersion = "2.1.0"
+
+// MarshalSARIF encodes the report as a SARIF log, which code scanning tools and CI
+// systems display natively. The issues and warnings become the results of one run, each
+// with the issue code as its rule, located in the document at documentURI by line and
+// column and by XPath; the XML Schema rule of each issue is kept as a property.
+func (r ValidationReport) MarshalSARIF(documentURI string) ([]byte, error) {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = "validatexml"
+	run.Tool.Driver.InformationURI = "https://github.com/moolekkari/validatexml-go"
+	if r.Omitted > 0 {
+		run.Properties = map[string]any{"omitted": r.Omitted}
+	}
+
+	codes := make(map[IssueCode]bool)
+	for _, issue := range append(append([]ValidationIssue(nil), r.Issues...), r.Warnings...) {
+		codes[issue.Code] = true
+		result := sarifResult{RuleID: string(issue.Code), Level: "error", Message: sarifMessage{Text: issue.Message}}
+		if issue.Severity == SeverityWarning {
+			result.Level = "warning"
+		}
+		location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: documentURI}}}
+		if issue.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: issue.Line, StartColumn: issue.Column}
+		}
+		if issue.XPath != "" {
+			kind := "element"
+			if strings.Contains(issue.XPath, "/@") {
+				kind = "attribute"
+			}
+			location.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: issue.XPath, Kind: kind}}
+		}
+		result.Locations = []sarifLocation{location}
+		if issue.Rule != "" {
+			result.Properties = map[string]any{"rule": issue.Rule}
+		}
+		run.Results = append(run.Results, result)
+	}
+	for code := range codes {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: string(code)})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	return json.Marshal(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: SARIFVersion,
+		Runs:    []sarifRun{run},
+	})
+}
+
+// The subset of the SARIF 2.1.0 object model MarshalSARIF writes.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool struct {
+			Driver struct {
+				Name           string      `json:"name"`
+				InformationURI string      `json:"informationUri"`
+				Rules          []sarifRule `json:"rules,omitempty"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results    []sarifResult  `json:"results"`
+		Properties map[string]any `json:"properties,omitempty"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID     string          `json:"ruleId"`
+		Level      string          `json:"level"`
+		Message    sarifMessage    `json:"message"`
+		Locations  []sarifLocation `json:"locations"`
+		Properties map[string]any  `json:"properties,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+		LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+	sarifLogicalLocation struct {
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		Kind               string `json:"kind"`
+	}
+)
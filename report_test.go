@@ -0,0 +1,99 @@
+package xmlparser
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger"/>
+      </xs:sequence>
+      <xs:anyAttribute namespace="##other" processContents="skip"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	doc, err := Parse([]byte(`<order xmlns:e="urn:ext" e:batch="7">
+  <quantity>0</quantity>
+</order>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	result := schema.ValidateResult(doc)
+	encoded, err := json.Marshal(result.Report("order"))
+	if err != nil {
+		t.Fatalf("Failed to marshal report: %v", err)
+	}
+	expected := `{"messageType":"order","valid":false,"issues":[{"code":"invalid-value",` +
+		`"message":"in element \u003cquantity\u003e: value '0' must be positive (at /order/quantity[1])",` +
+		`"severity":"error","rule":"cvc-datatype-valid.1.2.1","xpath":"/order/quantity[1]","line":2,"column":3}],` +
+		`"warnings":[{"code":"not-checked","message":"attribute 'batch' in element \u003corder\u003e is not checked: ` +
+		`it matches an attribute wildcard with processContents skip (at /order/@e:batch)","severity":"warning",` +
+		`"xpath":"/order/@e:batch","line":1,"column":26}]}`
+	if string(encoded) != expected {
+		t.Errorf("Unexpected JSON report:\n%s\nexpected:\n%s", encoded, expected)
+	}
+	if report := (*ValidationError)(nil).Report("order"); !report.Valid || report.MessageType != "order" {
+		t.Errorf("Expected a valid report for a nil error, got %+v", report)
+	}
+
+	sarif, err := result.Report("order").MarshalSARIF("orders/a.xml")
+	if err != nil {
+		t.Fatalf("Failed to marshal SARIF: %v", err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Level     string
+				Message   struct{ Text string }
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine, StartColumn int }
+					}
+					LogicalLocations []struct{ FullyQualifiedName, Kind string }
+				}
+				Properties map[string]string
+			}
+		}
+	}
+	if err := json.Unmarshal(sarif, &log); err != nil {
+		t.Fatalf("Failed to decode SARIF: %v", err)
+	}
+	if log.Version != SARIFVersion || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "validatexml" {
+		t.Fatalf("Unexpected SARIF log: %s", sarif)
+	}
+	run := log.Runs[0]
+	if rules := run.Tool.Driver.Rules; len(rules) != 2 || rules[0].ID != "invalid-value" || rules[1].ID != "not-checked" {
+		t.Errorf("Expected the issue codes as rules, got %+v", rules)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("Expected an issue and a warning, got %+v", run.Results)
+	}
+	issue, warning := run.Results[0], run.Results[1]
+	location := issue.Locations[0]
+	if issue.RuleID != "invalid-value" || issue.Level != "error" || issue.Properties["rule"] != "cvc-datatype-valid.1.2.1" ||
+		location.PhysicalLocation.ArtifactLocation.URI != "orders/a.xml" || location.PhysicalLocation.Region.StartLine != 2 ||
+		location.LogicalLocations[0].FullyQualifiedName != "/order/quantity[1]" || location.LogicalLocations[0].Kind != "element" {
+		t.Errorf("Unexpected SARIF result for the issue: %+v", issue)
+	}
+	if warning.RuleID != "not-checked" || warning.Level != "warning" || warning.Locations[0].LogicalLocations[0].Kind != "attribute" {
+		t.Errorf("Unexpected SARIF result for the warning: %+v", warning)
+	}
+}
@@ -0,0 +1,116 @@
+package xmlparser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+)
+
+// Resolver loads the schema documents referenced by xs:import and xs:include, replacing
+// the default loading from the filesystem and over http(s). It lets callers serve schemas
+// from an embed.FS, a database or an XML catalog, or block network access entirely.
+type Resolver interface {
+	// Resolve returns the content of the schema document at location: the schemaLocation
+	// resolved against the location of the referencing document, as a file path or an
+	// http(s) URL. namespace is the namespace of an xs:import, and empty for xs:include and
+	// for the main document of ParseXSDFromLocation.
+	Resolve(namespace, location string) (io.ReadCloser, error)
+}
+
+// ContextResolver is a Resolver that can stop loading a schema document when a context is
+// canceled. ParseXSDContext passes its context to resolvers implementing it; the default
+// loading and CatalogResolver do.
+type ContextResolver interface {
+	Resolver
+	ResolveContext(ctx context.Context, namespace, location string) (io.ReadCloser, error)
+}
+
+// ResolverFunc adapts a function to the Resolver interface.
+type ResolverFunc func(namespace, location string) (io.ReadCloser, error)
+
+// Resolve calls f(namespace, location).
+func (f ResolverFunc) Resolve(namespace, location string) (io.ReadCloser, error) {
+	return f(namespace, location)
+}
+
+// FSResolver returns a Resolver reading schema documents from a file system such as an
+// embed.FS. Locations are relative paths within fsys, resolved as usual against the
+// directory of the referencing document (ParseOptions.BasePath for the main schema). URLs
+// are rejected, so nothing is fetched from the network.
+func FSResolver(fsys fs.FS) Resolver {
+	return ResolverFunc(func(namespace, location string) (io.ReadCloser, error) {
+		if isRemoteLocation(location) {
+			return nil, fmt.Errorf("schema '%s' is not in the file system: remote locations are not resolved", location)
+		}
+		return fsys.Open(path.Clean(filepath.ToSlash(location)))
+	})
+}
+
+// defaultResolver loads schemas from the filesystem and, unless noNetwork is set, over
+// http(s) with client (defaultHTTPClient if nil).
+type defaultResolver struct {
+	noNetwork bool
+	client    *http.Client
+}
+
+// Resolve loads the schema at location with loadSchema.
+func (r defaultResolver) Resolve(namespace, location string) (io.ReadCloser, error) {
+	return r.ResolveContext(context.Background(), namespace, location)
+}
+
+// ResolveContext loads the schema at location with loadSchema, fetching URLs with ctx.
+func (r defaultResolver) ResolveContext(ctx context.Context, namespace, location string) (io.ReadCloser, error) {
+	if r.noNetwork && isRemoteLocation(location) {
+		return nil, fmt.Errorf("schema '%s' was not fetched: network access is disabled", location)
+	}
+	client := r.client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	data, err := loadSchema(ctx, client, location)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// loadTraced reads a schema document with readSchema in an "xmlparser.LoadSchema" span.
+func loadTraced(ctx context.Context, tracer Tracer, resolver Resolver, namespace, location string) ([]byte, error) {
+	ctx, span := startSpan(ctx, tracer, "xmlparser.LoadSchema",
+		SpanAttribute{"xsd.location", location}, SpanAttribute{"xsd.namespace", namespace})
+	data, err := readSchema(ctx, resolver, namespace, location)
+	span.SetAttributes(SpanAttribute{"xsd.bytes", len(data)})
+	span.End(err)
+	return data, err
+}
+
+// readSchema returns the content of a schema document through a resolver, unless ctx is
+// done. The context is passed on to resolvers implementing ContextResolver.
+func readSchema(ctx context.Context, resolver Resolver, namespace, location string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("schema '%s' was not loaded: %w", location, err)
+	}
+
+	var reader io.ReadCloser
+	var err error
+	if contextResolver, ok := resolver.(ContextResolver); ok {
+		reader, err = contextResolver.ResolveContext(ctx, namespace, location)
+	} else {
+		reader, err = resolver.Resolve(namespace, location)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema '%s': %w", location, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,314 @@
+package xmlparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// Test that imports and includes are loaded through a Resolver, here from an in-memory file system
+func TestFSResolver(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schemas/main.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	xmlns:ext="urn:example:ext">
+	<xs:include schemaLocation="common/types.xsd"/>
+	<xs:element name="order">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="code" type="Code"/>
+				<xs:element name="priority" type="ext:Priority"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`)},
+		"schemas/common/types.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:import namespace="urn:example:ext" schemaLocation="../ext/priority.xsd"/>
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string">
+			<xs:pattern value="[A-Z]{3}"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`)},
+		"schemas/ext/priority.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	targetNamespace="urn:example:ext">
+	<xs:simpleType name="Priority">
+		<xs:restriction base="xs:integer">
+			<xs:minInclusive value="1"/>
+			<xs:maxInclusive value="5"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`)},
+	}
+
+	var calls []string
+	resolver := FSResolver(fsys)
+	recording := ResolverFunc(func(namespace, location string) (io.ReadCloser, error) {
+		calls = append(calls, namespace+" "+location)
+		return resolver.Resolve(namespace, location)
+	})
+
+	schema, err := ParseXSDFromLocation("schemas/main.xsd", ParseOptions{Resolver: recording})
+	if err != nil {
+		t.Fatalf("Failed to parse schema from the file system: %v", err)
+	}
+
+	expectedCalls := []string{" schemas/main.xsd", " schemas/common/types.xsd", "urn:example:ext schemas/ext/priority.xsd"}
+	if strings.Join(calls, "|") != strings.Join(expectedCalls, "|") {
+		t.Errorf("Expected resolver calls %q, got %q", expectedCalls, calls)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Valid document",
+			xml:        `<order><code>ABC</code><priority>3</priority></order>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Included type",
+			xml:         `<order><code>abc</code><priority>3</priority></order>`,
+			shouldPass:  false,
+			errorString: "does not match pattern",
+		},
+		{
+			name:        "Imported type",
+			xml:         `<order><code>ABC</code><priority>9</priority></order>`,
+			shouldPass:  false,
+			errorString: "value '9' exceeds maximum allowed value 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test that resolver errors fail the parse, so network access can be blocked
+func TestResolverErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      string
+		resolver    Resolver
+		errorString string
+	}{
+		{
+			name: "Remote import with a file system resolver",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:import namespace="urn:example:ext" schemaLocation="https://example.com/ext.xsd"/>
+</xs:schema>`,
+			resolver:    FSResolver(fstest.MapFS{}),
+			errorString: "schema 'https://example.com/ext.xsd' is not in the file system: remote locations are not resolved",
+		},
+		{
+			name: "Missing file",
+			schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="missing.xsd"/>
+</xs:schema>`,
+			resolver:    FSResolver(fstest.MapFS{}),
+			errorString: "open missing.xsd: file does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseXSDWithOptions([]byte(tt.schema), ParseOptions{Resolver: tt.resolver})
+			if err == nil || !strings.Contains(err.Error(), tt.errorString) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errorString, err)
+			}
+		})
+	}
+}
+
+// Test that NoNetwork turns remote fetches into errors without contacting the server
+func TestNoNetwork(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="Code">
+		<xs:restriction base="xs:string"/>
+	</xs:simpleType>
+</xs:schema>`))
+	}))
+	defer server.Close()
+
+	schemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="` + server.URL + `/types.xsd"/>
+	<xs:element name="code" type="Code"/>
+</xs:schema>`
+
+	_, err := ParseXSDWithOptions([]byte(schemaContent), ParseOptions{NoNetwork: true})
+	expected := "schema '" + server.URL + "/types.xsd' was not fetched: network access is disabled"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error containing %q, got: %v", expected, err)
+	}
+	if _, err := ParseXSDFromLocation(server.URL+"/main.xsd", ParseOptions{NoNetwork: true}); err == nil {
+		t.Error("Expected an error for a remote main schema")
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request to reach the server, got %d", requests)
+	}
+
+	if _, err := ParseXSDWithOptions([]byte(schemaContent), ParseOptions{}); err != nil {
+		t.Errorf("Expected the remote include to load by default, got: %v", err)
+	}
+}
+
+// Test that remote schemas are fetched with the configured HTTP client
+func TestHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/slow.xsd" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="code" type="xs:string"/>
+</xs:schema>`))
+	}))
+	defer server.Close()
+
+	authenticated := &http.Client{Transport: headerTransport{"Authorization": "Bearer secret"}}
+	tests := []struct {
+		name        string
+		location    string
+		client      *http.Client
+		errorString string
+	}{
+		{
+			name:        "Default client",
+			location:    server.URL + "/main.xsd",
+			errorString: "HTTP 401",
+		},
+		{
+			name:     "Authenticated client",
+			location: server.URL + "/main.xsd",
+			client:   authenticated,
+		},
+		{
+			name:        "Timeout",
+			location:    server.URL + "/slow.xsd",
+			client:      &http.Client{Transport: authenticated.Transport, Timeout: 50 * time.Millisecond},
+			errorString: "Client.Timeout exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseXSDFromLocation(tt.location, ParseOptions{HTTPClient: tt.client})
+			if tt.errorString == "" {
+				if err != nil {
+					t.Errorf("Expected the schema to load, got: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.errorString) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errorString, err)
+			}
+		})
+	}
+}
+
+// headerTransport adds headers to every request.
+type headerTransport map[string]string
+
+func (h headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	for name, value := range h {
+		r.Header.Set(name, value)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// Test that ParseXSDContext stops loading referenced schemas when its context is done
+func TestParseXSDContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	schemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:include schemaLocation="` + server.URL + `/types.xsd"/>
+</xs:schema>`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := ParseXSDContext(ctx, []byte(schemaContent), ParseOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to abort the fetch, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the fetch to stop at the deadline, took %v", elapsed)
+	}
+
+	// A canceled context stops before a custom resolver is called
+	called := false
+	resolver := ResolverFunc(func(namespace, location string) (io.ReadCloser, error) {
+		called = true
+		return nil, fmt.Errorf("unexpected load of %s", location)
+	})
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ParseXSDContext(canceled, []byte(schemaContent), ParseOptions{Resolver: resolver})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a canceled error, got: %v", err)
+	}
+	if called {
+		t.Error("Expected the resolver not to be called")
+	}
+
+	// The context reaches context-aware resolvers behind a catalog
+	var seen context.Context
+	contextual := contextResolverFunc(func(ctx context.Context, namespace, location string) (io.ReadCloser, error) {
+		seen = ctx
+		return io.NopCloser(strings.NewReader(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"/>`)), nil
+	})
+	type key struct{}
+	valued := context.WithValue(context.Background(), key{}, "value")
+	if _, err := ParseXSDContext(valued, []byte(schemaContent), ParseOptions{Resolver: CatalogResolver(&Catalog{}, contextual)}); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if seen == nil || seen.Value(key{}) != "value" {
+		t.Error("Expected the resolver to receive the parse context")
+	}
+}
+
+// contextResolverFunc adapts a function to the ContextResolver interface.
+type contextResolverFunc func(ctx context.Context, namespace, location string) (io.ReadCloser, error)
+
+func (f contextResolverFunc) Resolve(namespace, location string) (io.ReadCloser, error) {
+	return f(context.Background(), namespace, location)
+}
+
+func (f contextResolverFunc) ResolveContext(ctx context.Context, namespace, location string) (io.ReadCloser, error) {
+	return f(ctx, namespace, location)
+}
@@ -0,0 +1,244 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Result is the outcome of ValidateAndAnnotate: the issues found, statistics, and for each
+// validated element and attribute the information a post-schema-validation infoset (PSVI)
+// carries, such as the declaration used, the type and the normalized value. It gives a
+// single place for result data, where Validate only reports issues through its error.
+type Result struct {
+	Issues  []string        // Validation issues in document order; empty when the document is valid
+	Omitted int             // Issues found but not stored because the limit was reached (see ValidationError)
+	Stats   ValidationStats // Counts and timing of the validation
+
+	// Warnings of what validation did not check, such as facets it does not support or
+	// attributes skipped by an attribute wildcard, in the order found; see
+	// ValidateOptions.OnWarning. A valid document with warnings was only partly checked.
+	Warnings []ValidationIssue
+
+	// Value of the shard key registered for the root element (see RegisterShardKey), and
+	// whether the document has it; invalid documents still report the value found
+	ShardKey      string
+	ShardKeyFound bool
+
+	// Facet failures among the issues, in document order, with the value each facet was
+	// checked against
+	FacetFailures []FacetFailure
+
+	elements   map[*Node]*ElementInfo
+	attributes map[*Node][]AttributeInfo
+	err        *ValidationError // Issues with their locations, for Err
+}
+
+// ValidationStats describes the work done by one validation.
+type ValidationStats struct {
+	Elements   int           // Elements validated against a declaration
+	Attributes int           // Attributes validated against a declaration, excluding namespace declarations
+	Duration   time.Duration // Time spent validating
+
+	// Elements and attributes among those validated that failed; an element fails when
+	// it or any of its descendants has an issue, so the root fails with any element
+	InvalidElements   int
+	InvalidAttributes int
+}
+
+// ElementInfo annotates an element with the outcome of its validation.
+type ElementInfo struct {
+	Declaration *Element // Declaration the element was validated against
+	TypeName    string   // Type used, including an xsi:type override; empty for anonymous types
+	Valid       bool     // Whether the element and its descendants have no issues
+
+	// Value of elements with simple content after whitespace normalization for its type
+	// (e.g. collapsed for xs:token and xs:integer); empty for elements with complex content
+	NormalizedValue string
+}
+
+// AttributeInfo annotates an attribute with the outcome of its validation.
+type AttributeInfo struct {
+	Name            xml.Name
+	Declaration     *Attribute
+	NormalizedValue string // Value after whitespace normalization for its type
+	Valid           bool   // Whether the value has no issues
+	Defaulted       bool   // Whether the attribute is absent and its value is the declared default
+}
+
+// FacetFailure details an issue reported by a facet of a simple type, such as maxLength or
+// pattern, with the exact string the facet was checked against. For element content that
+// is the text with entity and character references resolved and leading and trailing
+// whitespace removed; for attributes, the value with references resolved. Whitespace inside
+// the value is not normalized before facets are checked.
+type FacetFailure struct {
+	Node      *Node     // Element whose content or attribute failed
+	Attribute xml.Name  // Attribute whose value failed; empty for element content
+	Kind      FacetKind // Facets that failed
+	Value     string    // String the facets were checked against
+	Length    int       // Length of Value as measured by the length facets (octets for binary types)
+	Message   string    // Issue reported for the failure, as in Issues
+}
+
+// ValidateAndAnnotate validates the document like Validate and returns the issues together
+// with statistics and per-node annotations. An error is returned only when the document
+// cannot be validated at all, e.g. because its root element is not declared; issues found
+// during validation are reported in the Result.
+func (s *Schema) ValidateAndAnnotate(doc *Document) (Result, error) {
+	start := time.Now()
+	rootDef, rootErr := s.rootDeclaration(doc, ValidateOptions{})
+	if rootErr != nil {
+		return Result{}, rootErr
+	}
+	return s.annotate(doc, rootDef, start), nil
+}
+
+// ValidateResult validates the document like ValidateAndAnnotate but always returns a
+// Result, so dashboards and batch QA tooling can report every document the same way: one
+// that cannot be validated at all has that failure as its only issue, and no annotations.
+// Stats tells how much of the document passed, and Element the outcome of each element.
+func (s *Schema) ValidateResult(doc *Document) Result {
+	start := time.Now()
+	rootDef, rootErr := s.rootDeclaration(doc, ValidateOptions{})
+	if rootErr != nil {
+		result := Result{err: rootErr, Issues: rootErr.Messages(), Omitted: rootErr.Omitted}
+		result.Stats.Duration = time.Since(start)
+		return result
+	}
+	return s.annotate(doc, rootDef, start)
+}
+
+// annotate validates the document from its root declaration, collecting a Result.
+func (s *Schema) annotate(doc *Document, rootDef *Element, start time.Time) Result {
+	result := Result{
+		elements:   make(map[*Node]*ElementInfo),
+		attributes: make(map[*Node][]AttributeInfo),
+	}
+	v := newValidator(s, ValidateOptions{})
+	v.result = &result
+
+	v.validateNode(doc.Root, rootDef)
+	if issues := append(v.issues, v.checkIDReferences()...); len(issues) > 0 {
+		result.err = newValidationError(issues)
+		result.Issues, result.Omitted = result.err.Messages(), result.err.Omitted
+	}
+	result.ShardKey, result.ShardKeyFound = s.extractShardKey(doc.Root, rootDef)
+	result.Stats.Duration = time.Since(start)
+	return result
+}
+
+// Valid reports whether the document has no issues.
+func (r *Result) Valid() bool {
+	return len(r.Issues) == 0 && r.Omitted == 0
+}
+
+// Err returns the issues as a *ValidationError, as Validate would, or nil if there are none.
+func (r *Result) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	if r.err == nil {
+		issues := make([]ValidationIssue, len(r.Issues))
+		for i, message := range r.Issues {
+			issues[i] = newIssue(nil, "", "", message)
+		}
+		return &ValidationError{Issues: issues, Omitted: r.Omitted}
+	}
+	return r.err
+}
+
+// Element returns the annotation of an element; ok is false for elements that were not
+// validated against a declaration, such as undeclared children.
+func (r *Result) Element(node *Node) (info ElementInfo, ok bool) {
+	if annotated, exists := r.elements[node]; exists {
+		return *annotated, true
+	}
+	return ElementInfo{}, false
+}
+
+// Attributes returns the annotations of an element's declared attributes, in document order,
+// followed by those of absent attributes that take a default value.
+func (r *Result) Attributes(node *Node) []AttributeInfo {
+	return r.attributes[node]
+}
+
+// annotateElement records the declaration and type an element is validated against.
+func (v *validator) annotateElement(node *Node, def *Element) *ElementInfo {
+	v.result.Stats.Elements++
+	info := &ElementInfo{Declaration: def, TypeName: def.Type}
+	if typeName, ok := xsiType(node); ok {
+		info.TypeName = typeName
+	}
+	v.result.elements[node] = info
+	return info
+}
+
+// annotateValue records the normalized value of an element with simple content, masked
+// when it is sensitive.
+func (v *validator) annotateValue(node *Node, content, baseType string, sensitive bool) {
+	if info := v.result.elements[node]; info != nil {
+		info.NormalizedValue = normalizeWhitespace(content, baseType)
+		if sensitive {
+			info.NormalizedValue = DefaultRedaction
+		}
+	}
+}
+
+// annotateAttribute records the validation of an attribute, or of a defaulted absent one.
+func (v *validator) annotateAttribute(node *Node, attrDef *Attribute, name xml.Name, value string, valid, defaulted bool) {
+	if !defaulted {
+		v.result.Stats.Attributes++
+		if !valid {
+			v.result.Stats.InvalidAttributes++
+		}
+	}
+	baseType := v.builtInBase(attrDef.Type, v.attributeSimpleType(attrDef))
+	v.result.attributes[node] = append(v.result.attributes[node], AttributeInfo{
+		Name:            name,
+		Declaration:     attrDef,
+		NormalizedValue: normalizeWhitespace(value, baseType),
+		Valid:           valid,
+		Defaulted:       defaulted,
+	})
+}
+
+// recordingFacets returns the facet evaluation of the validator for a value of node, or of
+// its attribute named attribute, which records the failures in the result if there is one.
+// prefix returns the location that starts the value's issues, only built when one is
+// found. Sensitive values are recorded masked, with their length.
+func (v *validator) recordingFacets(node *Node, attribute xml.Name, value, baseType string, prefix func() string, sensitive bool) facetEvaluation {
+	facets := v.facets
+	if v.result == nil {
+		return facets
+	}
+	facets.failed = func(kind FacetKind, messages []string) {
+		if sensitive {
+			messages = maskValue(messages, value)
+		}
+		for _, message := range messages {
+			failure := FacetFailure{
+				Node:      node,
+				Attribute: attribute,
+				Kind:      kind,
+				Value:     value,
+				Length:    valueLength(value, baseType),
+				Message:   truncateUTF8(located(node, "", attribute.Local, prefix()+message).Message, maxErrorMessageLength, "... (truncated)"),
+			}
+			if sensitive {
+				failure.Value = DefaultRedaction
+			}
+			v.result.FacetFailures = append(v.result.FacetFailures, failure)
+		}
+	}
+	return facets
+}
+
+// attributeSimpleType returns the inline or referenced simple type of an attribute, if any.
+func (s *Schema) attributeSimpleType(attrDef *Attribute) *SimpleType {
+	if attrDef.linked {
+		return attrDef.linkedSimpleType
+	}
+	if attrDef.SimpleType != nil {
+		return attrDef.SimpleType
+	}
+	return s.lookupSimpleType(attrDef.Type, attrDef.typeRef)
+}
@@ -0,0 +1,175 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+// Test that ValidateAndAnnotate reports issues, statistics and per-node annotations
+func TestValidateAndAnnotate(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:complexType name="baseItem"/>
+    <xs:complexType name="item">
+        <xs:sequence>
+            <xs:element name="code" type="xs:token"/>
+            <xs:element name="quantity" type="xs:integer"/>
+            <xs:element name="note" type="xs:string" minOccurs="0"/>
+        </xs:sequence>
+        <xs:attribute name="unit" type="xs:string" default="each"/>
+        <xs:attribute name="label" type="xs:token"/>
+    </xs:complexType>
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="item" type="item" maxOccurs="unbounded"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	doc, err := Parse([]byte(`<order>
+    <item label="red box">
+        <code>A1</code>
+        <quantity> 5 </quantity>
+        <note>  keep  dry </note>
+    </item>
+    <item unit="kg">
+        <code>B2</code>
+        <quantity>many</quantity>
+    </item>
+</order>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	result, err := schema.ValidateAndAnnotate(doc)
+	if err != nil {
+		t.Fatalf("ValidateAndAnnotate failed: %v", err)
+	}
+	if result.Valid() || len(result.Issues) != 1 {
+		t.Fatalf("Expected exactly one issue, got: %v", result.Issues)
+	}
+	expectValidationError(t, result.Err(), "value 'many' is not a valid integer")
+	if result.Stats.Elements != 8 || result.Stats.Attributes != 2 {
+		t.Errorf("Expected 8 elements and 2 attributes, got %+v", result.Stats)
+	}
+	// The quantity, the item holding it and the root fail
+	if result.Stats.InvalidElements != 3 || result.Stats.InvalidAttributes != 0 {
+		t.Errorf("Expected 3 invalid elements and no invalid attributes, got %+v", result.Stats)
+	}
+
+	first, second := doc.Root.Children[0], doc.Root.Children[1]
+	if info, ok := result.Element(doc.Root); !ok || info.Valid || info.Declaration.Name != "order" {
+		t.Errorf("Unexpected root annotation: %+v", info)
+	}
+	if info, ok := result.Element(first); !ok || !info.Valid || info.TypeName != "item" {
+		t.Errorf("Unexpected annotation of the first item: %+v", info)
+	}
+	if info, _ := result.Element(second); info.Valid {
+		t.Error("Expected the second item to be invalid")
+	}
+
+	values := map[string]string{"code": "A1", "quantity": "5", "note": "keep  dry"}
+	for _, child := range first.Children {
+		info, ok := result.Element(child)
+		if !ok || info.NormalizedValue != values[child.Name.Local] {
+			t.Errorf("Expected normalized value '%s' for <%s>, got '%s'", values[child.Name.Local], child.Name.Local, info.NormalizedValue)
+		}
+	}
+
+	attributes := result.Attributes(first)
+	if len(attributes) != 2 {
+		t.Fatalf("Expected a given and a defaulted attribute, got %+v", attributes)
+	}
+	if attributes[0].Name.Local != "label" || attributes[0].NormalizedValue != "red box" || !attributes[0].Valid || attributes[0].Defaulted {
+		t.Errorf("Unexpected label annotation: %+v", attributes[0])
+	}
+	if attributes[1].Name != (xml.Name{Local: "unit"}) || attributes[1].NormalizedValue != "each" || !attributes[1].Defaulted {
+		t.Errorf("Unexpected unit annotation: %+v", attributes[1])
+	}
+	if attributes := result.Attributes(second); len(attributes) != 1 || attributes[0].NormalizedValue != "kg" || attributes[0].Defaulted {
+		t.Errorf("Unexpected annotations of the second item: %+v", attributes)
+	}
+
+	// Undeclared roots cannot be validated at all
+	other, _ := Parse([]byte(`<invoice/>`))
+	if _, err := schema.ValidateAndAnnotate(other); err == nil {
+		t.Error("Expected an error for an undeclared root element")
+	}
+
+	// ValidateResult reports them as the only issue instead
+	if result := schema.ValidateResult(doc); len(result.Issues) != 1 || result.Stats.InvalidElements != 3 {
+		t.Errorf("Expected ValidateResult to match ValidateAndAnnotate, got %v %+v", result.Issues, result.Stats)
+	}
+	result = schema.ValidateResult(other)
+	if result.Valid() || len(result.Issues) != 1 || result.Stats.Elements != 0 {
+		t.Fatalf("Expected the undeclared root as the only issue, got %v %+v", result.Issues, result.Stats)
+	}
+	expectValidationError(t, result.Err(), "root element <invoice> is not defined in the schema")
+	if _, ok := result.Element(other.Root); ok {
+		t.Error("Expected no annotation of an undeclared root")
+	}
+}
+
+// Test that facet failures report the value each facet was checked against and its length
+func TestFacetFailures(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="Code">
+        <xs:restriction base="xs:string">
+            <xs:maxLength value="4"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:element name="item">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="code" type="Code"/>
+                <xs:element name="quantity" type="xs:integer"/>
+            </xs:sequence>
+            <xs:attribute name="tag">
+                <xs:simpleType>
+                    <xs:restriction base="xs:string">
+                        <xs:enumeration value="ab"/>
+                    </xs:restriction>
+                </xs:simpleType>
+            </xs:attribute>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	doc, err := Parse([]byte(`<item tag="a&amp;b"><code>  A&lt;B C </code><quantity>x</quantity></item>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	result, err := schema.ValidateAndAnnotate(doc)
+	if err != nil {
+		t.Fatalf("ValidateAndAnnotate failed: %v", err)
+	}
+	if len(result.Issues) != 3 {
+		t.Fatalf("Expected 3 issues, got: %v", result.Issues)
+	}
+
+	// The built-in type check of quantity is not a facet failure
+	failures := result.FacetFailures
+	if len(failures) != 2 {
+		t.Fatalf("Expected 2 facet failures, got %+v", failures)
+	}
+	tag := failures[0]
+	if tag.Node != doc.Root || tag.Attribute.Local != "tag" || tag.Kind != FacetEnumeration ||
+		tag.Value != "a&b" || tag.Length != 3 || tag.Message != result.Issues[0] {
+		t.Errorf("Unexpected failure for the attribute: %+v", tag)
+	}
+	code := failures[1]
+	if code.Node != doc.Root.Children[0] || code.Attribute.Local != "" || code.Kind != FacetLength ||
+		code.Value != "A<B C" || code.Length != 5 || code.Message != result.Issues[1] {
+		t.Errorf("Unexpected failure for the element: %+v", code)
+	}
+}
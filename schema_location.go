@@ -0,0 +1,139 @@
+package xmlparser
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// resolveSchemaLocation resolves an xs:include or xs:import schemaLocation against the base
+// of the referencing schema and returns either an absolute http(s) URL or a filesystem path.
+//
+// schemaLocation values are URI references: they use forward slashes and may be
+// percent-encoded. They are therefore interpreted as URIs first, resolving against remote
+// bases with URL semantics and decoding relative references into native paths. Values that
+// are not valid URIs (Windows drive paths, backslash-separated paths) fall back to plain
+// filesystem joining, as does a decoded path that does not exist when the literal one does.
+func resolveSchemaLocation(schemaLocation, base string) (string, error) {
+	if isWindowsDrivePath(schemaLocation) || strings.Contains(schemaLocation, `\`) {
+		return joinFilesystemPath(base, schemaLocation), nil
+	}
+
+	ref, err := url.Parse(schemaLocation)
+	if err != nil {
+		return joinFilesystemPath(base, schemaLocation), nil
+	}
+
+	switch strings.ToLower(ref.Scheme) {
+	case "http", "https":
+		return ref.String(), nil
+
+	case "file":
+		return fileURIToPath(ref, runtime.GOOS == "windows"), nil
+
+	case "":
+		if isRemoteLocation(base) {
+			baseURL, err := url.Parse(base)
+			if err != nil {
+				return "", fmt.Errorf("invalid base URL '%s': %w", base, err)
+			}
+			return baseURL.ResolveReference(ref).String(), nil
+		}
+
+		decoded := joinFilesystemPath(base, filepath.FromSlash(ref.Path))
+		if decoded != joinFilesystemPath(base, schemaLocation) && !fileExists(decoded) {
+			if literal := joinFilesystemPath(base, schemaLocation); fileExists(literal) {
+				return literal, nil
+			}
+		}
+		return decoded, nil
+
+	default:
+		return "", fmt.Errorf("unsupported scheme '%s' in schemaLocation '%s'", ref.Scheme, schemaLocation)
+	}
+}
+
+// locationBase returns the base against which references inside the schema at location
+// are resolved: the URL itself for remote schemas, the containing directory for files.
+func locationBase(location string) string {
+	if isRemoteLocation(location) {
+		return location
+	}
+	return filepath.Dir(location)
+}
+
+// locationKey returns a canonical key for a resolved location, used for circular reference detection.
+func locationKey(location string) string {
+	if isRemoteLocation(location) {
+		return location
+	}
+	if absPath, err := filepath.Abs(location); err == nil {
+		return absPath
+	}
+	return location
+}
+
+// isRemoteLocation reports whether a location is an http or https URL.
+func isRemoteLocation(location string) bool {
+	lower := strings.ToLower(location)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// isWindowsDrivePath reports whether a location starts with a drive letter such as "C:\" or "C:/",
+// which URL parsing would otherwise mistake for a one-letter scheme.
+func isWindowsDrivePath(location string) bool {
+	if len(location) < 3 || location[1] != ':' || (location[2] != '\\' && location[2] != '/') {
+		return false
+	}
+	letter := location[0]
+	return ('a' <= letter && letter <= 'z') || ('A' <= letter && letter <= 'Z')
+}
+
+// fileURIToPath converts a file: URI to a filesystem path. On Windows, "file:///C:/dir/a.xsd"
+// becomes "C:\dir\a.xsd" and "file://server/share/a.xsd" becomes the UNC path
+// "\\server\share\a.xsd"; elsewhere the decoded URI path is used as is.
+func fileURIToPath(uri *url.URL, windows bool) string {
+	path := uri.Path
+	if uri.Opaque != "" {
+		// file:relative/a.xsd carries its path in the opaque part
+		if decoded, err := url.PathUnescape(uri.Opaque); err == nil {
+			path = decoded
+		} else {
+			path = uri.Opaque
+		}
+	}
+
+	if !windows {
+		return path
+	}
+
+	host := uri.Host
+	if host == "localhost" {
+		host = ""
+	}
+	if len(path) >= 3 && path[0] == '/' && isWindowsDrivePath(path[1:]) {
+		path = path[1:]
+	}
+	path = strings.ReplaceAll(path, "/", `\`)
+	if host != "" {
+		return `\\` + host + path
+	}
+	return path
+}
+
+// joinFilesystemPath joins a relative path to a base directory; absolute paths are returned unchanged.
+func joinFilesystemPath(base, path string) string {
+	if filepath.IsAbs(path) || isWindowsDrivePath(path) || base == "" {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+// fileExists reports whether a regular file exists at path.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,72 @@
+package xmlparser
+
+import "fmt"
+
+// SchemaSet holds independent schemas, each with its own target namespace, and validates a
+// document against the schema of its root element's namespace. Schemas that reference each
+// other should be combined with xs:import instead, so that one document may use several of
+// their namespaces.
+//
+// Schemas are added before validation starts: Validate may be called concurrently, but not
+// together with AddSchema or AddSchemaFile.
+type SchemaSet struct {
+	schemas map[string]*Schema // By target namespace; the empty string for no namespace
+}
+
+// NewSchemaSet returns an empty schema set.
+func NewSchemaSet() *SchemaSet {
+	return &SchemaSet{schemas: make(map[string]*Schema)}
+}
+
+// AddSchema parses a schema, like ParseXSD without a base path, and adds it to the set.
+func (set *SchemaSet) AddSchema(xsdBytes []byte) error {
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		return err
+	}
+	return set.add(schema)
+}
+
+// AddSchemaFile parses the schema document at a file path or http(s) URL, like
+// ParseXSDFromLocation, and adds it to the set.
+func (set *SchemaSet) AddSchemaFile(path string) error {
+	schema, err := ParseXSDFromLocation(path, ParseOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to add schema '%s': %w", path, err)
+	}
+	return set.add(schema)
+}
+
+// add registers a parsed schema under its target namespace.
+func (set *SchemaSet) add(schema *Schema) error {
+	if _, exists := set.schemas[schema.TargetNamespace]; exists {
+		return fmt.Errorf("the set already has a schema for %s", namespaceLabel(schema.TargetNamespace))
+	}
+	set.schemas[schema.TargetNamespace] = schema
+	return nil
+}
+
+// Schema returns the schema of a target namespace, or nil if the set has none.
+func (set *SchemaSet) Schema(namespace string) *Schema {
+	return set.schemas[namespace]
+}
+
+// Validate checks if the XML document conforms to the schema of its root element's
+// namespace. Returns ValidationError if validation fails, nil if valid.
+func (set *SchemaSet) Validate(doc *Document) error {
+	return set.ValidateWithOptions(doc, ValidateOptions{})
+}
+
+// ValidateWithOptions checks a document like Validate, with explicit options.
+func (set *SchemaSet) ValidateWithOptions(doc *Document, opts ValidateOptions) error {
+	if doc == nil || doc.Root == nil {
+		return documentFailure(nil, "XML document is empty")
+	}
+
+	schema := set.schemas[doc.Root.Name.Space]
+	if schema == nil {
+		return documentFailure(doc.Root, fmt.Sprintf("no schema in the set for root element <%s> in %s",
+			doc.Root.Name.Local, namespaceLabel(doc.Root.Name.Space)))
+	}
+	return schema.ValidateWithOptions(doc, opts)
+}
@@ -0,0 +1,131 @@
+package xmlparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that a schema set validates each document against the schema of its root namespace
+func TestSchemaSet(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "xmlparser_schema_set_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	invoiceSchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	targetNamespace="urn:example:invoice"
+	elementFormDefault="qualified">
+	<xs:element name="document">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="total" type="xs:decimal"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`
+
+	// Same root element name as the invoice schema, in another namespace
+	orderSchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+	targetNamespace="urn:example:order"
+	elementFormDefault="qualified">
+	<xs:element name="document">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="quantity" type="xs:positiveInteger"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`
+
+	noteSchemaContent := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="note" type="xs:string"/>
+</xs:schema>`
+
+	orderPath := filepath.Join(tmpDir, "order.xsd")
+	if err := os.WriteFile(orderPath, []byte(orderSchemaContent), 0644); err != nil {
+		t.Fatalf("Failed to write schema file: %v", err)
+	}
+
+	set := NewSchemaSet()
+	if err := set.AddSchema([]byte(invoiceSchemaContent)); err != nil {
+		t.Fatalf("Failed to add invoice schema: %v", err)
+	}
+	if err := set.AddSchemaFile(orderPath); err != nil {
+		t.Fatalf("Failed to add order schema: %v", err)
+	}
+	if err := set.AddSchema([]byte(noteSchemaContent)); err != nil {
+		t.Fatalf("Failed to add note schema: %v", err)
+	}
+
+	if err := set.AddSchema([]byte(invoiceSchemaContent)); err == nil ||
+		err.Error() != "the set already has a schema for namespace 'urn:example:invoice'" {
+		t.Errorf("Expected duplicate namespace error, got: %v", err)
+	}
+	if err := set.AddSchemaFile(filepath.Join(tmpDir, "missing.xsd")); err == nil {
+		t.Error("Expected an error for a missing schema file")
+	}
+	if set.Schema("urn:example:order") == nil || set.Schema("urn:example:unknown") != nil {
+		t.Error("Schema returned the wrong schemas by namespace")
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Invoice",
+			xml:        `<document xmlns="urn:example:invoice"><total>10.50</total></document>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Order",
+			xml:        `<o:document xmlns:o="urn:example:order"><o:quantity>3</o:quantity></o:document>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Document without namespace",
+			xml:        `<note>Hello</note>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Invoice content in an order",
+			xml:         `<document xmlns="urn:example:order"><total>10.50</total></document>`,
+			shouldPass:  false,
+			errorString: "element <total> is not a valid child of <document>",
+		},
+		{
+			name:        "Order with an invalid quantity",
+			xml:         `<document xmlns="urn:example:order"><quantity>0</quantity></document>`,
+			shouldPass:  false,
+			errorString: "in element <quantity>: value '0' must be positive",
+		},
+		{
+			name:        "Namespace without a schema",
+			xml:         `<document xmlns="urn:example:receipt"/>`,
+			shouldPass:  false,
+			errorString: "no schema in the set for root element <document> in namespace 'urn:example:receipt'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := set.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package xmlparser
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// SchemaVersion selects the edition of the XML Schema specification a schema is validated under.
+type SchemaVersion int
+
+const (
+	// XSD10 selects XML Schema 1.0 semantics (the default). Schemas using 1.1-only
+	// constructs are rejected with an error naming the construct.
+	XSD10 SchemaVersion = iota
+
+	// XSD11 selects XML Schema 1.1 semantics:
+	//   - elements in xs:all may have maxOccurs greater than 1
+	//   - xs:float and xs:double treat negative and positive zero as equal in range facets
+	//   - the year 0000 of the date types, 1 BCE in its astronomical numbering of years
+	//   - the xs:dateTimeStamp, xs:dayTimeDuration, xs:yearMonthDuration and xs:anyAtomicType types
+	//   - xs:assert and the xs:assertion facet are accepted (their XPath tests are not evaluated)
+	XSD11
+)
+
+// String returns the version number, e.g. "1.1".
+func (v SchemaVersion) String() string {
+	switch v {
+	case XSD10:
+		return "1.0"
+	case XSD11:
+		return "1.1"
+	default:
+		return fmt.Sprintf("SchemaVersion(%d)", int(v))
+	}
+}
+
+// ParseOptions configures how ParseXSDWithOptions parses a schema.
+type ParseOptions struct {
+	// BasePath is used to resolve relative schemaLocation values (defaults to the current
+	// directory). A directory for local schemas; for a schema retrieved over http(s), the
+	// URL of the schema document itself, against which references resolve as URI references.
+	BasePath string
+
+	// Version selects XSD 1.0 or 1.1 semantics (defaults to XSD10).
+	Version SchemaVersion
+
+	// Resolver loads the schema documents referenced by imports and includes (defaults to
+	// reading files and fetching http(s) URLs).
+	Resolver Resolver
+
+	// Catalog is the path of an OASIS XML catalog mapping schema locations to local copies.
+	// Mapped documents are loaded from their copy, through Resolver; the others directly.
+	Catalog string
+
+	// NoNetwork turns the http(s) fetches of the default loading into errors, so parsing
+	// never reaches the network (a Resolver decides for itself). Schemas mapped to local
+	// copies by the catalog are still loaded.
+	NoNetwork bool
+
+	// HTTPClient fetches remote schemas in the default loading, e.g. to set a proxy, TLS
+	// configuration or authentication (defaults to a client with a 30 second timeout).
+	HTTPClient *http.Client
+
+	// Cache, if set, serves the schema documents it already holds instead of loading them
+	// again, and keeps those loaded for later parses sharing it.
+	Cache *SchemaCache
+
+	// Tracer, if set, traces parsing, with a span per schema document loaded (see Tracer).
+	Tracer Tracer
+}
+
+// resolver returns the resolver selected by the options: Resolver or the default loading,
+// behind the catalog if one is set, behind the cache if one is set.
+func (opts ParseOptions) resolver() (Resolver, error) {
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = defaultResolver{noNetwork: opts.NoNetwork, client: opts.HTTPClient}
+	}
+	if opts.Catalog != "" {
+		catalog, err := LoadCatalog(opts.Catalog)
+		if err != nil {
+			return nil, err
+		}
+		resolver = CatalogResolver(catalog, resolver)
+	}
+	if opts.Cache != nil {
+		resolver = cachingResolver{cache: opts.Cache, fallback: resolver}
+	}
+	return resolver, nil
+}
+
+// ParseXSDWithOptions parses an XSD schema like ParseXSD, with explicit options.
+// ParseXSD is equivalent to ParseXSDWithOptions with the default options.
+// The schema, including all imported and included schemas, is checked against the
+// selected version; using an XSD 1.1 construct in 1.0 mode is an error.
+func ParseXSDWithOptions(xsdBytes []byte, opts ParseOptions) (*Schema, error) {
+	return ParseXSDContext(context.Background(), xsdBytes, opts)
+}
+
+// ParseXSDContext parses an XSD schema like ParseXSDWithOptions, loading imported and
+// included schemas until ctx is canceled or its deadline passes. Loading then stops with an
+// error wrapping ctx.Err(), and remote fetches in progress are aborted; custom resolvers
+// observe ctx by implementing ContextResolver.
+func ParseXSDContext(ctx context.Context, xsdBytes []byte, opts ParseOptions) (*Schema, error) {
+	return traceParse(ctx, opts.Tracer, nil, func(ctx context.Context) (*Schema, error) {
+		return parseXSD(ctx, xsdBytes, opts)
+	})
+}
+
+// traceParse runs parse in the "xmlparser.ParseXSD" span of a schema.
+func traceParse(ctx context.Context, tracer Tracer, attributes []SpanAttribute,
+	parse func(ctx context.Context) (*Schema, error)) (*Schema, error) {
+	ctx, span := startSpan(ctx, tracer, "xmlparser.ParseXSD", attributes...)
+	schema, err := parse(ctx)
+	if schema != nil {
+		span.SetAttributes(SpanAttribute{"xsd.target_namespace", schema.TargetNamespace},
+			SpanAttribute{"xsd.documents", len(schema.sources)})
+	}
+	span.End(err)
+	return schema, err
+}
+
+// parseXSD parses a schema as ParseXSDContext does, within its span.
+func parseXSD(ctx context.Context, xsdBytes []byte, opts ParseOptions) (*Schema, error) {
+	if opts.Version != XSD10 && opts.Version != XSD11 {
+		return nil, fmt.Errorf("unsupported schema version %s", opts.Version)
+	}
+
+	// Determine base path - use current directory if not provided
+	basePath := opts.BasePath
+	if basePath == "" {
+		basePath = "."
+	}
+
+	resolver, err := opts.resolver()
+	if err != nil {
+		return nil, err
+	}
+
+	// Always use the full parsing with import/include support and circular reference protection
+	loader := newSchemaLoader(ctx, resolver)
+	loader.tracer = opts.Tracer
+	schema, err := parseXSDWithImportsAndTracker(xsdBytes, basePath, loader)
+	if err != nil {
+		return nil, err
+	}
+
+	_, span := startSpan(ctx, opts.Tracer, "xmlparser.ComposeSchema")
+	err = schema.compose(opts, append([]SchemaSource{{Data: xsdBytes}}, loader.sources...))
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// compose completes a schema whose documents are all loaded and merged.
+func (s *Schema) compose(opts ParseOptions, sources []SchemaSource) error {
+	// References and derivations are resolved once every document is merged, as the
+	// components they name may come from any of them
+	if err := s.resolveElementRefs(); err != nil {
+		return err
+	}
+	if err := s.applyComplexDerivations(); err != nil {
+		return err
+	}
+
+	s.Version = opts.Version
+	s.options = opts
+	s.sources = sources
+	if err := s.checkVersion(); err != nil {
+		return err
+	}
+	return s.compile()
+}
+
+// ParseXSDFromLocation loads and parses the schema document at a file path or http(s) URL.
+// Relative schemaLocation values are resolved against the location of the schema document
+// that contains them, at any depth: a remote schema including "common/types.xsd" loads it
+// from next to the remote schema, not from a local directory. opts.BasePath is ignored;
+// opts.Resolver and opts.Catalog, if set, also apply to the schema document at location.
+func ParseXSDFromLocation(location string, opts ParseOptions) (*Schema, error) {
+	return parseXSDFromLocation(context.Background(), location, opts)
+}
+
+// parseXSDFromLocation parses the schema document at location like ParseXSDFromLocation,
+// loading documents until ctx is done as ParseXSDContext does.
+func parseXSDFromLocation(ctx context.Context, location string, opts ParseOptions) (*Schema, error) {
+	return traceParse(ctx, opts.Tracer, []SpanAttribute{{"xsd.location", location}}, func(ctx context.Context) (*Schema, error) {
+		resolver, err := opts.resolver()
+		if err != nil {
+			return nil, err
+		}
+		xsdBytes, err := loadTraced(ctx, opts.Tracer, resolver, "", location)
+		if err != nil {
+			return nil, err
+		}
+
+		// The catalog is loaded, and the cache applied, once for the referenced documents too
+		opts.BasePath = locationBase(location)
+		opts.Resolver, opts.Catalog, opts.Cache = resolver, "", nil
+		return parseXSD(ctx, xsdBytes, opts)
+	})
+}
+
+// xsd11Types lists the built-in types introduced in XSD 1.1.
+var xsd11Types = map[string]bool{
+	"xs:anyAtomicType":     true,
+	"xs:dateTimeStamp":     true,
+	"xs:dayTimeDuration":   true,
+	"xs:yearMonthDuration": true,
+}
+
+// checkVersion reports the first construct the schema uses that its version does not allow.
+func (s *Schema) checkVersion() error {
+	if s.Version == XSD11 {
+		return nil
+	}
+
+	for i := range s.Elements {
+		if err := s.checkElementVersion(&s.Elements[i]); err != nil {
+			return err
+		}
+	}
+	for i := range s.ComplexTypes {
+		if err := s.checkComplexTypeVersion(&s.ComplexTypes[i]); err != nil {
+			return fmt.Errorf("in complex type '%s': %w", s.ComplexTypes[i].Name, err)
+		}
+	}
+	for i := range s.SimpleTypes {
+		if err := checkSimpleTypeVersion(&s.SimpleTypes[i]); err != nil {
+			return fmt.Errorf("in simple type '%s': %w", s.SimpleTypes[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// checkElementVersion checks an element declaration and its inline types.
+func (s *Schema) checkElementVersion(element *Element) error {
+	if err := checkTypeReferenceVersion(element.Type); err != nil {
+		return fmt.Errorf("in element '%s': %w", element.Name, err)
+	}
+	if element.ComplexType != nil {
+		if err := s.checkComplexTypeVersion(element.ComplexType); err != nil {
+			return fmt.Errorf("in element '%s': %w", element.Name, err)
+		}
+	}
+	if element.SimpleType != nil {
+		if err := checkSimpleTypeVersion(element.SimpleType); err != nil {
+			return fmt.Errorf("in element '%s': %w", element.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkComplexTypeVersion checks assertions, xs:all occurrence bounds, attributes and nested elements.
+func (s *Schema) checkComplexTypeVersion(complexType *ComplexType) error {
+	if len(complexType.Assertions) > 0 {
+		return fmt.Errorf("xs:assert requires XSD 1.1")
+	}
+
+	if complexType.All != nil {
+		for i := range complexType.All.Elements {
+			element := &complexType.All.Elements[i]
+			if max := parseMaxOccurs(element.MaxOccurs); max < 0 || max > 1 {
+				return fmt.Errorf("element '%s' in xs:all has maxOccurs='%s', which requires XSD 1.1",
+					element.Name, element.MaxOccurs)
+			}
+			if err := s.checkElementVersion(element); err != nil {
+				return err
+			}
+		}
+	}
+	if complexType.Sequence != nil {
+		if err := s.checkSequenceVersion(complexType.Sequence); err != nil {
+			return err
+		}
+	}
+	if complexType.Choice != nil {
+		if err := s.checkChoiceVersion(complexType.Choice); err != nil {
+			return err
+		}
+	}
+
+	for i := range complexType.Attributes {
+		attribute := &complexType.Attributes[i]
+		if err := checkTypeReferenceVersion(attribute.Type); err != nil {
+			return fmt.Errorf("in attribute '%s': %w", attribute.Name, err)
+		}
+		if attribute.SimpleType != nil {
+			if err := checkSimpleTypeVersion(attribute.SimpleType); err != nil {
+				return fmt.Errorf("in attribute '%s': %w", attribute.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSequenceVersion checks the particles of an xs:sequence.
+func (s *Schema) checkSequenceVersion(sequence *Sequence) error {
+	for i := range sequence.Elements {
+		if err := s.checkElementVersion(&sequence.Elements[i]); err != nil {
+			return err
+		}
+	}
+	for i := range sequence.Sequences {
+		if err := s.checkSequenceVersion(&sequence.Sequences[i]); err != nil {
+			return err
+		}
+	}
+	for i := range sequence.Choices {
+		if err := s.checkChoiceVersion(&sequence.Choices[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkChoiceVersion checks the particles of an xs:choice.
+func (s *Schema) checkChoiceVersion(choice *Choice) error {
+	for i := range choice.Elements {
+		if err := s.checkElementVersion(&choice.Elements[i]); err != nil {
+			return err
+		}
+	}
+	for i := range choice.Sequences {
+		if err := s.checkSequenceVersion(&choice.Sequences[i]); err != nil {
+			return err
+		}
+	}
+	for i := range choice.Choices {
+		if err := s.checkChoiceVersion(&choice.Choices[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSimpleTypeVersion checks the base type and facets of a simple type restriction.
+func checkSimpleTypeVersion(simpleType *SimpleType) error {
+	if simpleType.Restriction == nil {
+		return nil
+	}
+	if len(simpleType.Restriction.Assertions) > 0 {
+		return fmt.Errorf("the xs:assertion facet requires XSD 1.1")
+	}
+	return checkTypeReferenceVersion(simpleType.Restriction.Base)
+}
+
+// checkTypeReferenceVersion rejects references to built-in types introduced in XSD 1.1.
+func checkTypeReferenceVersion(typeName string) error {
+	if xsd11Types[typeName] {
+		return fmt.Errorf("type '%s' requires XSD 1.1", typeName)
+	}
+	return nil
+}
+
+// orderZeros maps negative and positive zero to distinct ordered values, for XSD 1.0
+// range facets on xs:float and xs:double, where -0 sorts below 0. XSD 1.1 treats them as equal.
+func orderZeros(contentNum, limitNum float64) (float64, float64) {
+	if contentNum != 0 || limitNum != 0 {
+		return contentNum, limitNum
+	}
+	zeroOrder := func(v float64) float64 {
+		if math.Signbit(v) {
+			return -1
+		}
+		return 0
+	}
+	return zeroOrder(contentNum), zeroOrder(limitNum)
+}
+
+// isFloatingPointType reports whether a built-in type is xs:float or xs:double.
+func isFloatingPointType(typeName string) bool {
+	return typeName == "xs:float" || typeName == "xs:double"
+}
@@ -0,0 +1,242 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test that XSD 1.1 constructs are rejected in 1.0 mode and accepted in 1.1 mode
+func TestSchemaVersionConstructs(t *testing.T) {
+	tests := []struct {
+		name        string
+		xsd         string
+		errorString string
+	}{
+		{
+			name: "xs:all with maxOccurs greater than 1",
+			xsd: `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="test">
+        <xs:complexType>
+            <xs:all>
+                <xs:element name="item" type="xs:string" maxOccurs="3"/>
+            </xs:all>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`,
+			errorString: "element 'item' in xs:all has maxOccurs='3', which requires XSD 1.1",
+		},
+		{
+			name: "XSD 1.1 built-in type",
+			xsd: `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="stamp" type="xs:dateTimeStamp"/>
+</xs:schema>`,
+			errorString: "type 'xs:dateTimeStamp' requires XSD 1.1",
+		},
+		{
+			name: "XSD 1.1 type as restriction base",
+			xsd: `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="ShortDuration">
+        <xs:restriction base="xs:dayTimeDuration"/>
+    </xs:simpleType>
+</xs:schema>`,
+			errorString: "in simple type 'ShortDuration': type 'xs:dayTimeDuration' requires XSD 1.1",
+		},
+		{
+			name: "Assertion on complex type",
+			xsd: `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:complexType name="RangeType">
+        <xs:sequence>
+            <xs:element name="min" type="xs:integer"/>
+            <xs:element name="max" type="xs:integer"/>
+        </xs:sequence>
+        <xs:assert test="min le max"/>
+    </xs:complexType>
+</xs:schema>`,
+			errorString: "in complex type 'RangeType': xs:assert requires XSD 1.1",
+		},
+		{
+			name: "Assertion facet",
+			xsd: `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="even">
+        <xs:simpleType>
+            <xs:restriction base="xs:integer">
+                <xs:assertion test="$value mod 2 = 0"/>
+            </xs:restriction>
+        </xs:simpleType>
+    </xs:element>
+</xs:schema>`,
+			errorString: "the xs:assertion facet requires XSD 1.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseXSD([]byte(tt.xsd))
+			if err == nil || !strings.Contains(err.Error(), tt.errorString) {
+				t.Errorf("Expected error containing '%s' in XSD 1.0 mode, got: %v", tt.errorString, err)
+			}
+
+			schema, err := ParseXSDWithOptions([]byte(tt.xsd), ParseOptions{Version: XSD11})
+			if err != nil {
+				t.Fatalf("Expected schema to parse in XSD 1.1 mode, got: %v", err)
+			}
+			if schema.Version != XSD11 {
+				t.Errorf("Expected schema version 1.1, got %s", schema.Version)
+			}
+		})
+	}
+
+	if _, err := ParseXSDWithOptions([]byte(tests[1].xsd), ParseOptions{Version: SchemaVersion(7)}); err == nil {
+		t.Error("Expected error for unsupported schema version")
+	}
+}
+
+// Test validation behavior that differs between XSD 1.0 and 1.1
+func TestSchemaVersionValidation(t *testing.T) {
+	xsd10 := `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="test">
+        <xs:complexType>
+            <xs:all>
+                <xs:element name="weight">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:double">
+                            <xs:minInclusive value="0"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="day" type="xs:date" minOccurs="0"/>
+            </xs:all>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`
+
+	xsd11 := `
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="test">
+        <xs:complexType>
+            <xs:all>
+                <xs:element name="weight">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:double">
+                            <xs:minInclusive value="0"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="day" type="xs:date" minOccurs="0"/>
+                <xs:element name="tag" type="xs:string" minOccurs="0" maxOccurs="2"/>
+                <xs:element name="stamp" type="xs:dateTimeStamp" minOccurs="0"/>
+                <xs:element name="wait" type="xs:dayTimeDuration" minOccurs="0"/>
+                <xs:element name="term" type="xs:yearMonthDuration" minOccurs="0"/>
+            </xs:all>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`
+
+	tests := []struct {
+		name        string
+		version     SchemaVersion
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:        "Negative zero is below zero in XSD 1.0",
+			version:     XSD10,
+			xml:         `<test><weight>-0</weight></test>`,
+			shouldPass:  false,
+			errorString: "value '-0' below minimum allowed value 0",
+		},
+		{
+			name:       "Negative zero equals zero in XSD 1.1",
+			version:    XSD11,
+			xml:        `<test><weight>-0</weight></test>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Year 0000 is not allowed in XSD 1.0",
+			version:     XSD10,
+			xml:         `<test><weight>1</weight><day>0000-01-01</day></test>`,
+			shouldPass:  false,
+			errorString: "year 0000 is not allowed in XSD 1.0",
+		},
+		{
+			name:       "Year 0000 is 1 BCE in XSD 1.1",
+			version:    XSD11,
+			xml:        `<test><weight>1</weight><day>0000-02-29</day></test>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Repeated xs:all element in XSD 1.1",
+			version:    XSD11,
+			xml:        `<test><tag>a</tag><weight>1</weight><tag>b</tag></test>`,
+			shouldPass: true,
+		},
+		{
+			name:        "xs:all element above maxOccurs in XSD 1.1",
+			version:     XSD11,
+			xml:         `<test><tag>a</tag><tag>b</tag><tag>c</tag><weight>1</weight></test>`,
+			shouldPass:  false,
+			errorString: "element <tag> appears 3 times in xs:all group, but maximum is 2",
+		},
+		{
+			name:       "Valid XSD 1.1 types",
+			version:    XSD11,
+			xml:        `<test><weight>1</weight><stamp>2024-01-01T10:00:00Z</stamp><wait>P1DT2H</wait><term>P1Y6M</term></test>`,
+			shouldPass: true,
+		},
+		{
+			name:        "dateTimeStamp requires a timezone",
+			version:     XSD11,
+			xml:         `<test><weight>1</weight><stamp>2024-01-01T10:00:00</stamp></test>`,
+			shouldPass:  false,
+			errorString: "is not a valid dateTimeStamp",
+		},
+		{
+			name:        "dayTimeDuration rejects years",
+			version:     XSD11,
+			xml:         `<test><weight>1</weight><wait>P1Y</wait></test>`,
+			shouldPass:  false,
+			errorString: "is not a valid dayTimeDuration",
+		},
+		{
+			name:        "yearMonthDuration rejects empty duration",
+			version:     XSD11,
+			xml:         `<test><weight>1</weight><term>P</term></test>`,
+			shouldPass:  false,
+			errorString: "is not a valid yearMonthDuration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xsd := xsd10
+			if tt.version == XSD11 {
+				xsd = xsd11
+			}
+			schema, err := ParseXSDWithOptions([]byte(xsd), ParseOptions{Version: tt.version})
+			if err != nil {
+				t.Fatalf("Failed to parse XSD: %v", err)
+			}
+
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
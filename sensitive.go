@@ -0,0 +1,135 @@
+package xmlparser
+
+import (
+	"strings"
+)
+
+// SensitiveAppInfo is the source of the xs:appinfo marking an element or attribute
+// declaration as holding sensitive data, such as personal data. Its values are masked in
+// issues and validation results, and Filter can redact its content:
+//
+//	<xs:element name="ssn" type="xs:string">
+//	  <xs:annotation><xs:appinfo source="urn:validatexml:sensitive"/></xs:annotation>
+//	</xs:element>
+const SensitiveAppInfo = "urn:validatexml:sensitive"
+
+// DefaultRedaction is the text standing for a sensitive value in issues and validation
+// results, and replacing the content of sensitive elements in Filter when
+// FilterOptions.Redaction is empty.
+const DefaultRedaction = "[REDACTED]"
+
+// sensitive reports whether an annotation marks its component as holding sensitive data.
+func (a *Annotation) sensitive() bool {
+	if a == nil {
+		return false
+	}
+	for _, info := range a.AppInfo {
+		if info.Source == SensitiveAppInfo {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitivePath is a compiled entry of ValidateOptions.SensitivePaths.
+type sensitivePath struct {
+	steps     []string // Local names of the element steps, down to the element holding the value; "" matches any name
+	attribute string   // Local name of the attribute holding the value; empty for element content
+	anywhere  bool     // Whether the steps may start below the root element ("//" paths)
+}
+
+// compileSensitivePaths compiles the sensitive paths of ValidateOptions. Empty paths are
+// ignored.
+func compileSensitivePaths(paths []string) []sensitivePath {
+	var compiled []sensitivePath
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		var compiledPath sensitivePath
+		switch {
+		case strings.HasPrefix(path, "//"):
+			compiledPath.anywhere = true
+			path = path[2:]
+		case strings.HasPrefix(path, "/"):
+			path = path[1:]
+		default:
+			// Relative to the root element, as in RegisterShardKey
+			compiledPath.steps = []string{""}
+		}
+		if path == "" {
+			continue
+		}
+
+		for _, step := range strings.Split(path, "/") {
+			if strings.HasPrefix(step, "@") {
+				compiledPath.attribute = ParseQName(step[1:]).LocalName
+				break
+			}
+			compiledPath.steps = append(compiledPath.steps, ParseQName(step).LocalName)
+		}
+		compiled = append(compiled, compiledPath)
+	}
+	return compiled
+}
+
+// matches reports whether the path selects the content of node or, when attribute is not
+// empty, its attribute with that local name.
+func (p sensitivePath) matches(node *Node, attribute string) bool {
+	if p.attribute != attribute {
+		return false
+	}
+	i := len(p.steps) - 1
+	for ; node != nil && i >= 0; node, i = node.Parent, i-1 {
+		if p.steps[i] != "" && p.steps[i] != node.Name.Local {
+			return false
+		}
+	}
+	// Every step is matched, from the root element unless the path matches anywhere
+	return i < 0 && (node == nil || p.anywhere)
+}
+
+// sensitiveElement reports whether the content of node, validated against def, is masked.
+func (v *validator) sensitiveElement(node *Node, def *Element) bool {
+	if def.Annotation.sensitive() {
+		return true
+	}
+	for _, path := range v.sensitivePaths {
+		if path.matches(node, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveAttribute reports whether the value of the attribute at index i of node,
+// validated against attrDef, is masked.
+func (v *validator) sensitiveAttribute(node *Node, i int, attrDef *Attribute) bool {
+	if attrDef.Annotation.sensitive() {
+		return true
+	}
+	for _, path := range v.sensitivePaths {
+		if path.matches(node, node.Attrs[i].Name.Local) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskValue replaces the quoted occurrences of a sensitive value in issues, as excerpted
+// in messages, with DefaultRedaction. The value's trimmed and collapsed forms are replaced
+// too, as type checks report values after whitespace normalization.
+func maskValue(messages []string, value string) []string {
+	if len(messages) == 0 {
+		return messages
+	}
+	forms := []string{value, strings.TrimSpace(value), strings.Join(strings.Fields(value), " ")}
+	masked := make([]string, len(messages))
+	for i, message := range messages {
+		for _, form := range forms {
+			if form != "" {
+				message = strings.ReplaceAll(message, "'"+excerpt(form)+"'", "'"+DefaultRedaction+"'")
+			}
+		}
+		masked[i] = message
+	}
+	return masked
+}
@@ -0,0 +1,132 @@
+package xmlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSensitiveValues(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="customer">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="name" type="xs:string"/>
+        <xs:element name="ssn">
+          <xs:annotation><xs:appinfo source="urn:validatexml:sensitive"/></xs:annotation>
+          <xs:simpleType>
+            <xs:restriction base="xs:string">
+              <xs:maxLength value="9"/>
+            </xs:restriction>
+          </xs:simpleType>
+        </xs:element>
+        <xs:element name="card" minOccurs="0">
+          <xs:complexType>
+            <xs:sequence>
+              <xs:element name="number" type="xs:integer"/>
+            </xs:sequence>
+            <xs:attribute name="pin" type="xs:integer"/>
+          </xs:complexType>
+        </xs:element>
+        <xs:element name="age" type="xs:integer" minOccurs="0"/>
+        <xs:element name="referrer" type="xs:IDREF" minOccurs="0"/>
+      </xs:sequence>
+      <xs:attribute name="password" type="xs:integer">
+        <xs:annotation><xs:appinfo source="urn:validatexml:sensitive"/></xs:annotation>
+      </xs:attribute>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		paths     []string
+		xml       string
+		hidden    []string // Values that must not appear in the issues
+		reported  []string // Values that must still appear
+		maskCount int
+	}{
+		{
+			name:      "annotated element and attribute",
+			xml:       `<customer password="hunter2"><name>Ann</name><ssn>123-45-6789</ssn><age>old</age></customer>`,
+			hidden:    []string{"hunter2", "123-45-6789"},
+			reported:  []string{"'old'"},
+			maskCount: 2,
+		},
+		{
+			name:      "absolute path",
+			paths:     []string{"/customer/card/number"},
+			xml:       `<customer><name>Ann</name><ssn>123456789</ssn><card><number>4111-1111</number></card></customer>`,
+			hidden:    []string{"4111-1111"},
+			maskCount: 1,
+		},
+		{
+			name:      "relative and anywhere paths",
+			paths:     []string{"card/@pin", "//referrer"},
+			xml:       `<customer><name>Ann</name><ssn>123456789</ssn><card pin="12x4"><number>1</number></card><referrer>guest42</referrer></customer>`,
+			hidden:    []string{"12x4", "guest42"},
+			maskCount: 2,
+		},
+		{
+			name:      "paths selecting other nodes",
+			paths:     []string{"/card/number", "number", "//card/@number"},
+			xml:       `<customer><name>Ann</name><ssn>123456789</ssn><card><number>4111-1111</number></card></customer>`,
+			reported:  []string{"'4111-1111'"},
+			maskCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse document: %v", err)
+			}
+			err = schema.ValidateWithOptions(doc, ValidateOptions{SensitivePaths: tt.paths})
+			if err == nil {
+				t.Fatal("Expected validation errors")
+			}
+			message := err.Error()
+			for _, value := range tt.hidden {
+				if strings.Contains(message, value) {
+					t.Errorf("Expected '%s' to be masked, got: %v", value, message)
+				}
+			}
+			for _, value := range tt.reported {
+				if !strings.Contains(message, value) {
+					t.Errorf("Expected '%s' to be reported, got: %v", value, message)
+				}
+			}
+			if count := strings.Count(message, "'"+DefaultRedaction+"'"); count != tt.maskCount {
+				t.Errorf("Expected %d masked values, got %d: %v", tt.maskCount, count, message)
+			}
+		})
+	}
+
+	t.Run("annotations", func(t *testing.T) {
+		doc, err := Parse([]byte(`<customer password="1x"><name>Ann</name><ssn>123-45-6789</ssn></customer>`))
+		if err != nil {
+			t.Fatalf("Failed to parse document: %v", err)
+		}
+		result, err := schema.ValidateAndAnnotate(doc)
+		if err != nil {
+			t.Fatalf("ValidateAndAnnotate failed: %v", err)
+		}
+		if len(result.FacetFailures) != 1 || result.FacetFailures[0].Value != DefaultRedaction ||
+			result.FacetFailures[0].Length != 11 || strings.Contains(result.FacetFailures[0].Message, "6789") {
+			t.Errorf("Expected a masked facet failure, got %+v", result.FacetFailures)
+		}
+		if info, _ := result.Element(doc.Root.Children[1]); info.NormalizedValue != DefaultRedaction {
+			t.Errorf("Expected a masked element value, got '%s'", info.NormalizedValue)
+		}
+		if attrs := result.Attributes(doc.Root); len(attrs) != 1 || attrs[0].NormalizedValue != DefaultRedaction {
+			t.Errorf("Expected a masked attribute value, got %+v", attrs)
+		}
+		if info, _ := result.Element(doc.Root.Children[0]); info.NormalizedValue != "Ann" {
+			t.Errorf("Expected other values to be kept, got '%s'", info.NormalizedValue)
+		}
+	})
+}
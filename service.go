@@ -0,0 +1,233 @@
+package xmlparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServiceConfig configures a Service.
+type ServiceConfig struct {
+	// Bundles maps each message type, the name callers validate messages under, to the
+	// schema validating it.
+	Bundles map[string]SchemaBundle
+
+	// ParseOptions apply to every bundle; BasePath is ignored, as with ParseXSDFromLocation.
+	// A SchemaCache lets bundles that share imports fetch them once.
+	ParseOptions ParseOptions
+
+	// DocumentOptions apply when parsing every message, such as the limits of DocumentOptions
+	// for messages from untrusted clients; a message exceeding one is reported as a parse error.
+	DocumentOptions DocumentOptions
+
+	// ValidateOptions apply to every message.
+	ValidateOptions ValidateOptions
+
+	// OnValidate, when set, is called after every message Validate and ValidateContext
+	// check, including those of Middleware, TwirpHandler and Consumer, with the outcome: the
+	// hook for metrics, such as those of the metrics/prometheus module. It is called
+	// concurrently when messages are.
+	OnValidate func(ValidationEvent)
+}
+
+// ValidationEvent is the outcome of validating one message, as reported to
+// ServiceConfig.OnValidate.
+type ValidationEvent struct {
+	MessageType string
+	Bytes       int           // Size of the message
+	Duration    time.Duration // Time spent parsing and validating it
+
+	// KnownMessageType reports whether MessageType is one of the configured Bundles.
+	// Message types come from clients, through Middleware and TwirpHandler, so metrics
+	// should not label unknown ones with their name
+	KnownMessageType bool
+
+	// Err is what Validate returned: nil for a valid message, a *ValidationError for an
+	// invalid or malformed one, and another error when the message type is unknown or its
+	// schema is not loaded
+	Err error
+}
+
+// SchemaBundle is the schema of a message type: an entry schema document, loaded with the
+// documents it imports and includes.
+type SchemaBundle struct {
+	Location string // File path or http(s) URL of the entry schema document
+
+	// RootElement is the global element messages must have as root, by its name in
+	// ElementMap (e.g. "order", or "ord:order" for an imported element); any global element
+	// is accepted when empty
+	RootElement string
+}
+
+// Service validates messages against a configured set of schema bundles, keyed by message
+// type, for standing up a validation service. Start loads the bundles, typically at
+// startup; Ready and Health report whether they all compiled, for readiness and liveness
+// probes. A Service is safe for concurrent use, including Validate during a Start retry.
+type Service struct {
+	config ServiceConfig
+
+	mu      sync.RWMutex
+	bundles map[string]*loadedBundle // By message type, once Start has tried to load them
+}
+
+// loadedBundle is the outcome of loading a bundle.
+type loadedBundle struct {
+	schema *Schema
+	root   *Element // Declaration of SchemaBundle.RootElement; nil for any global element
+	err    error
+}
+
+// ServiceHealth is the status of a Service, as reported by Health.
+type ServiceHealth struct {
+	Ready   bool           `json:"ready"`   // Whether every bundle is loaded
+	Bundles []BundleStatus `json:"bundles"` // Status of each bundle, by message type
+}
+
+// BundleStatus is the status of one schema bundle of a Service.
+type BundleStatus struct {
+	MessageType string `json:"messageType"`
+	Ready       bool   `json:"ready"`           // Whether the schema is loaded and compiled
+	Error       string `json:"error,omitempty"` // Why loading failed, if it did
+}
+
+// NewService returns a service for the bundles of config. No schema is loaded until Start.
+func NewService(config ServiceConfig) *Service {
+	return &Service{config: config, bundles: make(map[string]*loadedBundle)}
+}
+
+// Start loads and compiles the bundles that are not loaded yet, so it can be called again
+// to retry failed ones. Loading stops when ctx is done. The error lists the bundles that
+// failed; the others can be validated against already, but the service is not Ready.
+func (s *Service) Start(ctx context.Context) error {
+	var failures []error
+	for _, messageType := range s.messageTypes() {
+		s.mu.RLock()
+		loaded := s.bundles[messageType]
+		s.mu.RUnlock()
+		if loaded != nil && loaded.err == nil {
+			continue
+		}
+
+		loaded = s.load(ctx, s.config.Bundles[messageType])
+		if loaded.err != nil {
+			failures = append(failures, fmt.Errorf("message type '%s': %w", messageType, loaded.err))
+		}
+		s.mu.Lock()
+		s.bundles[messageType] = loaded
+		s.mu.Unlock()
+	}
+	return errors.Join(failures...)
+}
+
+// load loads and compiles the schema of a bundle.
+func (s *Service) load(ctx context.Context, bundle SchemaBundle) *loadedBundle {
+	schema, err := parseXSDFromLocation(ctx, bundle.Location, s.config.ParseOptions)
+	if err != nil {
+		return &loadedBundle{err: err}
+	}
+	loaded := &loadedBundle{schema: schema}
+	if bundle.RootElement != "" {
+		if loaded.root = schema.ElementMap[bundle.RootElement]; loaded.root == nil {
+			return &loadedBundle{err: fmt.Errorf("root element '%s' is not defined in the schema", bundle.RootElement)}
+		}
+	}
+	return loaded
+}
+
+// Ready reports whether every bundle is loaded and compiled.
+func (s *Service) Ready() bool {
+	return s.Health().Ready
+}
+
+// Health reports the status of every bundle, sorted by message type.
+func (s *Service) Health() ServiceHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	health := ServiceHealth{Ready: true}
+	for _, messageType := range s.messageTypes() {
+		status := BundleStatus{MessageType: messageType}
+		switch loaded := s.bundles[messageType]; {
+		case loaded == nil:
+			status.Error = "not loaded"
+		case loaded.err != nil:
+			status.Error = loaded.err.Error()
+		default:
+			status.Ready = true
+		}
+		health.Ready = health.Ready && status.Ready
+		health.Bundles = append(health.Bundles, status)
+	}
+	return health
+}
+
+// Validate parses a message and validates it against the schema of its message type, with
+// the configured options. Parsing and validation failures are reported as a
+// *ValidationError, as with ValidateBytes, and so is a root element other than the
+// bundle's RootElement. Other errors mean the message type is unknown or its schema is not
+// loaded.
+func (s *Service) Validate(messageType string, xmlBytes []byte) error {
+	return s.ValidateContext(context.Background(), messageType, xmlBytes)
+}
+
+// ValidateContext validates a message like Validate, tracing the validation with the
+// Tracer of the configured ValidateOptions in the span of ctx, and stopping with the
+// context's error once ctx is done, as Schema.ValidateContext does; a message whose
+// context is done already is not parsed. Middleware, TwirpHandler and Consumer validate
+// messages with the context of the request or message.
+func (s *Service) ValidateContext(ctx context.Context, messageType string, xmlBytes []byte) error {
+	if s.config.OnValidate == nil {
+		return s.validate(ctx, messageType, xmlBytes)
+	}
+	start := time.Now()
+	err := s.validate(ctx, messageType, xmlBytes)
+	_, known := s.config.Bundles[messageType]
+	s.config.OnValidate(ValidationEvent{MessageType: messageType, Bytes: len(xmlBytes), Duration: time.Since(start),
+		KnownMessageType: known, Err: err})
+	return err
+}
+
+// validate is ValidateContext without the OnValidate hook.
+func (s *Service) validate(ctx context.Context, messageType string, xmlBytes []byte) error {
+	if _, exists := s.config.Bundles[messageType]; !exists {
+		return fmt.Errorf("unknown message type '%s'", messageType)
+	}
+	s.mu.RLock()
+	loaded := s.bundles[messageType]
+	s.mu.RUnlock()
+	if loaded == nil || loaded.err != nil {
+		return fmt.Errorf("schema of message type '%s' is not loaded", messageType)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	doc, err := ParseWithOptions(xmlBytes, s.config.DocumentOptions)
+	if err != nil {
+		return parseFailure(err)
+	}
+	if loaded.root != nil {
+		rootDef, rootErr := loaded.schema.rootDeclaration(doc, s.config.ValidateOptions)
+		if rootErr != nil {
+			return rootErr
+		}
+		if rootDef != loaded.root {
+			return documentFailure(doc.Root, fmt.Sprintf("root element <%s> is not allowed for message type '%s' (expected <%s>)",
+				doc.Root.Name.Local, messageType, loaded.root.Name))
+		}
+	}
+	return loaded.schema.ValidateContext(ctx, doc, s.config.ValidateOptions)
+}
+
+// messageTypes returns the configured message types in sorted order.
+func (s *Service) messageTypes() []string {
+	messageTypes := make([]string, 0, len(s.config.Bundles))
+	for messageType := range s.config.Bundles {
+		messageTypes = append(messageTypes, messageType)
+	}
+	sort.Strings(messageTypes)
+	return messageTypes
+}
@@ -0,0 +1,145 @@
+package xmlparser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestService(t *testing.T) {
+	dir, err := os.MkdirTemp("", "service")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeSchema := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	writeSchema("orders.xsd", `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+  <xs:element name="cancellation" type="xs:string"/>
+</xs:schema>`)
+
+	service := NewService(ServiceConfig{Bundles: map[string]SchemaBundle{
+		"order":    {Location: filepath.Join(dir, "orders.xsd"), RootElement: "order"},
+		"invoice":  {Location: filepath.Join(dir, "invoices.xsd")},
+		"shipment": {Location: filepath.Join(dir, "orders.xsd"), RootElement: "shipment"},
+	}})
+
+	if service.Ready() {
+		t.Error("Expected the service not to be ready before Start")
+	}
+	if err := service.Validate("order", []byte(`<order><quantity>1</quantity></order>`)); err == nil ||
+		!strings.Contains(err.Error(), "not loaded") {
+		t.Errorf("Expected an error for a schema not loaded yet, got: %v", err)
+	}
+
+	err = service.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "message type 'invoice'") ||
+		!strings.Contains(err.Error(), "root element 'shipment' is not defined") {
+		t.Errorf("Expected the failing bundles to be reported, got: %v", err)
+	}
+	health := service.Health()
+	if health.Ready || len(health.Bundles) != 3 || health.Bundles[0].MessageType != "invoice" ||
+		health.Bundles[0].Ready || health.Bundles[0].Error == "" || !health.Bundles[1].Ready {
+		t.Errorf("Unexpected health: %+v", health)
+	}
+
+	tests := []struct {
+		name        string
+		messageType string
+		xml         string
+		errorMsg    string // Empty when valid; "!" prefixes errors that are not a ValidationError
+	}{
+		{"valid", "order", `<order><quantity>1</quantity></order>`, ""},
+		{"invalid", "order", `<order><quantity>0</quantity></order>`, "must be positive"},
+		{"malformed", "order", `<order>`, "XML parsing error"},
+		{"other root", "order", `<cancellation>late</cancellation>`, "not allowed for message type 'order'"},
+		{"undeclared root", "order", `<refund/>`, "root element <refund> is not defined"},
+		{"unknown message type", "refund", `<refund/>`, "!unknown message type 'refund'"},
+		{"failed bundle", "invoice", `<invoice/>`, "!schema of message type 'invoice' is not loaded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.Validate(tt.messageType, []byte(tt.xml))
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Expected a valid message, got: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			expected := strings.TrimPrefix(tt.errorMsg, "!")
+			if err == nil || !strings.Contains(err.Error(), expected) ||
+				errors.As(err, &validationErr) != (expected == tt.errorMsg) {
+				t.Errorf("Expected error containing '%s', got: %T %v", expected, err, err)
+			}
+		})
+	}
+
+	// A retry loads the bundles that failed
+	writeSchema("invoices.xsd", `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="invoice" type="xs:string"/>
+</xs:schema>`)
+	if err := service.Start(context.Background()); err == nil || strings.Contains(err.Error(), "invoice") {
+		t.Errorf("Expected only the shipment bundle to fail, got: %v", err)
+	}
+	if err := service.Validate("invoice", []byte(`<invoice>42</invoice>`)); err != nil {
+		t.Errorf("Expected the retried bundle to validate, got: %v", err)
+	}
+
+	var events []ValidationEvent
+	observed := NewService(ServiceConfig{
+		Bundles:    map[string]SchemaBundle{"order": {Location: filepath.Join(dir, "orders.xsd")}},
+		OnValidate: func(event ValidationEvent) { events = append(events, event) },
+	})
+	if err := observed.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	observed.Validate("order", []byte(`<order><quantity>0</quantity></order>`))
+	observed.Validate("refund", []byte(`<refund/>`))
+	if len(events) != 2 || events[0].MessageType != "order" || events[0].Bytes != 37 || events[0].Duration <= 0 ||
+		!events[0].KnownMessageType || !errors.As(events[0].Err, new(*ValidationError)) ||
+		events[1].KnownMessageType || events[1].Err == nil {
+		t.Errorf("Unexpected validation events: %+v", events)
+	}
+
+	// Messages exceeding the parser limits are parse errors located where they exceed them
+	limited := NewService(ServiceConfig{
+		Bundles:         map[string]SchemaBundle{"order": {Location: filepath.Join(dir, "orders.xsd")}},
+		DocumentOptions: DocumentOptions{MaxDepth: 1, ProhibitDTD: true},
+	})
+	if err := limited.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	var limitErr *ValidationError
+	err = limited.Validate("order", []byte("<order>\n  <quantity>1</quantity>\n</order>"))
+	if !errors.As(err, &limitErr) || !errors.As(limitErr.ParseError, new(*LimitError)) ||
+		limitErr.Issues[0].Code != CodeParseError || limitErr.Issues[0].Line != 2 || limitErr.Issues[0].Column != 3 {
+		t.Errorf("Expected a located MaxDepth parse error, got: %v", err)
+	}
+	if err := limited.Validate("order", []byte(`<!DOCTYPE order><order/>`)); err == nil || !strings.Contains(err.Error(), "directives are not allowed") {
+		t.Errorf("Expected the document type declaration to be rejected, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled := NewService(ServiceConfig{Bundles: map[string]SchemaBundle{"order": {Location: filepath.Join(dir, "orders.xsd")}}})
+	if err := canceled.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected loading to stop with the context, got: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package xmlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shardKey is a compiled shard key path: the declarations of the elements it steps through
+// below the message's root element, and of the attribute it ends with, if any.
+type shardKey struct {
+	elements  []*Element
+	attribute *Attribute
+}
+
+// RegisterShardKey registers the path of the sharding key of one message type: the global
+// element message, looked up like NewGenerator. ValidateAndAnnotate then extracts the key
+// of every document with that root element into Result.ShardKey, so pipelines can route
+// documents right after validation without walking them again.
+//
+// The path is a simple XPath location path of child steps relative to the root element,
+// such as "header/orderId" or "customer/@id", optionally written from the root as
+// "/order/header/orderId". Prefixes of steps are ignored. The path is checked against the
+// schema: every step must be declared and the path must end with an attribute or an
+// element of simple type. Register keys before validating, as RegisterShardKey must not be
+// called concurrently with validation.
+func (s *Schema) RegisterShardKey(message, path string) error {
+	root, exists := s.ElementMap[message]
+	if !exists {
+		return fmt.Errorf("root element '%s' is not defined in the schema", message)
+	}
+
+	steps := strings.Split(strings.TrimSpace(path), "/")
+	if strings.HasPrefix(path, "/") {
+		if len(steps) < 2 || ParseQName(steps[1]).LocalName != ParseQName(root.Name).LocalName {
+			return fmt.Errorf("shard key path '%s' does not start at root element <%s>", path, message)
+		}
+		steps = steps[2:]
+	}
+	if len(steps) == 0 || steps[0] == "" {
+		return fmt.Errorf("shard key path '%s' selects no element or attribute below <%s>", path, message)
+	}
+
+	key := &shardKey{}
+	current := root
+	for i, step := range steps {
+		complexType := s.getComplexType(current)
+		if strings.HasPrefix(step, "@") {
+			if i != len(steps)-1 {
+				return fmt.Errorf("shard key path '%s': attribute step '%s' must be the last step", path, step)
+			}
+			name := ParseQName(step[1:]).LocalName
+			if complexType != nil {
+				key.attribute = findAttributeByLocalName(complexType.Attributes, name)
+			}
+			if key.attribute == nil {
+				return fmt.Errorf("shard key path '%s': attribute '%s' is not declared on <%s>", path, name, current.Name)
+			}
+			break
+		}
+
+		name := ParseQName(step).LocalName
+		var child *Element
+		if complexType != nil {
+			child = complexType.childDeclaration(name)
+		}
+		if child == nil {
+			return fmt.Errorf("shard key path '%s': element <%s> is not declared in <%s>", path, name, current.Name)
+		}
+		key.elements = append(key.elements, child)
+		current = child
+	}
+	if key.attribute == nil && s.getComplexType(current) != nil {
+		return fmt.Errorf("shard key path '%s': element <%s> has complex content", path, current.Name)
+	}
+
+	if s.shardKeys == nil {
+		s.shardKeys = make(map[*Element]*shardKey)
+	}
+	s.shardKeys[root] = key
+	return nil
+}
+
+// childDeclaration returns the declaration of the child elements with a local name in the
+// effective content model of a complex type, or nil.
+func (ct *ComplexType) childDeclaration(local string) *Element {
+	var found *Element
+	match := func(element *Element) {
+		if found == nil && ParseQName(element.Name).LocalName == local {
+			found = element
+		}
+	}
+	if ct.Sequence != nil {
+		forEachSequenceElement(ct.Sequence, match)
+	}
+	if ct.Choice != nil {
+		forEachChoiceElement(ct.Choice, match)
+	}
+	if ct.All != nil {
+		for i := range ct.All.Elements {
+			match(&ct.All.Elements[i])
+		}
+	}
+	return found
+}
+
+// findAttributeByLocalName returns the attribute use with a local name, or nil.
+func findAttributeByLocalName(attributeDefs []Attribute, local string) *Attribute {
+	for i := range attributeDefs {
+		if attributeDefs[i].Name == local && attributeDefs[i].Use != "prohibited" {
+			return &attributeDefs[i]
+		}
+	}
+	return nil
+}
+
+// extractShardKey returns the value the shard key registered for a root element's
+// declaration selects in a document, normalized for its type, and whether it was found.
+// The first match in document order is used; an absent attribute with a default or fixed
+// value selects that value.
+func (s *Schema) extractShardKey(root *Node, rootDef *Element) (string, bool) {
+	key := s.shardKeys[rootDef]
+	if key == nil {
+		return "", false
+	}
+	return s.matchShardKey(root, key, 0)
+}
+
+// matchShardKey matches the steps of a shard key from depth onwards below node.
+func (s *Schema) matchShardKey(node *Node, key *shardKey, depth int) (string, bool) {
+	if depth == len(key.elements) {
+		if attrDef := key.attribute; attrDef != nil {
+			baseType := s.builtInBase(attrDef.Type, s.attributeSimpleType(attrDef))
+			for _, attr := range node.Attrs {
+				if attr.Name.Local == attrDef.Name && attr.Name.Space == attrDef.namespace {
+					return normalizeWhitespace(attr.Value, baseType), true
+				}
+			}
+			if attrDef.Default != "" || attrDef.Fixed != "" {
+				return normalizeWhitespace(attrDef.Default+attrDef.Fixed, baseType), true
+			}
+			return "", false
+		}
+
+		def := key.elements[depth-1]
+		simpleType, _ := s.findSimpleType(def)
+		return normalizeWhitespace(strings.TrimSpace(node.Content), s.builtInBase(def.Type, simpleType)), true
+	}
+
+	local := ParseQName(key.elements[depth].Name).LocalName
+	for _, child := range node.Children {
+		if child.Name.Local == local {
+			if value, found := s.matchShardKey(child, key, depth+1); found {
+				return value, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,129 @@
+package xmlparser
+
+import "testing"
+
+// Test that registered shard keys are extracted into the validation result
+func TestShardKey(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+    targetNamespace="urn:example:orders"
+    xmlns:o="urn:example:orders"
+    elementFormDefault="qualified">
+    <xs:complexType name="Header">
+        <xs:sequence>
+            <xs:element name="orderId" type="xs:token"/>
+        </xs:sequence>
+        <xs:attribute name="region" type="xs:string" default="eu"/>
+    </xs:complexType>
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="header" type="o:Header"/>
+                <xs:element name="quantity" type="xs:positiveInteger"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+    <xs:element name="invoice">
+        <xs:complexType>
+            <xs:attribute name="number" type="xs:integer" use="required"/>
+        </xs:complexType>
+    </xs:element>
+    <xs:element name="note" type="xs:string"/>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	registrations := []struct {
+		message     string
+		path        string
+		errorString string
+	}{
+		{message: "order", path: "/o:order/o:header/o:orderId"},
+		{message: "invoice", path: "@number"},
+		{message: "receipt", path: "id", errorString: "root element 'receipt' is not defined in the schema"},
+		{message: "order", path: "/invoice/@number", errorString: "shard key path '/invoice/@number' does not start at root element <order>"},
+		{message: "order", path: "", errorString: "shard key path '' selects no element or attribute below <order>"},
+		{message: "order", path: "header/customer", errorString: "shard key path 'header/customer': element <customer> is not declared in <header>"},
+		{message: "order", path: "@id", errorString: "shard key path '@id': attribute 'id' is not declared on <order>"},
+		{message: "order", path: "@region/orderId", errorString: "shard key path '@region/orderId': attribute step '@region' must be the last step"},
+		{message: "order", path: "header", errorString: "shard key path 'header': element <header> has complex content"},
+	}
+	for _, registration := range registrations {
+		err := schema.RegisterShardKey(registration.message, registration.path)
+		if registration.errorString == "" {
+			if err != nil {
+				t.Errorf("Failed to register shard key %q: %v", registration.path, err)
+			}
+		} else if err == nil || err.Error() != registration.errorString {
+			t.Errorf("Expected error %q, got: %v", registration.errorString, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		xml      string
+		valid    bool
+		key      string
+		keyFound bool
+	}{
+		{
+			name:     "Element value normalized for its type",
+			xml:      `<order xmlns="urn:example:orders"><header><orderId>  A-17 </orderId></header><quantity>2</quantity></order>`,
+			valid:    true,
+			key:      "A-17",
+			keyFound: true,
+		},
+		{
+			name:     "Invalid document",
+			xml:      `<order xmlns="urn:example:orders"><header><orderId>B-2</orderId></header><quantity>0</quantity></order>`,
+			key:      "B-2",
+			keyFound: true,
+		},
+		{
+			name: "Missing key",
+			xml:  `<order xmlns="urn:example:orders"><quantity>2</quantity></order>`,
+		},
+		{
+			name:     "Attribute",
+			xml:      `<invoice xmlns="urn:example:orders" number="42"/>`,
+			valid:    true,
+			key:      "42",
+			keyFound: true,
+		},
+		{
+			name:  "Message type without shard key",
+			xml:   `<note xmlns="urn:example:orders">hello</note>`,
+			valid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			result, err := schema.ValidateAndAnnotate(doc)
+			if err != nil {
+				t.Fatalf("ValidateAndAnnotate failed: %v", err)
+			}
+			if result.Valid() != tt.valid {
+				t.Errorf("Expected validity %v, got issues: %v", tt.valid, result.Issues)
+			}
+			if result.ShardKey != tt.key || result.ShardKeyFound != tt.keyFound {
+				t.Errorf("Expected shard key %q (found %v), got %q (found %v)", tt.key, tt.keyFound, result.ShardKey, result.ShardKeyFound)
+			}
+		})
+	}
+
+	// A default attribute value stands in for an absent attribute
+	if err := schema.RegisterShardKey("order", "header/@region"); err != nil {
+		t.Fatalf("Failed to register shard key: %v", err)
+	}
+	doc, _ := Parse([]byte(`<order xmlns="urn:example:orders"><header><orderId>A</orderId></header><quantity>1</quantity></order>`))
+	if result, _ := schema.ValidateAndAnnotate(doc); result.ShardKey != "eu" || !result.ShardKeyFound {
+		t.Errorf("Expected the default region as shard key, got %q", result.ShardKey)
+	}
+}
@@ -0,0 +1,260 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// ValidateReader validates the XML document read from r as it is decoded, without building
+// the whole Node tree, for documents too large to hold in memory such as multi-gigabyte
+// exports. Each element is validated as soon as its end tag is read, after which its
+// content is dropped; the element itself is dropped once its parent's content model has
+// matched it, as the next sibling starts. Only the open elements, their first child and the
+// number of their children of each name are kept, so memory grows with the depth of the
+// document, not with its size.
+//
+// The issues are those Validate reports, but in the order elements end, so an element's
+// issues follow those of its children. Reading stops at the first malformed token, which is
+// returned as an error instead of the issues found so far.
+func (s *Schema) ValidateReader(r io.Reader) error {
+	parser, err := newReaderParser(r, DocumentOptions{})
+	if err != nil {
+		return err
+	}
+	return s.validateStream(parser)
+}
+
+// ValidateDecoder validates the next element read from decoder, with its subtree, as a
+// document of its own, for applications that already decode the surrounding XML, such as
+// a SOAP body read after its envelope. The element is validated as it is decoded, as with
+// ValidateReader, against the global declaration of its name. Whitespace and comments
+// before it are skipped, and the decoder is left after its end tag, so the application
+// can carry on decoding; an end tag found before any element is an error.
+//
+// Element and attribute names are resolved by the decoder, including prefixes declared on
+// enclosing elements. QName values such as xsi:type only see the declarations within the
+// element. Issues do not give line and column positions, as the source is not available.
+func (s *Schema) ValidateDecoder(decoder *xml.Decoder) error {
+	return s.validateStream(&xmlParser{decoder: decoder, element: true})
+}
+
+// validateStream validates the document read by parser as it is parsed.
+func (s *Schema) validateStream(parser *xmlParser) error {
+	stream := newStreamValidator(s)
+	parser.onStart, parser.onEnd = stream.start, stream.end
+	if _, err := parser.parseDocument(); err != nil {
+		return err
+	}
+	return stream.finish()
+}
+
+// streamValidator validates elements as a document is decoded.
+type streamValidator struct {
+	v       *validator
+	open    []*streamElement  // Elements whose end tag has not been read yet, innermost last
+	issues  []ValidationIssue // Issues found so far, up to the number a ValidationError stores
+	omitted int               // Issues found beyond those stored
+}
+
+// streamElement is an element being read and the declaration it is validated against.
+type streamElement struct {
+	node *Node
+	def  *Element // Nil for elements that are not validated, such as undeclared children
+
+	resolved bool         // Whether the type of the element has been looked up
+	matcher  childMatcher // Matcher of the content model its children are declared in, if any
+
+	// Children already fed to the matcher at the start of node.Children: the first child is
+	// kept for the messages naming it, and the others are dropped once fed
+	fed      int
+	siblings map[xml.Name]int // Number of children started so far, by name
+	dropped  subtreeStats     // Size of the subtrees of the children dropped, for subtree budgets
+}
+
+// newStreamValidator returns a validator for a document of the schema read as a stream.
+func newStreamValidator(s *Schema) *streamValidator {
+	v := newValidator(s, ValidateOptions{})
+	v.pruned = make(map[*Node]subtreeStats)
+	v.streamed = make(map[*Node]childMatcher)
+	return &streamValidator{v: v}
+}
+
+// finish checks the ID references once the whole document has been read, and returns the
+// issues found as a ValidationError, or nil.
+func (sv *streamValidator) finish() error {
+	sv.report(sv.v.checkIDReferences())
+	if len(sv.issues) > 0 {
+		validationErr := newValidationError(sv.issues)
+		validationErr.Omitted += sv.omitted
+		return validationErr
+	}
+	return nil
+}
+
+// start looks up the declaration of an element from that of its parent. An undeclared
+// root element ends validation.
+func (sv *streamValidator) start(node *Node) error {
+	if node.Parent == nil {
+		rootDef, rootErr := sv.v.rootDeclaration(&Document{Root: node}, sv.v.opts)
+		if rootErr != nil {
+			return rootErr
+		}
+		sv.open = append(sv.open, &streamElement{node: node, def: rootDef})
+		return nil
+	}
+
+	// The siblings before node have ended
+	parent := sv.open[len(sv.open)-1]
+	sv.feedChildren(parent, len(parent.node.Children)-1)
+	if parent.siblings == nil {
+		parent.siblings = make(map[xml.Name]int)
+	}
+	parent.siblings[node.Name]++
+	node.sibling = parent.siblings[node.Name]
+
+	sv.open = append(sv.open, &streamElement{node: node, def: sv.childDeclaration(parent, node)})
+	return nil
+}
+
+// end validates an element whose content has been read.
+func (sv *streamValidator) end(node *Node) error {
+	sv.report(sv.validateEnded(node))
+	return nil
+}
+
+// validateEnded validates an element whose content has been read, returning its issues,
+// then drops its content: its children have been validated already, and its parent
+// only needs its name.
+func (sv *streamValidator) validateEnded(node *Node) []ValidationIssue {
+	element := sv.open[len(sv.open)-1]
+	sv.open = sv.open[:len(sv.open)-1]
+	sv.feedChildren(element, len(node.Children))
+
+	if len(sv.v.opts.SubtreeBudgets) > 0 {
+		elements, textLength := sv.v.subtreeSize(node)
+		sv.v.pruned[node] = subtreeStats{
+			elements:   elements + element.dropped.elements,
+			textLength: textLength + element.dropped.textLength,
+		}
+	}
+
+	var issues []ValidationIssue
+	if element.def != nil {
+		if element.matcher != nil {
+			sv.v.streamed[node] = element.matcher
+		}
+		sv.v.validateNode(node, element.def)
+		issues = append(issues, sv.v.issues...)
+		sv.v.issues = sv.v.issues[:0]
+		delete(sv.v.streamed, node)
+	}
+	if element.matcher != nil {
+		element.matcher.release()
+	}
+
+	for _, child := range node.Children {
+		delete(sv.v.pruned, child)
+	}
+	node.Children, node.Content = nil, ""
+	return issues
+}
+
+// feedChildren feeds the ended children of an element before index end to its matcher,
+// then drops them, all but the first.
+func (sv *streamValidator) feedChildren(element *streamElement, end int) {
+	if element.fed >= end {
+		return
+	}
+	children := element.node.Children
+	for _, child := range children[element.fed:end] {
+		if element.matcher != nil {
+			element.matcher.feed(child)
+		}
+	}
+
+	kept := element.fed
+	if kept == 0 {
+		kept = 1
+	}
+	for i, child := range children[kept:end] {
+		if len(sv.v.opts.SubtreeBudgets) > 0 {
+			size := sv.v.pruned[child]
+			element.dropped.elements += 1 + size.elements
+			element.dropped.textLength += size.textLength
+			delete(sv.v.pruned, child)
+		}
+		children[kept+i] = nil
+	}
+	n := copy(children[kept:], children[end:])
+	for i := kept + n; i < len(children); i++ {
+		children[i] = nil
+	}
+	element.node.Children, element.fed = children[:kept+n], kept
+}
+
+// drop removes the last child of the innermost open element, which ended without being fed
+// to its matcher, as if it had not been read.
+func (sv *streamValidator) drop(node *Node) {
+	parent := sv.open[len(sv.open)-1]
+	children := parent.node.Children
+	children[len(children)-1] = nil
+	parent.node.Children = children[:len(children)-1]
+	parent.siblings[node.Name]--
+	delete(sv.v.pruned, node)
+}
+
+// childDeclaration returns the declaration a child is validated against: the one the
+// content model of its parent's type matches it with, as Validate finds it, or nil when
+// the child is not validated.
+func (sv *streamValidator) childDeclaration(parent *streamElement, child *Node) *Element {
+	if !parent.resolved {
+		parent.resolved = true
+		switch model, all := sv.contentOf(parent.node, parent.def); {
+		case model != nil:
+			parent.matcher = sv.v.acquireMatcher(parent.node, model)
+		case all != nil:
+			parent.matcher = sv.v.acquireAllMatcher(parent.node, all)
+		}
+	}
+
+	if parent.matcher == nil {
+		return nil
+	}
+	return parent.matcher.declaration(child)
+}
+
+// contentOf returns the content model or xs:all group declaring the children of an
+// element, taking xsi:type into account. Elements that are not validated, nilled, or
+// of an xsi:type that does not apply have none, as their children are not validated.
+func (sv *streamValidator) contentOf(node *Node, def *Element) (*compiledParticle, *All) {
+	if def == nil {
+		return nil, nil
+	}
+	if typeName, ok := xsiType(node); ok {
+		substituted, typeErrors := sv.v.resolveXsiType(node, def, typeName)
+		if len(typeErrors) > 0 {
+			return nil, nil
+		}
+		def = substituted
+	}
+	if nilled, _ := xsiNil(node); nilled {
+		return nil, nil
+	}
+
+	complexType := sv.v.getComplexType(def)
+	if complexType == nil {
+		return nil, nil
+	}
+	return sv.v.contentModel(complexType), complexType.All
+}
+
+// report records issues, keeping as many as a ValidationError stores.
+func (sv *streamValidator) report(issues []ValidationIssue) {
+	for _, issue := range issues {
+		if len(sv.issues) < maxStoredErrors {
+			sv.issues = append(sv.issues, issue)
+		} else {
+			sv.omitted++
+		}
+	}
+}
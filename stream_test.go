@@ -0,0 +1,286 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestValidateReader(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="catalog">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="item" type="ItemType" maxOccurs="unbounded"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+  <xs:complexType name="ItemType">
+    <xs:sequence>
+      <xs:element name="name" type="xs:string"/>
+      <xs:element name="price" type="xs:decimal"/>
+      <xs:element name="related" type="xs:IDREF" minOccurs="0"/>
+    </xs:sequence>
+    <xs:attribute name="id" type="xs:ID" use="required"/>
+  </xs:complexType>
+  <xs:complexType name="BookType">
+    <xs:complexContent>
+      <xs:extension base="ItemType">
+        <xs:sequence>
+          <xs:element name="isbn" type="xs:string"/>
+        </xs:sequence>
+      </xs:extension>
+    </xs:complexContent>
+  </xs:complexType>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"valid", `<catalog xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <item id="a"><name>Pen</name><price>1.50</price></item>
+  <item id="b" xsi:type="BookType"><name>Go</name><price>30</price><related>a</related><isbn>978</isbn></item>
+</catalog>`},
+		{"invalid values", `<catalog><item id="a"><name>Pen</name><price>cheap</price></item></catalog>`},
+		{"invalid content", `<catalog><item id="a"><price>1</price><name>Pen</name></item><item/></catalog>`},
+		{"invalid later siblings", `<catalog><item id="a"><name>Pen</name><price>1</price></item><item id="b"><name>Ink</name><price>2</price></item>
+  <item id="c"><price>3</price><name>Pad</name><related>a</related><related>b</related></item></catalog>`},
+		{"undeclared child", `<catalog><item id="a"><name>Pen</name><price>1</price><color>red</color></item></catalog>`},
+		{"extension content", `<catalog xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <item id="b" xsi:type="BookType"><name>Go</name><price>x</price></item>
+</catalog>`},
+		{"unknown xsi:type", `<catalog xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <item id="b" xsi:type="MissingType"><name>Go</name><price>x</price></item>
+</catalog>`},
+		{"ID references", `<catalog><item id="a"><name>Pen</name><price>1</price><related>missing</related></item><item id="a"><name>Ink</name><price>2</price></item></catalog>`},
+		{"undeclared root", `<inventory/>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Failed to parse document: %v", err)
+			}
+			expected := sortedIssues(schema.Validate(doc))
+			actual := sortedIssues(schema.ValidateReader(iotest.OneByteReader(strings.NewReader(tt.input))))
+			if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+				t.Errorf("Expected the issues of Validate:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+			}
+		})
+	}
+
+	t.Run("large document", func(t *testing.T) {
+		var b strings.Builder
+		b.WriteString("<catalog>\n")
+		for i := 0; i < 20000; i++ {
+			price := "1.50"
+			if i%5000 == 0 {
+				price = "free"
+			}
+			fmt.Fprintf(&b, "  <item id=\"i%d\"><name>Item %d</name><price>%s</price></item>\n", i, i, price)
+		}
+		b.WriteString("</catalog>\n")
+
+		err := schema.ValidateReader(strings.NewReader(b.String()))
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || len(validationErr.Issues) != 4 {
+			t.Fatalf("Expected 4 issues, got: %v", err)
+		}
+		expectValidationError(t, err, "value 'free' is not a valid decimal")
+	})
+
+	t.Run("malformed document", func(t *testing.T) {
+		err := schema.ValidateReader(strings.NewReader(`<catalog><item id="a"></catalog>`))
+		var validationErr *ValidationError
+		if err == nil || errors.As(err, &validationErr) {
+			t.Errorf("Expected a parsing error, got: %v", err)
+		}
+	})
+}
+
+func TestValidateReaderMemory(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="feed">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="header" type="xs:string"/>
+        <xs:element name="record" maxOccurs="unbounded">
+          <xs:complexType>
+            <xs:all>
+              <xs:element name="name" type="xs:string"/>
+              <xs:element name="quantity" type="xs:positiveInteger"/>
+            </xs:all>
+          </xs:complexType>
+        </xs:element>
+        <xs:element name="trailer" type="xs:string"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	// Every 10000th record has an invalid quantity, and the trailer is missing
+	const records = 50000
+	parser, err := newReaderParser(&recordReader{records: records}, DocumentOptions{})
+	if err != nil {
+		t.Fatalf("Failed to start parsing: %v", err)
+	}
+	stream := newStreamValidator(schema)
+	var heap [2]uint64
+	ended, kept := 0, 0
+	parser.onStart = stream.start
+	parser.onEnd = func(node *Node) error {
+		if err := stream.end(node); err != nil {
+			return err
+		}
+		if node.Name.Local != "record" {
+			return nil
+		}
+		if n := len(node.Parent.Children); n > kept {
+			kept = n
+		}
+		if ended++; ended == 1000 || ended == records {
+			var stats runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			heap[ended/records] = stats.HeapAlloc
+		}
+		return nil
+	}
+	if _, err := parser.parseDocument(); err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	err = stream.finish()
+
+	// The first child is kept, with the record just ended
+	if kept > 2 {
+		t.Errorf("Expected at most 2 children of <feed> to be kept, got %d", kept)
+	}
+	if len(stream.v.pruned) > 0 || len(stream.v.streamed) > 0 {
+		t.Errorf("Expected no state left for ended elements, got %d subtree sizes and %d matchers",
+			len(stream.v.pruned), len(stream.v.streamed))
+	}
+	if growth := int64(heap[1]) - int64(heap[0]); growth > 1<<20 {
+		t.Errorf("Expected memory to stay bounded as records are read, grew by %d bytes", growth)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Issues) != 6 {
+		t.Fatalf("Expected 6 issues, got: %v", err)
+	}
+	for i, issue := range validationErr.Issues[:5] {
+		if path := fmt.Sprintf("/feed/record[%d]/quantity[1]", 10000*(i+1)); issue.XPath != path {
+			t.Errorf("Expected issue %d at %s, got %s", i, path, issue.XPath)
+		}
+	}
+	expectValidationError(t, err, "element <feed> requires at least 1 <trailer> child, but found 0")
+}
+
+// recordReader generates a feed of records as it is read.
+type recordReader struct {
+	records, written int
+	pending          []byte
+}
+
+func (r *recordReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		switch {
+		case r.written == 0:
+			r.pending = []byte("<feed><header>export</header>\n")
+		case r.written <= r.records:
+			quantity := 1 + r.written%7
+			if r.written%10000 == 0 {
+				quantity = 0
+			}
+			r.pending = []byte(fmt.Sprintf("  <record><quantity>%d</quantity><name>Item %d</name></record>\n", quantity, r.written))
+		case r.written == r.records+1:
+			r.pending = []byte("</feed>\n")
+		default:
+			return 0, io.EOF
+		}
+		r.written++
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// sortedIssues returns the issues of a validation error in sorted order.
+func sortedIssues(err error) []string {
+	issues := append([]string(nil), issueMessages(err)...)
+	sort.Strings(issues)
+	return issues
+}
+
+func TestValidateDecoder(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:orders"
+           xmlns="urn:orders" elementFormDefault="qualified">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger"/>
+      </xs:sequence>
+      <xs:attribute name="id" type="xs:string" use="required"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		errorMsg string
+	}{
+		{"valid", `<!-- payload --> <o:order id="1"><o:quantity>3</o:quantity></o:order>`, ""},
+		{"invalid", `<o:order><o:quantity>0</o:quantity></o:order>`, "2 validation errors"},
+		{"missing", ``, "expected an element, found end tag </Body>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := xml.NewDecoder(strings.NewReader(
+				`<Envelope xmlns="urn:envelope" xmlns:o="urn:orders"><Header/><Body>` + tt.body + `</Body></Envelope>`))
+			for {
+				token, err := decoder.Token()
+				if err != nil {
+					t.Fatalf("Failed to read the envelope: %v", err)
+				}
+				if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Body" {
+					break
+				}
+			}
+
+			err := schema.ValidateDecoder(decoder)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("Expected error containing '%s', got: %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected the payload to be valid, got: %v", err)
+			}
+			if token, err := decoder.Token(); err != nil || token.(xml.EndElement).Name.Local != "Body" {
+				t.Errorf("Expected the decoder to be left before </Body>, got %v (%v)", token, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package xmlparser
+
+import "context"
+
+// Tracer starts the spans of schema parsing and validation, for tracing systems such as
+// OpenTelemetry, which the tracing/otel module adapts; set it as ParseOptions.Tracer and
+// ValidateOptions.Tracer. A span is started:
+//
+//   - "xmlparser.ParseXSD" for each schema parsed, with the attributes xsd.location (for
+//     schemas parsed from a location), xsd.target_namespace and xsd.documents, the number
+//     of schema documents loaded
+//   - "xmlparser.LoadSchema" for each schema document loaded, the entry document of
+//     ParseXSDFromLocation and every import and include, with xsd.location, xsd.namespace
+//     (of imports) and xsd.bytes
+//   - "xmlparser.ComposeSchema" for resolving the references and derivations across the
+//     documents of a schema once they are all loaded
+//   - "xmlparser.Validate" for each document validated, with xml.root, the local name of
+//     the root element, xsd.target_namespace, validation.valid and validation.issue_count
+//   - "xmlparser.ValidateElement" for each child of the root element, when
+//     ValidateOptions.TraceChildren is set, with xml.element, xml.xpath and
+//     validation.issue_count
+//
+// The spans of a schema nest in that of its parsing, and those of the children of a
+// document in that of its validation. Documents validated without a context, such as with
+// Validate, start a new trace.
+type Tracer interface {
+	// Start starts a span, as a child of the span of ctx if any, and returns a context
+	// holding it.
+	Start(ctx context.Context, name string, attributes ...SpanAttribute) (context.Context, Span)
+}
+
+// Span is an operation being traced.
+type Span interface {
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attributes ...SpanAttribute)
+
+	// End ends the span, which failed with err if it is not nil.
+	End(err error)
+}
+
+// SpanAttribute is an attribute of a Span. Values are strings, ints or bools.
+type SpanAttribute struct {
+	Key   string
+	Value any
+}
+
+// startSpan starts a span with tracer, or a span that records nothing when tracer is nil.
+func startSpan(ctx context.Context, tracer Tracer, name string, attributes ...SpanAttribute) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noSpan{}
+	}
+	return tracer.Start(ctx, name, attributes...)
+}
+
+// noSpan is the span of operations that are not traced.
+type noSpan struct{}
+
+func (noSpan) SetAttributes(...SpanAttribute) {}
+func (noSpan) End(error)                      {}
@@ -0,0 +1,21 @@
+module github.com/moolekkari/validatexml-go/tracing/otel
+
+go 1.20
+
+require (
+	github.com/moolekkari/validatexml-go v0.2.0
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+)
+
+// The replace directive builds against the working tree within this repository only;
+// importers resolve the version required above, the first with Tracer, Span and SpanAttribute.
+replace github.com/moolekkari/validatexml-go => ../..
@@ -0,0 +1,70 @@
+// Package otel traces schema parsing and validation with OpenTelemetry. It is a module of
+// its own, so that the validatexml-go module stays free of dependencies. With otelapi
+// standing for go.opentelemetry.io/otel:
+//
+//	tracer := otel.NewTracer(otelapi.Tracer("github.com/moolekkari/validatexml-go"))
+//	schema, err := xmlparser.ParseXSDFromLocation(location, xmlparser.ParseOptions{Tracer: tracer})
+//	err = schema.ValidateContext(ctx, doc, xmlparser.ValidateOptions{Tracer: tracer})
+//
+// See xmlparser.Tracer for the spans and their attributes.
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	xmlparser "github.com/moolekkari/validatexml-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracer returns an xmlparser.Tracer starting its spans with an OpenTelemetry tracer.
+func NewTracer(tracer trace.Tracer) xmlparser.Tracer {
+	return otelTracer{tracer: tracer}
+}
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+func (t otelTracer) Start(ctx context.Context, name string, attributes ...xmlparser.SpanAttribute) (context.Context, xmlparser.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attributes)...))
+	return ctx, otelSpan{span: span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(attributes ...xmlparser.SpanAttribute) {
+	s.span.SetAttributes(convert(attributes)...)
+}
+
+// End records a failure as an error event and the status of the span before ending it.
+func (s otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+// convert returns the OpenTelemetry attributes of span attributes, whose values of other
+// types than strings, ints and bools are formatted as strings.
+func convert(attributes []xmlparser.SpanAttribute) []attribute.KeyValue {
+	converted := make([]attribute.KeyValue, len(attributes))
+	for i, a := range attributes {
+		switch value := a.Value.(type) {
+		case string:
+			converted[i] = attribute.String(a.Key, value)
+		case int:
+			converted[i] = attribute.Int(a.Key, value)
+		case bool:
+			converted[i] = attribute.Bool(a.Key, value)
+		default:
+			converted[i] = attribute.String(a.Key, fmt.Sprint(value))
+		}
+	}
+	return converted
+}
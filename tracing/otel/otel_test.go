@@ -0,0 +1,64 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	xmlparser "github.com/moolekkari/validatexml-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test"))
+
+	if _, err := xmlparser.ParseXSDFromLocation("missing.xsd", xmlparser.ParseOptions{Tracer: tracer}); err == nil {
+		t.Fatalf("Expected parsing to fail")
+	}
+	schema, err := xmlparser.ParseXSDWithOptions([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="quantity" type="xs:positiveInteger"/>
+</xs:schema>`), xmlparser.ParseOptions{Tracer: tracer})
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	doc, err := xmlparser.Parse([]byte(`<quantity>0</quantity>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	if err := schema.ValidateContext(context.Background(), doc, xmlparser.ValidateOptions{Tracer: tracer}); err == nil {
+		t.Fatalf("Expected validation to fail")
+	}
+
+	spans := recorder.Ended()
+	names := []string{"xmlparser.LoadSchema", "xmlparser.ParseXSD", "xmlparser.ComposeSchema", "xmlparser.ParseXSD", "xmlparser.Validate"}
+	if len(spans) != len(names) {
+		t.Fatalf("Expected %d spans, got %d", len(names), len(spans))
+	}
+	for i, name := range names {
+		if spans[i].Name() != name {
+			t.Errorf("Span %d: expected %s, got %s", i, name, spans[i].Name())
+		}
+	}
+	if spans[0].Status().Code != codes.Error || len(spans[0].Events()) != 1 || spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("Expected the failed load to be an error in its parse span, got: %+v", spans[0])
+	}
+
+	expected := map[attribute.Key]attribute.Value{
+		"xml.root":               attribute.StringValue("quantity"),
+		"validation.valid":       attribute.BoolValue(false),
+		"validation.issue_count": attribute.IntValue(1),
+	}
+	for _, kv := range spans[4].Attributes() {
+		if value, ok := expected[kv.Key]; ok && value != kv.Value {
+			t.Errorf("Attribute %s: expected %v, got %v", kv.Key, value.Emit(), kv.Value.Emit())
+		}
+		delete(expected, kv.Key)
+	}
+	if len(expected) > 0 || spans[4].Status().Code != codes.Unset {
+		t.Errorf("Unexpected validation span: %v (%v)", spans[4].Attributes(), spans[4].Status())
+	}
+}
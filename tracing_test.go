@@ -0,0 +1,147 @@
+package xmlparser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordingTracer records the spans it starts, each with its parent.
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]any
+	ended      bool
+	err        error
+}
+
+type spanKey struct{}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attributes ...SpanAttribute) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: make(map[string]any)}
+	span.SetAttributes(attributes...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...SpanAttribute) {
+	for _, attribute := range attributes {
+		s.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *recordedSpan) End(err error) {
+	s.ended, s.err = true, err
+}
+
+// describe describes a span as "name(key=value ...) < parent", with the attributes given.
+func (s *recordedSpan) describe(keys ...string) string {
+	var attributes []string
+	for _, key := range keys {
+		attributes = append(attributes, fmt.Sprintf("%s=%v", key, s.attributes[key]))
+	}
+	description := s.name + "(" + strings.Join(attributes, " ") + ")"
+	if s.parent != nil {
+		description += " < " + s.parent.name
+	}
+	return description
+}
+
+func TestTracing(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"order.xsd": `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:c="urn:common" targetNamespace="urn:order">
+  <xs:import namespace="urn:common" schemaLocation="common.xsd"/>
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="c:Quantity" form="unqualified" maxOccurs="unbounded"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`,
+		"common.xsd": `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:common">
+  <xs:simpleType name="Quantity">
+    <xs:restriction base="xs:positiveInteger"/>
+  </xs:simpleType>
+</xs:schema>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tracer := &recordingTracer{}
+	schema, err := ParseXSDFromLocation(filepath.Join(dir, "order.xsd"), ParseOptions{Tracer: tracer})
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	expected := []string{
+		"xmlparser.ParseXSD(xsd.target_namespace=urn:order xsd.documents=2)",
+		fmt.Sprintf("xmlparser.LoadSchema(xsd.namespace= xsd.bytes=%d) < xmlparser.ParseXSD", len(files["order.xsd"])),
+		fmt.Sprintf("xmlparser.LoadSchema(xsd.namespace=urn:common xsd.bytes=%d) < xmlparser.ParseXSD", len(files["common.xsd"])),
+		"xmlparser.ComposeSchema() < xmlparser.ParseXSD",
+	}
+	keys := [][]string{{"xsd.target_namespace", "xsd.documents"}, {"xsd.namespace", "xsd.bytes"}, {"xsd.namespace", "xsd.bytes"}, nil}
+	if len(tracer.spans) != len(expected) {
+		t.Fatalf("Expected %d spans, got %d", len(expected), len(tracer.spans))
+	}
+	for i, span := range tracer.spans {
+		if description := span.describe(keys[i]...); description != expected[i] || !span.ended || span.err != nil {
+			t.Errorf("Span %d: expected %s, got %s (ended: %t, error: %v)", i, expected[i], description, span.ended, span.err)
+		}
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		doc, err := Parse([]byte(`<o:order xmlns:o="urn:order"><quantity>1</quantity><quantity>0</quantity></o:order>`))
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+		ctx, parent := tracer.Start(context.Background(), "request")
+		err = schema.ValidateContext(ctx, doc, ValidateOptions{Tracer: tracer, TraceChildren: true})
+		parent.End(nil)
+		if err == nil {
+			t.Fatalf("Expected validation to fail")
+		}
+
+		expected := []string{
+			"request()",
+			"xmlparser.Validate(xml.root=order validation.valid=false validation.issue_count=1) < request",
+			"xmlparser.ValidateElement(xml.xpath=/o:order/quantity[1] validation.issue_count=0) < xmlparser.Validate",
+			"xmlparser.ValidateElement(xml.xpath=/o:order/quantity[2] validation.issue_count=1) < xmlparser.Validate",
+		}
+		keys := [][]string{nil, {"xml.root", "validation.valid", "validation.issue_count"},
+			{"xml.xpath", "validation.issue_count"}, {"xml.xpath", "validation.issue_count"}}
+		if len(tracer.spans) != len(expected) {
+			t.Fatalf("Expected %d spans, got %d", len(expected), len(tracer.spans))
+		}
+		for i, span := range tracer.spans {
+			if description := span.describe(keys[i]...); description != expected[i] || !span.ended {
+				t.Errorf("Span %d: expected %s, got %s (ended: %t)", i, expected[i], description, span.ended)
+			}
+		}
+	})
+
+	t.Run("failed load", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		if _, err := ParseXSDFromLocation(filepath.Join(dir, "missing.xsd"), ParseOptions{Tracer: tracer}); err == nil {
+			t.Fatalf("Expected parsing to fail")
+		}
+		if len(tracer.spans) != 2 || tracer.spans[0].err == nil || tracer.spans[1].name != "xmlparser.LoadSchema" ||
+			tracer.spans[1].err == nil {
+			t.Errorf("Expected the failed load to end both spans with the error, got: %+v", tracer.spans)
+		}
+	})
+}
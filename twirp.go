@@ -0,0 +1,156 @@
+package xmlparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// TwirpPrefix is the path prefix of the routes of Service.TwirpHandler.
+const TwirpPrefix = "/twirp/validatexml.v1.ValidationService/"
+
+// ValidateDocumentRequest is the request of the ValidateDocument RPC of
+// Service.TwirpHandler.
+type ValidateDocumentRequest struct {
+	SchemaRef string `json:"schemaRef"` // Message type of the document
+	Document  []byte `json:"document"`  // XML document; base64 in JSON
+}
+
+// TwirpHandler returns an http.Handler serving the ValidationService of
+// proto/validatexml/v1/validation.proto with the Twirp protocol, for services written in
+// other languages: clients generated from the definition POST a ValidateDocumentRequest to
+// TwirpPrefix+"ValidateDocument", in JSON or protobuf, and get a ValidationReport back in
+// the same encoding. Mount it with http.Handle(TwirpPrefix, service.TwirpHandler()).
+//
+// The document is validated as with Validate. Invalid and malformed documents give a
+// report with issues; an unknown message type is a not_found Twirp error, and one whose
+// schema is not loaded an unavailable error. gRPC is not served: generate gRPC stubs from
+// the definition and call Validate from them.
+func (s *Service) TwirpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != TwirpPrefix+"ValidateDocument" {
+			writeTwirpError(w, "bad_route", fmt.Sprintf("no handler for %s %s", r.Method, r.URL.Path))
+			return
+		}
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if contentType != "application/json" && contentType != "application/protobuf" {
+			writeTwirpError(w, "bad_route", fmt.Sprintf("unexpected Content-Type '%s'", r.Header.Get("Content-Type")))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, DefaultMaxBodyBytes))
+		if err != nil {
+			writeTwirpError(w, "malformed", fmt.Sprintf("failed to read request: %v", err))
+			return
+		}
+		var request ValidateDocumentRequest
+		if contentType == "application/json" {
+			err = request.unmarshalJSON(body)
+		} else {
+			err = request.unmarshalProto(body)
+		}
+		if err != nil {
+			writeTwirpError(w, "malformed", fmt.Sprintf("failed to decode request: %v", err))
+			return
+		}
+
+		report := ValidationReport{MessageType: request.SchemaRef, Valid: true}
+		if err := s.ValidateContext(r.Context(), request.SchemaRef, request.Document); err != nil {
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				code := "unavailable"
+				if _, exists := s.config.Bundles[request.SchemaRef]; !exists {
+					code = "not_found"
+				}
+				writeTwirpError(w, code, err.Error())
+				return
+			}
+			report = validationErr.Report(request.SchemaRef)
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		if contentType == "application/json" {
+			json.NewEncoder(w).Encode(report)
+		} else {
+			w.Write(report.appendProto(nil))
+		}
+	})
+}
+
+// unmarshalJSON decodes a request in the JSON mapping of protobuf, whose decoders accept
+// both the lowerCamelCase names and the original field names.
+func (req *ValidateDocumentRequest) unmarshalJSON(data []byte) error {
+	var fields struct {
+		ValidateDocumentRequest
+		SchemaRef string `json:"schema_ref"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*req = fields.ValidateDocumentRequest
+	if req.SchemaRef == "" {
+		req.SchemaRef = fields.SchemaRef
+	}
+	return nil
+}
+
+// unmarshalProto decodes a request in the protobuf wire format.
+func (req *ValidateDocumentRequest) unmarshalProto(data []byte) error {
+	fields, err := parseProtoFields(data)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		switch {
+		case field.number == 1 && field.wireType == protoBytes:
+			req.SchemaRef = string(field.bytes)
+		case field.number == 2 && field.wireType == protoBytes:
+			req.Document = field.bytes
+		}
+	}
+	return nil
+}
+
+// appendProto appends a report in the protobuf wire format.
+func (r *ValidationReport) appendProto(b []byte) []byte {
+	b = appendProtoString(b, 1, r.MessageType)
+	if r.Valid {
+		b = appendProtoInt(b, 2, 1)
+	}
+	for _, issue := range r.Issues {
+		var encoded []byte
+		encoded = appendProtoString(encoded, 1, string(issue.Code))
+		encoded = appendProtoString(encoded, 2, issue.Message)
+		encoded = appendProtoString(encoded, 3, string(issue.Severity))
+		encoded = appendProtoString(encoded, 4, issue.XPath)
+		encoded = appendProtoInt(encoded, 5, int64(issue.Line))
+		encoded = appendProtoInt(encoded, 6, int64(issue.Column))
+		encoded = appendProtoString(encoded, 7, issue.Rule)
+		b = appendProtoVarint(b, 3<<3|protoBytes)
+		b = appendProtoVarint(b, uint64(len(encoded)))
+		b = append(b, encoded...)
+	}
+	return appendProtoInt(b, 4, int64(r.Omitted))
+}
+
+// twirpStatus is the HTTP status of each Twirp error code used.
+var twirpStatus = map[string]int{
+	"bad_route":   http.StatusNotFound,
+	"malformed":   http.StatusBadRequest,
+	"not_found":   http.StatusNotFound,
+	"unavailable": http.StatusServiceUnavailable,
+}
+
+// writeTwirpError writes a Twirp error response, which is JSON whatever the request's
+// encoding.
+func writeTwirpError(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(twirpStatus[code])
+	json.NewEncoder(w).Encode(struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}{code, message})
+}
@@ -0,0 +1,121 @@
+package xmlparser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTwirpHandler(t *testing.T) {
+	dir, err := os.MkdirTemp("", "twirp")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = os.WriteFile(filepath.Join(dir, "orders.xsd"), []byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="quantity" type="xs:positiveInteger"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	service := NewService(ServiceConfig{Bundles: map[string]SchemaBundle{
+		"order":   {Location: filepath.Join(dir, "orders.xsd")},
+		"invoice": {Location: filepath.Join(dir, "invoices.xsd")},
+	}})
+	service.Start(context.Background())
+	handler := service.TwirpHandler()
+
+	call := func(method, path, contentType string, body []byte) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewReader(body))
+		request.Header.Set("Content-Type", contentType)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	route := TwirpPrefix + "ValidateDocument"
+
+	t.Run("JSON", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			request string
+			status  int
+			check   func(report ValidationReport) bool
+		}{
+			{"valid", `{"schemaRef":"order","document":"PG9yZGVyPjxxdWFudGl0eT4xPC9xdWFudGl0eT48L29yZGVyPg=="}`, 200,
+				func(report ValidationReport) bool { return report.Valid && len(report.Issues) == 0 }},
+			{"invalid, proto field names", `{"schema_ref":"order","document":"PG9yZGVyPjxxdWFudGl0eT4wPC9xdWFudGl0eT48L29yZGVyPg=="}`, 200,
+				func(report ValidationReport) bool {
+					return !report.Valid && len(report.Issues) == 1 && report.Issues[0].XPath == "/order/quantity[1]"
+				}},
+		}
+		for _, tt := range tests {
+			recorder := call("POST", route, "application/json", []byte(tt.request))
+			var report ValidationReport
+			json.Unmarshal(recorder.Body.Bytes(), &report)
+			if recorder.Code != tt.status || report.MessageType != "order" || !tt.check(report) {
+				t.Errorf("%s: unexpected response %d: %s", tt.name, recorder.Code, recorder.Body)
+			}
+		}
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		request := appendProtoString(nil, 1, "order")
+		request = appendProtoString(request, 2, "<order>\n  <quantity>0</quantity>\n</order>")
+		recorder := call("POST", route, "application/protobuf", request)
+		if recorder.Code != 200 || recorder.Header().Get("Content-Type") != "application/protobuf" {
+			t.Fatalf("Unexpected response %d: %s", recorder.Code, recorder.Body)
+		}
+
+		fields, err := parseProtoFields(recorder.Body.Bytes())
+		if err != nil || len(fields) != 2 || string(fields[0].bytes) != "order" || fields[1].number != 3 {
+			t.Fatalf("Unexpected report fields: %+v (%v)", fields, err)
+		}
+		issue, err := parseProtoFields(fields[1].bytes)
+		if err != nil || len(issue) != 7 || string(issue[0].bytes) != "invalid-value" ||
+			!strings.Contains(string(issue[1].bytes), "must be positive") || string(issue[3].bytes) != "/order/quantity[1]" ||
+			issue[4].varint != 2 || issue[5].varint != 3 || string(issue[6].bytes) != "cvc-datatype-valid.1.2.1" {
+			t.Errorf("Unexpected issue fields: %+v (%v)", issue, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			method      string
+			path        string
+			contentType string
+			body        string
+			status      int
+			code        string
+		}{
+			{"unknown method", "POST", TwirpPrefix + "Compile", "application/json", `{}`, 404, "bad_route"},
+			{"GET", "GET", route, "application/json", ``, 404, "bad_route"},
+			{"content type", "POST", route, "text/xml", `<order/>`, 404, "bad_route"},
+			{"malformed JSON", "POST", route, "application/json", `{"schemaRef":`, 400, "malformed"},
+			{"malformed protobuf", "POST", route, "application/protobuf", "\x0a\x10order", 400, "malformed"},
+			{"unknown message type", "POST", route, "application/json", `{"schemaRef":"refund"}`, 404, "not_found"},
+			{"schema not loaded", "POST", route, "application/json", `{"schemaRef":"invoice"}`, 503, "unavailable"},
+		}
+		for _, tt := range tests {
+			recorder := call(tt.method, tt.path, tt.contentType, []byte(tt.body))
+			var twirpErr struct{ Code, Msg string }
+			json.Unmarshal(recorder.Body.Bytes(), &twirpErr)
+			if recorder.Code != tt.status || twirpErr.Code != tt.code || twirpErr.Msg == "" {
+				t.Errorf("%s: expected %d %s, got %d: %s", tt.name, tt.status, tt.code, recorder.Code, recorder.Body)
+			}
+		}
+	})
+}
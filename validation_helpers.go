@@ -2,74 +2,199 @@ package xmlparser
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"regexp"
+	"regexp/syntax"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
+// Lexical forms of built-in types, compiled once
+var (
+	durationRegexp          = regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+	dayTimeDurationRegexp   = regexp.MustCompile(`^-?P(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+	yearMonthDurationRegexp = regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?$`)
+	nameRegexp              = regexp.MustCompile(`^[a-zA-Z_:][\w\-\.]*$`)
+	ncNameRegexp            = regexp.MustCompile(`^[a-zA-Z_][\w\-\.]*$`)
+	qNameRegexp             = regexp.MustCompile(`^([a-zA-Z_][\w\-\.]*:)?[a-zA-Z_][\w\-\.]*$`)
+	nmtokenRegexp           = regexp.MustCompile(`^[\p{L}\p{Nd}\p{Mn}\p{Mc}._:\-\x{B7}]+$`)
+	languageRegexp          = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+)
+
+// compilePattern compiles a pattern facet value. XSD patterns match the whole value, so
+// the expression is anchored; it is checked on its own first, so that a value such as
+// "a)|(b" cannot close the group anchoring it.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if _, err := syntax.Parse(pattern, syntax.Perl); err != nil {
+		return nil, err
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
 // validatePattern checks if content matches the given regex pattern.
-func validatePattern(content, pattern string) error {
-	matched, err := regexp.MatchString(pattern, content)
+func (s *Schema) validatePattern(content, pattern string) error {
+	compiled, err := s.pattern(pattern)
 	if err != nil {
-		return fmt.Errorf("invalid pattern in schema: %s", pattern)
+		return err
 	}
-	if !matched {
-		return fmt.Errorf("value '%s' does not match pattern '%s'", content, pattern)
+	if !compiled.MatchString(content) {
+		return fmt.Errorf("value '%s' does not match pattern '%s'", excerpt(content), pattern)
 	}
 	return nil
 }
 
-// validateEnumeration checks if content is in the allowed enumeration values.
-func validateEnumeration(content string, enumerations []*Facet) error {
-	allowedValues := make([]string, len(enumerations))
-	for i, enum := range enumerations {
-		allowedValues[i] = enum.Value
-		if content == enum.Value {
-			return nil
-		}
+// enumerationSetThreshold is the number of enumeration values above which membership is
+// checked with a hash set instead of a linear scan.
+const enumerationSetThreshold = 16
+
+// maxListedEnumerations bounds the number of allowed values quoted in an error message.
+const maxListedEnumerations = 20
+
+// validateEnumeration checks if content is in the allowed enumeration values. Values are
+// compared in the value space of baseType, the built-in type the restriction derives from.
+// Large code lists (airport codes, tariff numbers) are hashed once per restriction, so each
+// check is a map lookup; the error message is only built on failure and lists a bounded prefix.
+func (s *Schema) validateEnumeration(content string, restriction *Restriction, baseType string) error {
+	if s.enumerationContains(content, restriction, baseType) {
+		return nil
+	}
+
+	enumerations := restriction.Enumeration
+	listed := enumerations
+	if len(listed) > maxListedEnumerations {
+		listed = listed[:maxListedEnumerations]
+	}
+	allowedValues := make([]string, len(listed))
+	for i, enum := range listed {
+		allowedValues[i] = excerpt(enum.Value)
+	}
+	if omitted := len(enumerations) - len(listed); omitted > 0 {
+		return fmt.Errorf("value '%s' is not in the list of allowed values: [%s, ...] (%d more not shown)",
+			excerpt(content), strings.Join(allowedValues, ", "), omitted)
 	}
 	return fmt.Errorf("value '%s' is not in the list of allowed values: [%s]",
-		content, strings.Join(allowedValues, ", "))
+		excerpt(content), strings.Join(allowedValues, ", "))
+}
+
+// enumerationContains reports whether content is one of the restriction's enumeration values.
+func (s *Schema) enumerationContains(content string, restriction *Restriction, baseType string) bool {
+	key := enumerationKey(content, baseType)
+
+	// Short lists, and those of schemas assembled by hand, are scanned
+	if set := s.enumerationSet(restriction); set != nil {
+		_, exists := set[key]
+		return exists
+	}
+	for _, enum := range restriction.Enumeration {
+		if key == enumerationKey(enum.Value, baseType) {
+			return true
+		}
+	}
+	return false
+}
+
+// enumerationKey maps a lexical value to a canonical form of its value in the value space of
+// a built-in type, so equal values compare equal: "01" and "1" as xs:integer, "1.50" and
+// "1.5" as xs:decimal, "1" and "true" as xs:boolean, or values differing only in collapsed
+// whitespace. Values that are not valid for the type are compared as written.
+func enumerationKey(value, baseType string) string {
+	value = normalizeWhitespace(value, baseType)
+	switch {
+	case isDecimalType(baseType):
+		if number, ok := parseDecimal(value); ok {
+			return number.RatString()
+		}
+	case baseType == "xs:float" || baseType == "xs:double":
+		bitSize := 64
+		if baseType == "xs:float" {
+			bitSize = 32
+		}
+		if number, err := strconv.ParseFloat(value, bitSize); err == nil {
+			return strconv.FormatFloat(number, 'g', -1, bitSize)
+		}
+	case baseType == "xs:boolean":
+		switch value {
+		case "1":
+			return "true"
+		case "0":
+			return "false"
+		}
+	case baseType == "xs:hexBinary":
+		return strings.ToUpper(value)
+	case baseType == "xs:base64Binary":
+		if data, err := decodeBase64Binary(value); err == nil {
+			return string(data)
+		}
+	}
+	return value
+}
+
+// normalizeWhitespace applies the whiteSpace facet of a built-in type: xs:string preserves
+// whitespace, xs:normalizedString replaces each whitespace character with a space, and all
+// other types collapse whitespace.
+func normalizeWhitespace(value, baseType string) string {
+	switch baseType {
+	case "xs:string":
+		return value
+	case "xs:normalizedString":
+		return whitespaceToSpace.Replace(value)
+	}
+	return collapseWhitespace(value)
+}
+
+// whitespaceToSpace replaces each whitespace character with a space (whiteSpace="replace").
+var whitespaceToSpace = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+// collapseWhitespace applies whiteSpace="collapse": runs of whitespace become a single space
+// and leading and trailing whitespace is removed.
+func collapseWhitespace(value string) string {
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool {
+		return r < utf8.RuneSelf && isXMLSpace(byte(r))
+	}), " ")
 }
 
-// validateLengthConstraints checks minLength and maxLength constraints.
-func validateLengthConstraints(content string, restriction *Restriction) []string {
+// validateLengthConstraints checks minLength and maxLength constraints. baseType is the
+// built-in type the restriction derives from; binary values are measured in octets.
+func validateLengthConstraints(content string, restriction *Restriction, baseType string) []string {
 	var errors []string
+	length := valueLength(content, baseType)
 
 	if restriction.MinLength != nil && restriction.MinLength.Value != "" {
 		if minLen, err := strconv.Atoi(restriction.MinLength.Value); err != nil {
 			errors = append(errors, fmt.Sprintf("invalid minLength value in schema: %s", restriction.MinLength.Value))
-		} else if len(content) < minLen {
+		} else if length < minLen {
 			errors = append(errors, fmt.Sprintf("value '%s' is too short (minimum length: %d, actual: %d)",
-				content, minLen, len(content)))
+				excerpt(content), minLen, length))
 		}
 	}
 
 	if restriction.MaxLength != nil && restriction.MaxLength.Value != "" {
 		if maxLen, err := strconv.Atoi(restriction.MaxLength.Value); err != nil {
 			errors = append(errors, fmt.Sprintf("invalid maxLength value in schema: %s", restriction.MaxLength.Value))
-		} else if len(content) > maxLen {
+		} else if length > maxLen {
 			errors = append(errors, fmt.Sprintf("value '%s' is too long (maximum length: %d, actual: %d)",
-				content, maxLen, len(content)))
+				excerpt(content), maxLen, length))
 		}
 	}
 
 	return errors
 }
 
-// validateNumericConstraints checks minInclusive and maxInclusive constraints.
-func validateNumericConstraints(content string, restriction *Restriction) []string {
+// validateNumericConstraints checks minInclusive and maxInclusive constraints. baseType is
+// the built-in type the restriction ultimately derives from, which selects the comparison.
+func validateNumericConstraints(content string, restriction *Restriction, baseType string, version SchemaVersion) []string {
 	var errors []string
 
 	if restriction.MinInclusive != nil && restriction.MinInclusive.Value != "" {
-		if err := validateNumericRange(content, restriction.MinInclusive.Value, true, true, restriction.Base); err != nil {
+		if err := validateNumericRange(content, restriction.MinInclusive.Value, true, true, baseType, version); err != nil {
 			errors = append(errors, err.Error())
 		}
 	}
 
 	if restriction.MaxInclusive != nil && restriction.MaxInclusive.Value != "" {
-		if err := validateNumericRange(content, restriction.MaxInclusive.Value, false, true, restriction.Base); err != nil {
+		if err := validateNumericRange(content, restriction.MaxInclusive.Value, false, true, baseType, version); err != nil {
 			errors = append(errors, err.Error())
 		}
 	}
@@ -78,48 +203,55 @@ func validateNumericConstraints(content string, restriction *Restriction) []stri
 }
 
 // validateNumericRange validates that a numeric value is within the specified range.
-func validateNumericRange(content, limitValue string, isMin, inclusive bool, baseType string) error {
-	contentNum, limitNum, err := parseNumericValues(content, limitValue, baseType)
-	if err != nil {
-		return err
+// Decimal and integer types are compared with arbitrary precision.
+func validateNumericRange(content, limitValue string, isMin, inclusive bool, baseType string, version SchemaVersion) error {
+	var cmp int
+	if isDecimalType(baseType) {
+		var err error
+		if cmp, err = compareDecimal(strings.TrimSpace(content), limitValue, baseType); err != nil {
+			return err
+		}
+	} else {
+		contentNum, limitNum, err := parseNumericValues(content, limitValue, baseType)
+		if err != nil {
+			return err
+		}
+		if version == XSD10 && isFloatingPointType(baseType) {
+			contentNum, limitNum = orderZeros(contentNum, limitNum)
+		}
+		switch {
+		case contentNum < limitNum:
+			cmp = -1
+		case contentNum > limitNum:
+			cmp = 1
+		}
 	}
 
 	violatesRange := false
 	if isMin {
-		violatesRange = (inclusive && contentNum < limitNum) || (!inclusive && contentNum <= limitNum)
+		violatesRange = (inclusive && cmp < 0) || (!inclusive && cmp <= 0)
 	} else {
-		violatesRange = (inclusive && contentNum > limitNum) || (!inclusive && contentNum >= limitNum)
+		violatesRange = (inclusive && cmp > 0) || (!inclusive && cmp >= 0)
 	}
 
 	if violatesRange {
 		direction := map[bool]string{true: "below minimum", false: "exceeds maximum"}[isMin]
-		return fmt.Errorf("value '%s' %s allowed value %s", content, direction, limitValue)
+		return fmt.Errorf("value '%s' %s allowed value %s", excerpt(content), direction, limitValue)
 	}
 
 	return nil
 }
 
-// parseNumericValues parses content and limit values based on the base type.
+// parseNumericValues parses content and limit values of floating-point and unknown types.
 func parseNumericValues(content, limitValue, baseType string) (contentNum, limitNum float64, err error) {
 	content = strings.TrimSpace(content)
 
 	switch baseType {
-	case "xs:integer", "xs:int", "xs:long", "xs:short", "xs:byte":
-		contentInt, err1 := strconv.ParseInt(content, 10, 64)
-		limitInt, err2 := strconv.ParseInt(limitValue, 10, 64)
-		if err1 != nil {
-			return 0, 0, fmt.Errorf("value '%s' is not a valid integer", content)
-		}
-		if err2 != nil {
-			return 0, 0, fmt.Errorf("invalid limit value in schema: %s", limitValue)
-		}
-		return float64(contentInt), float64(limitInt), nil
-
-	case "xs:decimal", "xs:double", "xs:float":
+	case "xs:double", "xs:float":
 		contentNum, err1 := strconv.ParseFloat(content, 64)
 		limitNum, err2 := strconv.ParseFloat(limitValue, 64)
 		if err1 != nil {
-			return 0, 0, fmt.Errorf("value '%s' is not a valid decimal number", content)
+			return 0, 0, fmt.Errorf("value '%s' is not a valid decimal number", excerpt(content))
 		}
 		if err2 != nil {
 			return 0, 0, fmt.Errorf("invalid limit value in schema: %s", limitValue)
@@ -140,146 +272,229 @@ func parseNumericValues(content, limitValue, baseType string) (contentNum, limit
 	}
 }
 
-// validateBuiltInType validates content against XML Schema built-in types.
-func validateBuiltInType(content, typeName string) error {
+// validateBuiltInType validates content against XML Schema built-in types, under the
+// semantics of version where the editions differ.
+func validateBuiltInType(content, typeName string, version SchemaVersion) error {
 	content = strings.TrimSpace(content)
 
 	switch typeName {
 	// Integer types
 	case "xs:integer":
-		if _, err := strconv.ParseInt(content, 10, 64); err != nil {
-			return fmt.Errorf("value '%s' is not a valid integer", content)
+		if _, ok := parseInteger(content); !ok {
+			return fmt.Errorf("value '%s' is not a valid integer", excerpt(content))
 		}
 
 	case "xs:int":
 		if val, err := strconv.ParseInt(content, 10, 32); err != nil {
-			return fmt.Errorf("value '%s' is not a valid int", content)
+			return fmt.Errorf("value '%s' is not a valid int", excerpt(content))
 		} else if val > 2147483647 || val < -2147483648 {
-			return fmt.Errorf("value '%s' is out of range for int", content)
+			return fmt.Errorf("value '%s' is out of range for int", excerpt(content))
 		}
 
 	case "xs:long":
 		if _, err := strconv.ParseInt(content, 10, 64); err != nil {
-			return fmt.Errorf("value '%s' is not a valid long", content)
+			return fmt.Errorf("value '%s' is not a valid long", excerpt(content))
 		}
 
 	case "xs:short":
 		if val, err := strconv.ParseInt(content, 10, 16); err != nil {
-			return fmt.Errorf("value '%s' is not a valid short", content)
+			return fmt.Errorf("value '%s' is not a valid short", excerpt(content))
 		} else if val > 32767 || val < -32768 {
-			return fmt.Errorf("value '%s' is out of range for short", content)
+			return fmt.Errorf("value '%s' is out of range for short", excerpt(content))
 		}
 
 	case "xs:byte":
 		if val, err := strconv.ParseInt(content, 10, 8); err != nil {
-			return fmt.Errorf("value '%s' is not a valid byte", content)
+			return fmt.Errorf("value '%s' is not a valid byte", excerpt(content))
 		} else if val > 127 || val < -128 {
-			return fmt.Errorf("value '%s' is out of range for byte", content)
+			return fmt.Errorf("value '%s' is out of range for byte", excerpt(content))
 		}
 
 	case "xs:nonNegativeInteger":
-		if val, err := strconv.ParseInt(content, 10, 64); err != nil {
-			return fmt.Errorf("value '%s' is not a valid nonNegativeInteger", content)
-		} else if val < 0 {
-			return fmt.Errorf("value '%s' must be non-negative", content)
+		if val, ok := parseInteger(content); !ok {
+			return fmt.Errorf("value '%s' is not a valid nonNegativeInteger", excerpt(content))
+		} else if val.Sign() < 0 {
+			return fmt.Errorf("value '%s' must be non-negative", excerpt(content))
 		}
 
 	case "xs:positiveInteger":
-		if val, err := strconv.ParseInt(content, 10, 64); err != nil {
-			return fmt.Errorf("value '%s' is not a valid positiveInteger", content)
-		} else if val <= 0 {
-			return fmt.Errorf("value '%s' must be positive", content)
+		if val, ok := parseInteger(content); !ok {
+			return fmt.Errorf("value '%s' is not a valid positiveInteger", excerpt(content))
+		} else if val.Sign() <= 0 {
+			return fmt.Errorf("value '%s' must be positive", excerpt(content))
 		}
 
-	case "xs:unsignedInt":
-		if val, err := strconv.ParseUint(content, 10, 32); err != nil {
-			return fmt.Errorf("value '%s' is not a valid unsignedInt", content)
-		} else if val > 4294967295 {
-			return fmt.Errorf("value '%s' is out of range for unsignedInt", content)
+	case "xs:nonPositiveInteger":
+		if val, ok := parseInteger(content); !ok {
+			return fmt.Errorf("value '%s' is not a valid nonPositiveInteger", excerpt(content))
+		} else if val.Sign() > 0 {
+			return fmt.Errorf("value '%s' must be non-positive", excerpt(content))
 		}
 
+	case "xs:negativeInteger":
+		if val, ok := parseInteger(content); !ok {
+			return fmt.Errorf("value '%s' is not a valid negativeInteger", excerpt(content))
+		} else if val.Sign() >= 0 {
+			return fmt.Errorf("value '%s' must be negative", excerpt(content))
+		}
+
+	case "xs:unsignedLong":
+		return validateUnsigned(content, "unsignedLong", 64)
+
+	case "xs:unsignedShort":
+		return validateUnsigned(content, "unsignedShort", 16)
+
+	case "xs:unsignedByte":
+		return validateUnsigned(content, "unsignedByte", 8)
+
+	case "xs:unsignedInt":
+		return validateUnsigned(content, "unsignedInt", 32)
+
 	// Decimal types
 	case "xs:decimal":
-		if _, err := strconv.ParseFloat(content, 64); err != nil {
-			return fmt.Errorf("value '%s' is not a valid decimal", content)
+		if _, ok := parseDecimal(content); !ok {
+			return fmt.Errorf("value '%s' is not a valid decimal", excerpt(content))
 		}
 
 	case "xs:double":
 		if _, err := strconv.ParseFloat(content, 64); err != nil {
-			return fmt.Errorf("value '%s' is not a valid double", content)
+			return fmt.Errorf("value '%s' is not a valid double", excerpt(content))
 		}
 
 	case "xs:float":
 		if _, err := strconv.ParseFloat(content, 32); err != nil {
-			return fmt.Errorf("value '%s' is not a valid float", content)
+			return fmt.Errorf("value '%s' is not a valid float", excerpt(content))
 		}
 
 	// Boolean type
 	case "xs:boolean":
 		if content != "true" && content != "false" && content != "1" && content != "0" {
-			return fmt.Errorf("value '%s' is not a valid boolean (expected: true, false, 1, or 0)", content)
+			return fmt.Errorf("value '%s' is not a valid boolean (expected: true, false, 1, or 0)", excerpt(content))
 		}
 
 	// Date and time types
 	case "xs:date":
-		if matched, _ := regexp.MatchString(`^\d{4}-\d{2}-\d{2}$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid date (expected format: YYYY-MM-DD)", content)
-		}
+		return validateDate(content, version)
 
 	case "xs:dateTime":
-		if matched, _ := regexp.MatchString(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid dateTime (expected format: YYYY-MM-DDTHH:mm:ss)", content)
-		}
+		return validateDateTime(content, false, version)
+
+	case "xs:dateTimeStamp":
+		return validateDateTime(content, true, version)
 
 	case "xs:time":
-		if matched, _ := regexp.MatchString(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid time (expected format: HH:mm:ss)", content)
-		}
+		return validateTime(content)
 
 	case "xs:gYear":
-		if matched, _ := regexp.MatchString(`^\d{4}$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid gYear (expected format: YYYY)", content)
-		}
+		return validateGYear(content, version)
+
+	case "xs:gYearMonth":
+		return validateGYearMonth(content, version)
 
 	case "xs:gMonth":
-		if matched, _ := regexp.MatchString(`^--\d{2}$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid gMonth (expected format: --MM)", content)
-		}
+		return validateGMonth(content)
+
+	case "xs:gMonthDay":
+		return validateGMonthDay(content)
 
 	case "xs:gDay":
-		if matched, _ := regexp.MatchString(`^---\d{2}$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid gDay (expected format: ---DD)", content)
-		}
+		return validateGDay(content)
 
 	// Duration type
 	case "xs:duration":
-		if matched, _ := regexp.MatchString(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid duration (expected format: PnYnMnDTnHnMnS)", content)
+		if matched := durationRegexp.MatchString(content); !matched || !hasDurationComponent(content) {
+			return fmt.Errorf("value '%s' is not a valid duration (expected format: PnYnMnDTnHnMnS)", excerpt(content))
+		}
+
+	case "xs:dayTimeDuration":
+		if matched := dayTimeDurationRegexp.MatchString(content); !matched || !hasDurationComponent(content) {
+			return fmt.Errorf("value '%s' is not a valid dayTimeDuration (expected format: PnDTnHnMnS)", excerpt(content))
+		}
+
+	case "xs:yearMonthDuration":
+		if matched := yearMonthDurationRegexp.MatchString(content); !matched || !hasDurationComponent(content) {
+			return fmt.Errorf("value '%s' is not a valid yearMonthDuration (expected format: PnYnM)", excerpt(content))
 		}
 
 	// String types
-	case "xs:string", "xs:normalizedString":
+	case "xs:string", "xs:normalizedString", "xs:anyAtomicType":
 		// All strings are valid
 
 	case "xs:token":
 		// Token cannot have leading/trailing whitespace or consecutive spaces
 		if strings.TrimSpace(content) != content || strings.Contains(content, "  ") {
-			return fmt.Errorf("value '%s' is not a valid token (no leading/trailing/consecutive whitespace allowed)", content)
+			return fmt.Errorf("value '%s' is not a valid token (no leading/trailing/consecutive whitespace allowed)", excerpt(content))
 		}
 
 	case "xs:Name":
-		if matched, _ := regexp.MatchString(`^[a-zA-Z_:][\w\-\.]*$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid Name", content)
+		if matched := nameRegexp.MatchString(content); !matched {
+			return fmt.Errorf("value '%s' is not a valid Name", excerpt(content))
 		}
 
 	case "xs:NCName":
-		if matched, _ := regexp.MatchString(`^[a-zA-Z_][\w\-\.]*$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid NCName (no colons allowed)", content)
+		if matched := ncNameRegexp.MatchString(content); !matched {
+			return fmt.Errorf("value '%s' is not a valid NCName (no colons allowed)", excerpt(content))
 		}
 
 	case "xs:ID", "xs:IDREF":
-		if matched, _ := regexp.MatchString(`^[a-zA-Z_][\w\-\.]*$`, content); !matched {
-			return fmt.Errorf("value '%s' is not a valid %s", content, typeName)
+		if matched := ncNameRegexp.MatchString(content); !matched {
+			return fmt.Errorf("value '%s' is not a valid %s", excerpt(content), typeName)
+		}
+
+	case "xs:IDREFS":
+		refs := strings.Fields(content)
+		if len(refs) == 0 {
+			return fmt.Errorf("value '%s' is not a valid IDREFS (expected at least one IDREF)", excerpt(content))
+		}
+		for _, ref := range refs {
+			if matched := ncNameRegexp.MatchString(ref); !matched {
+				return fmt.Errorf("value '%s' is not a valid IDREFS ('%s' is not a valid IDREF)", excerpt(content), excerpt(ref))
+			}
+		}
+
+	case "xs:ENTITY":
+		// Only lexical: there is no DTD to check for a matching unparsed entity declaration
+		if matched := ncNameRegexp.MatchString(content); !matched {
+			return fmt.Errorf("value '%s' is not a valid ENTITY (expected an NCName)", excerpt(content))
+		}
+
+	case "xs:ENTITIES":
+		entities := strings.Fields(content)
+		if len(entities) == 0 {
+			return fmt.Errorf("value '%s' is not a valid ENTITIES (expected at least one ENTITY)", excerpt(content))
+		}
+		for _, entity := range entities {
+			if matched := ncNameRegexp.MatchString(entity); !matched {
+				return fmt.Errorf("value '%s' is not a valid ENTITIES ('%s' is not a valid ENTITY)", excerpt(content), excerpt(entity))
+			}
+		}
+
+	case "xs:QName", "xs:NOTATION":
+		// Only lexical: the prefix is resolved with the namespaces in scope at the element
+		if matched := qNameRegexp.MatchString(content); !matched {
+			return fmt.Errorf("value '%s' is not a valid %s (expected a name with an optional prefix, such as tns:Name)",
+				excerpt(content), strings.TrimPrefix(typeName, "xs:"))
+		}
+
+	case "xs:NMTOKEN":
+		if matched := nmtokenRegexp.MatchString(content); !matched {
+			return fmt.Errorf("value '%s' is not a valid NMTOKEN", excerpt(content))
+		}
+
+	case "xs:NMTOKENS":
+		tokens := strings.Fields(content)
+		if len(tokens) == 0 {
+			return fmt.Errorf("value '%s' is not a valid NMTOKENS (expected at least one NMTOKEN)", excerpt(content))
+		}
+		for _, token := range tokens {
+			if matched := nmtokenRegexp.MatchString(token); !matched {
+				return fmt.Errorf("value '%s' is not a valid NMTOKENS ('%s' is not a valid NMTOKEN)", excerpt(content), excerpt(token))
+			}
+		}
+
+	case "xs:language":
+		if matched := languageRegexp.MatchString(content); !matched {
+			return fmt.Errorf("value '%s' is not a valid language (expected a tag such as en or en-US)", excerpt(content))
 		}
 
 	// URI types
@@ -289,18 +504,18 @@ func validateBuiltInType(content, typeName string) error {
 			return fmt.Errorf("URI cannot be empty")
 		}
 		if strings.Contains(content, " ") {
-			return fmt.Errorf("value '%s' is not a valid URI (contains spaces)", content)
+			return fmt.Errorf("value '%s' is not a valid URI (contains spaces)", excerpt(content))
 		}
 
 	// Base64 and hex
 	case "xs:base64Binary":
-		if matched, _ := regexp.MatchString(`^[A-Za-z0-9+/]*={0,2}$`, content); !matched {
-			return fmt.Errorf("value '%s' is not valid base64Binary", content)
+		if _, err := decodeBase64Binary(content); err != nil {
+			return err
 		}
 
 	case "xs:hexBinary":
-		if matched, _ := regexp.MatchString(`^[0-9A-Fa-f]*$`, content); !matched {
-			return fmt.Errorf("value '%s' is not valid hexBinary", content)
+		if _, err := decodeHexBinary(content); err != nil {
+			return err
 		}
 
 	default:
@@ -310,105 +525,68 @@ func validateBuiltInType(content, typeName string) error {
 	return nil
 }
 
-// validateSequenceOccurrences validates occurrence constraints for xs:sequence.
-func (s *Schema) validateSequenceOccurrences(node *Node, sequence *Sequence, childCounts map[string]int) []string {
-	var errors []string
-
-	for _, element := range sequence.Elements {
-		count := childCounts[element.Name]
-
-		// Check minOccurs
-		if element.MinOccurs != "" {
-			if min, _ := strconv.Atoi(element.MinOccurs); count < min {
-				errors = append(errors, fmt.Sprintf(
-					"element <%s> requires at least %d <%s> child, but found %d",
-					node.Name.Local, min, element.Name, count))
-			}
-		}
-
-		// Check maxOccurs
-		if element.MaxOccurs != "" && element.MaxOccurs != "unbounded" {
-			if max, err := strconv.Atoi(element.MaxOccurs); err != nil {
-				errors = append(errors, fmt.Sprintf(
-					"invalid maxOccurs value in schema for element <%s>: %s",
-					element.Name, element.MaxOccurs))
-			} else if count > max {
-				errors = append(errors, fmt.Sprintf(
-					"element <%s> allows at most %d <%s> child, but found %d",
-					node.Name.Local, max, element.Name, count))
-			}
-		}
-	}
-
-	return errors
+// hasDurationComponent reports whether a duration has at least one component, and no
+// dangling time designator: "P" and "PT" are not valid durations.
+func hasDurationComponent(content string) bool {
+	return strings.IndexAny(content, "YMDHS") >= 0 && !strings.HasSuffix(content, "T")
 }
 
-// validateChoiceOccurrences validates occurrence constraints for xs:choice.
-func (s *Schema) validateChoiceOccurrences(node *Node, choice *Choice, validChoices int) []string {
-	var errors []string
+// defaultOccurs is the value of minOccurs and maxOccurs when the attribute is absent.
+const defaultOccurs = 1
 
-	// Check minOccurs for choice
-	minOccurs := 1 // Default minOccurs for choice is 1
-	if choice.MinOccurs != "" {
-		if min, err := strconv.Atoi(choice.MinOccurs); err == nil {
-			minOccurs = min
-		}
+// validateUnsigned validates a value of an unsigned integer type of the given bit size. The
+// sign may be given, "+" on any value and "-" on zero.
+func validateUnsigned(content, typeName string, bitSize int) error {
+	digits := strings.TrimPrefix(content, "+")
+	if len(content) > 1 && content[0] == '-' && strings.Trim(content[1:], "0") == "" {
+		digits = content[1:]
 	}
-
-	if validChoices < minOccurs {
-		errors = append(errors, fmt.Sprintf(
-			"element <%s> choice requires at least %d selections, but found %d",
-			node.Name.Local, minOccurs, validChoices))
-	}
-
-	// Check maxOccurs for choice
-	if choice.MaxOccurs != "" && choice.MaxOccurs != "unbounded" {
-		if max, err := strconv.Atoi(choice.MaxOccurs); err != nil {
-			errors = append(errors, fmt.Sprintf(
-				"invalid maxOccurs value in choice for element <%s>: %s",
-				node.Name.Local, choice.MaxOccurs))
-		} else if validChoices > max {
-			errors = append(errors, fmt.Sprintf(
-				"element <%s> choice allows at most %d selections, but found %d",
-				node.Name.Local, max, validChoices))
+	if _, err := strconv.ParseUint(digits, 10, bitSize); err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return fmt.Errorf("value '%s' is out of range for %s", excerpt(content), typeName)
 		}
+		return fmt.Errorf("value '%s' is not a valid %s", excerpt(content), typeName)
 	}
-
-	return errors
+	return nil
 }
 
-// findChoiceElement finds an element definition in an xs:choice.
-func (s *Schema) findChoiceElement(childName xml.Name, choice *Choice) *Element {
-	// Check direct elements
-	for i := range choice.Elements {
-		if s.elementsMatch(childName, choice.Elements[i].Name) {
-			return &choice.Elements[i]
-		}
+// parseOccurs converts a minOccurs or maxOccurs attribute value to an integer,
+// applying the XSD default of 1 when the attribute is absent.
+func parseOccurs(value string) (int, error) {
+	if value == "" {
+		return defaultOccurs, nil
 	}
+	return strconv.Atoi(value)
+}
 
-	// Check sequences within choice
-	for _, sequence := range choice.Sequences {
-		for i := range sequence.Elements {
-			if s.elementsMatch(childName, sequence.Elements[i].Name) {
-				return &sequence.Elements[i]
-			}
-		}
+// parseMaxOccurs converts a maxOccurs attribute value to a bound, using -1 for no upper bound.
+// Malformed values are treated as unbounded; schemas containing them are rejected when parsed.
+func parseMaxOccurs(value string) int {
+	if value == "unbounded" {
+		return -1
+	}
+	max, err := parseOccurs(value)
+	if err != nil {
+		return -1
 	}
+	return max
+}
 
-	// Check nested choices
-	for _, nestedChoice := range choice.Choices {
-		if elem := s.findChoiceElement(childName, &nestedChoice); elem != nil {
-			return elem
+// findAllElement finds an element definition in an xs:all group.
+func (v *validator) findAllElement(childName xml.Name, all *All) *Element {
+	for i := range all.Elements {
+		if v.elementMatches(childName, &all.Elements[i]) {
+			return &all.Elements[i]
 		}
 	}
-
 	return nil
 }
 
-// findAllElement finds an element definition in an xs:all group.
-func (s *Schema) findAllElement(childName xml.Name, all *All) *Element {
+// findAllElementByLocalName finds the definition in an xs:all group that a child in the
+// wrong namespace would match if namespaces were ignored, or nil.
+func (v *validator) findAllElementByLocalName(childName xml.Name, all *All) *Element {
 	for i := range all.Elements {
-		if s.elementsMatch(childName, all.Elements[i].Name) {
+		if ParseQName(all.Elements[i].Name).LocalName == childName.Local {
 			return &all.Elements[i]
 		}
 	}
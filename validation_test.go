@@ -1,10 +1,89 @@
 package xmlparser
 
 import (
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 )
 
+// Test that a ValidationError unwraps into its individual issues
+func TestValidationErrorUnwrap(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="order">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="quantity" type="xs:integer"/>
+                <xs:element name="price" type="xs:decimal"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+	doc, err := Parse([]byte(`<order><quantity>many</quantity><price>cheap</price></order>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	err = fmt.Errorf("order rejected: %w", schema.Validate(doc))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a *ValidationError, got %T", err)
+	}
+	issues := validationErr.Unwrap()
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d: %v", len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.Error() != validationErr.Issues[i].Message {
+			t.Errorf("Issue %d: expected %q, got %q", i, validationErr.Issues[i].Message, issue.Error())
+		}
+	}
+
+	var issue *ValidationIssue
+	if !errors.As(err, &issue) || !strings.Contains(issue.Message, "many") || issue.Code == "" {
+		t.Errorf("Expected errors.As to find the first issue, got %v", issue)
+	}
+	if joined := errors.Join(issues...).Error(); joined != strings.Join(validationErr.Messages(), "\n") {
+		t.Errorf("Expected issues to join like errors.Join, got %q", joined)
+	}
+}
+
+// Test that ValidateBytes reports parsing and validation failures as a ValidationError
+func TestValidateBytes(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="quantity" type="xs:integer"/>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	if err := schema.ValidateBytes([]byte(`<quantity>3</quantity>`)); err != nil {
+		t.Errorf("Expected a valid document, got: %v", err)
+	}
+
+	err = schema.ValidateBytes([]byte(`<quantity>many</quantity>`))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Issues) != 1 || validationErr.ParseError != nil {
+		t.Errorf("Expected one validation issue, got: %v", err)
+	}
+
+	err = schema.ValidateBytes([]byte(`<quantity>3</amount>`))
+	if !errors.As(err, &validationErr) || len(validationErr.Issues) != 1 || validationErr.ParseError == nil {
+		t.Fatalf("Expected a parse error as a ValidationError, got: %v", err)
+	}
+	var syntaxErr *xml.SyntaxError
+	if !errors.As(err, &syntaxErr) || !strings.Contains(validationErr.Issues[0].Message, "XML parsing error") {
+		t.Errorf("Expected the parse error to be reported and unwrapped, got: %v", err)
+	}
+}
+
 // Test individual validation features
 
 func TestPatternValidation2(t *testing.T) {
@@ -27,6 +106,13 @@ func TestPatternValidation2(t *testing.T) {
                         </xs:restriction>
                     </xs:simpleType>
                 </xs:element>
+                <xs:element name="code" minOccurs="0">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:string">
+                            <xs:pattern value="[A-Z]{3}|[0-9]{3}" />
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
             </xs:sequence>
         </xs:complexType>
     </xs:element>
@@ -60,6 +146,23 @@ func TestPatternValidation2(t *testing.T) {
 			shouldPass:  false,
 			errorString: "does not match pattern",
 		},
+		{
+			name:       "Pattern alternatives",
+			xml:        `<test><isbn>9780743273565</isbn><email>test@example.com</email><code>123</code></test>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Pattern matching part of the value",
+			xml:         `<test><isbn>9780743273565</isbn><email>test@example.com</email><code>xxABCxx</code></test>`,
+			shouldPass:  false,
+			errorString: "value 'xxABCxx' does not match pattern '[A-Z]{3}|[0-9]{3}'",
+		},
+		{
+			name:        "Pattern alternative matching part of the value",
+			xml:         `<test><isbn>9780743273565</isbn><email>test@example.com</email><code>ABC1</code></test>`,
+			shouldPass:  false,
+			errorString: "does not match pattern",
+		},
 	}
 
 	for _, tt := range tests {
@@ -299,3 +402,849 @@ func TestMaxOccursValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestSequenceOrderValidation(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="user">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="id" type="xs:integer"/>
+                <xs:element name="email" type="xs:string"/>
+                <xs:element name="tag" type="xs:string" minOccurs="0" maxOccurs="unbounded"/>
+                <xs:element name="note" type="xs:string" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Declared order",
+			xml:        `<user><id>1</id><email>x@y.z</email><tag>a</tag><tag>b</tag><note>n</note></user>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Optional elements omitted",
+			xml:        `<user><id>1</id><email>x@y.z</email><note>n</note></user>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Swapped required elements",
+			xml:         `<user><email>x@y.z</email><id>1</id></user>`,
+			shouldPass:  false,
+			errorString: "element <id> is out of order in <user>",
+		},
+		{
+			name:        "Repeated element interrupted",
+			xml:         `<user><id>1</id><email>x@y.z</email><tag>a</tag><note>n</note><tag>b</tag></user>`,
+			shouldPass:  false,
+			errorString: "element <tag> is out of order in <user>: it must appear before <note>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+func TestDefaultOccurrenceConstraints(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="test">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="title" type="xs:string"/>
+                <xs:element name="subtitle" type="xs:string" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Required element present once",
+			xml:        `<test><title>Go</title></test>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Missing element without explicit minOccurs",
+			xml:         `<test><subtitle>Intro</subtitle></test>`,
+			shouldPass:  false,
+			errorString: "requires at least 1 <title> child, but found 0",
+		},
+		{
+			name:        "Repeated element without explicit maxOccurs",
+			xml:         `<test><title>Go</title><title>Rust</title></test>`,
+			shouldPass:  false,
+			errorString: "allows at most 1 <title> child, but found 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+func TestValidationErrorIsBounded(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="test">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="n" type="xs:integer" maxOccurs="unbounded"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	t.Run("Huge offending value is excerpted", func(t *testing.T) {
+		huge := strings.Repeat("x", 1<<20)
+		doc, err := Parse([]byte("<test><n>" + huge + "</n></test>"))
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+
+		validationErr := schema.Validate(doc)
+		expectValidationError(t, validationErr, "bytes total")
+		if len(validationErr.Error()) > 2*maxErrorMessageLength {
+			t.Errorf("Expected a bounded error message, got %d bytes", len(validationErr.Error()))
+		}
+	})
+
+	t.Run("Number of stored errors is capped", func(t *testing.T) {
+		xml := "<test>" + strings.Repeat("<n>x</n>", maxStoredErrors+5) + "</test>"
+		doc, err := Parse([]byte(xml))
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+
+		validationErr, ok := schema.Validate(doc).(*ValidationError)
+		if !ok {
+			t.Fatalf("Expected a *ValidationError")
+		}
+		if len(validationErr.Issues) != maxStoredErrors || validationErr.Omitted != 5 {
+			t.Errorf("Expected %d stored and 5 omitted errors, got %d and %d",
+				maxStoredErrors, len(validationErr.Issues), validationErr.Omitted)
+		}
+		expectValidationError(t, validationErr, "... and 5 more")
+	})
+}
+
+func TestElementOnlyContentRejectsText(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:complexType name="PersonType">
+        <xs:sequence>
+            <xs:element name="name" type="xs:string" minOccurs="0"/>
+        </xs:sequence>
+    </xs:complexType>
+    <xs:element name="people">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="person" type="PersonType" maxOccurs="unbounded"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name: "Whitespace between elements",
+			xml: `<people>
+				<person>
+					<name>John</name>
+				</person>
+			</people>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Text before child element",
+			xml:         `<people><person>garbage<name>John</name></person></people>`,
+			shouldPass:  false,
+			errorString: "element <person> contains unexpected text 'garbage'",
+		},
+		{
+			name:        "Text in element-only type without children",
+			xml:         `<people><person>garbage</person></people>`,
+			shouldPass:  false,
+			errorString: "element <person> contains unexpected text 'garbage'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test complex types without particles (EMPTY content)
+func TestEmptyContentValidation(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:complexType name="MarkerType">
+        <xs:attribute name="id" type="xs:string" use="required"/>
+    </xs:complexType>
+    <xs:complexType name="NoteType" mixed="true"/>
+    <xs:element name="doc">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="marker" type="MarkerType" minOccurs="0"/>
+                <xs:element name="note" type="NoteType" minOccurs="0"/>
+                <xs:element name="code" type="xs:string" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Empty element with attributes",
+			xml:        `<doc><marker id="m1"/></doc>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Empty element with whitespace",
+			xml:        `<doc><marker id="m1">  </marker></doc>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Empty element with child",
+			xml:         `<doc><marker id="m1"><extra/></marker></doc>`,
+			shouldPass:  false,
+			errorString: "element <marker> must be empty (content model is empty), but found child element <extra>",
+		},
+		{
+			name:        "Empty element with text",
+			xml:         `<doc><marker id="m1">text</marker></doc>`,
+			shouldPass:  false,
+			errorString: "element <marker> contains unexpected text 'text' (content model is empty)",
+		},
+		{
+			name:        "Empty element still validates attributes",
+			xml:         `<doc><marker/></doc>`,
+			shouldPass:  false,
+			errorString: "required attribute 'id' is missing",
+		},
+		{
+			name:       "Mixed empty type allows text",
+			xml:        `<doc><note>free text</note></doc>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Mixed empty type rejects children",
+			xml:         `<doc><note>text <b>bold</b></note></doc>`,
+			shouldPass:  false,
+			errorString: "element <note> must be empty",
+		},
+		{
+			name:        "Simple type element with children",
+			xml:         `<doc><code><x/></code></doc>`,
+			shouldPass:  false,
+			errorString: "element <code> has a simple type and must not contain child elements, but found <x>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test lexical validation of the name and token built-in types
+func TestNameAndTokenBuiltInTypes(t *testing.T) {
+	tests := []struct {
+		typeName   string
+		value      string
+		shouldPass bool
+	}{
+		{"xs:NMTOKEN", "2024-release", true},
+		{"xs:NMTOKEN", "ns:name.v1", true},
+		{"xs:NMTOKEN", "Größe", true},
+		{"xs:NMTOKEN", "two words", false},
+		{"xs:NMTOKEN", "a/b", false},
+		{"xs:NMTOKENS", "red green  blue", true},
+		{"xs:NMTOKENS", "   ", false},
+		{"xs:NMTOKENS", "red gr@y", false},
+		{"xs:ENTITY", "logo", true},
+		{"xs:ENTITY", "1logo", false},
+		{"xs:ENTITY", "ns:logo", false},
+		{"xs:ENTITIES", "logo banner", true},
+		{"xs:ENTITIES", "logo 2banner", false},
+		{"xs:language", "en", true},
+		{"xs:language", "en-US", true},
+		{"xs:language", "zh-Hant-TW", true},
+		{"xs:language", "x-klingon", true},
+		{"xs:language", "english-language", true},
+		{"xs:language", "en_US", false},
+		{"xs:language", "verylongtag", false},
+		{"xs:language", "en-", false},
+		{"xs:QName", "tns:Name", true},
+		{"xs:QName", "Name", true},
+		{"xs:QName", "a:b:c", false},
+		{"xs:QName", ":Name", false},
+		{"xs:QName", "1ns:Name", false},
+		{"xs:NOTATION", "img:png", true},
+		{"xs:NOTATION", "png:", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeName+" "+tt.value, func(t *testing.T) {
+			err := validateBuiltInType(tt.value, tt.typeName, XSD10)
+			if tt.shouldPass && err != nil {
+				t.Errorf("Expected '%s' to be a valid %s, got: %v", tt.value, tt.typeName, err)
+			}
+			if !tt.shouldPass && err == nil {
+				t.Errorf("Expected '%s' to be an invalid %s", tt.value, tt.typeName)
+			}
+		})
+	}
+}
+
+// Test that enumerations with tens of thousands of values are checked by lookup and
+// reported with a truncated list of allowed values
+func TestLargeEnumeration(t *testing.T) {
+	var facets strings.Builder
+	for i := 0; i < 30000; i++ {
+		fmt.Fprintf(&facets, `<xs:enumeration value="C%05d"/>`, i)
+	}
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="codes">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="code" maxOccurs="unbounded">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:string">` + facets.String() + `</xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	var xml strings.Builder
+	xml.WriteString("<codes>")
+	for i := 0; i < 30000; i += 7 {
+		fmt.Fprintf(&xml, "<code>C%05d</code>", i)
+	}
+	xml.WriteString("<code>X00001</code></codes>")
+	doc, err := Parse([]byte(xml.String()))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	validationErr, ok := schema.Validate(doc).(*ValidationError)
+	if !ok || len(validationErr.Issues) != 1 {
+		t.Fatalf("Expected exactly one validation error, got: %v", validationErr)
+	}
+	message := validationErr.Issues[0].Message
+	for _, expected := range []string{"value 'X00001' is not in the list of allowed values: [C00000, C00001", "C00019, ...] (29980 more not shown)"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected error containing '%s', got: %s", expected, message)
+		}
+	}
+}
+
+// Test xs:decimal lexical rules and arbitrary-precision facets
+func TestDecimalPrecision(t *testing.T) {
+	xsdBytes := []byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="test">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="amount" minOccurs="0">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:decimal">
+                            <xs:minInclusive value="10000000000000000000.1"/>
+                            <xs:maxInclusive value="10000000000000000000.9"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="serial" minOccurs="0">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:integer">
+                            <xs:maxInclusive value="123456789012345678901234567890"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="price" minOccurs="0">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:decimal">
+                            <xs:totalDigits value="5"/>
+                            <xs:fractionDigits value="2"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="ratio" type="xs:double" minOccurs="0"/>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`)
+
+	schema, err := ParseXSD(xsdBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Decimal within a range beyond float64 precision",
+			xml:        `<test><amount>10000000000000000000.5</amount></test>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Decimal below a minimum that float64 cannot distinguish",
+			xml:         `<test><amount>10000000000000000000.05</amount></test>`,
+			shouldPass:  false,
+			errorString: "below minimum allowed value 10000000000000000000.1",
+		},
+		{
+			name:        "Decimal with exponent",
+			xml:         `<test><amount>1e19</amount></test>`,
+			shouldPass:  false,
+			errorString: "value '1e19' is not a valid decimal",
+		},
+		{
+			name:        "Decimal special value",
+			xml:         `<test><amount>Inf</amount></test>`,
+			shouldPass:  false,
+			errorString: "value 'Inf' is not a valid decimal",
+		},
+		{
+			name:       "Integer beyond int64",
+			xml:        `<test><serial>123456789012345678901234567890</serial></test>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Integer above a large maximum",
+			xml:         `<test><serial>123456789012345678901234567891</serial></test>`,
+			shouldPass:  false,
+			errorString: "exceeds maximum allowed value 123456789012345678901234567890",
+		},
+		{
+			name:       "Digits within totalDigits and fractionDigits",
+			xml:        `<test><price>00123.450</price></test>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Too many total digits",
+			xml:         `<test><price>1234.56</price></test>`,
+			shouldPass:  false,
+			errorString: "value '1234.56' has too many digits (totalDigits: 5, actual: 6)",
+		},
+		{
+			name:        "Too many fraction digits",
+			xml:         `<test><price>1.234</price></test>`,
+			shouldPass:  false,
+			errorString: "value '1.234' has too many fraction digits (fractionDigits: 2, actual: 3)",
+		},
+		{
+			name:       "Double still accepts exponents",
+			xml:        `<test><ratio>1.5E-3</ratio></test>`,
+			shouldPass: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+
+			validationErr := schema.Validate(doc)
+			if tt.shouldPass {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test that xs:integer and its unbounded derivations are validated with arbitrary precision
+func TestArbitraryPrecisionIntegers(t *testing.T) {
+	builtIns := []struct {
+		typeName   string
+		value      string
+		shouldPass bool
+	}{
+		{"xs:integer", "-987654321098765432109876543210", true},
+		{"xs:integer", "+42", true},
+		{"xs:integer", "4.0", false},
+		{"xs:nonNegativeInteger", "98765432109876543210", true},
+		{"xs:nonNegativeInteger", "-98765432109876543210", false},
+		{"xs:nonNegativeInteger", "-0", true},
+		{"xs:positiveInteger", "98765432109876543210", true},
+		{"xs:positiveInteger", "0", false},
+	}
+	for _, tt := range builtIns {
+		t.Run(tt.typeName+" "+tt.value, func(t *testing.T) {
+			err := validateBuiltInType(tt.value, tt.typeName, XSD10)
+			if tt.shouldPass && err != nil {
+				t.Errorf("Expected '%s' to be a valid %s, got: %v", tt.value, tt.typeName, err)
+			}
+			if !tt.shouldPass && err == nil {
+				t.Errorf("Expected '%s' to be an invalid %s", tt.value, tt.typeName)
+			}
+		})
+	}
+
+	// Range facets on a type derived from a named integer type
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="SerialType">
+        <xs:restriction base="xs:nonNegativeInteger"/>
+    </xs:simpleType>
+    <xs:element name="serial">
+        <xs:simpleType>
+            <xs:restriction base="SerialType">
+                <xs:minInclusive value="100000000000000000000"/>
+                <xs:maxInclusive value="100000000000000000009"/>
+            </xs:restriction>
+        </xs:simpleType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	ranges := []struct {
+		value       string
+		errorString string
+	}{
+		{"100000000000000000005", ""},
+		{"100000000000000000010", "value '100000000000000000010' exceeds maximum allowed value 100000000000000000009"},
+		{"99999999999999999999", "value '99999999999999999999' below minimum allowed value 100000000000000000000"},
+	}
+	for _, tt := range ranges {
+		t.Run("range "+tt.value, func(t *testing.T) {
+			doc, err := Parse([]byte("<serial>" + tt.value + "</serial>"))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			validationErr := schema.Validate(doc)
+			if tt.errorString == "" {
+				if validationErr != nil {
+					t.Errorf("Expected validation to pass, but got error: %v", validationErr)
+				}
+			} else {
+				expectValidationError(t, validationErr, tt.errorString)
+			}
+		})
+	}
+}
+
+// Test the range of the unsigned and sign-restricted integer types
+func TestDerivedIntegerTypes(t *testing.T) {
+	tests := []struct {
+		typeName    string
+		value       string
+		errorString string // empty when the value is valid
+	}{
+		{"xs:unsignedLong", "18446744073709551615", ""},
+		{"xs:unsignedLong", "18446744073709551616", "out of range for unsignedLong"},
+		{"xs:unsignedLong", "-1", "not a valid unsignedLong"},
+		{"xs:unsignedLong", "-0", ""},
+		{"xs:unsignedInt", "+5", ""},
+		{"xs:unsignedInt", "-00", ""},
+		{"xs:unsignedInt", "4294967296", "out of range for unsignedInt"},
+		{"xs:unsignedInt", "-", "not a valid unsignedInt"},
+		{"xs:unsignedShort", "65535", ""},
+		{"xs:unsignedShort", "+7", ""},
+		{"xs:unsignedShort", "65536", "out of range for unsignedShort"},
+		{"xs:unsignedByte", "255", ""},
+		{"xs:unsignedByte", "256", "out of range for unsignedByte"},
+		{"xs:unsignedByte", "1.0", "not a valid unsignedByte"},
+		{"xs:nonPositiveInteger", "0", ""},
+		{"xs:nonPositiveInteger", "-98765432109876543210", ""},
+		{"xs:nonPositiveInteger", "1", "must be non-positive"},
+		{"xs:negativeInteger", "-1", ""},
+		{"xs:negativeInteger", "0", "must be negative"},
+		{"xs:negativeInteger", "-x", "not a valid negativeInteger"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeName+" "+tt.value, func(t *testing.T) {
+			err := validateBuiltInType(tt.value, tt.typeName, XSD10)
+			if tt.errorString == "" {
+				if err != nil {
+					t.Errorf("Expected '%s' to be a valid %s, got: %v", tt.value, tt.typeName, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorString) {
+				t.Errorf("Expected error containing '%s' for %s '%s', got: %v", tt.errorString, tt.typeName, tt.value, err)
+			}
+		})
+	}
+}
+
+// Test that derived simple types are enforced through their whole derivation, the same way
+// for elements and attributes, whether referenced by name or declared inline
+func TestSimpleTypeDerivationChain(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:simpleType name="code">
+        <xs:restriction base="xs:integer">
+            <xs:pattern value="[a-z0-9]+"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:simpleType name="shortCode">
+        <xs:restriction base="code">
+            <xs:maxLength value="2"/>
+        </xs:restriction>
+    </xs:simpleType>
+    <xs:element name="root">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="code" type="shortCode" minOccurs="0"/>
+            </xs:sequence>
+            <xs:attribute name="named" type="code"/>
+            <xs:attribute name="derived" type="shortCode"/>
+            <xs:attribute name="inline">
+                <xs:simpleType>
+                    <xs:restriction base="code">
+                        <xs:maxLength value="1"/>
+                    </xs:restriction>
+                </xs:simpleType>
+            </xs:attribute>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{"Valid values", `<root named="123" derived="12" inline="7"><code>42</code></root>`, true, ""},
+		{"Attribute restricting integer with pattern", `<root named="abc"/>`, false, "attribute 'named' in element <root> (line 1, column 7): value 'abc' is not a valid integer"},
+		{"Attribute of twice-derived type", `<root derived="ab"/>`, false, "value 'ab' is not a valid integer"},
+		{"Facet of the derived type", `<root derived="123"/>`, false, "value '123' is too long (maximum length: 2, actual: 3)"},
+		{"Inline type derived from named type", `<root inline="x"/>`, false, "value 'x' is not a valid integer"},
+		{"Facet of the inline type", `<root inline="12"/>`, false, "value '12' is too long (maximum length: 1, actual: 2)"},
+		{"Element of twice-derived type", `<root><code>ab</code></root>`, false, "in element <code>: value 'ab' is not a valid integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+}
+
+// Test that enumeration values are compared in the value space of the base type
+func TestTypeAwareEnumeration(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+    <xs:element name="root">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="level" minOccurs="0">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:integer">
+                            <xs:enumeration value="1"/>
+                            <xs:enumeration value="2"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="rate" minOccurs="0">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:decimal">
+                            <xs:enumeration value="0.5"/>
+                            <xs:enumeration value="1.25"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+                <xs:element name="label" minOccurs="0">
+                    <xs:simpleType>
+                        <xs:restriction base="xs:hexBinary">
+                            <xs:enumeration value="0FB7"/>
+                        </xs:restriction>
+                    </xs:simpleType>
+                </xs:element>
+            </xs:sequence>
+            <xs:attribute name="enabled">
+                <xs:simpleType>
+                    <xs:restriction base="xs:boolean">
+                        <xs:enumeration value="true"/>
+                    </xs:restriction>
+                </xs:simpleType>
+            </xs:attribute>
+            <xs:attribute name="name">
+                <xs:simpleType>
+                    <xs:restriction base="xs:string">
+                        <xs:enumeration value="New York"/>
+                    </xs:restriction>
+                </xs:simpleType>
+            </xs:attribute>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{"Integer with leading zero", `<root><level>01</level></root>`, true, ""},
+		{"Integer with sign", `<root><level>+2</level></root>`, true, ""},
+		{"Integer not listed", `<root><level>3</level></root>`, false, "value '3' is not in the list of allowed values: [1, 2]"},
+		{"Decimal with trailing zero", `<root><rate>0.50</rate></root>`, true, ""},
+		{"Decimal without leading zero", `<root><rate>.5</rate></root>`, true, ""},
+		{"Decimal not listed", `<root><rate>0.51</rate></root>`, false, "is not in the list of allowed values"},
+		{"Hex digits in lower case", `<root><label>0fb7</label></root>`, true, ""},
+		{"Boolean as digit", `<root enabled="1"/>`, true, ""},
+		{"Boolean not listed", `<root enabled="0"/>`, false, "is not in the list of allowed values"},
+		{"String compared as written", `<root name="New  York"/>`, false, "is not in the list of allowed values"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+}
@@ -1,192 +1,628 @@
 package xmlparser
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
-	"strconv"
 	"strings"
+	"unicode/utf8"
+)
+
+// Limits that keep the size of a ValidationError bounded regardless of the document,
+// so that logging a failure on a huge element cannot flood log pipelines.
+const (
+	maxValueExcerptLength = 128  // Bytes of an offending value quoted in a message
+	maxErrorMessageLength = 1024 // Bytes kept of a single error message
+	maxStoredErrors       = 1000 // Messages kept in a ValidationError
 )
 
 // ValidationError aggregates all validation errors found during validation.
+// At most 1000 issues are stored, in document order; the remainder is counted in Omitted.
 type ValidationError struct {
-	Errors []string
+	Issues  []ValidationIssue
+	Omitted int // Issues found but not stored because the limit was reached
+
+	// Stopped tells that validation stopped at ValidateOptions.MaxErrors or FailFast, so the
+	// document may have issues beyond those reported
+	Stopped bool
+
+	// ParseError is the error that prevented a document validated from source, as with
+	// ValidateBytes, from being parsed; its message is then the only entry of Issues
+	ParseError error
 }
 
 func (e *ValidationError) Error() string {
-	return fmt.Sprintf("%d validation errors found:\n - %s",
-		len(e.Errors), strings.Join(e.Errors, "\n - "))
+	message := fmt.Sprintf("%d validation errors found:\n - %s",
+		len(e.Issues)+e.Omitted, strings.Join(e.Messages(), "\n - "))
+	if e.Omitted > 0 {
+		message += fmt.Sprintf("\n - ... and %d more", e.Omitted)
+	}
+	if e.Stopped {
+		message += "\n - ... validation stopped at the error limit"
+	}
+	return message
+}
+
+// Messages returns the message of each stored issue, in document order.
+func (e *ValidationError) Messages() []string {
+	return messagesOf(e.Issues)
+}
+
+// Unwrap returns the stored issues as *ValidationIssue errors, in document order. Together
+// with errors.Is and errors.As (and the same shape as errors.Join), it lets error-inspection
+// middleware and logging frameworks traverse individual failures. Omitted issues are not
+// included. For a document that could not be parsed, it returns the parse error.
+func (e *ValidationError) Unwrap() []error {
+	if e.ParseError != nil {
+		return []error{e.ParseError}
+	}
+	issues := make([]error, len(e.Issues))
+	for i := range e.Issues {
+		issues[i] = &e.Issues[i]
+	}
+	return issues
+}
+
+// newValidationError builds a ValidationError, truncating overlong messages and
+// capping the number of stored issues.
+func newValidationError(issues []ValidationIssue) *ValidationError {
+	validationErr := &ValidationError{}
+	if len(issues) > maxStoredErrors {
+		validationErr.Omitted = len(issues) - maxStoredErrors
+		issues = issues[:maxStoredErrors]
+	}
+
+	validationErr.Issues = make([]ValidationIssue, len(issues))
+	for i, issue := range issues {
+		issue.Message = truncateUTF8(issue.Message, maxErrorMessageLength, "... (truncated)")
+		validationErr.Issues[i] = issue
+	}
+	return validationErr
+}
+
+// storedMessages caps and truncates messages as a ValidationError stores them, for results
+// that report issues as plain messages.
+func storedMessages(messages []string) []string {
+	if len(messages) > maxStoredErrors {
+		messages = messages[:maxStoredErrors]
+	}
+	stored := make([]string, len(messages))
+	for i, message := range messages {
+		stored[i] = truncateUTF8(message, maxErrorMessageLength, "... (truncated)")
+	}
+	return stored
+}
+
+// excerpt shortens an offending value for inclusion in an error message.
+func excerpt(value string) string {
+	if len(value) <= maxValueExcerptLength {
+		return value
+	}
+	return truncateUTF8(value, maxValueExcerptLength, fmt.Sprintf("... (%d bytes total)", len(value)))
+}
+
+// truncateUTF8 cuts s to at most limit bytes on a rune boundary and appends suffix when it was cut.
+func truncateUTF8(s string, limit int, suffix string) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + suffix
 }
 
 // Validate checks if the XML document conforms to the schema.
 // Returns ValidationError if validation fails, nil if valid.
 func (s *Schema) Validate(doc *Document) error {
+	return s.ValidateWithOptions(doc, ValidateOptions{})
+}
+
+// ValidateBytes parses an XML document and validates it, reporting both parsing and
+// validation failures as a *ValidationError. A document that cannot be parsed gives a
+// single issue, with the parse error in ParseError.
+func (s *Schema) ValidateBytes(xmlBytes []byte) error {
+	doc, err := Parse(xmlBytes)
+	if err != nil {
+		return parseFailure(err)
+	}
+	return s.Validate(doc)
+}
+
+// ValidateWithOptions checks if the XML document conforms to the schema, like Validate,
+// with explicit options.
+func (s *Schema) ValidateWithOptions(doc *Document, opts ValidateOptions) error {
+	return newValidator(s, opts).validate(context.Background(), doc)
+}
+
+// ValidateContext checks if the XML document conforms to the schema, like
+// ValidateWithOptions, tracing the validation with opts.Tracer in the span of ctx.
+// The context is checked before each element, so validating an enormous document stops
+// soon after ctx is canceled or its deadline passes, such as that of a server request;
+// the context's error is then returned instead of the issues found so far.
+func (s *Schema) ValidateContext(ctx context.Context, doc *Document, opts ValidateOptions) error {
+	return newValidator(s, opts).validate(ctx, doc)
+}
+
+// validate validates a document from its root element, in an "xmlparser.Validate" span.
+func (v *validator) validate(ctx context.Context, doc *Document) error {
+	var attributes []SpanAttribute
+	if doc != nil && doc.Root != nil {
+		attributes = append(attributes, SpanAttribute{"xml.root", doc.Root.Name.Local})
+	}
+	ctx, span := startSpan(ctx, v.opts.Tracer, "xmlparser.Validate",
+		append(attributes, SpanAttribute{"xsd.target_namespace", v.TargetNamespace})...)
+	v.ctx = ctx
+	err := v.validateDocument(doc)
+	if v.canceled != nil {
+		err = nil
+	}
+	issueCount := 0
+	if err != nil {
+		issueCount = len(err.Issues) + err.Omitted
+	}
+	span.SetAttributes(SpanAttribute{"validation.valid", err == nil}, SpanAttribute{"validation.issue_count", issueCount})
+	span.End(nil)
+	v.ctx = nil
+	if v.canceled != nil {
+		return v.canceled
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateDocument validates a document as validate does, within its span.
+func (v *validator) validateDocument(doc *Document) *ValidationError {
+	v.reset()
+	rootDef, rootErr := v.rootDeclaration(doc, v.opts)
+	if rootErr != nil {
+		v.found(rootErr.Issues...)
+		return rootErr
+	}
+
+	v.validateNode(doc.Root, rootDef)
+	issues := v.issues
+	if !v.stopped {
+		references := v.checkIDReferences()
+		v.found(references...)
+		issues = append(issues, references...)
+	}
+	stopped := v.stopped
+	if limit := v.errorLimit(); limit > 0 && len(issues) > limit {
+		issues, stopped = issues[:limit], true
+	}
+	if len(issues) > 0 {
+		validationErr := newValidationError(issues)
+		validationErr.Stopped = stopped
+		return validationErr
+	}
+	return nil
+}
+
+// errorLimit returns the number of issues validation stops at, or 0 for no limit.
+func (v *validator) errorLimit() int {
+	if v.opts.FailFast {
+		return 1
+	}
+	return v.opts.MaxErrors
+}
+
+// rootDeclaration returns the declaration of the document's root element.
+func (s *Schema) rootDeclaration(doc *Document, opts ValidateOptions) (*Element, *ValidationError) {
 	if doc == nil || doc.Root == nil {
-		return &ValidationError{Errors: []string{"XML document is empty"}}
+		return nil, documentFailure(nil, "XML document is empty")
+	}
+	if opts.StrictNamespaces {
+		return s.strictRootDeclaration(doc.Root)
 	}
 
-	// Use namespace-aware element lookup
+	// The global element with the root's namespace and local name, whatever prefix the
+	// document uses for it; then the namespace-aware lookup by merged name
+	if rootDef := s.globalElement(doc.Root.Name); rootDef != nil {
+		return rootDef, nil
+	}
 	elementKey := s.GetElementKey(doc.Root.Name)
 	rootDef, exists := s.ElementMap[elementKey]
 	if !exists {
 		// Fallback to local name for compatibility
 		if rootDef, exists = s.ElementMap[doc.Root.Name.Local]; !exists {
-			return &ValidationError{Errors: []string{
-				fmt.Sprintf("root element <%s> is not defined in the schema", doc.Root.Name.Local),
-			}}
+			return nil, documentFailure(doc.Root,
+				fmt.Sprintf("root element <%s> is not defined in the schema", doc.Root.Name.Local))
 		}
 	}
 
-	if errors := s.validateNode(doc.Root, rootDef); len(errors) > 0 {
-		return &ValidationError{Errors: errors}
+	return rootDef, nil
+}
+
+// validator holds the state of a single Validate call. The schema itself is only read,
+// so one schema can validate many documents concurrently.
+type validator struct {
+	*Schema
+	opts   ValidateOptions
+	facets facetEvaluation // Facet order and short-circuiting selected by opts
+
+	sensitivePaths []sensitivePath // Compiled from opts.SensitivePaths
+
+	issues   []ValidationIssue       // Issues of the elements validated so far, located (see validateNode)
+	ids      map[string]idReference  // xs:ID values seen so far, with where they appear
+	idrefs   []idReference           // xs:IDREF and xs:IDREFS values, resolved once the whole document is seen
+	records  map[*Node]*RecordResult // Batch records whose errors are collected separately (see ValidateRecords)
+	warned   map[string]bool         // Warnings reported for the document, by message
+	result   *Result                 // Annotations collected for ValidateAndAnnotate; nil otherwise
+	ctx      context.Context         // Context of the span of the document being validated, if traced
+	stopped  bool                    // Whether elements were skipped at the error limit or on cancellation
+	canceled error                   // Error of the context, once done before validation completed
+	reported int                     // Issues passed to opts.OnIssue
+
+	// Elements validated separately, such as the records of ValidateRecordsAt: instead of
+	// being validated, they are mapped to the declaration the content model matches them with
+	deferred map[*Node]*Element
+
+	// Subtree sizes of the elements whose content streaming validation dropped, for the
+	// subtree budgets of their ancestors
+	pruned map[*Node]subtreeStats
+
+	// Matchers of the elements being streamed, which were fed their children as they ended
+	streamed map[*Node]childMatcher
+
+	// Scratch storage reused across elements and, through a Validator, across documents
+	matchers []*contentMatcher   // Released content matchers
+	counts   []map[*Element]int  // Released xs:all child counts
+	present  map[*Attribute]bool // Attribute uses present on the element being validated
+}
+
+// newValidator returns a validator for one document.
+func newValidator(s *Schema, opts ValidateOptions) *validator {
+	return &validator{
+		Schema:         s,
+		opts:           opts,
+		facets:         newFacetEvaluation(opts),
+		sensitivePaths: compileSensitivePaths(opts.SensitivePaths),
+		ids:            make(map[string]idReference),
 	}
-	return nil
 }
 
 // validateNode recursively validates a node and its children against the schema.
-func (s *Schema) validateNode(node *Node, def *Element) []string {
+// Errors within a batch record are collected in its RecordResult instead of returned.
+//
+// The errors returned are also appended to v.issues, located on the element that reported
+// them: an element's errors include those of its children, which keep the location their
+// own validateNode gave them.
+func (v *validator) validateNode(node *Node, def *Element) []string {
+	if _, ok := v.deferred[node]; ok {
+		v.deferred[node] = def
+		return nil
+	}
+	if limit := v.errorLimit(); limit > 0 && len(v.issues) >= limit {
+		v.stopped = true
+		return nil
+	}
+	if v.canceled != nil {
+		return nil
+	}
+	if v.ctx != nil {
+		if v.canceled = v.ctx.Err(); v.canceled != nil {
+			v.stopped = true
+			return nil
+		}
+	}
+	mark := len(v.issues)
+
+	var span Span
+	if v.opts.TraceChildren && v.ctx != nil && node.Parent != nil && node.Parent.Parent == nil {
+		_, span = startSpan(v.ctx, v.opts.Tracer, "xmlparser.ValidateElement",
+			SpanAttribute{"xml.element", node.Name.Local}, SpanAttribute{"xml.xpath", nodePath(node)})
+	}
+
+	var info *ElementInfo
+	if v.result != nil {
+		info = v.annotateElement(node, def)
+	}
+
 	var errors []string
+	if budget, ok := v.subtreeBudget(node); ok {
+		errors = v.checkSubtreeBudget(node, budget)
+	}
+	errors = append(errors, v.validateElement(node, def)...)
+	if info != nil {
+		info.Valid = len(errors) == 0
+		if !info.Valid {
+			v.result.Stats.InvalidElements++
+		}
+	}
+	v.locate(node, mark, errors)
+	if span != nil {
+		span.SetAttributes(SpanAttribute{"validation.issue_count", len(errors)})
+		span.End(nil)
+	}
+	if record, ok := v.records[node]; ok {
+		record.Errors = append(record.Errors, errors...)
+		v.issues = v.issues[:mark]
+		return nil
+	}
+	return errors
+}
 
-	// Validate text content for leaf nodes
-	if len(node.Children) == 0 && strings.TrimSpace(node.Content) != "" {
-		errors = append(errors, s.validateTextContent(node, def)...)
+// locate replaces the issues appended to v.issues from mark on, those of the children of
+// node, with the issues of its errors: the children's where the messages are theirs, and
+// new ones located on node for the others, whose messages get the location path of node.
+func (v *validator) locate(node *Node, mark int, errors []string) {
+	children := v.issues[mark:]
+	if len(errors) == len(children) {
+		same := true
+		for i, message := range errors {
+			same = same && children[i].Message == message
+		}
+		if same {
+			return
+		}
+	}
+
+	issues := make([]ValidationIssue, 0, len(errors))
+	next := 0
+	for j, message := range errors {
+		found := false
+		for i := next; i < len(children); i++ {
+			if children[i].Message == message {
+				issues = append(issues, children[i])
+				next, found = i+1, true
+				break
+			}
+		}
+		if !found {
+			issue := located(node, "", "", message)
+			errors[j] = issue.Message
+			issues = append(issues, issue)
+			v.found(issue)
+		}
+	}
+	v.issues = append(v.issues[:mark], issues...)
+}
+
+// found passes newly located issues to ValidateOptions.OnIssue, up to the error limit,
+// with their messages truncated as a ValidationError stores them.
+func (v *validator) found(issues ...ValidationIssue) {
+	if v.opts.OnIssue == nil {
+		return
+	}
+	for _, issue := range issues {
+		if limit := v.errorLimit(); limit > 0 && v.reported >= limit {
+			return
+		}
+		v.reported++
+		issue.Message = truncateUTF8(issue.Message, maxErrorMessageLength, "... (truncated)")
+		v.opts.OnIssue(issue)
+	}
+}
+
+// validateElement validates a node and its children against an element declaration.
+func (v *validator) validateElement(node *Node, def *Element) []string {
+	errors := validateXsiAttributes(node)
+
+	// xsi:type replaces the declared type for this element and its content
+	if typeName, ok := xsiType(node); ok {
+		substituted, typeErrors := v.resolveXsiType(node, def, typeName)
+		if len(typeErrors) > 0 {
+			return append(errors, typeErrors...)
+		}
+		def = substituted
+	}
+
+	// xsi:nil="true" stands for an element without a value, so its content is not validated
+	nilled, nilErrors := xsiNil(node)
+	errors = append(errors, nilErrors...)
+	if nilled {
+		return append(errors, v.validateNil(node, def)...)
+	}
+
+	complexType := v.getComplexType(def)
+	if v.warns() {
+		v.warnUncheckedElement(node, def, complexType)
+	}
+	hasText := strings.TrimSpace(node.Content) != ""
+	mixed := complexType != nil && complexType.Mixed
+	minOccurs, _ := particle{element: def}.occurs()
+
+	switch {
+	case !hasText && complexType == nil && len(node.Children) == 0 && minOccurs == 0:
+		errors = append(errors, v.validateEmptyOptional(node, def)...)
+	case !hasText && complexType == nil && len(node.Children) == 0:
+		// A required element has its empty string value checked, as optional ones with EmptyAsValue
+		errors = append(errors, v.validateTextContent(node, def)...)
+	case !hasText || mixed:
+		// Nothing to check; text in mixed content is unconstrained
+	case complexType != nil && complexType.hasEmptyContent():
+		errors = append(errors, fmt.Sprintf("element <%s> contains unexpected text '%s' (content model is empty)",
+			node.Name.Local, v.contentExcerpt(node, def)))
+	case complexType != nil:
+		// Element-only content may contain whitespace between child elements, but no text
+		errors = append(errors, fmt.Sprintf("element <%s> contains unexpected text '%s' (content model is element-only)",
+			node.Name.Local, v.contentExcerpt(node, def)))
+	case len(node.Children) == 0:
+		// Validate text content for leaf nodes of simple type
+		errors = append(errors, v.validateTextContent(node, def)...)
 	}
 
 	// Validate complex type structure
-	if complexType := s.getComplexType(def); complexType != nil {
-		errors = append(errors, s.validateComplexType(node, complexType)...)
+	if complexType != nil {
+		errors = append(errors, v.validateComplexType(node, complexType)...)
 	} else if len(node.Children) > 0 {
-		errors = append(errors, fmt.Sprintf("element <%s> should be empty but has children", node.Name.Local))
+		errors = append(errors, fmt.Sprintf("element <%s> has a simple type and must not contain child elements, but found <%s>",
+			node.Name.Local, node.Children[0].Name.Local))
 	}
 
 	return errors
 }
 
 // validateTextContent validates the text content of a leaf node.
-func (s *Schema) validateTextContent(node *Node, def *Element) []string {
+func (v *validator) validateTextContent(node *Node, def *Element) []string {
 	var errors []string
 	content := strings.TrimSpace(node.Content)
+	sensitive := v.sensitiveElement(node, def)
 
-	// Validate built-in types
-	if def.Type != "" && strings.HasPrefix(def.Type, "xs:") {
-		if err := validateBuiltInType(content, def.Type); err != nil {
-			errors = append(errors, fmt.Sprintf("in element <%s>: %s", def.Name, err.Error()))
+	// Validate against the type and every type it derives from
+	simpleType, err := v.findSimpleType(def)
+	if err != nil {
+		errors = append(errors, fmt.Sprintf("in element <%s>: %v", def.Name, err))
+	} else {
+		if v.warns() {
+			v.warnUncheckedType(node, "", simpleType)
+		}
+		prefix := func() string { return "in element <" + def.Name + ">: " }
+		facets := v.recordingFacets(node, xml.Name{}, content, v.builtInBase(def.Type, simpleType), prefix, sensitive)
+		for _, validationErr := range v.validateSimpleValue(content, def.Type, simpleType, facets) {
+			errors = append(errors, prefix()+validationErr)
+		}
+		for _, validationErr := range v.checkQNamePrefix(content, def.Type, simpleType, node) {
+			errors = append(errors, prefix()+validationErr)
 		}
 	}
 
-	// Validate simple type constraints
-	if simpleType, err := s.findSimpleType(def); err != nil {
-		errors = append(errors, fmt.Sprintf("in element <%s>: %v", def.Name, err))
-	} else if simpleType != nil {
-		for _, validationErr := range validateSimpleTypeConstraints(content, simpleType) {
-			errors = append(errors, fmt.Sprintf("in element <%s>: %s", def.Name, validationErr))
-		}
+	// Track IDs and references for the document-wide integrity checks
+	errors = append(errors, v.recordIdentity(content, def.Type, simpleType, node, "", sensitive)...)
+
+	if v.result != nil {
+		v.annotateValue(node, content, v.builtInBase(def.Type, simpleType), sensitive)
 	}
 
+	if sensitive {
+		return maskValue(errors, content)
+	}
 	return errors
 }
 
+// contentExcerpt returns the trimmed text of node for error messages, masked when it is
+// sensitive.
+func (v *validator) contentExcerpt(node *Node, def *Element) string {
+	if v.sensitiveElement(node, def) {
+		return DefaultRedaction
+	}
+	return excerpt(strings.TrimSpace(node.Content))
+}
+
 // validateComplexType validates a complex type's structure and occurrence constraints.
-func (s *Schema) validateComplexType(node *Node, complexType *ComplexType) []string {
+func (v *validator) validateComplexType(node *Node, complexType *ComplexType) []string {
 	var errors []string
 
 	// Validate attributes
-	errors = append(errors, s.validateAttributes(node, complexType.Attributes)...)
+	errors = append(errors, v.validateAttributes(node, complexType)...)
 
-	// Validate content model
-	if complexType.Sequence != nil {
-		errors = append(errors, s.validateSequence(node, complexType.Sequence)...)
-	} else if complexType.Choice != nil {
-		errors = append(errors, s.validateChoice(node, complexType.Choice)...)
+	// Validate content model; streamed children have been fed to its matcher as they ended
+	if m, ok := v.streamed[node]; ok {
+		errors = append(errors, m.finish()...)
+	} else if model := v.contentModel(complexType); model != nil {
+		errors = append(errors, v.validateContentModel(node, model)...)
 	} else if complexType.All != nil {
-		errors = append(errors, s.validateAll(node, complexType.All)...)
+		errors = append(errors, v.validateAll(node, complexType.All)...)
+	} else if len(node.Children) > 0 {
+		errors = append(errors, fmt.Sprintf("element <%s> must be empty (content model is empty), but found child element <%s>",
+			node.Name.Local, node.Children[0].Name.Local))
 	}
 
 	return errors
 }
 
-// validateOccurrenceConstraints checks minOccurs and maxOccurs constraints.
-func (s *Schema) validateOccurrenceConstraints(node *Node, sequence *Sequence, childCounts map[string]int) []string {
-	var errors []string
-
-	for _, element := range sequence.Elements {
-		count := childCounts[element.Name]
-
-		// Check minOccurs
-		if element.MinOccurs != "" {
-			if min, _ := strconv.Atoi(element.MinOccurs); count < min {
-				errors = append(errors, fmt.Sprintf(
-					"element <%s> requires at least %d <%s> child, but found %d",
-					node.Name.Local, min, element.Name, count))
-			}
+// validateSimpleValue validates a value of an element or attribute against its simple type,
+// given by name or as a definition, and every type that type derives from. The value must
+// first be a valid instance of the built-in type at the root of the derivation; if it is,
+// the facets of each restriction are checked kind by kind in the order of the facet
+// evaluation, from the built-in type towards the given type. Unless the evaluation asks for
+// all diagnostics, checking stops at the first failure, the built-in type check included.
+// Elements and attributes share this path, so a type is enforced the same way wherever it
+// is used and however it is referenced.
+func (s *Schema) validateSimpleValue(content, typeName string, simpleType *SimpleType, facets facetEvaluation) []string {
+	if simpleType == nil {
+		if !strings.HasPrefix(typeName, "xs:") {
+			return nil
 		}
-
-		// Check maxOccurs
-		if element.MaxOccurs != "" && element.MaxOccurs != "unbounded" {
-			if max, err := strconv.Atoi(element.MaxOccurs); err != nil {
-				errors = append(errors, fmt.Sprintf(
-					"invalid maxOccurs value in schema for element <%s>: %s",
-					element.Name, element.MaxOccurs))
-			} else if count > max {
-				errors = append(errors, fmt.Sprintf(
-					"element <%s> allows at most %d <%s> child, but found %d",
-					node.Name.Local, max, element.Name, count))
-			}
+		if err := validateBuiltInType(content, typeName, s.Version); err != nil {
+			return []string{err.Error()}
 		}
+		return nil
 	}
 
-	return errors
-}
-
-// validateSimpleTypeConstraints validates content against simple type restrictions.
-func validateSimpleTypeConstraints(content string, simpleType *SimpleType) []string {
-	if simpleType == nil || simpleType.Restriction == nil {
+	var chain []*SimpleType
+	for depth := 0; simpleType != nil && simpleType.Restriction != nil && depth < maxDerivationDepth; depth++ {
+		chain = append(chain, simpleType)
+		simpleType = s.restrictionBase(simpleType.Restriction)
+	}
+	if len(chain) == 0 {
 		return nil
 	}
 
+	// Bases that cannot be resolved are treated as xs:string, as in builtInBase
 	var errors []string
-	restriction := simpleType.Restriction
-
-	// Pattern validation
-	if restriction.Pattern != nil && restriction.Pattern.Value != "" {
-		if err := validatePattern(content, restriction.Pattern.Value); err != nil {
+	if base := chain[len(chain)-1].Restriction.Base; strings.HasPrefix(base, "xs:") {
+		if err := validateBuiltInType(content, base, s.Version); err != nil {
+			if !facets.all {
+				return []string{err.Error()}
+			}
 			errors = append(errors, err.Error())
 		}
 	}
 
-	// Enumeration validation
-	if len(restriction.Enumeration) > 0 {
-		if err := validateEnumeration(content, restriction.Enumeration); err != nil {
-			errors = append(errors, err.Error())
+	for _, kind := range facets.kinds {
+		for i := len(chain) - 1; i >= 0; i-- {
+			failures := s.checkFacets(kind, content, chain[i].Restriction)
+			if len(failures) > 0 && facets.failed != nil {
+				facets.failed(kind, failures)
+			}
+			errors = append(errors, failures...)
+			if len(errors) > 0 && !facets.all {
+				return errors
+			}
 		}
 	}
-
-	// Length validation
-	errors = append(errors, validateLengthConstraints(content, restriction)...)
-
-	// Numeric range validation
-	errors = append(errors, validateNumericConstraints(content, restriction)...)
-
 	return errors
 }
 
 // Helper functions for getting types and elements
 
 func (s *Schema) getComplexType(def *Element) *ComplexType {
+	if def.linked {
+		return def.linkedComplexType
+	}
 	if def.ComplexType != nil {
 		return def.ComplexType
 	}
-	if complexType, exists := s.ComplexTypeMap[def.Type]; exists {
+	return s.lookupComplexType(def.Type, def.typeRef)
+}
+
+// lookupComplexType resolves a complex type reference such as "t:Address" like
+// lookupSimpleType: by its resolved name, or else by literal name and then through the
+// schema's namespace declarations.
+func (s *Schema) lookupComplexType(typeName string, ref xml.Name) *ComplexType {
+	if ref.Local != "" {
+		return s.complexTypeNSMap[ref]
+	}
+	if strings.HasPrefix(typeName, "xs:") {
+		return nil // Built-in types have no definition in the schema
+	}
+	if complexType, exists := s.ComplexTypeMap[typeName]; exists {
 		return complexType
 	}
-	return nil
+	if typeName == "" {
+		return nil
+	}
+	resolved := s.ResolveQName(typeName)
+	if resolved.Prefix == "" && resolved.Namespace == "" {
+		resolved.Namespace = s.TargetNamespace
+	}
+	return s.complexTypeNSMap[xml.Name{Space: resolved.Namespace, Local: resolved.LocalName}]
 }
 
 func (s *Schema) findSimpleType(def *Element) (*SimpleType, error) {
+	if def.linked && (def.linkedSimpleType != nil || def.Type == "" || strings.HasPrefix(def.Type, "xs:")) {
+		return def.linkedSimpleType, nil
+	}
 	if def.SimpleType != nil {
 		return def.SimpleType, nil
 	}
 	if def.Type != "" {
-		if simpleType, exists := s.SimpleTypeMap[def.Type]; exists {
+		if simpleType := s.lookupSimpleType(def.Type, def.typeRef); simpleType != nil {
 			return simpleType, nil
 		}
 		if strings.HasPrefix(def.Type, "xs:") {
@@ -197,24 +633,56 @@ func (s *Schema) findSimpleType(def *Element) (*SimpleType, error) {
 	return nil, nil
 }
 
-func (s *Schema) countChildren(node *Node) map[string]int {
-	childCounts := make(map[string]int)
-	for _, child := range node.Children {
-		childCounts[child.Name.Local]++
+// lookupSimpleType resolves a simple type reference such as "common:CurrencyCode".
+// References parsed from a schema document carry the name resolved with that document's
+// namespace declarations, which is looked up directly. Otherwise the literal name is tried
+// first, and then the prefix is resolved through the schema's namespace declarations so types
+// from imported schemas are found regardless of the prefix they were merged under.
+func (s *Schema) lookupSimpleType(typeName string, ref xml.Name) *SimpleType {
+	if ref.Local != "" {
+		return s.simpleTypeNSMap[ref]
+	}
+	if strings.HasPrefix(typeName, "xs:") {
+		return nil // Built-in types have no definition in the schema
+	}
+	if simpleType, exists := s.SimpleTypeMap[typeName]; exists {
+		return simpleType
+	}
+	resolved := s.ResolveQName(typeName)
+	if resolved.Prefix == "" && resolved.Namespace == "" {
+		resolved.Namespace = s.TargetNamespace
+	}
+	return s.simpleTypeNSMap[xml.Name{Space: resolved.Namespace, Local: resolved.LocalName}]
+}
+
+// restrictionBase returns the simple type a restriction derives from, or nil for built-in bases.
+func (s *Schema) restrictionBase(restriction *Restriction) *SimpleType {
+	if restriction.builtIn != "" {
+		return restriction.linkedBase
 	}
-	return childCounts
+	return s.lookupSimpleType(restriction.Base, restriction.baseRef)
 }
 
-func (s *Schema) findChildElement(childName xml.Name, sequence *Sequence) *Element {
-	// Try exact namespace-aware match first
-	for i := range sequence.Elements {
-		element := &sequence.Elements[i]
-		// Check if element matches considering namespace
-		if s.elementsMatch(childName, element.Name) {
-			return element
+// maxDerivationDepth bounds restriction chains, guarding against circular type definitions.
+const maxDerivationDepth = 64
+
+// builtInBase follows restriction bases until a built-in type is reached. Types without
+// a built-in ancestor are treated as xs:string.
+func (s *Schema) builtInBase(typeName string, simpleType *SimpleType) string {
+	if strings.HasPrefix(typeName, "xs:") {
+		return typeName
+	}
+	for depth := 0; simpleType != nil && simpleType.Restriction != nil && depth < maxDerivationDepth; depth++ {
+		if builtIn := simpleType.Restriction.builtIn; builtIn != "" {
+			return builtIn
 		}
+		base := simpleType.Restriction.Base
+		if strings.HasPrefix(base, "xs:") {
+			return base
+		}
+		simpleType = s.restrictionBase(simpleType.Restriction)
 	}
-	return nil
+	return "xs:string"
 }
 
 // elementsMatch checks if a child element matches a schema element definition considering namespaces.
@@ -232,182 +700,198 @@ func (s *Schema) elementsMatch(childName xml.Name, schemaElementName string) boo
 			(childName.Space == s.TargetNamespace && resolved.Namespace == s.TargetNamespace))
 }
 
-// validateSequence validates an xs:sequence content model.
-func (s *Schema) validateSequence(node *Node, sequence *Sequence) []string {
-	var errors []string
-	childCounts := s.countChildren(node)
+// validateAll validates an xs:all content model.
+func (v *validator) validateAll(node *Node, all *All) []string {
+	m := v.acquireAllMatcher(node, all)
+	defer m.release()
 
 	// Validate each child element
 	for _, child := range node.Children {
-		if childDef := s.findChildElement(child.Name, sequence); childDef != nil {
-			errors = append(errors, s.validateNode(child, childDef)...)
-		} else {
-			errors = append(errors, fmt.Sprintf("element <%s> is not a valid child of <%s>",
-				child.Name.Local, node.Name.Local))
+		if childDef := m.feed(child); childDef != nil {
+			m.errors = append(m.errors, v.validateNode(child, childDef)...)
 		}
 	}
-
-	// Validate occurrence constraints
-	errors = append(errors, s.validateSequenceOccurrences(node, sequence, childCounts)...)
-
-	return errors
+	return m.finish()
 }
 
-// validateChoice validates an xs:choice content model.
-func (s *Schema) validateChoice(node *Node, choice *Choice) []string {
-	var errors []string
+// allMatcher counts the children of a node declared in an xs:all group.
+type allMatcher struct {
+	v      *validator
+	parent *Node
+	all    *All
+	counts map[*Element]int
+	errors []string
+}
 
-	if len(node.Children) == 0 {
-		// Check if choice is required
-		if choice.MinOccurs == "" || choice.MinOccurs != "0" {
-			errors = append(errors, fmt.Sprintf("element <%s> must contain at least one choice element", node.Name.Local))
-		}
-		return errors
-	}
+// acquireAllMatcher returns a matcher for the children of a node declared in an xs:all group.
+func (v *validator) acquireAllMatcher(node *Node, all *All) *allMatcher {
+	return &allMatcher{v: v, parent: node, all: all, counts: v.acquireCounts()}
+}
 
-	// In a choice, only one alternative should be present (default behavior)
-	maxOccurs := 1
-	if choice.MaxOccurs != "" {
-		if choice.MaxOccurs == "unbounded" {
-			maxOccurs = -1 // unlimited
-		} else if max, err := strconv.Atoi(choice.MaxOccurs); err == nil {
-			maxOccurs = max
-		}
-	}
+// declaration returns the declaration of a child in the group.
+func (m *allMatcher) declaration(child *Node) *Element {
+	return m.v.findAllElement(child.Name, m.all)
+}
 
-	// Count valid choice elements
-	choiceElementCounts := make(map[string]int)
-	for _, child := range node.Children {
-		if childDef := s.findChoiceElement(child.Name, choice); childDef != nil {
-			errors = append(errors, s.validateNode(child, childDef)...)
-			choiceElementCounts[child.Name.Local]++
-		} else {
-			errors = append(errors, fmt.Sprintf("element <%s> is not a valid choice for <%s>",
-				child.Name.Local, node.Name.Local))
-		}
+// feed counts the next child and returns its declaration in the group, or reports it.
+func (m *allMatcher) feed(child *Node) *Element {
+	if childDef := m.v.findAllElement(child.Name, m.all); childDef != nil {
+		m.counts[childDef]++
+		return childDef
+	} else if mismatched := m.v.findAllElementByLocalName(child.Name, m.all); mismatched != nil {
+		m.errors = append(m.errors, namespaceMismatch(child, mismatched))
+	} else {
+		m.errors = append(m.errors, fmt.Sprintf("element <%s> is not allowed in xs:all group of <%s>",
+			child.Name.Local, m.parent.Name.Local))
 	}
+	return nil
+}
 
-	// Check choice constraints - by default, only one choice type is allowed
-	if maxOccurs == 1 && len(choiceElementCounts) > 1 {
-		choiceNames := make([]string, 0, len(choiceElementCounts))
-		for name := range choiceElementCounts {
-			choiceNames = append(choiceNames, name)
+// finish checks the occurrence bounds once every child has been fed, and returns the
+// errors found.
+//
+// XSD 1.0 limits xs:all elements to at most one occurrence; XSD 1.1 allows larger
+// maxOccurs, which ParseXSDWithOptions only accepts in 1.1 mode.
+func (m *allMatcher) finish() []string {
+	errors := m.errors
+	for i := range m.all.Elements {
+		element := &m.all.Elements[i]
+		count := m.counts[element]
+		min, _ := parseOccurs(element.MinOccurs)
+		max := parseMaxOccurs(element.MaxOccurs)
+
+		switch {
+		case count == 0 && min > 0:
+			errors = append(errors, fmt.Sprintf("required element <%s> is missing from xs:all group in <%s>",
+				element.Name, m.parent.Name.Local))
+		case count < min:
+			errors = append(errors, fmt.Sprintf("element <%s> appears %d times in xs:all group, but minimum is %d",
+				element.Name, count, min))
+		case max >= 0 && count > max:
+			errors = append(errors, fmt.Sprintf("element <%s> appears %d times in xs:all group, but maximum is %d",
+				element.Name, count, max))
 		}
-		errors = append(errors, fmt.Sprintf("element <%s> choice allows only one alternative, but found: [%s]",
-			node.Name.Local, strings.Join(choiceNames, ", ")))
 	}
-
 	return errors
 }
 
-// validateAll validates an xs:all content model.
-func (s *Schema) validateAll(node *Node, all *All) []string {
-	var errors []string
-	childCounts := s.countChildren(node)
-
-	// In xs:all, each element can appear at most once
-	for childName, count := range childCounts {
-		if count > 1 {
-			errors = append(errors, fmt.Sprintf("element <%s> appears %d times in xs:all group, but maximum is 1",
-				childName, count))
-		}
-	}
-
-	// Validate each child element
-	for _, child := range node.Children {
-		if childDef := s.findAllElement(child.Name, all); childDef != nil {
-			errors = append(errors, s.validateNode(child, childDef)...)
-		} else {
-			errors = append(errors, fmt.Sprintf("element <%s> is not allowed in xs:all group of <%s>",
-				child.Name.Local, node.Name.Local))
-		}
-	}
-
-	// Check required elements in xs:all
-	for _, element := range all.Elements {
-		if element.MinOccurs == "" || element.MinOccurs != "0" {
-			if childCounts[element.Name] == 0 {
-				errors = append(errors, fmt.Sprintf("required element <%s> is missing from xs:all group in <%s>",
-					element.Name, node.Name.Local))
-			}
-		}
-	}
-
-	return errors
+// release makes the child counts available to acquireCounts again.
+func (m *allMatcher) release() {
+	m.v.releaseCounts(m.counts)
+	m.counts = nil
 }
 
 // validateAttributes validates XML attributes against XSD attribute definitions.
-func (s *Schema) validateAttributes(node *Node, attributeDefs []Attribute) []string {
+// Attributes are checked in document order, so several issues on one element are reported
+// in the order they appear in the source; missing required attributes are reported last.
+func (v *validator) validateAttributes(node *Node, complexType *ComplexType) []string {
 	var errors []string
-
-	// Create maps for easier lookup
-	attrValues := make(map[string]string)
-	for _, attr := range node.Attrs {
-		attrValues[attr.Name.Local] = attr.Value
+	attributeDefs := complexType.Attributes
+	if v.present == nil {
+		v.present = make(map[*Attribute]bool, len(node.Attrs))
 	}
-
-	// Validate each defined attribute
-	for _, attrDef := range attributeDefs {
-		value, present := attrValues[attrDef.Name]
-
-		// Check required attributes
-		if attrDef.Use == "required" && !present {
-			errors = append(errors, fmt.Sprintf("required attribute '%s' is missing from element <%s>",
-				attrDef.Name, node.Name.Local))
-			continue
+	present := v.present
+	defer func() {
+		for attrDef := range present {
+			delete(present, attrDef)
 		}
+	}()
 
-		// Skip validation if attribute is not present and not required
-		if !present {
+	for i, attr := range node.Attrs {
+		// Skip namespace declarations and xsi:* attributes, which are handled by validateElement
+		if v.isNamespaceDeclaration(attr) || isXsiAttribute(attr) {
 			continue
 		}
 
-		// Validate fixed value
-		if attrDef.Fixed != "" && value != attrDef.Fixed {
-			errors = append(errors, fmt.Sprintf("attribute '%s' in element <%s> has fixed value '%s', but got '%s'",
-				attrDef.Name, node.Name.Local, attrDef.Fixed, value))
-		}
-
-		// Validate attribute type
-		if attrDef.Type != "" && strings.HasPrefix(attrDef.Type, "xs:") {
-			if err := validateBuiltInType(value, attrDef.Type); err != nil {
-				errors = append(errors, fmt.Sprintf("attribute '%s' in element <%s>: %s",
-					attrDef.Name, node.Name.Local, err.Error()))
+		// Attributes not matching a declaration by namespace and local name must be allowed
+		// by the attribute wildcard; they are validated against their global declaration
+		attrDef := findAttributeDef(attributeDefs, attr.Name)
+		if attrDef == nil {
+			var wildcardErrors []string
+			attrDef, wildcardErrors = v.matchAttributeWildcard(node, i, complexType)
+			errors = append(errors, wildcardErrors...)
+			if attrDef == nil {
+				continue
 			}
 		}
-
-		// Validate inline simple type constraints
-		if attrDef.SimpleType != nil {
-			for _, validationErr := range validateSimpleTypeConstraints(value, attrDef.SimpleType) {
-				errors = append(errors, fmt.Sprintf("attribute '%s' in element <%s>: %s",
-					attrDef.Name, node.Name.Local, validationErr))
+		present[attrDef] = true
+		attrErrors := v.validateAttributeValue(node, i, attrDef)
+		errors = append(errors, attrErrors...)
+		if v.result != nil {
+			value := attr.Value
+			if v.sensitiveAttribute(node, i, attrDef) {
+				value = DefaultRedaction
 			}
+			v.annotateAttribute(node, attrDef, attr.Name, value, len(attrErrors) == 0, false)
 		}
 	}
 
-	// Check for prohibited attributes (attributes not defined in schema)
-	for _, attr := range node.Attrs {
-		// Skip namespace declarations
-		if s.isNamespaceDeclaration(attr) {
-			continue
+	// Check required attributes, and annotate absent attributes that take a default value
+	for i := range attributeDefs {
+		attrDef := &attributeDefs[i]
+		switch {
+		case present[attrDef]:
+		case attrDef.Use == "required":
+			errors = append(errors, fmt.Sprintf("required attribute '%s' is missing from element <%s>",
+				attrDef.Name, node.Name.Local))
+		case attrDef.Default != "" && v.result != nil:
+			name := xml.Name{Space: attrDef.namespace, Local: attrDef.Name}
+			v.annotateAttribute(node, attrDef, name, attrDef.Default, true, true)
 		}
+	}
 
-		found := false
-		for _, attrDef := range attributeDefs {
-			if attrDef.Name == attr.Name.Local {
-				found = true
-				break
-			}
+	return errors
+}
+
+// validateAttributeValue validates the value of the attribute at index i of node against its definition.
+func (v *validator) validateAttributeValue(node *Node, i int, attrDef *Attribute) []string {
+	var errors []string
+	value := node.Attrs[i].Value
+	sensitive := v.sensitiveAttribute(node, i, attrDef)
+	// Validate fixed value
+	if attrDef.Fixed != "" && value != attrDef.Fixed {
+		errors = append(errors, fmt.Sprintf("%s has fixed value '%s', but got '%s'",
+			attributeLocation(node, i), attrDef.Fixed, excerpt(value)))
+	}
+
+	// Validate against the inline or referenced type and every type it derives from
+	simpleType := v.attributeSimpleType(attrDef)
+	if simpleType == nil && attrDef.Type != "" && !strings.HasPrefix(attrDef.Type, "xs:") {
+		errors = append(errors, fmt.Sprintf("%s: type definition '%s' not found in schema",
+			attributeLocation(node, i), attrDef.Type))
+	}
+	if simpleType != nil && v.warns() {
+		v.warnUncheckedType(node, node.Attrs[i].Name.Local, simpleType)
+	}
+	if simpleType != nil || strings.HasPrefix(attrDef.Type, "xs:") {
+		prefix := func() string { return attributeLocation(node, i) + ": " }
+		facets := v.recordingFacets(node, node.Attrs[i].Name, value, v.builtInBase(attrDef.Type, simpleType), prefix, sensitive)
+		for _, validationErr := range v.validateSimpleValue(value, attrDef.Type, simpleType, facets) {
+			errors = append(errors, prefix()+validationErr)
 		}
-		if !found {
-			errors = append(errors, fmt.Sprintf("unexpected attribute '%s' in element <%s>",
-				attr.Name.Local, node.Name.Local))
+		for _, validationErr := range v.checkQNamePrefix(value, attrDef.Type, simpleType, node) {
+			errors = append(errors, prefix()+validationErr)
 		}
 	}
 
+	errors = append(errors, v.recordIdentity(value, attrDef.Type, simpleType, node, attrDef.Name, sensitive)...)
+	if sensitive {
+		return maskValue(errors, value)
+	}
 	return errors
 }
 
+// attributeLocation describes the attribute at index i of node for error messages,
+// including its source position when the document was parsed from source.
+func attributeLocation(node *Node, i int) string {
+	location := fmt.Sprintf("attribute '%s' in element <%s>", node.Attrs[i].Name.Local, node.Name.Local)
+	if i < len(node.AttrPositions) && node.AttrPositions[i].Line > 0 {
+		position := node.AttrPositions[i]
+		location += fmt.Sprintf(" (line %d, column %d)", position.Line, position.Column)
+	}
+	return location
+}
+
 // isNamespaceDeclaration checks if an attribute is a namespace declaration.
 func (s *Schema) isNamespaceDeclaration(attr xml.Attr) bool {
 	return attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns"
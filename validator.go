@@ -0,0 +1,104 @@
+package xmlparser
+
+import (
+	"context"
+	"encoding/xml"
+)
+
+// Validator validates documents against a schema with options fixed at creation, reusing
+// its scratch state (child counts, matched particles, ID tables) from one document to the
+// next. In a loop validating many documents, it avoids the allocations that
+// ValidateWithOptions makes for every call.
+//
+// A Validator is not safe for concurrent use: give each goroutine its own. They may all
+// share the Schema, whose content models are compiled once and cached.
+type Validator struct {
+	v *validator
+}
+
+// NewValidator returns a Validator for the schema.
+func (s *Schema) NewValidator(opts ValidateOptions) *Validator {
+	return &Validator{v: newValidator(s, opts)}
+}
+
+// Validate checks if the XML document conforms to the schema, like ValidateWithOptions
+// with the Validator's options.
+func (val *Validator) Validate(doc *Document) error {
+	return val.v.validate(context.Background(), doc)
+}
+
+// ValidateContext checks if the XML document conforms to the schema, like
+// Schema.ValidateContext with the Validator's options, stopping when ctx is done. The
+// Validator can go on with the next document afterwards.
+func (val *Validator) ValidateContext(ctx context.Context, doc *Document) error {
+	return val.v.validate(ctx, doc)
+}
+
+// reset clears the state left by the previous document, keeping the allocated storage.
+func (v *validator) reset() {
+	for value := range v.ids {
+		delete(v.ids, value)
+	}
+	for i := range v.idrefs {
+		v.idrefs[i] = idReference{}
+	}
+	v.idrefs = v.idrefs[:0]
+	for i := range v.issues {
+		v.issues[i] = ValidationIssue{}
+	}
+	v.issues = v.issues[:0]
+	v.stopped, v.canceled, v.reported = false, nil, 0
+	for message := range v.warned {
+		delete(v.warned, message)
+	}
+}
+
+// acquireMatcher returns a content matcher for the children of a node, reusing one released
+// earlier if possible. Content models nest, so several matchers are in use at a time.
+func (v *validator) acquireMatcher(node *Node, root *compiledParticle) *contentMatcher {
+	var m *contentMatcher
+	if n := len(v.matchers); n > 0 {
+		m, v.matchers = v.matchers[n-1], v.matchers[:n-1]
+	} else {
+		m = &contentMatcher{v: v, declared: make(map[xml.Name]int), matched: make(map[*Node]*Element)}
+	}
+	m.parent, m.root = node, root
+	m.pushParticle(root)
+	return m
+}
+
+// releaseMatcher clears a content matcher and makes it available to acquireMatcher.
+func (v *validator) releaseMatcher(m *contentMatcher) {
+	for i := range m.frames {
+		m.frames[i] = matchFrame{}
+	}
+	for name := range m.declared {
+		delete(m.declared, name)
+	}
+	for child := range m.matched {
+		delete(m.matched, child)
+	}
+	m.parent, m.root, m.frames = nil, nil, m.frames[:0]
+	m.current, m.ended, m.pos, m.previous, m.unmatched = nil, false, 0, "", nil
+	m.required, m.recordMatches = m.required[:0], false
+	m.undeclared, m.errors = nil, nil
+	v.matchers = append(v.matchers, m)
+}
+
+// acquireCounts returns an empty map for counting the children of an xs:all group.
+func (v *validator) acquireCounts() map[*Element]int {
+	if n := len(v.counts); n > 0 {
+		counts := v.counts[n-1]
+		v.counts = v.counts[:n-1]
+		return counts
+	}
+	return make(map[*Element]int)
+}
+
+// releaseCounts clears a map returned by acquireCounts and makes it available again.
+func (v *validator) releaseCounts(counts map[*Element]int) {
+	for element := range counts {
+		delete(counts, element)
+	}
+	v.counts = append(v.counts, counts)
+}
@@ -0,0 +1,160 @@
+package xmlparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const validatorTestSchema = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="library">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="book" maxOccurs="unbounded">
+					<xs:complexType>
+						<xs:all>
+							<xs:element name="title" type="xs:string"/>
+							<xs:element name="year" type="xs:gYear" minOccurs="0"/>
+						</xs:all>
+						<xs:attribute name="id" type="xs:ID" use="required"/>
+						<xs:attribute name="sequel" type="xs:IDREF"/>
+					</xs:complexType>
+				</xs:element>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`
+
+// Test that a Validator reports the same issues as ValidateWithOptions for every document
+// of a sequence, with no state carried over from one document to the next
+func TestValidatorReuse(t *testing.T) {
+	schema, err := ParseXSD([]byte(validatorTestSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	documents := []string{
+		`<library><book id="b1"><title>Dune</title><year>1965</year></book><book id="b2" sequel="b1"><title>Dune Messiah</title></book></library>`,
+		`<library><book id="b1"><year>1965</year></book><book id="b1" sequel="b3"><title>Dune</title><title>Again</title></book></library>`,
+		// Same IDs as the first document: they must not be reported as duplicates
+		`<library><book id="b1"><title>Dune</title></book><book id="b2" sequel="b1"><title>Dune Messiah</title></book></library>`,
+		`<library><book><title>Dune</title><isbn>0441013597</isbn></book></library>`,
+		`<catalog/>`,
+	}
+
+	validator := schema.NewValidator(ValidateOptions{})
+	for round := 0; round < 2; round++ {
+		for i, xml := range documents {
+			doc, err := Parse([]byte(xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML %d: %v", i, err)
+			}
+
+			expected := fmt.Sprint(schema.ValidateWithOptions(doc, ValidateOptions{}))
+			if actual := fmt.Sprint(validator.Validate(doc)); actual != expected {
+				t.Errorf("Round %d, document %d: expected %q, got %q", round, i, expected, actual)
+			}
+		}
+	}
+}
+
+// countdownContext is canceled once its Err method has been called a number of times.
+type countdownContext struct {
+	context.Context
+	remaining int
+}
+
+func (c *countdownContext) Err() error {
+	if c.remaining--; c.remaining < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+// Test that ValidateContext stops between elements once its context is done, and that a
+// Validator carries on with the next document
+func TestValidateContext(t *testing.T) {
+	schema, err := ParseXSD([]byte(validatorTestSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	var builder strings.Builder
+	builder.WriteString("<library>")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&builder, `<book id="b%d"><title>Title %d</title></book>`, i, i)
+	}
+	builder.WriteString("</library>")
+	doc, err := Parse([]byte(builder.String()))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if err := schema.ValidateContext(expired, doc, ValidateOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got: %v", err)
+	}
+
+	validator := schema.NewValidator(ValidateOptions{})
+	countdown := &countdownContext{Context: context.Background(), remaining: 10}
+	if err := validator.ValidateContext(countdown, doc); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected validation to be canceled, got: %v", err)
+	}
+	if countdown.remaining != -1 {
+		t.Errorf("Expected validation to stop at the first element after cancellation, %d checks too many", -1-countdown.remaining)
+	}
+	if err := validator.Validate(doc); err != nil {
+		t.Errorf("Expected the next validation to pass, got: %v", err)
+	}
+}
+
+// BenchmarkValidator compares a reused Validator with ValidateWithOptions on a document
+// with many elements.
+func BenchmarkValidator(b *testing.B) {
+	schema, err := ParseXSD([]byte(validatorTestSchema))
+	if err != nil {
+		b.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("<library>")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&builder, `<book id="b%d"><title>Title %d</title><year>1965</year></book>`, i, i)
+	}
+	builder.WriteString("</library>")
+	doc, err := Parse([]byte(builder.String()))
+	if err != nil {
+		b.Fatalf("Failed to parse XML: %v", err)
+	}
+
+	b.Run("ValidateWithOptions", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := schema.ValidateWithOptions(doc, ValidateOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Validator", func(b *testing.B) {
+		validator := schema.NewValidator(ValidateOptions{})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := validator.Validate(doc); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("ValidateParallel", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := schema.ValidateParallel(doc, ParallelOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package xmlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// warns reports whether validation collects warnings, for ValidateAndAnnotate or
+// ValidateOptions.OnWarning.
+func (v *validator) warns() bool {
+	return v.result != nil || v.opts.OnWarning != nil
+}
+
+// warn reports a CodeNotChecked warning located on node, or on its attribute if given,
+// unless the same warning was reported for the document already.
+func (v *validator) warn(node *Node, attribute, message string) {
+	if v.warned[message] {
+		return
+	}
+	issue := located(node, "", attribute, message)
+	issue.Code, issue.Severity, issue.Rule = CodeNotChecked, SeverityWarning, ""
+	v.reportWarning(message, issue)
+}
+
+// warnLocated reports a warning another validator found, such as that of a subtree of
+// ValidateParallel, unless the same warning was reported for the document already.
+func (v *validator) warnLocated(issue ValidationIssue) {
+	v.reportWarning(strings.TrimSuffix(issue.Message, " (at "+issue.XPath+")"), issue)
+}
+
+// reportWarning records the warning issue with the given message, unlocated, and passes it on.
+func (v *validator) reportWarning(message string, issue ValidationIssue) {
+	if v.warned[message] {
+		return
+	}
+	if v.warned == nil {
+		v.warned = make(map[string]bool)
+	}
+	v.warned[message] = true
+
+	if v.result != nil {
+		v.result.Warnings = append(v.result.Warnings, issue)
+	}
+	if v.opts.OnWarning != nil {
+		v.opts.OnWarning(issue)
+	}
+}
+
+// warnUncheckedElement warns of the constraints of an element's declaration and complex
+// type that validation does not check.
+func (v *validator) warnUncheckedElement(node *Node, def *Element, complexType *ComplexType) {
+	v.warnConstructs(node, "", fmt.Sprintf("element <%s>", node.Name.Local), def.Unchecked)
+	if complexType != nil && len(complexType.Assertions) > 0 {
+		v.warn(node, "", fmt.Sprintf("xs:assert of the type of element <%s> is not checked", node.Name.Local))
+	}
+}
+
+// warnUncheckedType warns of the constraints of a simple type, and of the types it derives
+// from, that validation does not check, for the value of an element or of its attribute.
+func (v *validator) warnUncheckedType(node *Node, attribute string, simpleType *SimpleType) {
+	owner := fmt.Sprintf("the type of element <%s>", node.Name.Local)
+	if attribute != "" {
+		owner = fmt.Sprintf("the type of attribute '%s' in element <%s>", attribute, node.Name.Local)
+	}
+	for depth := 0; simpleType != nil && depth < maxDerivationDepth; depth++ {
+		v.warnConstructs(node, attribute, owner, simpleType.Unchecked)
+		restriction := simpleType.Restriction
+		if restriction == nil {
+			break
+		}
+		v.warnConstructs(node, attribute, owner, restriction.Unchecked)
+		if len(restriction.Assertions) > 0 {
+			v.warn(node, attribute, fmt.Sprintf("xs:assertion of %s is not checked", owner))
+		}
+		simpleType = v.restrictionBase(restriction)
+	}
+}
+
+// warnConstructs warns of the unchecked constructs of a schema component, but annotations.
+func (v *validator) warnConstructs(node *Node, attribute, owner string, constructs []UncheckedConstruct) {
+	for _, construct := range constructs {
+		if construct.XMLName.Local != "annotation" {
+			v.warn(node, attribute, fmt.Sprintf("xs:%s of %s is not checked", construct.XMLName.Local, owner))
+		}
+	}
+}
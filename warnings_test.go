@@ -0,0 +1,84 @@
+package xmlparser
+
+import "testing"
+
+func TestWarnings(t *testing.T) {
+	schema, err := ParseXSD([]byte(`<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:simpleType name="Code">
+    <xs:annotation><xs:documentation>Three letters</xs:documentation></xs:annotation>
+    <xs:restriction base="xs:string">
+      <xs:length value="3"/>
+      <xs:pattern value="[A-Z]+"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="Sizes">
+    <xs:list itemType="xs:integer"/>
+  </xs:simpleType>
+  <xs:element name="catalog">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="item" maxOccurs="unbounded">
+          <xs:complexType>
+            <xs:sequence>
+              <xs:element name="code" type="Code"/>
+            </xs:sequence>
+            <xs:attribute name="sizes" type="Sizes"/>
+            <xs:anyAttribute namespace="urn:ext" processContents="lax"/>
+          </xs:complexType>
+        </xs:element>
+      </xs:sequence>
+      <xs:anyAttribute namespace="##other" processContents="skip"/>
+    </xs:complexType>
+    <xs:key name="itemCode">
+      <xs:selector xpath="item"/>
+      <xs:field xpath="code"/>
+    </xs:key>
+  </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	doc, err := Parse([]byte(`<catalog xmlns:e="urn:ext" e:version="2">
+  <item sizes="1 2"><code>ABC</code></item>
+  <item e:color="red"><code>XYZ</code></item>
+</catalog>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	expected := []struct {
+		message string
+		xpath   string
+	}{
+		{"xs:key of element <catalog> is not checked (at /catalog)", "/catalog"},
+		{"attribute 'version' in element <catalog> is not checked: it matches an attribute wildcard with processContents skip (at /catalog/@e:version)", "/catalog/@e:version"},
+		{"xs:list of the type of attribute 'sizes' in element <item> is not checked (at /catalog/item[1]/@sizes)", "/catalog/item[1]/@sizes"},
+		{"xs:length of the type of element <code> is not checked (at /catalog/item[1]/code[1])", "/catalog/item[1]/code[1]"},
+		{"attribute 'color' in element <item> is not checked: it matches a lax attribute wildcard, but no global declaration of it is found (at /catalog/item[2]/@e:color)", "/catalog/item[2]/@e:color"},
+	}
+
+	result, err := schema.ValidateAndAnnotate(doc)
+	if err != nil || !result.Valid() {
+		t.Fatalf("Expected a valid document, got: %v %v", err, result.Issues)
+	}
+	var reported []ValidationIssue
+	if err := schema.ValidateWithOptions(doc, ValidateOptions{OnWarning: func(issue ValidationIssue) {
+		reported = append(reported, issue)
+	}}); err != nil {
+		t.Fatalf("Expected a valid document, got: %v", err)
+	}
+
+	for name, warnings := range map[string][]ValidationIssue{"Result.Warnings": result.Warnings, "OnWarning": reported} {
+		if len(warnings) != len(expected) {
+			t.Fatalf("%s: expected %d warnings, got %d: %+v", name, len(expected), len(warnings), warnings)
+		}
+		for i, want := range expected {
+			got := warnings[i]
+			if got.Message != want.message || got.XPath != want.xpath || got.Code != CodeNotChecked ||
+				got.Severity != SeverityWarning || got.Rule != "" {
+				t.Errorf("%s %d: expected %q at %s, got %+v", name, i, want.message, want.xpath, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"unicode/utf8"
+)
+
+// checkStartElement checks the attributes and prefixes of a new element, for
+// DocumentOptions.Strict. The decoder resolves the prefixes of names to namespaces, but
+// leaves those of undeclared prefixes in place, so a name whose namespace is not bound in
+// scope has an undeclared prefix.
+func (p *xmlParser) checkStartElement(node *Node) error {
+	if node.Name.Space != "" && !node.bindsNamespace(node.Name.Space) {
+		return p.syntaxError(node.Position, fmt.Sprintf("element <%s:%s> uses undeclared namespace prefix '%s'",
+			node.Name.Space, node.Name.Local, node.Name.Space))
+	}
+	for i, attr := range node.Attrs {
+		position := node.Position
+		if i < len(node.AttrPositions) {
+			position = node.AttrPositions[i]
+		}
+		if attr.Name.Space != "" && attr.Name.Space != "xmlns" && !node.bindsNamespace(attr.Name.Space) {
+			return p.syntaxError(position, fmt.Sprintf("attribute '%s:%s' in element <%s> uses undeclared namespace prefix '%s'",
+				attr.Name.Space, attr.Name.Local, node.Name.Local, attr.Name.Space))
+		}
+		for _, earlier := range node.Attrs[:i] {
+			if earlier.Name != attr.Name {
+				continue
+			}
+			if attr.Name.Space == "" || attr.Name.Space == "xmlns" {
+				name := attr.Name.Local
+				if attr.Name.Space == "xmlns" {
+					name = "xmlns:" + name
+				}
+				return p.syntaxError(position, fmt.Sprintf("element <%s> has attribute '%s' more than once", node.Name.Local, name))
+			}
+			// Attributes with different prefixes bound to the same namespace are the same too
+			return p.syntaxError(position, fmt.Sprintf("element <%s> has attribute '%s' of namespace '%s' more than once",
+				node.Name.Local, attr.Name.Local, attr.Name.Space))
+		}
+	}
+	return nil
+}
+
+// checkMarkup checks that a comment, processing instruction or directive starting at byte
+// offset start holds only characters XML allows, for DocumentOptions.Strict; the decoder
+// checks those of text and attribute values.
+func (p *xmlParser) checkMarkup(token xml.Token, start int64) error {
+	var data []byte
+	var kind string
+	switch t := token.(type) {
+	case xml.Comment:
+		data, kind = t, "comment"
+	case xml.ProcInst:
+		data, kind = t.Inst, "processing instruction"
+	case xml.Directive:
+		data, kind = t, "directive"
+	default:
+		return nil
+	}
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			return p.syntaxError(p.markupPosition(start), fmt.Sprintf("invalid UTF-8 in %s", kind))
+		}
+		if !isXMLChar(r) {
+			return p.syntaxError(p.markupPosition(start), fmt.Sprintf("illegal character code %U in %s", r, kind))
+		}
+		i += size
+	}
+	return nil
+}
+
+// markupPosition returns the position of the markup starting at byte offset start, when
+// the source is available.
+func (p *xmlParser) markupPosition(start int64) Position {
+	if p.source == nil {
+		return Position{}
+	}
+	return p.position(start)
+}
+
+// syntaxError returns the parse error of a document that is not well-formed at position,
+// or at the decoder's line when the position is not known.
+func (p *xmlParser) syntaxError(position Position, message string) error {
+	line := position.Line
+	if line == 0 {
+		line, _ = p.decoder.InputPos()
+	}
+	return fmt.Errorf("XML parsing error: %w", &xml.SyntaxError{Msg: message, Line: line})
+}
+
+// bindsNamespace reports whether a namespace is bound to a prefix, or is the default
+// namespace, in scope at the node.
+func (n *Node) bindsNamespace(namespace string) bool {
+	if namespace == xmlNamespace {
+		return true
+	}
+	for scope := n.scope; scope != nil; scope = scope.parent {
+		for _, bound := range scope.bindings {
+			if bound == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isXMLChar reports whether r is a character XML documents may contain (the Char
+// production of XML 1.0).
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF || r >= 0xE000 && r <= 0xFFFD || r >= 0x10000 && r <= 0x10FFFF
+}
@@ -0,0 +1,185 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// wildcard is the namespace constraint and processContents of an attribute wildcard. It
+// allows the namespaces in the set or, when negated, every namespace except those in the
+// set; the empty string stands for attributes without a namespace.
+type wildcard struct {
+	negated         bool
+	namespaces      map[string]bool
+	processContents string // strict, lax or skip
+}
+
+// newWildcard returns the wildcard declared by an xs:anyAttribute, or nil if there is none.
+func newWildcard(anyAttribute *AnyAttribute) *wildcard {
+	if anyAttribute == nil {
+		return nil
+	}
+
+	w := &wildcard{namespaces: make(map[string]bool), processContents: anyAttribute.ProcessContents}
+	if w.processContents == "" {
+		w.processContents = "strict"
+	}
+	switch namespace := strings.TrimSpace(anyAttribute.Namespace); namespace {
+	case "", "##any":
+		w.negated = true
+	case "##other":
+		// Any namespace other than the target namespace; unqualified attributes are excluded as well
+		w.negated = true
+		w.namespaces[anyAttribute.namespace] = true
+		w.namespaces[""] = true
+	default:
+		for _, token := range strings.Fields(namespace) {
+			switch token {
+			case "##targetNamespace":
+				w.namespaces[anyAttribute.namespace] = true
+			case "##local":
+				w.namespaces[""] = true
+			default:
+				w.namespaces[token] = true
+			}
+		}
+	}
+	return w
+}
+
+// allows reports whether the wildcard matches attributes in a namespace.
+func (w *wildcard) allows(namespace string) bool {
+	return w.namespaces[namespace] != w.negated
+}
+
+// union returns a wildcard allowing the namespaces allowed by either wildcard, with the
+// processContents of w.
+func (w *wildcard) union(other *wildcard) *wildcard {
+	result := &wildcard{namespaces: make(map[string]bool), processContents: w.processContents}
+	switch {
+	case !w.negated && !other.negated:
+		for namespace := range w.namespaces {
+			result.namespaces[namespace] = true
+		}
+		for namespace := range other.namespaces {
+			result.namespaces[namespace] = true
+		}
+	case w.negated && other.negated:
+		// Excluded only if both exclude it
+		result.negated = true
+		for namespace := range w.namespaces {
+			if other.namespaces[namespace] {
+				result.namespaces[namespace] = true
+			}
+		}
+	default:
+		// Excluded by the negated wildcard and not listed by the other one
+		positive, negative := w, other
+		if w.negated {
+			positive, negative = other, w
+		}
+		result.negated = true
+		for namespace := range negative.namespaces {
+			if !positive.namespaces[namespace] {
+				result.namespaces[namespace] = true
+			}
+		}
+	}
+	return result
+}
+
+// attributeWildcard returns the attribute wildcard in effect for a complex type, or nil if
+// the type allows no undeclared attributes. A type that is not derived, or derived by
+// restriction, has only the wildcard it declares. An extension also inherits the effective
+// wildcard of its base type: the result is the union of both, with the processContents of
+// its own. xs:anyType has a wildcard allowing any attribute with lax processing.
+func (s *Schema) attributeWildcard(complexType *ComplexType) *wildcard {
+	return s.effectiveWildcard(complexType, 0)
+}
+
+// effectiveWildcard computes attributeWildcard, with depth guarding against circular derivations.
+func (s *Schema) effectiveWildcard(complexType *ComplexType, depth int) *wildcard {
+	declared := newWildcard(complexType.declaredWildcard())
+	if _, method := complexType.derivation(); method != "extension" || depth > maxDerivationDepth {
+		return declared
+	}
+
+	var inherited *wildcard
+	if complexType.base == anyTypeName {
+		inherited = &wildcard{negated: true, processContents: "lax"}
+	} else if base := s.complexTypeNSMap[complexType.base]; base != nil {
+		inherited = s.effectiveWildcard(base, depth+1)
+	}
+
+	switch {
+	case inherited == nil:
+		return declared
+	case declared == nil:
+		return inherited
+	default:
+		return declared.union(inherited)
+	}
+}
+
+// matchAttributeWildcard handles an attribute at index i of node that matches none of the
+// complex type's attribute uses. It returns the global declaration to validate the attribute
+// against, or nil if there is none; issues are returned when the attribute is not allowed.
+func (v *validator) matchAttributeWildcard(node *Node, i int, complexType *ComplexType) (*Attribute, []string) {
+	name := node.Attrs[i].Name
+	location := attributeLocation(node, i)
+
+	w := v.attributeWildcard(complexType)
+	if w == nil || !w.allows(name.Space) {
+		if reason := attributeFormMismatch(complexType.Attributes, name); reason != "" {
+			return nil, []string{fmt.Sprintf("unexpected %s: %s", location, reason)}
+		}
+		if w != nil {
+			return nil, []string{fmt.Sprintf("unexpected %s: %s is not allowed by the attribute wildcard",
+				location, describeNamespace(name.Space))}
+		}
+		return nil, []string{fmt.Sprintf("unexpected %s", location)}
+	}
+
+	if w.processContents == "skip" {
+		if v.warns() {
+			v.warn(node, name.Local, fmt.Sprintf("attribute '%s' in element <%s> is not checked: it matches an attribute wildcard with processContents skip",
+				name.Local, node.Name.Local))
+		}
+		return nil, nil
+	}
+	if global := v.globalAttribute(name); global != nil {
+		return global, nil
+	}
+	if w.processContents == "strict" {
+		return nil, []string{fmt.Sprintf("%s matches the attribute wildcard, but no global declaration of it is found (processContents is strict)",
+			location)}
+	}
+	if v.warns() {
+		v.warn(node, name.Local, fmt.Sprintf("attribute '%s' in element <%s> is not checked: it matches a lax attribute wildcard, but no global declaration of it is found",
+			name.Local, node.Name.Local))
+	}
+	return nil, nil
+}
+
+// globalAttribute returns the global attribute declaration of an instance attribute name,
+// including the built-in attributes of the XML namespace, or nil.
+func (s *Schema) globalAttribute(name xml.Name) *Attribute {
+	if name.Space == xmlNamespace {
+		attribute, exists := builtInXMLAttributes[name.Local]
+		if !exists {
+			return nil
+		}
+		attribute.qualified, attribute.namespace = true, xmlNamespace
+		return &attribute
+	}
+	return s.attributeNSMap[name]
+}
+
+// describeNamespace names a namespace in error messages.
+func describeNamespace(namespace string) string {
+	if namespace == "" {
+		return "an attribute without a namespace"
+	}
+	return fmt.Sprintf("namespace '%s'", namespace)
+}
@@ -0,0 +1,186 @@
+package xmlparser
+
+import "testing"
+
+// Test attribute wildcards, including those inherited by extension
+func TestAttributeWildcards(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:test"
+           targetNamespace="urn:test" elementFormDefault="qualified">
+    <xs:attribute name="version" type="xs:integer"/>
+
+    <xs:complexType name="Base">
+        <xs:attribute name="id" type="xs:string" use="required"/>
+        <xs:attribute name="note" type="xs:string"/>
+        <xs:anyAttribute namespace="##other" processContents="lax"/>
+    </xs:complexType>
+
+    <xs:complexType name="Extended">
+        <xs:complexContent>
+            <xs:extension base="t:Base">
+                <xs:anyAttribute namespace="##targetNamespace" processContents="strict"/>
+            </xs:extension>
+        </xs:complexContent>
+    </xs:complexType>
+
+    <xs:complexType name="Restricted">
+        <xs:complexContent>
+            <xs:restriction base="t:Base">
+                <xs:attribute name="note" use="prohibited"/>
+            </xs:restriction>
+        </xs:complexContent>
+    </xs:complexType>
+
+    <xs:element name="root">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="base" type="t:Base" minOccurs="0"/>
+                <xs:element name="extended" type="t:Extended" minOccurs="0"/>
+                <xs:element name="restricted" type="t:Restricted" minOccurs="0"/>
+                <xs:element name="skip" minOccurs="0">
+                    <xs:complexType>
+                        <xs:anyAttribute processContents="skip"/>
+                    </xs:complexType>
+                </xs:element>
+                <xs:element name="local" minOccurs="0">
+                    <xs:complexType>
+                        <xs:anyAttribute namespace="##local urn:listed" processContents="lax"/>
+                    </xs:complexType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	const open = `<root xmlns="urn:test" xmlns:t="urn:test" xmlns:o="urn:other" xmlns:l="urn:listed">`
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Lax wildcard accepts undeclared foreign attribute",
+			xml:        open + `<base id="a" o:color="red"/></root>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Lax wildcard validates declared global attribute",
+			xml:        open + `<base id="a" xml:lang="en-US"/></root>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Wildcard excludes unqualified attributes",
+			xml:         open + `<base id="a" color="red"/></root>`,
+			errorString: "unexpected attribute 'color' in element <base> (line 1, column 97): an attribute without a namespace is not allowed by the attribute wildcard",
+		},
+		{
+			name:        "##other excludes the target namespace",
+			xml:         open + `<base id="a" t:version="1"/></root>`,
+			errorString: "namespace 'urn:test' is not allowed by the attribute wildcard",
+		},
+		{
+			name:       "Extension unions its wildcard with the inherited one",
+			xml:        open + `<extended id="a" t:version="1"/></root>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Attributes matched by a wildcard are validated against their declaration",
+			xml:         open + `<extended id="a" t:version="one"/></root>`,
+			errorString: "attribute 'version' in element <extended> (line 1, column 101): value 'one' is not a valid integer",
+		},
+		{
+			name:        "Strict processing requires a global declaration",
+			xml:         open + `<extended id="a" o:color="red"/></root>`,
+			errorString: "attribute 'color' in element <extended> (line 1, column 101) matches the attribute wildcard, but no global declaration of it is found (processContents is strict)",
+		},
+		{
+			name:        "Extension inherits attribute uses",
+			xml:         open + `<extended note="n"/></root>`,
+			errorString: "required attribute 'id' is missing from element <extended>",
+		},
+		{
+			name:        "Restriction does not inherit the wildcard",
+			xml:         open + `<restricted id="a" o:color="red"/></root>`,
+			errorString: "unexpected attribute 'color' in element <restricted>",
+		},
+		{
+			name:        "Restriction removes prohibited attributes",
+			xml:         open + `<restricted id="a" note="n"/></root>`,
+			errorString: "unexpected attribute 'note' in element <restricted>",
+		},
+		{
+			name:       "Skip wildcard accepts anything without validation",
+			xml:        open + `<skip color="red" t:version="one" o:size="L"/></root>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Namespace list with ##local",
+			xml:        open + `<local color="red" l:size="L"/></root>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Namespace not in the list",
+			xml:         open + `<local o:size="L"/></root>`,
+			errorString: "namespace 'urn:other' is not allowed by the attribute wildcard",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+}
+
+// Test the union of wildcard namespace constraints
+func TestWildcardUnion(t *testing.T) {
+	tests := []struct {
+		name      string
+		derived   string
+		base      string
+		allowed   []string
+		forbidden []string
+	}{
+		{"Two lists", "urn:a", "urn:b ##local", []string{"urn:a", "urn:b", ""}, []string{"urn:c"}},
+		{"Any with list", "##any", "urn:a", []string{"urn:a", "urn:z", ""}, nil},
+		{"Other with list containing target", "##other", "##targetNamespace", []string{"urn:tns", "urn:z"}, []string{""}},
+		{"Other with ##local", "##other", "##local", []string{"", "urn:z"}, []string{"urn:tns"}},
+		{"Two negations", "##other", "##other", []string{"urn:z"}, []string{"urn:tns", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			derived := newWildcard(&AnyAttribute{Namespace: tt.derived, ProcessContents: "skip", namespace: "urn:tns"})
+			base := newWildcard(&AnyAttribute{Namespace: tt.base, namespace: "urn:tns"})
+			union := derived.union(base)
+			if union.processContents != "skip" {
+				t.Errorf("Expected processContents of the derived wildcard, got %q", union.processContents)
+			}
+			for _, namespace := range tt.allowed {
+				if !union.allows(namespace) {
+					t.Errorf("Expected namespace %q to be allowed", namespace)
+				}
+			}
+			for _, namespace := range tt.forbidden {
+				if union.allows(namespace) {
+					t.Errorf("Expected namespace %q not to be allowed", namespace)
+				}
+			}
+		})
+	}
+}
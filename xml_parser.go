@@ -5,22 +5,290 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"unicode/utf8"
 )
 
 // Parse parses XML data and constructs a Document tree structure for validation.
 // The resulting Document can be validated against an XSD schema.
 func Parse(xmlBytes []byte) (*Document, error) {
+	return ParseWithOptions(xmlBytes, DocumentOptions{})
+}
+
+// ParseReader parses an XML document read from r, such as a file, an HTTP body or a pipe,
+// like Parse. The input is decoded as it is read: only the start tag being parsed and the
+// decoder's read-ahead are buffered, besides the document tree itself.
+func ParseReader(r io.Reader) (*Document, error) {
+	return ParseReaderWithOptions(r, DocumentOptions{})
+}
+
+// DocumentOptions configures ParseWithOptions and ParseReaderWithOptions. The zero value
+// gives the behavior of Parse.
+type DocumentOptions struct {
+	// PreserveNodes keeps the text, CDATA sections, comments and processing instructions
+	// of the document as nodes of their kinds, listed in document order with the elements
+	// in the Nodes of their parent element, or of the Document outside the root element,
+	// so that tools can tell them apart and Document.WriteXML writes them back. By default
+	// only elements are kept, with the text and CDATA sections within them as their Content.
+	PreserveNodes bool
+
+	// Limits for documents from untrusted sources, such as the requests of a public
+	// service; a document exceeding one fails to parse with a *LimitError, and zero values
+	// set no limit. Entities are never expanded, whatever the limits: only the predefined
+	// entities and character references are known, and references to any other entity,
+	// internal or external, are parse errors, so documents cannot expand to a multiple of
+	// their size (as in the billion laughs attack) or read other files.
+
+	// MaxDepth is the deepest element nesting accepted, the root element being at depth 1.
+	MaxDepth int
+
+	// MaxAttributes is the largest number of attributes, namespace declarations included,
+	// accepted on one element.
+	MaxAttributes int
+
+	// MaxBytes is the size of the largest document accepted. Documents read from a reader
+	// are rejected as soon as more has been read, without reading the rest.
+	MaxBytes int64
+
+	// ProhibitDTD rejects documents with a document type declaration (<!DOCTYPE ...>) or
+	// any other <!...> directive, which validation against a schema has no use for.
+	ProhibitDTD bool
+
+	// CharsetReader converts documents whose XML declaration names an encoding other than
+	// UTF-8, such as encoding="ISO-8859-1", to UTF-8, as xml.Decoder.CharsetReader does; it
+	// returns an error for the encodings it does not support. Defaults to CharsetReader,
+	// which supports the common single-byte encodings.
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+
+	// Strict checks the well-formedness constraints the decoder of encoding/xml leaves out:
+	// that no element has the same attribute twice, by name or by namespace and local name;
+	// that element and attribute prefixes are declared; and that comments and processing
+	// instructions hold only characters XML allows. A document breaking one fails to parse
+	// with an *xml.SyntaxError giving its line. Other malformed input, such as invalid
+	// characters in text and attribute values, always fails to parse.
+	Strict bool
+}
+
+// charsetReader returns the CharsetReader of the options, or the default.
+func (opts DocumentOptions) charsetReader() charsetReaderFunc {
+	if opts.CharsetReader != nil {
+		return opts.CharsetReader
+	}
+	return CharsetReader
+}
+
+// LimitError is the error of a document exceeding a limit of DocumentOptions, wrapped in
+// the error of the parse functions. A ValidationError reporting it as a parse error gives
+// its position.
+type LimitError struct {
+	Limit    string   // The DocumentOptions field of the limit exceeded, such as "MaxDepth"
+	Position Position // Where the document exceeds it; zero for MaxBytes
+	message  string
+}
+
+// Error describes the limit exceeded.
+func (e *LimitError) Error() string {
+	return e.message
+}
+
+// ParseWithOptions parses an XML document like Parse, with explicit options.
+func ParseWithOptions(xmlBytes []byte, opts DocumentOptions) (*Document, error) {
+	if opts.MaxBytes > 0 && int64(len(xmlBytes)) > opts.MaxBytes {
+		return nil, fmt.Errorf("XML parsing error: %w", sizeLimitError(opts.MaxBytes))
+	}
+	charsetReader := opts.charsetReader()
+	if charset := declaredCharset(xmlBytes); charset != "" {
+		converted, err := charsetReader(charset, bytes.NewReader(xmlBytes))
+		if err == nil {
+			xmlBytes, err = io.ReadAll(converted)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("XML parsing error: %w", err)
+		}
+		charsetReader = passThroughCharset(charset, charsetReader)
+	}
 	decoder := xml.NewDecoder(bytes.NewReader(xmlBytes))
-	parser := &xmlParser{decoder: decoder}
+	decoder.CharsetReader = charsetReader
+	parser := &xmlParser{decoder: decoder, source: &sourceWindow{data: xmlBytes}, lastPosition: Position{Line: 1, Column: 1},
+		preserve: opts.PreserveNodes, opts: opts}
 
 	return parser.parseDocument()
 }
 
+// ParseReaderWithOptions parses an XML document read from r like ParseReader, with
+// explicit options.
+func ParseReaderWithOptions(r io.Reader, opts DocumentOptions) (*Document, error) {
+	parser, err := newReaderParser(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	return parser.parseDocument()
+}
+
+// newReaderParser returns a parser of the document read from r.
+func newReaderParser(r io.Reader, opts DocumentOptions) (*xmlParser, error) {
+	if opts.MaxBytes > 0 {
+		r = &limitedReader{reader: r, limit: opts.MaxBytes}
+	}
+	input, charsetReader, err := utf8Input(r, opts.charsetReader())
+	if err != nil {
+		return nil, fmt.Errorf("XML parsing error: %w", err)
+	}
+	source := &sourceWindow{reader: input}
+	decoder := xml.NewDecoder(source)
+	decoder.CharsetReader = charsetReader
+	return &xmlParser{decoder: decoder, source: source, lastPosition: Position{Line: 1, Column: 1},
+		preserve: opts.PreserveNodes, opts: opts}, nil
+}
+
+// limitedReader reads a document of at most limit bytes (DocumentOptions.MaxBytes). It
+// reads one byte more than allowed, to tell a document of exactly the limit from a larger
+// one, and then fails.
+type limitedReader struct {
+	reader io.Reader
+	limit  int64
+	read   int64
+}
+
+// Read reads from the underlying reader, failing once more than limit bytes were read.
+func (r *limitedReader) Read(b []byte) (int, error) {
+	if r.read > r.limit {
+		return 0, sizeLimitError(r.limit)
+	}
+	if remaining := r.limit + 1 - r.read; int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+	n, err := r.reader.Read(b)
+	r.read += int64(n)
+	return n, err
+}
+
+// sizeLimitError reports a document larger than DocumentOptions.MaxBytes.
+func sizeLimitError(maxBytes int64) *LimitError {
+	return &LimitError{Limit: "MaxBytes", message: fmt.Sprintf("document is larger than the limit of %d bytes", maxBytes)}
+}
+
 // xmlParser handles the XML parsing state and logic.
 type xmlParser struct {
 	decoder     *xml.Decoder
 	currentNode *Node
 	document    *Document
+
+	source   *sourceWindow // Raw document, used to locate attributes within start tags; nil when unknown
+	element  bool          // Whether to parse one element from the decoder's position, instead of a whole document
+	preserve bool          // Whether to keep the nodes of every kind (see DocumentOptions.PreserveNodes)
+
+	// Limits and checks of the document (MaxDepth, Strict, ...); its other fields are not
+	// read from it
+	opts DocumentOptions
+
+	lastOffset   int64    // Offset of the last position computed, to count lines and columns incrementally
+	lastPosition Position // The last position computed
+
+	// Hooks of streaming validation, called when an element starts (with its attributes)
+	// and when it ends (with its content); nil when only the tree is built
+	onStart func(node *Node) error
+	onEnd   func(node *Node) error
+
+	// Hook of streaming filters, called with the other tokens: text, comments, processing
+	// instructions and directives. Filters copy tokens with tokenSource.
+	onData func(token xml.Token) error
+
+	tokenStart int64 // Offset of the token being processed
+
+	// Text of the open elements, innermost last, each from its offset in textStarts on:
+	// an element's text is converted to its Content once, at its end tag
+	text       []byte
+	textStarts []int
+
+	// Storage the nodes of a document tree are allocated from (see newNode)
+	arena nodeArena
+}
+
+// nodeArena allocates the nodes of a document tree, with their attributes and attribute
+// positions, in blocks, sparing the garbage collector an object per node. Each block is as
+// large as the nodes allocated before it, up to maxArenaBlock entries, so at most half the
+// storage of a small document is unused. Documents outlive the parse, so nodes are never returned
+// for reuse.
+type nodeArena struct {
+	nodes     []Node
+	attrs     []xml.Attr
+	positions []Position
+	allocated int // Nodes allocated so far
+}
+
+// maxArenaBlock is the largest number of nodes, attributes or positions allocated at once.
+const maxArenaBlock = 1024
+
+// arenaBlock returns the size of the next block of a nodeArena that needs at least n entries.
+func (a *nodeArena) arenaBlock(n int) int {
+	size := a.allocated
+	if size < 8 {
+		size = 8
+	} else if size > maxArenaBlock {
+		size = maxArenaBlock
+	}
+	if size < n {
+		size = n
+	}
+	return size
+}
+
+// node returns a new zero Node.
+func (a *nodeArena) node() *Node {
+	if len(a.nodes) == 0 {
+		a.nodes = make([]Node, a.arenaBlock(1))
+	}
+	node := &a.nodes[0]
+	a.nodes = a.nodes[1:]
+	a.allocated++
+	return node
+}
+
+// attributes returns a copy of attrs.
+func (a *nodeArena) attributes(attrs []xml.Attr) []xml.Attr {
+	if len(a.attrs) < len(attrs) {
+		a.attrs = make([]xml.Attr, a.arenaBlock(len(attrs)))
+	}
+	copied := a.attrs[:len(attrs):len(attrs)]
+	a.attrs = a.attrs[len(attrs):]
+	copy(copied, attrs)
+	return copied
+}
+
+// positionSlice returns a slice of n zero Positions.
+func (a *nodeArena) positionSlice(n int) []Position {
+	if len(a.positions) < n {
+		a.positions = make([]Position, a.arenaBlock(n))
+	}
+	positions := a.positions[:n:n]
+	a.positions = a.positions[n:]
+	return positions
+}
+
+// maxRetainedSource is the number of bytes of a streamed document kept before the token
+// being parsed, past which they are discarded.
+const maxRetainedSource = 64 * 1024
+
+// sourceWindow holds the raw bytes of a document from offset base on. For a document
+// read from a reader, it records the bytes read through it, so that the part already
+// parsed can be discarded.
+type sourceWindow struct {
+	reader io.Reader // Reader of a streamed document; nil when data is the whole document
+	data   []byte
+	base   int64
+}
+
+// Read reads from the underlying reader and records the bytes read.
+func (w *sourceWindow) Read(b []byte) (int, error) {
+	n, err := w.reader.Read(b)
+	w.data = append(w.data, b[:n]...)
+	return n, err
+}
+
+// discard drops the bytes before offset.
+func (w *sourceWindow) discard(offset int64) {
+	w.data = append(w.data[:0], w.data[offset-w.base:]...)
+	w.base = offset
 }
 
 // parseDocument parses the entire XML document into a Document tree.
@@ -28,6 +296,12 @@ func (p *xmlParser) parseDocument() (*Document, error) {
 	p.document = &Document{}
 
 	for {
+		start := p.decoder.InputOffset()
+		if p.source != nil && p.source.reader != nil && start-p.source.base > maxRetainedSource {
+			// Positions are counted up to the token before the bytes are dropped
+			p.position(start)
+			p.source.discard(start)
+		}
 		token, err := p.decoder.Token()
 		if err != nil {
 			if err == io.EOF {
@@ -36,9 +310,17 @@ func (p *xmlParser) parseDocument() (*Document, error) {
 			return nil, fmt.Errorf("XML parsing error: %w", err)
 		}
 
-		if err := p.processToken(token); err != nil {
+		if p.element && p.document.Root == nil {
+			if end, ok := token.(xml.EndElement); ok {
+				return nil, fmt.Errorf("XML parsing error: expected an element, found end tag </%s>", end.Name.Local)
+			}
+		}
+		if err := p.processToken(token, start); err != nil {
 			return nil, err
 		}
+		if p.element && p.document.Root != nil && p.currentNode == nil {
+			break
+		}
 	}
 
 	if p.document.Root == nil {
@@ -48,35 +330,98 @@ func (p *xmlParser) parseDocument() (*Document, error) {
 	return p.document, nil
 }
 
-// processToken processes a single XML token and updates the document tree.
-func (p *xmlParser) processToken(token xml.Token) error {
+// processToken processes a single XML token, which starts at byte offset start, and updates the document tree.
+func (p *xmlParser) processToken(token xml.Token, start int64) error {
+	p.tokenStart = start
 	switch t := token.(type) {
 	case xml.StartElement:
-		return p.handleStartElement(t)
+		if err := p.handleStartElement(t, start); err != nil {
+			return err
+		}
+		if p.onStart != nil {
+			return p.onStart(p.currentNode)
+		}
 	case xml.CharData:
 		p.handleCharData(t)
+		if p.preserve {
+			p.preserveCharData(t, start)
+		}
+		if p.onData != nil {
+			return p.onData(t)
+		}
 	case xml.EndElement:
+		p.endText()
+		if p.onEnd != nil && p.currentNode != nil {
+			if err := p.onEnd(p.currentNode); err != nil {
+				return err
+			}
+		}
 		p.handleEndElement()
-	case xml.Comment:
-		// Ignore comments for validation purposes
-	case xml.ProcInst:
-		// Ignore processing instructions for validation purposes
 	default:
-		// Other token types are ignored
+		if _, ok := token.(xml.Directive); ok && p.opts.ProhibitDTD {
+			return p.limitError("ProhibitDTD", start, "document type declarations and other directives are not allowed")
+		}
+		if p.opts.Strict {
+			if err := p.checkMarkup(token, start); err != nil {
+				return err
+			}
+		}
+		// Comments, processing instructions and directives are ignored for validation purposes
+		if p.preserve {
+			p.preserveMarkup(t, start)
+		}
+		if p.onData != nil {
+			return p.onData(t)
+		}
 	}
 	return nil
 }
 
+// tokenSource returns the source bytes of the token being processed. The end tag of an
+// empty-element tag such as <a/> has none: the start tag holds the whole element.
+func (p *xmlParser) tokenSource() []byte {
+	return p.source.data[p.tokenStart-p.source.base : p.decoder.InputOffset()-p.source.base]
+}
+
 // handleStartElement processes an XML start element token.
-func (p *xmlParser) handleStartElement(element xml.StartElement) error {
-	node := &Node{
-		Parent: p.currentNode,
-		Name:   element.Name,
-		Attrs:  make([]xml.Attr, len(element.Attr)),
+func (p *xmlParser) handleStartElement(element xml.StartElement, start int64) error {
+	// textStarts has an entry per open element
+	if depth := len(p.textStarts) + 1; p.opts.MaxDepth > 0 && depth > p.opts.MaxDepth {
+		return p.limitError("MaxDepth", start, fmt.Sprintf("element <%s> is nested %d elements deep, deeper than the limit of %d",
+			element.Name.Local, depth, p.opts.MaxDepth))
+	}
+	if p.opts.MaxAttributes > 0 && len(element.Attr) > p.opts.MaxAttributes {
+		return p.limitError("MaxAttributes", start, fmt.Sprintf("element <%s> has %d attributes, more than the limit of %d",
+			element.Name.Local, len(element.Attr), p.opts.MaxAttributes))
 	}
 
-	// Copy attributes to avoid referencing the token's memory
-	copy(node.Attrs, element.Attr)
+	// Nodes of streamed documents are dropped once validated, so they are allocated one by
+	// one: a block would be kept as long as any of its nodes is
+	var node *Node
+	if p.onEnd != nil {
+		node = &Node{Attrs: make([]xml.Attr, len(element.Attr))}
+		copy(node.Attrs, element.Attr)
+	} else {
+		node = p.arena.node()
+		node.Attrs = p.arena.attributes(element.Attr) // Copied to avoid referencing the token's memory
+	}
+	node.Parent, node.Name = p.currentNode, element.Name
+	parentScope := emptyNamespaceScope
+	if p.currentNode != nil {
+		parentScope = p.currentNode.scope
+	}
+	node.scope = newNamespaceScope(parentScope, node.Attrs)
+	if p.source != nil {
+		node.Position = p.position(start)
+	}
+	if len(element.Attr) > 0 && p.source != nil {
+		node.AttrPositions = p.attributePositions(start, p.decoder.InputOffset(), len(element.Attr))
+	}
+	if p.opts.Strict {
+		if err := p.checkStartElement(node); err != nil {
+			return err
+		}
+	}
 
 	// Set as root if this is the first element
 	if p.document.Root == nil {
@@ -87,16 +432,91 @@ func (p *xmlParser) handleStartElement(element xml.StartElement) error {
 	if p.currentNode != nil {
 		p.currentNode.Children = append(p.currentNode.Children, node)
 	}
+	if p.preserve {
+		p.appendNode(node)
+	}
 
 	// Move into the new element
 	p.currentNode = node
+	p.textStarts = append(p.textStarts, len(p.text))
 	return nil
 }
 
-// handleCharData processes character data (text content) within an element.
+// limitError returns the parse error of a document exceeding a limit at byte offset start.
+func (p *xmlParser) limitError(limit string, start int64, message string) error {
+	limitErr := &LimitError{Limit: limit, message: message}
+	if p.source != nil {
+		limitErr.Position = p.position(start)
+		limitErr.message += fmt.Sprintf(" (line %d, column %d)", limitErr.Position.Line, limitErr.Position.Column)
+	}
+	return fmt.Errorf("XML parsing error: %w", limitErr)
+}
+
+// handleCharData processes character data (text content) within an element, collecting it
+// until the element ends.
 func (p *xmlParser) handleCharData(data xml.CharData) {
 	if p.currentNode != nil {
-		p.currentNode.Content += string(data)
+		p.text = append(p.text, data...)
+	}
+}
+
+// preserveCharData keeps character data starting at byte offset start as a text node, or a
+// CDATA node if the source shows a CDATA section. Whitespace outside the root is dropped.
+func (p *xmlParser) preserveCharData(data xml.CharData, start int64) {
+	if p.currentNode == nil {
+		return
+	}
+	kind := TextNode
+	if p.source != nil && bytes.HasPrefix(p.tokenSource(), []byte("<![CDATA[")) {
+		kind = CDATANode
+	}
+	p.appendData(kind, xml.Name{}, string(data), start)
+}
+
+// preserveMarkup keeps a comment or processing instruction starting at byte offset start.
+// The XML declaration and directives such as the document type declaration are dropped.
+func (p *xmlParser) preserveMarkup(token xml.Token, start int64) {
+	switch t := token.(type) {
+	case xml.Comment:
+		p.appendData(CommentNode, xml.Name{}, string(t), start)
+	case xml.ProcInst:
+		if t.Target != "xml" {
+			p.appendData(ProcInstNode, xml.Name{Local: t.Target}, string(t.Inst), start)
+		}
+	}
+}
+
+// appendData adds a node of a kind other than element to the current element, or to the
+// document outside the root.
+func (p *xmlParser) appendData(kind NodeKind, name xml.Name, content string, start int64) {
+	node := p.arena.node()
+	node.Kind, node.Name, node.Content, node.Parent = kind, name, content, p.currentNode
+	if p.source != nil {
+		node.Position = p.position(start)
+	}
+	p.appendNode(node)
+}
+
+// appendNode adds a node to the nodes of the current element, or of the document outside
+// the root.
+func (p *xmlParser) appendNode(node *Node) {
+	if p.currentNode != nil {
+		p.currentNode.Nodes = append(p.currentNode.Nodes, node)
+	} else {
+		p.document.Nodes = append(p.document.Nodes, node)
+	}
+}
+
+// endText sets the content of the element being ended to the text collected for it.
+func (p *xmlParser) endText() {
+	if p.currentNode == nil || len(p.textStarts) == 0 {
+		return
+	}
+	start := p.textStarts[len(p.textStarts)-1]
+	p.textStarts = p.textStarts[:len(p.textStarts)-1]
+	if len(p.text) > start {
+		p.currentNode.Content = string(p.text[start:])
+		p.text = p.text[:start]
 	}
 }
 
@@ -106,3 +526,92 @@ func (p *xmlParser) handleEndElement() {
 		p.currentNode = p.currentNode.Parent
 	}
 }
+
+// attributePositions locates the attributes of the start tag spanning the source bytes from
+// offset start to end. The decoder reports attributes in source order, so the n-th attribute
+// found in the raw tag is the n-th entry of the token's Attr. Nil is returned if the tag
+// cannot be matched up.
+func (p *xmlParser) attributePositions(start, end int64, count int) []Position {
+	data, base := p.source.data, p.source.base
+	if start < base || end > base+int64(len(data)) || start >= end {
+		return nil
+	}
+
+	offsets := scanAttributeOffsets(data[start-base : end-base])
+	if len(offsets) != count {
+		return nil
+	}
+
+	var positions []Position
+	if p.onEnd != nil {
+		positions = make([]Position, count)
+	} else {
+		positions = p.arena.positionSlice(count)
+	}
+	for i, offset := range offsets {
+		positions[i] = p.position(start + int64(offset))
+	}
+	return positions
+}
+
+// scanAttributeOffsets returns the offset of each attribute name within a raw start tag.
+func scanAttributeOffsets(tag []byte) []int {
+	var offsets []int
+
+	// Skip "<" and the element name
+	i := 1
+	for i < len(tag) && !isXMLSpace(tag[i]) && tag[i] != '>' && tag[i] != '/' {
+		i++
+	}
+
+	for i < len(tag) {
+		for i < len(tag) && isXMLSpace(tag[i]) {
+			i++
+		}
+		if i >= len(tag) || tag[i] == '>' || tag[i] == '/' {
+			break
+		}
+		offsets = append(offsets, i)
+
+		// Attribute name, "=" with optional whitespace, then the quoted value
+		for i < len(tag) && tag[i] != '=' && !isXMLSpace(tag[i]) {
+			i++
+		}
+		for i < len(tag) && (tag[i] == '=' || isXMLSpace(tag[i])) {
+			i++
+		}
+		if i < len(tag) && (tag[i] == '"' || tag[i] == '\'') {
+			quote := tag[i]
+			i++
+			for i < len(tag) && tag[i] != quote {
+				i++
+			}
+			i++
+		}
+	}
+	return offsets
+}
+
+// isXMLSpace reports whether b is XML whitespace.
+func isXMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// position converts a byte offset in the source to a line and column. Positions are
+// requested in increasing order, so lines and columns are counted on from the previous
+// position, which also keeps long lines such as minified documents linear.
+func (p *xmlParser) position(offset int64) Position {
+	segment := p.source.data[p.lastOffset-p.source.base : offset-p.source.base]
+	line, column := p.lastPosition.Line, p.lastPosition.Column
+	for {
+		i := bytes.IndexByte(segment, '\n')
+		if i < 0 {
+			break
+		}
+		line, column = line+1, 1
+		segment = segment[i+1:]
+	}
+	column += utf8.RuneCount(segment)
+	p.lastOffset, p.lastPosition = offset, Position{Line: line, Column: column}
+	return p.lastPosition
+}
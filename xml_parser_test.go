@@ -0,0 +1,298 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+// Test that ParseReader builds the same tree as Parse, source positions included,
+// while keeping only a bounded part of the input
+func TestParseReader(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\"?>\n<catalog xmlns=\"urn:example:catalog\" version=\"2\">\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&b, "  <item id=\"i%d\"\n        label=\"Ünïcødé %d\"><name>Item &amp; %d</name><note>line one\nline two</note></item>\n", i, i, i)
+	}
+	b.WriteString("</catalog>\n")
+	input := b.String()
+
+	expected, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	source := &sourceWindow{reader: iotest.HalfReader(strings.NewReader(input))}
+	parser := &xmlParser{decoder: xml.NewDecoder(source), source: source, lastPosition: Position{Line: 1, Column: 1}}
+	doc, err := parser.parseDocument()
+	if err != nil {
+		t.Fatalf("ParseReader failed: %v", err)
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Error("Expected ParseReader to build the same tree as Parse")
+	}
+	if last := doc.Root.Children[4999].AttrPositions[1]; last != (Position{Line: 2 + 3*4999 + 2, Column: 9}) {
+		t.Errorf("Unexpected position of the last label attribute: %+v", last)
+	}
+	last := doc.Root.Children[4999]
+	if doc.Root.Position != (Position{Line: 2, Column: 1}) || last.Position != (Position{Line: 2 + 3*4999 + 1, Column: 3}) ||
+		last.Children[0].Position != (Position{Line: 2 + 3*4999 + 2, Column: 30}) {
+		t.Errorf("Unexpected element positions: %+v, %+v, %+v", doc.Root.Position, last.Position, last.Children[0].Position)
+	}
+	if retained := len(source.data); retained > 2*maxRetainedSource {
+		t.Errorf("Expected a bounded part of the input to be retained, got %d of %d bytes", retained, len(input))
+	}
+
+	if _, err := ParseReader(strings.NewReader(input)); err != nil {
+		t.Errorf("ParseReader failed: %v", err)
+	}
+	readErr := errors.New("connection reset")
+	if _, err := ParseReader(iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Errorf("Expected the read error to be returned, got: %v", err)
+	}
+	if _, err := ParseReader(strings.NewReader("<a><b></a>")); err == nil ||
+		!strings.Contains(err.Error(), "XML parsing error") {
+		t.Errorf("Expected a parsing error, got: %v", err)
+	}
+}
+
+// Test that the text of elements with mixed content is collected around their children,
+// and that nodes allocated together do not share attributes
+func TestParseContent(t *testing.T) {
+	doc, err := Parse([]byte(`<p lang="en">Hello <b class="x">bold <i>very</i> text</b>, <b/>world<br/>!</p>`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	root := doc.Root
+	bold, italic := root.Children[0], root.Children[0].Children[0]
+	if root.Content != "Hello , world!" || bold.Content != "bold  text" || italic.Content != "very" ||
+		root.Children[1].Content != "" || root.Children[2].Content != "" {
+		t.Errorf("Unexpected content: %q, %q, %q", root.Content, bold.Content, italic.Content)
+	}
+
+	root.Attrs = append(root.Attrs, xml.Attr{Name: xml.Name{Local: "dir"}, Value: "ltr"})
+	if len(bold.Attrs) != 1 || bold.Attrs[0].Value != "x" {
+		t.Errorf("Expected the attributes of <b> to be kept, got %+v", bold.Attrs)
+	}
+	if italic.Parent != bold || bold.Parent != root || root.Children[2].Parent != root {
+		t.Error("Unexpected parents")
+	}
+}
+
+// Test that comments, processing instructions, CDATA sections and text are kept as nodes
+// with PreserveNodes, and written back as parsed
+func TestParsePreserveNodes(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- generated -->
+<?xml-stylesheet href="a.xsl"?>
+<note id="1">Hi <![CDATA[<b> & ]]]]><![CDATA[>]]><!-- inline --><to>Ann</to><?pi data?> bye</note>
+`
+	doc, err := ParseWithOptions([]byte(input), DocumentOptions{PreserveNodes: true})
+	if err != nil {
+		t.Fatalf("ParseWithOptions failed: %v", err)
+	}
+
+	kinds := func(nodes []*Node) string {
+		var names []string
+		for _, node := range nodes {
+			names = append(names, fmt.Sprintf("%v %s %q", node.Kind, node.Name.Local, node.Content))
+		}
+		return strings.Join(names, ", ")
+	}
+	if actual := kinds(doc.Nodes); actual != `CommentNode  " generated ", ProcInstNode xml-stylesheet "href=\"a.xsl\"", ElementNode note "Hi <b> & ]]> bye"` {
+		t.Errorf("Unexpected document nodes: %s", actual)
+	}
+	root := doc.Root
+	if actual := kinds(root.Nodes); actual != `TextNode  "Hi ", CDATANode  "<b> & ]]", CDATANode  ">", CommentNode  " inline ", `+
+		`ElementNode to "Ann", ProcInstNode pi "data", TextNode  " bye"` {
+		t.Errorf("Unexpected nodes of the root: %s", actual)
+	}
+	if len(root.Children) != 1 || root.Nodes[4] != root.Children[0] || root.Nodes[5].Parent != root ||
+		root.Nodes[1].Position != (Position{Line: 4, Column: 17}) {
+		t.Errorf("Expected the element nodes to be the children, with parents and positions: %+v", root.Nodes)
+	}
+
+	output, err := doc.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(output) != input {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", output, input)
+	}
+	// CDATA sections are split around the "]]>" that would end them
+	root.Nodes[2].Content = "a]]>b"
+	if output, _ := doc.Marshal(); !strings.Contains(string(output), "<![CDATA[a]]]]><![CDATA[>b]]>") {
+		t.Errorf("Expected the CDATA section to be split, got:\n%s", output)
+	}
+	root.Nodes[2].Content = ">"
+
+	streamed, err := ParseReaderWithOptions(strings.NewReader(input), DocumentOptions{PreserveNodes: true})
+	if err != nil || !reflect.DeepEqual(streamed, doc) {
+		t.Errorf("Expected ParseReaderWithOptions to build the same tree, got %v", err)
+	}
+	if plain, _ := Parse([]byte(input)); plain.Nodes != nil || plain.Root.Nodes != nil || plain.Root.Content != root.Content {
+		t.Errorf("Expected Parse to keep elements only, got %+v", plain.Root)
+	}
+}
+
+// Test the limits of DocumentOptions for untrusted documents, with documents parsed from
+// bytes and from a reader
+func TestParseLimits(t *testing.T) {
+	nested := "<a>" + strings.Repeat("<b>", 3) + strings.Repeat("</b>", 3) + "</a>"
+	laughs := `<?xml version="1.0"?>
+<!DOCTYPE lolz [
+  <!ENTITY lol "lol">
+  <!ENTITY lol2 "&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;">
+]>
+<lolz>&lol2;</lolz>`
+
+	tests := []struct {
+		name        string
+		xmlData     string
+		opts        DocumentOptions
+		limit       string // The limit exceeded; empty when the document parses
+		errorString string
+	}{
+		{"depth within the limit", nested, DocumentOptions{MaxDepth: 4}, "", ""},
+		{"too deep", nested, DocumentOptions{MaxDepth: 3}, "MaxDepth",
+			"element <b> is nested 4 elements deep, deeper than the limit of 3 (line 1, column 10)"},
+		{"attributes within the limit", `<a xmlns:p="urn:p" p:x="1" y="2"/>`, DocumentOptions{MaxAttributes: 3}, "", ""},
+		{"too many attributes", `<a><b xmlns:p="urn:p" p:x="1" y="2"/></a>`, DocumentOptions{MaxAttributes: 2}, "MaxAttributes",
+			"element <b> has 3 attributes, more than the limit of 2 (line 1, column 4)"},
+		{"size within the limit", nested, DocumentOptions{MaxBytes: int64(len(nested))}, "", ""},
+		{"too large", nested, DocumentOptions{MaxBytes: int64(len(nested)) - 1}, "MaxBytes",
+			"document is larger than the limit of 27 bytes"},
+		{"document type declaration", `<!DOCTYPE a><a/>`, DocumentOptions{ProhibitDTD: true}, "ProhibitDTD",
+			"document type declarations and other directives are not allowed (line 1, column 1)"},
+		{"document type declaration allowed", `<!DOCTYPE a><a/>`, DocumentOptions{}, "", ""},
+		{"entity expansion", laughs, DocumentOptions{}, "", "invalid character entity &lol2;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, parse := range []func() (*Document, error){
+				func() (*Document, error) { return ParseWithOptions([]byte(tt.xmlData), tt.opts) },
+				func() (*Document, error) {
+					return ParseReaderWithOptions(iotest.OneByteReader(strings.NewReader(tt.xmlData)), tt.opts)
+				},
+			} {
+				_, err := parse()
+				var limitErr *LimitError
+				switch {
+				case tt.errorString == "":
+					if err != nil {
+						t.Errorf("Expected the document to parse, got: %v", err)
+					}
+				case err == nil || !strings.Contains(err.Error(), tt.errorString):
+					t.Errorf("Expected error containing %q, got: %v", tt.errorString, err)
+				case tt.limit != "" && (!errors.As(err, &limitErr) || limitErr.Limit != tt.limit):
+					t.Errorf("Expected a LimitError of %s, got: %#v", tt.limit, err)
+				}
+			}
+		})
+	}
+}
+
+// Test that documents declared in other encodings than UTF-8 are converted, from bytes and
+// from a reader, with source positions counting characters
+func TestParseCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		xmlData     string
+		opts        DocumentOptions
+		content     string // Content of the root element, when the document parses
+		errorString string
+	}{
+		{"ISO-8859-1", "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<p a=\"caf\xe9\">na\xefve <b/></p>", DocumentOptions{}, "naïve ", ""},
+		{"Latin-9 by alias", "<?xml version='1.0' encoding='latin9'?><p a=\"caf\xe9\">\xa4 5 <b/></p>", DocumentOptions{}, "€ 5 ", ""},
+		{"Windows-1252", "<?xml version=\"1.0\" encoding=\"windows-1252\"?><p a=\"caf\xe9\">\x93quoted\x94 <b/></p>", DocumentOptions{}, "“quoted” ", ""},
+		{"UTF-8 declared", `<?xml version="1.0" encoding="UTF-8"?><p a="café">naïve <b/></p>`, DocumentOptions{}, "naïve ", ""},
+		{"no declaration", `<p a="café">naïve <b/></p>`, DocumentOptions{}, "naïve ", ""},
+		{"byte outside US-ASCII", "<?xml version=\"1.0\" encoding=\"US-ASCII\"?><p>caf\xe9</p>", DocumentOptions{}, "",
+			"byte 0xE9 is not a character of encoding 'US-ASCII'"},
+		{"unsupported encoding", `<?xml version="1.0" encoding="Shift_JIS"?><p/>`, DocumentOptions{}, "",
+			"encoding 'Shift_JIS' is not supported"},
+		{"plugged charset reader", `<?xml version="1.0" encoding="x-upper"?><p a="café">naive <b/></p>`, DocumentOptions{
+			CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+				data, err := io.ReadAll(input)
+				return strings.NewReader(strings.Replace(string(data), "naive", "NAIVE", 1)), err
+			},
+		}, "NAIVE ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, parse := range []func() (*Document, error){
+				func() (*Document, error) { return ParseWithOptions([]byte(tt.xmlData), tt.opts) },
+				func() (*Document, error) {
+					return ParseReaderWithOptions(iotest.OneByteReader(strings.NewReader(tt.xmlData)), tt.opts)
+				},
+			} {
+				doc, err := parse()
+				if tt.errorString != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.errorString) {
+						t.Errorf("Expected error containing %q, got: %v", tt.errorString, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Parse failed: %v", err)
+				}
+				b := doc.Root.Children[0]
+				if doc.Root.Content != tt.content || doc.Root.Attrs[0].Value != "café" {
+					t.Errorf("Unexpected content %q and attribute %q", doc.Root.Content, doc.Root.Attrs[0].Value)
+				}
+				if b.Position.Column-doc.Root.Position.Column != utf8.RuneCountInString(`<p a="café">`+tt.content) {
+					t.Errorf("Expected the column of <b> to count characters, got %+v after %+v", b.Position, doc.Root.Position)
+				}
+			}
+		})
+	}
+}
+
+// Test the well-formedness checks of DocumentOptions.Strict, which the same documents pass
+// without it
+func TestParseStrict(t *testing.T) {
+	tests := []struct {
+		name        string
+		xmlData     string
+		line        int // Line of the error; 0 when the document is well-formed
+		errorString string
+	}{
+		{"well-formed", `<a xmlns="urn:a" xmlns:p="urn:p" p:b="1" b="2" xml:lang="en"><p:c/><!-- ok --><?pi ok?></a>`, 0, ""},
+		{"duplicate attribute", "<a>\n<b c=\"1\" c=\"2\"/></a>", 2, "element <b> has attribute 'c' more than once"},
+		{"duplicate namespace declaration", `<a xmlns:p="urn:p" xmlns:p="urn:q"/>`, 1, "element <a> has attribute 'xmlns:p' more than once"},
+		{"duplicate attribute by namespace", `<a xmlns:p="urn:p" xmlns:q="urn:p" p:b="1" q:b="2"/>`, 1,
+			"element <a> has attribute 'b' of namespace 'urn:p' more than once"},
+		{"undeclared element prefix", "<a>\n\n<p:b/></a>", 3, "element <p:b> uses undeclared namespace prefix 'p'"},
+		{"undeclared attribute prefix", `<a><b xmlns:p="urn:p"/><c p:d="1"/></a>`, 1,
+			"attribute 'p:d' in element <c> uses undeclared namespace prefix 'p'"},
+		{"control character in comment", "<a><!-- \x01 --></a>", 1, "illegal character code U+0001 in comment"},
+		{"invalid UTF-8 in processing instruction", "<a>\n<?pi \xff?></a>", 2, "invalid UTF-8 in processing instruction"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.xmlData)); err != nil {
+				t.Fatalf("Expected the document to parse without Strict, got: %v", err)
+			}
+			_, err := ParseWithOptions([]byte(tt.xmlData), DocumentOptions{Strict: true})
+			if tt.errorString == "" {
+				if err != nil {
+					t.Errorf("Expected the document to be well-formed, got: %v", err)
+				}
+				return
+			}
+			var syntaxErr *xml.SyntaxError
+			if err == nil || !strings.Contains(err.Error(), tt.errorString) || !errors.As(err, &syntaxErr) || syntaxErr.Line != tt.line {
+				t.Errorf("Expected a syntax error on line %d containing %q, got: %v", tt.line, tt.errorString, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package xmlparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// textEscaper escapes character data. Attribute values are escaped with xml.EscapeText,
+// which also escapes quotes and whitespace characters that attribute normalization would alter.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+// Marshal serializes the document as XML.
+func (d *Document) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.WriteXML(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes the document as XML, as WriteXML does, and returns the number of bytes
+// written. It makes a Document an io.WriterTo.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	err := d.WriteXML(counter)
+	return counter.n, err
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write writes b to the underlying writer.
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteXML writes the document as XML with an XML declaration. Names use the prefixes
+// declared in the document for their namespaces; namespaces without a declaration, as in
+// documents built in code, are declared where they are used: as the default namespace for
+// elements, and under a generated prefix such as ns1 for attributes.
+// Children of elements without text are indented; the text of mixed content is written
+// before the child elements, since Node does not record how text and elements interleave.
+// Elements whose content was kept with DocumentOptions.PreserveNodes are written as they
+// were parsed instead, text, CDATA sections, comments and processing instructions included,
+// and so are the comments and processing instructions around the root.
+func (d *Document) WriteXML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	if d.Nodes != nil {
+		for _, node := range d.Nodes {
+			if node.Kind == ElementNode {
+				writeNode(bw, node, 0, "")
+			} else {
+				writeData(bw, node)
+			}
+			bw.WriteString("\n")
+		}
+	} else if d.Root != nil {
+		writeNode(bw, d.Root, 0, "")
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// writeData writes a node of a kind other than element.
+func writeData(w *bufio.Writer, node *Node) {
+	switch node.Kind {
+	case TextNode:
+		textEscaper.WriteString(w, node.Content)
+	case CDATANode:
+		// "]]>" ends a section, so it is split across two
+		w.WriteString("<![CDATA[" + strings.ReplaceAll(node.Content, "]]>", "]]]]><![CDATA[>") + "]]>")
+	case CommentNode:
+		w.WriteString("<!--" + node.Content + "-->")
+	case ProcInstNode:
+		if node.Content == "" {
+			w.WriteString("<?" + node.Name.Local + "?>")
+		} else {
+			w.WriteString("<?" + node.Name.Local + " " + node.Content + "?>")
+		}
+	}
+}
+
+// writeNode writes an element and its descendants at the given indentation depth.
+// defaultNamespace is the default namespace in effect in the output at the parent.
+func writeNode(w *bufio.Writer, node *Node, depth int, defaultNamespace string) {
+	for _, attr := range node.Attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			defaultNamespace = attr.Value
+		}
+	}
+
+	name, declaration := node.Name.Local, ""
+	switch {
+	case node.Name.Space == defaultNamespace:
+	case node.Name.Space == "":
+		declaration, defaultNamespace = ` xmlns=""`, ""
+	default:
+		if prefix, ok := declaredPrefix(node, node.Name.Space); ok {
+			name = prefix + ":" + node.Name.Local
+		} else if strings.ContainsAny(node.Name.Space, ":/") {
+			declaration, defaultNamespace = ` xmlns="`+escapeAttr(node.Name.Space)+`"`, node.Name.Space
+		} else {
+			// Undeclared prefixes are kept by the decoder as the namespace; write them back as is
+			name = node.Name.Space + ":" + node.Name.Local
+		}
+	}
+
+	// Qualified attributes need a prefix; namespaces without one get a generated prefix
+	prefixes := make(map[string]string)
+	for _, attr := range node.Attrs {
+		space := attr.Name.Space
+		if _, declared := declaredPrefix(node, space); declared || prefixes[space] != "" ||
+			!strings.ContainsAny(space, ":/") || space == xmlNamespace {
+			continue
+		}
+		prefix := fmt.Sprintf("ns%d", len(prefixes)+1)
+		for n := len(prefixes) + 2; isBoundPrefix(node, prefix); n++ {
+			prefix = fmt.Sprintf("ns%d", n)
+		}
+		prefixes[space] = prefix
+		declaration += ` xmlns:` + prefix + `="` + escapeAttr(space) + `"`
+	}
+
+	w.WriteString("<" + name + declaration)
+	for _, attr := range node.Attrs {
+		attrName := attributeName(node, attr.Name)
+		if prefix := prefixes[attr.Name.Space]; prefix != "" {
+			attrName = prefix + ":" + attr.Name.Local
+		}
+		w.WriteString(" " + attrName + `="` + escapeAttr(attr.Value) + `"`)
+	}
+
+	if node.Nodes != nil {
+		if len(node.Nodes) == 0 {
+			w.WriteString("/>")
+			return
+		}
+		w.WriteString(">")
+		for _, child := range node.Nodes {
+			if child.Kind == ElementNode {
+				writeNode(w, child, depth+1, defaultNamespace)
+			} else {
+				writeData(w, child)
+			}
+		}
+		w.WriteString("</" + name + ">")
+		return
+	}
+
+	text := node.Content
+	if len(node.Children) > 0 && strings.TrimSpace(text) == "" {
+		text = ""
+	}
+	if text == "" && len(node.Children) == 0 {
+		w.WriteString("/>")
+		return
+	}
+
+	w.WriteString(">")
+	textEscaper.WriteString(w, text)
+	for _, child := range node.Children {
+		if text == "" {
+			w.WriteString("\n" + strings.Repeat("  ", depth+1))
+		}
+		writeNode(w, child, depth+1, defaultNamespace)
+	}
+	if text == "" {
+		w.WriteString("\n" + strings.Repeat("  ", depth))
+	}
+	w.WriteString("</" + name + ">")
+}
+
+// attributeName returns the name to write for an attribute of node.
+func attributeName(node *Node, name xml.Name) string {
+	switch name.Space {
+	case "":
+		return name.Local
+	case "xmlns":
+		return "xmlns:" + name.Local
+	case xmlNamespace:
+		return "xml:" + name.Local
+	}
+	if prefix, ok := declaredPrefix(node, name.Space); ok {
+		return prefix + ":" + name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// declaredPrefix returns a non-empty prefix bound to namespace in scope at node.
+func declaredPrefix(node *Node, namespace string) (string, bool) {
+	for ancestor := node; ancestor != nil; ancestor = ancestor.Parent {
+		for _, attr := range ancestor.Attrs {
+			if attr.Name.Space == "xmlns" && attr.Value == namespace {
+				// The prefix may be rebound to another namespace closer to node
+				if bound, _ := node.LookupNamespace(attr.Name.Local); bound == namespace {
+					return attr.Name.Local, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// isBoundPrefix reports whether a prefix is declared in scope at node.
+func isBoundPrefix(node *Node, prefix string) bool {
+	_, bound := node.LookupNamespace(prefix)
+	return bound
+}
+
+// escapeAttr escapes an attribute value.
+func escapeAttr(value string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
@@ -0,0 +1,88 @@
+package xmlparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+// Test serializing documents as XML
+func TestDocumentMarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		expected string
+	}{
+		{
+			name:     "Prefixed namespace",
+			xml:      `<p:root xmlns:p="urn:test" p:id="1"><p:item>a</p:item><p:item/></p:root>`,
+			expected: "<p:root xmlns:p=\"urn:test\" p:id=\"1\">\n  <p:item>a</p:item>\n  <p:item/>\n</p:root>",
+		},
+		{
+			name:     "Default namespace with unqualified child",
+			xml:      `<root xmlns="urn:test"><item xmlns="">a</item></root>`,
+			expected: "<root xmlns=\"urn:test\">\n  <item xmlns=\"\">a</item>\n</root>",
+		},
+		{
+			name:     "Escaping",
+			xml:      `<root note="&quot;a&quot; &amp; b">x &lt; y &amp;&amp; y &gt; z</root>`,
+			expected: `<root note="&#34;a&#34; &amp; b">x &lt; y &amp;&amp; y &gt; z</root>`,
+		},
+		{
+			name:     "Language attribute",
+			xml:      `<root xml:lang="en">text</root>`,
+			expected: `<root xml:lang="en">text</root>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			data, err := doc.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + tt.expected + "\n"
+			if string(data) != expected {
+				t.Errorf("Unexpected output:\n%s\nwant:\n%s", data, expected)
+			}
+			if _, err := Parse(data); err != nil {
+				t.Errorf("Output does not parse: %v", err)
+			}
+		})
+	}
+
+	t.Run("Document built in code", func(t *testing.T) {
+		root := &Node{Name: xml.Name{Space: "urn:test", Local: "root"}}
+		root.Children = []*Node{{Parent: root, Name: xml.Name{Space: "urn:test", Local: "item"}, Content: "a"}}
+		data, err := (&Document{Root: root}).Marshal()
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		reparsed, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Output does not parse: %v\n%s", err, data)
+		}
+		if reparsed.Root.Name != root.Name || reparsed.Root.Children[0].Name != root.Children[0].Name {
+			t.Errorf("Namespaces were not preserved:\n%s", data)
+		}
+	})
+
+	t.Run("WriteTo", func(t *testing.T) {
+		doc, err := Parse([]byte(`<root xmlns="urn:test" id="1"><item>a</item></root>`))
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+		expected, _ := doc.Marshal()
+		var buf bytes.Buffer
+		var writerTo io.WriterTo = doc
+		n, err := writerTo.WriteTo(&buf)
+		if err != nil || n != int64(len(expected)) || buf.String() != string(expected) {
+			t.Errorf("Expected %d bytes of %s, got %d of %s (%v)", len(expected), expected, n, buf.String(), err)
+		}
+	})
+}
@@ -58,9 +58,9 @@ detailed information about all validation failures found in the document:
 
 	if err := schema.Validate(document); err != nil {
 		if validationErr, ok := err.(*xmlparser.ValidationError); ok {
-			fmt.Printf("Found %d validation errors:\n", len(validationErr.Errors))
-			for _, errMsg := range validationErr.Errors {
-				fmt.Printf("- %s\n", errMsg)
+			fmt.Printf("Found %d validation errors:\n", len(validationErr.Issues))
+			for _, issue := range validationErr.Issues {
+				fmt.Printf("- %s [%s]\n", issue.Message, issue.Code)
 			}
 		}
 	}
@@ -72,7 +72,7 @@ This package currently has the following limitations:
 • Limited namespace support (basic functionality only)
 • No support for xs:choice or xs:all content models (only xs:sequence)
 • No support for xs:import or xs:include
-• No support for XML Schema 1.1 features
+• Partial XML Schema 1.1 support, opt-in via ParseOptions.Version (assertions are not evaluated)
 • No support for identity constraints (xs:key, xs:keyref, xs:unique)
 
 For more examples and detailed documentation, see the examples directory
@@ -83,13 +83,14 @@ package xmlparser
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 )
 
 // ParseXSD parses an XSD schema from bytes and returns a Schema ready for validation.
@@ -102,14 +103,31 @@ import (
 //
 // Returns a fully processed schema with all imports and includes resolved.
 func ParseXSD(xsdBytes []byte, basePath ...string) (*Schema, error) {
-	// Determine base path - use current directory if not provided
-	resolvedBasePath := "."
-	if len(basePath) > 0 && basePath[0] != "" {
-		resolvedBasePath = basePath[0]
+	var opts ParseOptions
+	if len(basePath) > 0 {
+		opts.BasePath = basePath[0]
 	}
+	return ParseXSDWithOptions(xsdBytes, opts)
+}
 
-	// Always use the full parsing with import/include support and circular reference protection
-	return parseXSDWithImportsAndTracker(xsdBytes, resolvedBasePath, make(map[string]bool))
+// ParseXSDReader parses an XSD schema read from r like ParseXSD, with an optional base path
+// for resolving relative schemaLocation paths.
+func ParseXSDReader(r io.Reader, basePath ...string) (*Schema, error) {
+	xsdBytes, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	return ParseXSD(xsdBytes, basePath...)
+}
+
+// ParseXSDFile parses the XSD schema in a file like ParseXSD. Relative schemaLocation paths
+// are resolved against the directory of the file.
+func ParseXSDFile(path string) (*Schema, error) {
+	xsdBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %w", err)
+	}
+	return ParseXSDWithOptions(xsdBytes, ParseOptions{BasePath: filepath.Dir(path)})
 }
 
 // parseBasicXSD parses an XSD schema without processing imports/includes.
@@ -127,6 +145,17 @@ func parseBasicXSD(xsdBytes []byte) (*Schema, error) {
 		return nil, fmt.Errorf("failed to decode XSD schema: %w", err)
 	}
 
+	if err := checkOccurrenceAttributes(xsdBytes); err != nil {
+		return nil, fmt.Errorf("invalid occurrence bounds: %w", err)
+	}
+
+	// Remember which namespace each global type and qualified attribute was defined in,
+	// and what each reference names while this document's prefixes are known
+	schema.assignElementForms()
+	schema.assignAttributeForms()
+	schema.resolveReferences()
+	schema.assignComponentNamespace(schema.TargetNamespace)
+
 	if err := schema.buildLookupMaps(); err != nil {
 		return nil, fmt.Errorf("failed to build schema lookup maps: %w", err)
 	}
@@ -138,8 +167,12 @@ func parseBasicXSD(xsdBytes []byte) (*Schema, error) {
 // This optimization avoids linear searches through slices during validation.
 func (s *Schema) buildLookupMaps() error {
 	s.ElementMap = make(map[string]*Element)
+	s.elementNSMap = make(map[xml.Name]*Element)
 	s.ComplexTypeMap = make(map[string]*ComplexType)
 	s.SimpleTypeMap = make(map[string]*SimpleType)
+	s.simpleTypeNSMap = make(map[xml.Name]*SimpleType)
+	s.complexTypeNSMap = make(map[xml.Name]*ComplexType)
+	s.attributeNSMap = make(map[xml.Name]*Attribute)
 
 	// Build element lookup map
 	if err := s.buildElementMap(); err != nil {
@@ -156,6 +189,11 @@ func (s *Schema) buildLookupMaps() error {
 		return err
 	}
 
+	// Build global attribute lookup map
+	if err := s.buildAttributeMap(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -170,6 +208,11 @@ func (s *Schema) buildElementMap() error {
 			return fmt.Errorf("duplicate element definition: '%s'", element.Name)
 		}
 		s.ElementMap[element.Name] = element
+
+		key := componentKey(element.namespace, element.Name)
+		if _, exists := s.elementNSMap[key]; !exists {
+			s.elementNSMap[key] = element
+		}
 	}
 	return nil
 }
@@ -185,6 +228,11 @@ func (s *Schema) buildComplexTypeMap() error {
 			return fmt.Errorf("duplicate complexType definition: '%s'", complexType.Name)
 		}
 		s.ComplexTypeMap[complexType.Name] = complexType
+
+		key := componentKey(complexType.namespace, complexType.Name)
+		if _, exists := s.complexTypeNSMap[key]; !exists {
+			s.complexTypeNSMap[key] = complexType
+		}
 	}
 	return nil
 }
@@ -200,10 +248,68 @@ func (s *Schema) buildSimpleTypeMap() error {
 			return fmt.Errorf("duplicate simpleType definition: '%s'", simpleType.Name)
 		}
 		s.SimpleTypeMap[simpleType.Name] = simpleType
+
+		// Imported types carry a prefix added during merging; index them by local name
+		key := componentKey(simpleType.namespace, simpleType.Name)
+		if _, exists := s.simpleTypeNSMap[key]; !exists {
+			s.simpleTypeNSMap[key] = simpleType
+		}
 	}
 	return nil
 }
 
+// assignComponentNamespace records the namespace of global types, qualified elements and
+// attributes, attribute wildcards, and of the references and derivation bases that have none
+// yet. Components from chameleon includes (no targetNamespace) take on the including schema's
+// namespace.
+func (s *Schema) assignComponentNamespace(namespace string) {
+	adopt := func(name *xml.Name) {
+		if name.Local != "" && name.Space == "" {
+			name.Space = namespace
+		}
+	}
+	s.forEachElement(func(element *Element, global bool) {
+		if element.qualified && element.namespace == "" {
+			element.namespace = namespace
+		}
+		adopt(&element.typeRef)
+		adopt(&element.ref)
+		if element.SimpleType != nil && element.SimpleType.Restriction != nil {
+			adopt(&element.SimpleType.Restriction.baseRef)
+		}
+	})
+	s.forEachAttribute(func(attribute *Attribute, global bool) {
+		if attribute.qualified && attribute.namespace == "" {
+			attribute.namespace = namespace
+		}
+		adopt(&attribute.typeRef)
+		if attribute.SimpleType != nil && attribute.SimpleType.Restriction != nil {
+			adopt(&attribute.SimpleType.Restriction.baseRef)
+		}
+	})
+	s.forEachComplexType(func(complexType *ComplexType) {
+		if wildcard := complexType.declaredWildcard(); wildcard != nil && wildcard.namespace == "" {
+			wildcard.namespace = namespace
+		}
+		if complexType.ComplexContent != nil && complexType.base.Space == "" {
+			complexType.base.Space = namespace
+		}
+	})
+	for i := range s.SimpleTypes {
+		if s.SimpleTypes[i].namespace == "" {
+			s.SimpleTypes[i].namespace = namespace
+		}
+		if restriction := s.SimpleTypes[i].Restriction; restriction != nil {
+			adopt(&restriction.baseRef)
+		}
+	}
+	for i := range s.ComplexTypes {
+		if s.ComplexTypes[i].namespace == "" {
+			s.ComplexTypes[i].namespace = namespace
+		}
+	}
+}
+
 // extractNamespaces parses namespace declarations from the schema root element.
 func (s *Schema) extractNamespaces(xsdBytes []byte) error {
 	s.Xmlns = make(map[string]string)
@@ -237,21 +343,57 @@ func (s *Schema) extractNamespaces(xsdBytes []byte) error {
 
 	// Ensure we have the standard XML Schema namespace
 	if _, exists := s.Xmlns["xs"]; !exists {
-		s.Xmlns["xs"] = "http://www.w3.org/2001/XMLSchema"
+		s.Xmlns["xs"] = xsdNamespace
 	}
 
 	return nil
 }
 
+// schemaLoader carries the state shared while loading a schema and the schemas it references.
+type schemaLoader struct {
+	ctx       context.Context    // Cancels loading; checked before each document is loaded
+	visited   map[string]bool    // Locations being processed, for circular reference detection
+	resolver  Resolver           // Loads a resolved location
+	sources   []SchemaSource     // Every referenced schema document loaded, once per location
+	documents map[string][]byte  // Content of each location in sources
+	schemas   map[string]*Schema // Parsed schema of each location, its references merged
+	tracer    Tracer             // Traces each document loaded; nil when not traced
+}
+
+// newSchemaLoader returns a loader that reads schemas through a resolver, or from the
+// filesystem and the network if it is nil, until ctx is done.
+func newSchemaLoader(ctx context.Context, resolver Resolver) *schemaLoader {
+	if resolver == nil {
+		resolver = defaultResolver{}
+	}
+	return &schemaLoader{ctx: ctx, visited: make(map[string]bool), resolver: resolver,
+		documents: make(map[string][]byte), schemas: make(map[string]*Schema)}
+}
+
+// load reads a resolved location and records it as a source of the schema being parsed.
+// A location referenced by several documents is read once.
+func (l *schemaLoader) load(namespace, location string) ([]byte, error) {
+	if data, ok := l.documents[location]; ok {
+		return data, nil
+	}
+	data, err := loadTraced(l.ctx, l.tracer, l.resolver, namespace, location)
+	if err != nil {
+		return nil, err
+	}
+	l.sources = append(l.sources, SchemaSource{Location: location, Data: data})
+	l.documents[location] = data
+	return data, nil
+}
+
 // parseXSDWithImportsAndTracker is the internal version with circular reference tracking.
-func parseXSDWithImportsAndTracker(xsdBytes []byte, basePath string, visited map[string]bool) (*Schema, error) {
+func parseXSDWithImportsAndTracker(xsdBytes []byte, basePath string, loader *schemaLoader) (*Schema, error) {
 	schema, err := parseBasicXSD(xsdBytes)
 	if err != nil {
 		return nil, err
 	}
 
 	// Process imports and includes with circular reference detection
-	if err := schema.processImportsAndIncludesWithTracker(basePath, visited); err != nil {
+	if err := schema.processImportsAndIncludesWithTracker(basePath, loader); err != nil {
 		return nil, fmt.Errorf("failed to process imports and includes: %w", err)
 	}
 
@@ -260,27 +402,33 @@ func parseXSDWithImportsAndTracker(xsdBytes []byte, basePath string, visited map
 		return nil, fmt.Errorf("failed to rebuild lookup maps after import/include processing: %w", err)
 	}
 
+	// Attribute references are resolved with this document's prefixes once the global
+	// attributes of referenced schemas are known
+	schema.resolveAttributeRefs()
+
 	return schema, nil
 }
 
 // processImportsAndIncludes loads and merges all external schemas referenced by xs:import and xs:include.
 func (s *Schema) processImportsAndIncludes(basePath string) error {
-	return s.processImportsAndIncludesWithTracker(basePath, make(map[string]bool))
+	return s.processImportsAndIncludesWithTracker(basePath, newSchemaLoader(context.Background(), nil))
 }
 
 // processImportsAndIncludesWithTracker loads and merges all external schemas with circular reference detection.
-func (s *Schema) processImportsAndIncludesWithTracker(basePath string, visited map[string]bool) error {
+// basePath is the base URI of the schema document: its directory, or its URL when it was
+// retrieved over http(s). Each referenced document is processed with its own base URI.
+func (s *Schema) processImportsAndIncludesWithTracker(basePath string, loader *schemaLoader) error {
 	// Process includes first (same namespace)
 	for _, include := range s.Includes {
-		if err := s.processIncludeWithTracker(include, basePath, visited); err != nil {
-			return fmt.Errorf("failed to process include '%s': %w", include.SchemaLocation, err)
+		if err := s.processIncludeWithTracker(include, basePath, loader); err != nil {
+			return fmt.Errorf("failed to process include '%s' relative to '%s': %w", include.SchemaLocation, basePath, err)
 		}
 	}
 
 	// Process imports (different namespaces)
 	for _, imp := range s.Imports {
-		if err := s.processImportWithTracker(imp, basePath, visited); err != nil {
-			return fmt.Errorf("failed to process import '%s': %w", imp.SchemaLocation, err)
+		if err := s.processImportWithTracker(imp, basePath, loader); err != nil {
+			return fmt.Errorf("failed to process import '%s' relative to '%s': %w", imp.SchemaLocation, basePath, err)
 		}
 	}
 
@@ -289,145 +437,127 @@ func (s *Schema) processImportsAndIncludesWithTracker(basePath string, visited m
 
 // processInclude loads and merges an included schema (same namespace).
 func (s *Schema) processInclude(include Include, basePath string) error {
-	return s.processIncludeWithTracker(include, basePath, make(map[string]bool))
+	return s.processIncludeWithTracker(include, basePath, newSchemaLoader(context.Background(), nil))
 }
 
 // processIncludeWithTracker loads and merges an included schema with circular reference detection.
-func (s *Schema) processIncludeWithTracker(include Include, basePath string, visited map[string]bool) error {
+func (s *Schema) processIncludeWithTracker(include Include, basePath string, loader *schemaLoader) error {
 	if include.SchemaLocation == "" {
 		return fmt.Errorf("include element is missing schemaLocation attribute")
 	}
 
-	// Create absolute path for circular reference detection
-	includedSchemaPath := include.SchemaLocation
-	if !filepath.IsAbs(includedSchemaPath) && basePath != "" {
-		includedSchemaPath = filepath.Join(basePath, include.SchemaLocation)
-	}
-
-	// Clean the path to ensure consistent comparison
-	cleanPath, err := filepath.Abs(includedSchemaPath)
-	if err != nil {
-		cleanPath = includedSchemaPath
-	}
-
-	// Check for circular reference
-	if visited[cleanPath] {
-		return fmt.Errorf("circular reference detected: schema '%s' already being processed", cleanPath)
-	}
-
-	// Mark this schema as being processed
-	visited[cleanPath] = true
-	defer delete(visited, cleanPath)
-
-	schemaBytes, err := loadSchema(include.SchemaLocation, basePath)
+	includedSchema, err := loadReferencedSchema(include.SchemaLocation, "", basePath, "included", loader)
 	if err != nil {
 		return err
 	}
 
-	// Use parseXSDWithImportsAndTracker to handle any nested imports/includes consistently
-	includedBasePath := filepath.Dir(includedSchemaPath)
-	includedSchema, err := parseXSDWithImportsAndTracker(schemaBytes, includedBasePath, visited)
-	if err != nil {
-		return fmt.Errorf("failed to parse included schema: %w", err)
-	}
+	// Chameleon includes adopt the namespace of the including schema
+	includedSchema.assignComponentNamespace(s.TargetNamespace)
 
 	// Merge elements, types from included schema (which now includes all nested imports/includes)
 	s.Elements = append(s.Elements, includedSchema.Elements...)
 	s.ComplexTypes = append(s.ComplexTypes, includedSchema.ComplexTypes...)
 	s.SimpleTypes = append(s.SimpleTypes, includedSchema.SimpleTypes...)
+	s.Attributes = append(s.Attributes, includedSchema.Attributes...)
 
 	return nil
 }
 
 // processImport loads and merges an imported schema (different namespace).
 func (s *Schema) processImport(imp Import, basePath string) error {
-	return s.processImportWithTracker(imp, basePath, make(map[string]bool))
+	return s.processImportWithTracker(imp, basePath, newSchemaLoader(context.Background(), nil))
 }
 
 // processImportWithTracker loads and merges an imported schema with circular reference detection.
-func (s *Schema) processImportWithTracker(imp Import, basePath string, visited map[string]bool) error {
+func (s *Schema) processImportWithTracker(imp Import, basePath string, loader *schemaLoader) error {
 	if imp.SchemaLocation == "" {
 		// Import without schemaLocation is allowed for built-in namespaces
 		return nil
 	}
 
-	// Create absolute path for circular reference detection
-	importedSchemaPath := imp.SchemaLocation
-	if !filepath.IsAbs(importedSchemaPath) && basePath != "" {
-		importedSchemaPath = filepath.Join(basePath, imp.SchemaLocation)
+	importedSchema, err := loadReferencedSchema(imp.SchemaLocation, imp.Namespace, basePath, "imported", loader)
+	if err != nil {
+		return err
+	}
+
+	// Verify namespace consistency
+	if imp.Namespace != "" && importedSchema.TargetNamespace != imp.Namespace {
+		return fmt.Errorf("imported schema target namespace '%s' does not match expected namespace '%s'",
+			importedSchema.TargetNamespace, imp.Namespace)
 	}
 
-	// Clean the path to ensure consistent comparison
-	cleanPath, err := filepath.Abs(importedSchemaPath)
+	s.mergeImportedSchema(importedSchema)
+	return nil
+}
+
+// loadReferencedSchema resolves a schemaLocation against basePath, then loads and parses the
+// referenced schema together with its own imports and includes. namespace is that of an
+// import, passed on to the resolver; kind ("included" or "imported") is used in error messages.
+func loadReferencedSchema(schemaLocation, namespace, basePath, kind string, loader *schemaLoader) (*Schema, error) {
+	location, err := resolveSchemaLocation(schemaLocation, basePath)
 	if err != nil {
-		cleanPath = importedSchemaPath
+		return nil, err
 	}
 
 	// Check for circular reference
-	if visited[cleanPath] {
-		return fmt.Errorf("circular reference detected: schema '%s' already being processed", cleanPath)
+	key := locationKey(location)
+	if loader.visited[key] {
+		return nil, fmt.Errorf("circular reference detected: schema '%s' already being processed", key)
+	}
+	if schema, ok := loader.schemas[key]; ok {
+		return schema, nil
 	}
 
 	// Mark this schema as being processed
-	visited[cleanPath] = true
-	defer delete(visited, cleanPath)
+	loader.visited[key] = true
+	defer delete(loader.visited, key)
 
-	schemaBytes, err := loadSchema(imp.SchemaLocation, basePath)
+	schemaBytes, err := loader.load(namespace, location)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Use parseXSDWithImportsAndTracker to handle any nested imports/includes consistently
-	importedBasePath := filepath.Dir(importedSchemaPath)
-	importedSchema, err := parseXSDWithImportsAndTracker(schemaBytes, importedBasePath, visited)
+	schema, err := parseXSDWithImportsAndTracker(schemaBytes, locationBase(location), loader)
 	if err != nil {
-		return fmt.Errorf("failed to parse imported schema: %w", err)
-	}
-
-	// Verify namespace consistency
-	if imp.Namespace != "" && importedSchema.TargetNamespace != imp.Namespace {
-		return fmt.Errorf("imported schema target namespace '%s' does not match expected namespace '%s'",
-			importedSchema.TargetNamespace, imp.Namespace)
+		return nil, fmt.Errorf("failed to parse %s schema: %w", kind, err)
 	}
 
-	// Add namespace prefix for imported elements/types if needed
-	prefix := s.getNamespacePrefix(imp.Namespace)
-	if prefix != "" {
-		s.mergeImportedSchemaWithPrefix(importedSchema, prefix)
-	} else {
-		// Merge directly if no prefix needed
-		s.Elements = append(s.Elements, importedSchema.Elements...)
-		s.ComplexTypes = append(s.ComplexTypes, importedSchema.ComplexTypes...)
-		s.SimpleTypes = append(s.SimpleTypes, importedSchema.SimpleTypes...)
+	// A schema without target namespace takes on that of each schema including it, so it
+	// is parsed again for each
+	if schema.TargetNamespace != "" {
+		loader.schemas[key] = schema
 	}
-
-	return nil
+	return schema, nil
 }
 
-// loadSchema loads schema content from a file path or URL.
-func loadSchema(schemaLocation, basePath string) ([]byte, error) {
+// defaultHTTPClient fetches remote schemas when ParseOptions.HTTPClient is not set. Unlike
+// http.DefaultClient it bounds each fetch, so an unresponsive server cannot stall parsing.
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// loadSchema loads schema content from a resolved file path or URL, fetching URLs with
+// client; ctx bounds the fetch.
+func loadSchema(ctx context.Context, client *http.Client, location string) ([]byte, error) {
 	// Handle absolute URLs
-	if strings.HasPrefix(schemaLocation, "http://") || strings.HasPrefix(schemaLocation, "https://") {
-		resp, err := http.Get(schemaLocation)
+	if isRemoteLocation(location) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch schema from URL '%s': %w", location, err)
+		}
+		resp, err := client.Do(request)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch schema from URL '%s': %w", schemaLocation, err)
+			return nil, fmt.Errorf("failed to fetch schema from URL '%s': %w", location, err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to fetch schema from URL '%s': HTTP %d", schemaLocation, resp.StatusCode)
+			return nil, fmt.Errorf("failed to fetch schema from URL '%s': HTTP %d", location, resp.StatusCode)
 		}
 
 		return io.ReadAll(resp.Body)
 	}
 
-	// Handle file paths
-	path := schemaLocation
-	if !filepath.IsAbs(path) && basePath != "" {
-		path = filepath.Join(basePath, schemaLocation)
-	}
-
-	return os.ReadFile(path)
+	return os.ReadFile(location)
 }
 
 // getNamespacePrefix returns the prefix used for a given namespace.
@@ -442,23 +572,73 @@ func (s *Schema) getNamespacePrefix(namespace string) string {
 	return ""
 }
 
-// mergeImportedSchemaWithPrefix merges an imported schema, adding namespace prefixes to names.
-func (s *Schema) mergeImportedSchemaWithPrefix(importedSchema *Schema, prefix string) {
-	// Add prefix to element names and merge
+// mergeImportedSchema merges the global components of an imported schema, including those
+// it merged from its own imports. Components are keyed by their own namespace and local
+// name: one already merged, as when two imported schemas import a third, is merged once.
+// Each is named with the prefix this schema binds to its namespace, or else keeps the
+// name it was merged under, so that names are prefixed once whatever the depth of imports.
+func (s *Schema) mergeImportedSchema(importedSchema *Schema) {
+	elements := make(map[xml.Name]bool, len(s.Elements))
+	for i := range s.Elements {
+		elements[componentKey(s.Elements[i].namespace, s.Elements[i].Name)] = true
+	}
 	for _, element := range importedSchema.Elements {
-		element.Name = prefix + ":" + element.Name
-		s.Elements = append(s.Elements, element)
+		if key := componentKey(element.namespace, element.Name); !elements[key] {
+			elements[key] = true
+			element.Name = s.importedName(element.namespace, element.Name)
+			s.Elements = append(s.Elements, element)
+		}
 	}
 
-	// Add prefix to complex type names and merge
+	complexTypes := make(map[xml.Name]bool, len(s.ComplexTypes))
+	for i := range s.ComplexTypes {
+		complexTypes[componentKey(s.ComplexTypes[i].namespace, s.ComplexTypes[i].Name)] = true
+	}
 	for _, complexType := range importedSchema.ComplexTypes {
-		complexType.Name = prefix + ":" + complexType.Name
-		s.ComplexTypes = append(s.ComplexTypes, complexType)
+		if key := componentKey(complexType.namespace, complexType.Name); !complexTypes[key] {
+			complexTypes[key] = true
+			complexType.Name = s.importedName(complexType.namespace, complexType.Name)
+			s.ComplexTypes = append(s.ComplexTypes, complexType)
+		}
 	}
 
-	// Add prefix to simple type names and merge
+	simpleTypes := make(map[xml.Name]bool, len(s.SimpleTypes))
+	for i := range s.SimpleTypes {
+		simpleTypes[componentKey(s.SimpleTypes[i].namespace, s.SimpleTypes[i].Name)] = true
+	}
 	for _, simpleType := range importedSchema.SimpleTypes {
-		simpleType.Name = prefix + ":" + simpleType.Name
-		s.SimpleTypes = append(s.SimpleTypes, simpleType)
+		if key := componentKey(simpleType.namespace, simpleType.Name); !simpleTypes[key] {
+			simpleTypes[key] = true
+			simpleType.Name = s.importedName(simpleType.namespace, simpleType.Name)
+			s.SimpleTypes = append(s.SimpleTypes, simpleType)
+		}
+	}
+
+	// Attribute names are never prefixed
+	attributes := make(map[xml.Name]bool, len(s.Attributes))
+	for i := range s.Attributes {
+		attributes[xml.Name{Space: s.Attributes[i].namespace, Local: s.Attributes[i].Name}] = true
+	}
+	for _, attribute := range importedSchema.Attributes {
+		if key := (xml.Name{Space: attribute.namespace, Local: attribute.Name}); !attributes[key] {
+			attributes[key] = true
+			s.Attributes = append(s.Attributes, attribute)
+		}
+	}
+}
+
+// componentKey returns the namespace and local name of a global component, whatever
+// prefix it was merged under.
+func componentKey(namespace, name string) xml.Name {
+	return xml.Name{Space: namespace, Local: ParseQName(name).LocalName}
+}
+
+// importedName returns the name an imported component in namespace is merged under: its
+// local name with the prefix this schema binds to the namespace, or name as it is when
+// this schema binds none.
+func (s *Schema) importedName(namespace, name string) string {
+	if prefix := s.getNamespacePrefix(namespace); prefix != "" {
+		return prefix + ":" + ParseQName(name).LocalName
 	}
+	return name
 }
@@ -0,0 +1,77 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// isXsiAttribute reports whether an attribute is in the XMLSchema-instance namespace.
+// These attributes control validation itself and are never declared in schemas.
+func isXsiAttribute(attr xml.Attr) bool {
+	return attr.Name.Space == xsiNamespace
+}
+
+// validateXsiAttributes checks the XMLSchema-instance attributes of a node. xsi:type and
+// xsi:nil are applied by validateElement; xsi:schemaLocation and xsi:noNamespaceSchemaLocation
+// are hints for locating schemas, which are accepted and ignored since the schema is given.
+func validateXsiAttributes(node *Node) []string {
+	var errors []string
+	for i, attr := range node.Attrs {
+		if !isXsiAttribute(attr) {
+			continue
+		}
+		switch attr.Name.Local {
+		case "type", "nil", "noNamespaceSchemaLocation":
+		case "schemaLocation":
+			if len(strings.Fields(attr.Value))%2 != 0 {
+				errors = append(errors, fmt.Sprintf("%s must list pairs of a namespace and a schema location, but got '%s'",
+					attributeLocation(node, i), excerpt(attr.Value)))
+			}
+		default:
+			errors = append(errors, fmt.Sprintf("unexpected %s: the XMLSchema-instance namespace has no such attribute",
+				attributeLocation(node, i)))
+		}
+	}
+	return errors
+}
+
+// xsiNil returns whether a node carries xsi:nil="true". Invalid values are reported and
+// treated as false.
+func xsiNil(node *Node) (bool, []string) {
+	for i, attr := range node.Attrs {
+		if !isXsiAttribute(attr) || attr.Name.Local != "nil" {
+			continue
+		}
+		switch strings.TrimSpace(attr.Value) {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		default:
+			return false, []string{fmt.Sprintf("%s must be a boolean, but got '%s'", attributeLocation(node, i), excerpt(attr.Value))}
+		}
+	}
+	return false, nil
+}
+
+// validateNil checks an element with xsi:nil="true": its declaration must be nillable and
+// the element must have no text or child elements. Attributes are validated as usual.
+func (v *validator) validateNil(node *Node, def *Element) []string {
+	if !def.Nillable {
+		return []string{fmt.Sprintf("element <%s> has xsi:nil=\"true\", but its declaration is not nillable", node.Name.Local)}
+	}
+
+	var errors []string
+	if len(node.Children) > 0 {
+		errors = append(errors, fmt.Sprintf("element <%s> has xsi:nil=\"true\" and must be empty, but found child element <%s>",
+			node.Name.Local, node.Children[0].Name.Local))
+	} else if strings.TrimSpace(node.Content) != "" {
+		errors = append(errors, fmt.Sprintf("element <%s> has xsi:nil=\"true\" and must be empty, but contains text '%s'",
+			node.Name.Local, v.contentExcerpt(node, def)))
+	}
+	if complexType := v.getComplexType(def); complexType != nil {
+		errors = append(errors, v.validateAttributes(node, complexType)...)
+	}
+	return errors
+}
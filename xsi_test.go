@@ -0,0 +1,116 @@
+package xmlparser
+
+import "testing"
+
+// Test the handling of XMLSchema-instance attributes on instance elements
+func TestXsiAttributes(t *testing.T) {
+	schema, err := ParseXSD([]byte(`
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:test" elementFormDefault="qualified">
+    <xs:element name="person">
+        <xs:complexType>
+            <xs:sequence>
+                <xs:element name="name" type="xs:string"/>
+                <xs:element name="birthDate" type="xs:date" nillable="true"/>
+                <xs:element name="email" type="xs:string" minOccurs="0"/>
+                <xs:element name="address" nillable="true" minOccurs="0">
+                    <xs:complexType>
+                        <xs:sequence>
+                            <xs:element name="city" type="xs:string"/>
+                        </xs:sequence>
+                        <xs:attribute name="kind" type="xs:string" use="required"/>
+                    </xs:complexType>
+                </xs:element>
+            </xs:sequence>
+        </xs:complexType>
+    </xs:element>
+</xs:schema>`))
+	if err != nil {
+		t.Fatalf("Failed to parse XSD: %v", err)
+	}
+
+	const open = `<person xmlns="urn:test" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"`
+	tests := []struct {
+		name        string
+		xml         string
+		shouldPass  bool
+		errorString string
+	}{
+		{
+			name:       "Schema location hints",
+			xml:        open + ` xsi:schemaLocation="urn:test person.xsd"><name>A</name><birthDate>2000-01-01</birthDate></person>`,
+			shouldPass: true,
+		},
+		{
+			name:       "No-namespace schema location hint",
+			xml:        open + ` xsi:noNamespaceSchemaLocation="person.xsd"><name>A</name><birthDate>2000-01-01</birthDate></person>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Nil simple element",
+			xml:        open + `><name>A</name><birthDate xsi:nil="true"/></person>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Nil complex element keeps its attributes",
+			xml:        open + `><name>A</name><birthDate xsi:nil="1"/><address xsi:nil="true" kind="home"/></person>`,
+			shouldPass: true,
+		},
+		{
+			name:       "Explicitly not nil",
+			xml:        open + `><name>A</name><birthDate xsi:nil="false">2000-01-01</birthDate></person>`,
+			shouldPass: true,
+		},
+		{
+			name:        "Nil on a non-nillable element",
+			xml:         open + `><name xsi:nil="true"/><birthDate>2000-01-01</birthDate></person>`,
+			errorString: `element <name> has xsi:nil="true", but its declaration is not nillable`,
+		},
+		{
+			name:        "Nil element with content",
+			xml:         open + `><name>A</name><birthDate xsi:nil="true">2000-01-01</birthDate></person>`,
+			errorString: `element <birthDate> has xsi:nil="true" and must be empty, but contains text '2000-01-01'`,
+		},
+		{
+			name:        "Nil element with children",
+			xml:         open + `><name>A</name><birthDate xsi:nil="true"/><address xsi:nil="true" kind="home"><city>X</city></address></person>`,
+			errorString: "must be empty, but found child element <city>",
+		},
+		{
+			name:        "Nil element missing a required attribute",
+			xml:         open + `><name>A</name><birthDate xsi:nil="true"/><address xsi:nil="true"/></person>`,
+			errorString: "required attribute 'kind' is missing from element <address>",
+		},
+		{
+			name:        "Invalid nil value",
+			xml:         open + `><name>A</name><birthDate xsi:nil="yes">2000-01-01</birthDate></person>`,
+			errorString: "attribute 'nil' in element <birthDate> (line 1, column 105) must be a boolean, but got 'yes'",
+		},
+		{
+			name:        "Odd schema location list",
+			xml:         open + ` xsi:schemaLocation="urn:test"><name>A</name><birthDate>2000-01-01</birthDate></person>`,
+			errorString: "must list pairs of a namespace and a schema location, but got 'urn:test'",
+		},
+		{
+			name:        "Unknown instance attribute",
+			xml:         open + ` xsi:nill="true"><name>A</name><birthDate>2000-01-01</birthDate></person>`,
+			errorString: "unexpected attribute 'nill' in element <person> (line 1, column 80): the XMLSchema-instance namespace has no such attribute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Failed to parse XML: %v", err)
+			}
+			err = schema.Validate(doc)
+			if tt.shouldPass {
+				if err != nil {
+					t.Errorf("Expected validation to pass, got: %v", err)
+				}
+				return
+			}
+			expectValidationError(t, err, tt.errorString)
+		})
+	}
+}
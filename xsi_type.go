@@ -0,0 +1,158 @@
+package xmlparser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// builtInBaseTypes maps each derived built-in type to the type it restricts.
+// Types not listed derive directly from xs:anySimpleType.
+var builtInBaseTypes = map[string]string{
+	"xs:integer":            "xs:decimal",
+	"xs:long":               "xs:integer",
+	"xs:int":                "xs:long",
+	"xs:short":              "xs:int",
+	"xs:byte":               "xs:short",
+	"xs:nonNegativeInteger": "xs:integer",
+	"xs:positiveInteger":    "xs:nonNegativeInteger",
+	"xs:unsignedLong":       "xs:nonNegativeInteger",
+	"xs:unsignedInt":        "xs:unsignedLong",
+	"xs:unsignedShort":      "xs:unsignedInt",
+	"xs:unsignedByte":       "xs:unsignedShort",
+	"xs:nonPositiveInteger": "xs:integer",
+	"xs:negativeInteger":    "xs:nonPositiveInteger",
+	"xs:normalizedString":   "xs:string",
+	"xs:token":              "xs:normalizedString",
+	"xs:language":           "xs:token",
+	"xs:NMTOKEN":            "xs:token",
+	"xs:Name":               "xs:token",
+	"xs:NCName":             "xs:Name",
+	"xs:ID":                 "xs:NCName",
+	"xs:IDREF":              "xs:NCName",
+	"xs:ENTITY":             "xs:NCName",
+	"xs:dateTimeStamp":      "xs:dateTime",
+	"xs:dayTimeDuration":    "xs:duration",
+	"xs:yearMonthDuration":  "xs:duration",
+}
+
+// primitiveTypes lists the built-in types that are not derived from another built-in type.
+var primitiveTypes = map[string]bool{
+	"xs:anyType": true, "xs:anySimpleType": true, "xs:anyAtomicType": true,
+	"xs:string": true, "xs:boolean": true, "xs:decimal": true, "xs:float": true, "xs:double": true,
+	"xs:duration": true, "xs:dateTime": true, "xs:time": true, "xs:date": true,
+	"xs:gYearMonth": true, "xs:gYear": true, "xs:gMonthDay": true, "xs:gDay": true, "xs:gMonth": true,
+	"xs:hexBinary": true, "xs:base64Binary": true, "xs:anyURI": true, "xs:QName": true, "xs:NOTATION": true,
+	"xs:IDREFS": true, "xs:ENTITIES": true, "xs:NMTOKENS": true,
+}
+
+// isBuiltInType reports whether typeName (with the "xs:" prefix) names a built-in type.
+func isBuiltInType(typeName string) bool {
+	return primitiveTypes[typeName] || builtInBaseTypes[typeName] != ""
+}
+
+// xsiType returns the value of the xsi:type attribute of a node, if present.
+func xsiType(node *Node) (string, bool) {
+	for _, attr := range node.Attrs {
+		if isXsiTypeAttribute(attr) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// isXsiTypeAttribute reports whether an attribute is xsi:type.
+func isXsiTypeAttribute(attr xml.Attr) bool {
+	return attr.Name.Space == xsiNamespace && attr.Name.Local == "type"
+}
+
+// resolveXsiType returns the declaration to validate a node against when it carries an
+// xsi:type attribute: a copy of def whose type is the type named by xsi:type. The QName is
+// resolved with the namespace bindings in scope at the node, so documents that rebind the
+// default namespace or reuse prefixes within a subtree resolve to the right type. The named
+// type must be the declared type or derived from it: complex types by extension or
+// restriction, simple types by restriction. Elements declared without a type (xs:anyType)
+// accept any type.
+func (v *validator) resolveXsiType(node *Node, def *Element, value string) (*Element, []string) {
+	name, err := node.ResolveQName(value)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("invalid xsi:type on element <%s>: %v", node.Name.Local, err)}
+	}
+
+	substituted := &Element{Name: def.Name, MinOccurs: def.MinOccurs, MaxOccurs: def.MaxOccurs, Nillable: def.Nillable}
+	switch {
+	case name.Space == xsdNamespace && isBuiltInType("xs:"+name.Local):
+		substituted.Type = "xs:" + name.Local
+	case v.complexTypeNSMap[name] != nil:
+		substituted.ComplexType = v.complexTypeNSMap[name]
+	case v.simpleTypeNSMap[name] != nil:
+		substituted.SimpleType = v.simpleTypeNSMap[name]
+	default:
+		return nil, []string{fmt.Sprintf("xsi:type '%s' on element <%s> does not resolve to a type in the schema",
+			excerpt(value), node.Name.Local)}
+	}
+
+	if !v.derivesFrom(substituted, def) {
+		return nil, []string{fmt.Sprintf("xsi:type '%s' on element <%s> is not derived from its declared type '%s'",
+			excerpt(value), node.Name.Local, declaredTypeName(def))}
+	}
+	return substituted, nil
+}
+
+// derivesFrom reports whether the type of derived may replace the type declared by def.
+func (v *validator) derivesFrom(derived, def *Element) bool {
+	declaredComplex := v.getComplexType(def)
+	declaredSimple, _ := v.findSimpleType(def)
+
+	// Elements without a declared type are xs:anyType
+	if declaredComplex == nil && declaredSimple == nil && (def.Type == "" || def.Type == "xs:anyType") {
+		return true
+	}
+
+	// Complex types qualify when derived from the declared type by extension or restriction
+	if derived.ComplexType != nil {
+		return declaredComplex != nil && v.derivesFromComplex(derived.ComplexType, declaredComplex)
+	}
+	if declaredComplex != nil {
+		return false
+	}
+	if def.Type == "xs:anySimpleType" {
+		return true
+	}
+
+	// Walk the restriction chain of the derived simple type towards the built-in types
+	simpleType, typeName := derived.SimpleType, derived.Type
+	for depth := 0; depth < maxDerivationDepth; depth++ {
+		if simpleType != nil {
+			if simpleType == declaredSimple {
+				return true
+			}
+			if simpleType.Restriction == nil {
+				return false
+			}
+			typeName = simpleType.Restriction.Base
+			simpleType = v.restrictionBase(simpleType.Restriction)
+			continue
+		}
+
+		if typeName == "" {
+			return false
+		}
+		if declaredSimple == nil && typeName == def.Type {
+			return true
+		}
+		typeName = builtInBaseTypes[typeName]
+	}
+	return false
+}
+
+// declaredTypeName describes the declared type of an element for error messages.
+func declaredTypeName(def *Element) string {
+	switch {
+	case def.Type != "":
+		return def.Type
+	case def.ComplexType != nil || def.SimpleType != nil:
+		return "(anonymous)"
+	default:
+		return "xs:anyType"
+	}
+}